@@ -49,6 +49,7 @@ func createMsg(d didcomm.MsgInit) Msg {
 		ID:     d.ID,
 		Ready:  d.Ready,
 		Msg:    d.Msg,
+		Info:   d.Info,
 	}
 	return m
 }
@@ -89,6 +90,10 @@ func (m *msgImpl) Ready() bool {
 	return m.Msg.Ready
 }
 
+func (m *msgImpl) Info() string {
+	return m.Msg.Info
+}
+
 func (m *msgImpl) FieldObj() interface{} {
 	return m.Msg
 }
@@ -102,6 +107,7 @@ type Msg struct {
 	ID    string                 `json:"id,omitempty"`    // Used for transferring additional ID like the Cred Def ID
 	Ready bool                   `json:"ready,omitempty"` // In queries tells if something is ready when true
 	Msg   map[string]interface{} `json:"msg,omitempty"`   // Forwarded message
+	Info  string                 `json:"info,omitempty"`  // Additional info from the controller, e.g. an answer
 }
 
 func newMsg(data []byte) *msgImpl {
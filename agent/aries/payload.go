@@ -11,11 +11,14 @@ package aries
 
 import (
 	"encoding/gob"
+	"sort"
+	"strings"
 
 	"github.com/findy-network/findy-agent/agent/didcomm"
 	"github.com/findy-network/findy-agent/agent/pltype"
 	"github.com/findy-network/findy-agent/std/decorator"
 	"github.com/findy-network/findy-common-go/dto"
+	"github.com/golang/glog"
 )
 
 var PayloadCreator = PayloadFactor{}
@@ -36,6 +39,24 @@ func (f *Factor) Add(t string, factor didcomm.Factor) {
 	f.factors[t] = factor
 }
 
+// Versions returns the sorted versions of the Aries protocol family, which
+// have registered message types.
+func (f *Factor) Versions(family string) []string {
+	found := make(map[string]bool)
+	for t := range f.factors {
+		isAries := strings.HasPrefix(t, pltype.Aries) || strings.HasPrefix(t, pltype.DIDOrgAries)
+		if isAries && ProtocolForType(t) == family {
+			found[ProtocolVersionForType(t)] = true
+		}
+	}
+	versions := make([]string, 0, len(found))
+	for v := range found {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
 type PayloadFactor struct{}
 
 // NewFromData creates a new Aries PL in correct Go struct type. If @Type is
@@ -45,12 +66,22 @@ func (f PayloadFactor) NewFromData(data []byte) didcomm.Payload {
 	pl := &PayloadImpl{MessageHdr: newMsg(data)}
 	t, id := pl.Type(), pl.ID()
 
+	// the generic decorators are not in the message types. The error of
+	// them is kept, and the PSM rejects the message.
+	d, err := decorator.ParseDecorators(data)
+	if err != nil {
+		glog.Warningf("message %s has invalid decorators: %v", id, err)
+	}
+
 	factor, ok := Creator.factors[pl.Type()]
 	if !ok {
+		pl.Decor, pl.decorErr = d, err
 		return pl
 	}
 	m := factor.NewMessage(data)
-	return f.NewMsg(id, t, m)
+	pl = f.NewMsg(id, t, m).(*PayloadImpl)
+	pl.Decor, pl.decorErr = d, err
+	return pl
 }
 
 // New creates a new Aries PL with PayloadInit struct. The type of the Msg is
@@ -72,6 +103,13 @@ func (f PayloadFactor) NewMsg(id, t string, m didcomm.MessageHdr) didcomm.Payloa
 
 type PayloadImpl struct {
 	didcomm.MessageHdr
+	Decor decorator.Decorators // generic decorators of the incoming message
+
+	decorErr error
+}
+
+func (pl *PayloadImpl) Decorators() (decorator.Decorators, error) {
+	return pl.Decor, pl.decorErr
 }
 
 func (pl *PayloadImpl) MsgHdr() didcomm.MessageHdr {
@@ -139,6 +177,10 @@ func ProtocolForType(typeStr string) string {
 	return didcomm.FieldAtInd(typeStr, 1)
 }
 
+func ProtocolVersionForType(typeStr string) string {
+	return didcomm.FieldAtInd(typeStr, 2)
+}
+
 func ProtocolMsgForType(typeStr string) string {
 	return didcomm.FieldAtInd(typeStr, 3)
 }
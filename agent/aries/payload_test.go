@@ -24,3 +24,18 @@ func TestPayload_ReadWriteJSON(t *testing.T) {
 		t.Errorf("%v to JSON from %v", pl, pl2)
 	}
 }
+
+func TestPayload_Decorators(t *testing.T) {
+	pl := PayloadCreator.NewFromData([]byte(
+		`{"@id":"1","@type":"test-type","~please_ack":{"on":["OUTCOME"]}}`))
+	d, err := pl.Decorators()
+	if err != nil || d.PleaseAck == nil || d.PleaseAck.On[0] != "OUTCOME" {
+		t.Errorf("decorators = %v, %v", d, err)
+	}
+
+	pl = PayloadCreator.NewFromData([]byte(
+		`{"@id":"1","@type":"test-type","~timing":{"expires_time":"tomorrow"}}`))
+	if _, err = pl.Decorators(); err == nil {
+		t.Error("malformed timing should be an error")
+	}
+}
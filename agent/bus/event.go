@@ -191,6 +191,16 @@ func (m mapIndex) AgentBroadcast(state AgentNotify) {
 	}
 }
 
+// AgentTryBroadcast broadcasts the notification to the currently connected
+// Agent Ctrls only. Nothing is buffered, and it returns false if no one is
+// listening. It's for the notifications which are useless later, like SA pings.
+func (m mapIndex) AgentTryBroadcast(state AgentNotify) (sent bool) {
+	agentMaps[m].Lock()
+	defer agentMaps[m].Unlock()
+
+	return m.broadcast(&state)
+}
+
 func (m mapIndex) pushBufferedNotify(state *AgentNotify) {
 	agentMaps[m].Unlock()
 	agentMaps[m].buffer.Lock()
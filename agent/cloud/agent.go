@@ -8,6 +8,7 @@ import (
 	"github.com/findy-network/findy-agent/agent/endp"
 	"github.com/findy-network/findy-agent/agent/sec"
 	"github.com/findy-network/findy-agent/agent/ssi"
+	storage "github.com/findy-network/findy-agent/agent/storage/api"
 	"github.com/findy-network/findy-agent/agent/utils"
 	"github.com/findy-network/findy-agent/core"
 	"github.com/findy-network/findy-agent/enclave"
@@ -145,6 +146,44 @@ func (a *Agent) AttachSAImpl(implID string) {
 	}
 }
 
+// SetManualConnApproval sets the connection approval mode of the CA and its
+// worker agent. The mode is saved to the settings of the worker, and it's
+// loaded when the worker is opened, see loadConnApproval.
+func (a *Agent) SetManualConnApproval(on bool) (err error) {
+	defer err2.Handle(&err, "set connection approval")
+
+	assert.That(a.IsCA(), "connection approval is set thru CA")
+	wa, ok := a.WorkerEA().(*Agent)
+	assert.That(ok, "type assert, wrong agent type for %s",
+		a.RootDid().Did())
+
+	_, ms := wa.ManagedWallet()
+	try.To1(ms.Storage().SettingStorage().UpdateSettings(
+		func(settings *storage.Settings) error {
+			settings.ManualConnApproval = on
+			return nil
+		}))
+
+	a.DIDAgent.SetManualConnApproval(on)
+	wa.DIDAgent.SetManualConnApproval(on)
+	glog.V(3).Infof("manual connection approval (%v)", on)
+	return nil
+}
+
+// loadConnApproval sets the saved connection approval mode to the worker and
+// its CA. It's done before the worker is used, so that no connection request
+// is accepted before the mode is set.
+func (a *Agent) loadConnApproval() {
+	defer err2.Catch(err2.Err(func(err error) {
+		glog.Error("cannot load connection approval:", err)
+	}))
+
+	_, ms := a.ManagedWallet()
+	settings := try.To1(ms.Storage().SettingStorage().GetSettings())
+	a.DIDAgent.SetManualConnApproval(settings.ManualConnApproval)
+	a.ca.DIDAgent.SetManualConnApproval(settings.ManualConnApproval)
+}
+
 func (a *Agent) SetMyDID(myDID core.DID) {
 	a.myDID = myDID
 }
@@ -241,6 +280,10 @@ func (a *Agent) workerAgent(waDID, suffix string) (wa *Agent) {
 		comm.ActiveRcvrs.Add(waDID, wca)
 
 		wca.loadPWMap()
+		wca.loadConnApproval()
+
+		// the openers use the worker, which isn't set before we return
+		comm.Opened(ca)
 
 		return wca
 	})
@@ -344,6 +387,15 @@ func (a *Agent) AddPipeToPWMap(p sec.Pipe, connID string) {
 	a.pws[connID] = p
 }
 
+// RmPWFromMap removes the pairwise pipe of the connection, e.g. when the
+// connection is deleted.
+func (a *Agent) RmPWFromMap(connID string) {
+	a.pwLock.Lock()
+	defer a.pwLock.Unlock()
+
+	delete(a.pws, connID)
+}
+
 func (a *Agent) SecPipe(connID string) sec.Pipe {
 	a.pwLock.Lock()
 	defer a.pwLock.Unlock()
@@ -16,6 +16,7 @@ import (
 	"github.com/findy-network/findy-agent/agent/endp"
 	"github.com/findy-network/findy-agent/agent/sec"
 	"github.com/findy-network/findy-agent/agent/utils"
+	"github.com/findy-network/findy-agent/std/decorator"
 	"github.com/golang/glog"
 	"github.com/lainio/err2"
 	"github.com/lainio/err2/try"
@@ -123,6 +124,17 @@ defined in the Task.ReceiverEndp. Function will encrypt messages before sending.
 It doesn't resend PL in case of failure. The recovering in done at PSM level.
 */
 func SendPL(sendPipe sec.Pipe, task Task, opl didcomm.Payload) (err error) {
+	return SendDecoratedPL(sendPipe, task, opl, decorator.Decorators{})
+}
+
+// SendDecoratedPL sends the protocol message like SendPL with the generic
+// decorators, e.g. ~please_ack. The ~timing is always set by us.
+func SendDecoratedPL(
+	sendPipe sec.Pipe,
+	task Task,
+	opl didcomm.Payload,
+	d decorator.Decorators,
+) (err error) {
 	defer err2.Handle(&err, "send payload")
 
 	cnxAddr := endp.NewAddrFromPublic(task.ReceiverEndp())
@@ -134,7 +146,10 @@ func SendPL(sendPipe sec.Pipe, task Task, opl didcomm.Payload) (err error) {
 		glog.Info("=====")
 	}
 
-	cryptSendPL, _ := try.To2(sendPipe.Pack(opl.JSON()))
+	// every message tells when it was sent, RFC 0032
+	d.Timing = &decorator.Timing{OutTime: time.Now()}
+	data := try.To1(decorator.AddDecorators(opl.JSON(), d))
+	cryptSendPL, _ := try.To2(sendPipe.Pack(data))
 
 	_, err = SendAndWaitReq(cnxAddr.Address(), bytes.NewReader(cryptSendPL),
 		utils.Settings.Timeout())
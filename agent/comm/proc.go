@@ -1,6 +1,8 @@
 package comm
 
 import (
+	"sort"
+
 	"github.com/findy-network/findy-agent/agent/didcomm"
 	pb "github.com/findy-network/findy-common-go/grpc/agency/v1"
 	"github.com/golang/glog"
@@ -35,6 +37,16 @@ func (p *processor) Process(packet Packet) (err error) {
 	return handler.Process(packet)
 }
 
+// Families returns the registered protocol families in sorted order.
+func (p *processor) Families() []string {
+	families := make([]string, 0, len(p.protHandlers))
+	for family := range p.protHandlers {
+		families = append(families, family)
+	}
+	sort.Strings(families)
+	return families
+}
+
 func (p *processor) Add(t string, proc ProtHandler) {
 	if p.protHandlers == nil {
 		p.protHandlers = make(map[string]ProtHandler)
@@ -32,8 +32,12 @@ type Receiver interface {
 	SaveTheirDID(did, vk string) (err error)
 	CAEndp(connID string) (endP *endp.Addr)
 	AddPipeToPWMap(p sec.Pipe, name string)
+	RmPWFromMap(name string)
+	RmDIDCache(did string)
 	MasterSecret() (string, error)
 	AutoPermission() bool
+	ManualConnApproval() bool
+	SetManualConnApproval(on bool) error
 	ID() string
 }
 
@@ -58,6 +62,31 @@ func (rs *Receivers) Get(DID string) Receiver {
 	return rs.Rcvrs[DID]
 }
 
+// openers are called when the worker agent of a CA is opened, which is the
+// first time the CA is used after the agency has started.
+var openers = struct {
+	sync.Mutex
+	fns []func(ca Receiver)
+}{}
+
+// AddOpener adds the function which resumes what the agent had running
+// before the agency restarted, e.g. the health monitor. The functions are
+// called in their own goroutines with the CA.
+func AddOpener(f func(ca Receiver)) {
+	openers.Lock()
+	defer openers.Unlock()
+	openers.fns = append(openers.fns, f)
+}
+
+// Opened calls the openers for the CA whose worker agent has been opened.
+func Opened(ca Receiver) {
+	openers.Lock()
+	defer openers.Unlock()
+	for _, f := range openers.fns {
+		go f(ca)
+	}
+}
+
 // Handler can be Agency or Agent. They can input Payloads.
 type Handler interface {
 	// TODO: lapi, should we consider something else for handler after
@@ -2,6 +2,7 @@ package comm
 
 import (
 	"encoding/gob"
+	"time"
 
 	"github.com/findy-network/findy-agent/agent/aries"
 	"github.com/findy-network/findy-agent/agent/pltype"
@@ -24,6 +25,11 @@ type Task interface {
 	ReceiverEndp() service.Addr     // Pairwise receiver endpoint
 	SetReceiverEndp(r service.Addr)
 	DIDMethod() method.Type
+	Silent() bool // Don't notify controllers, e.g. background health pings
+
+	// ExpiresTime is the ~timing.expires_time of the message the task
+	// handles, zero if it doesn't expire.
+	ExpiresTime() time.Time
 }
 
 type TaskHeader struct {
@@ -32,6 +38,8 @@ type TaskHeader struct {
 	ProtocolRole     pb.Protocol_Role
 	ConnID           string
 	UserActionPLType string
+	Silent           bool
+	Expires          time.Time
 
 	Sender   service.Addr
 	Receiver service.Addr
@@ -68,6 +76,10 @@ func (t *TaskBase) UserActionType() string {
 	return t.UserActionPLType
 }
 
+func (t *TaskBase) Silent() bool {
+	return t.TaskHeader.Silent
+}
+
 func (t *TaskBase) ReceiverEndp() service.Addr {
 	return t.Receiver
 }
@@ -86,3 +98,7 @@ func (t *TaskHeader) SwitchDirection() {
 func (t *TaskBase) DIDMethod() method.Type {
 	return t.Method
 }
+
+func (t *TaskBase) ExpiresTime() time.Time {
+	return t.Expires
+}
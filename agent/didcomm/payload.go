@@ -90,6 +90,10 @@ type Payload interface {
 	Message() Msg       // this is mostly for legacy message handling, before Aries
 	MsgHdr() MessageHdr // this generic and preferable way to get the message
 
+	// Decorators returns the generic decorators like ~timing, and the error
+	// if they were malformed.
+	Decorators() (decorator.Decorators, error)
+
 	Protocol() string
 	ProtocolMsg() string
 	Namespace() string
@@ -134,21 +138,56 @@ type CredentialAttribute struct {
 	MimeType string `json:"mime-type,omitempty"`
 }
 
-// ProofAttribute for proof request attributes
+// ProofAttribute for proof request attributes. CredDefID is the shorthand for
+// the only restriction. Restrictions are alternatives: the credential must
+// match one of them. Names requests the attribute group from the same
+// credential instead of the Name. The attribute without any restrictions can
+// be self-attested. SchemaID, SelfAttested and Value are set from the proof.
 type ProofAttribute struct {
-	ID        string `json:"-"`
-	Name      string `json:"name,omitempty"`
-	CredDefID string `json:"credDefId,omitempty"`
-	Predicate string `json:"predicate,omitempty"`
-	Value     string `json:"-"`
+	ID           string             `json:"-"`
+	Name         string             `json:"name,omitempty"`
+	Names        []string           `json:"names,omitempty"`
+	CredDefID    string             `json:"credDefId,omitempty"`
+	Predicate    string             `json:"predicate,omitempty"`
+	Restrictions []ProofRestriction `json:"restrictions,omitempty"`
+	NonRevoked   *NonRevocInterval  `json:"nonRevoked,omitempty"`
+	SchemaID     string             `json:"-"`
+	SelfAttested bool               `json:"-"`
+	Value        string             `json:"-"`
 }
 
-// ProofPredicate for proof request predicates
+// ProofPredicate for proof request predicates. The restrictions are as in
+// ProofAttribute. CredDefID and SchemaID are set from the proof.
 type ProofPredicate struct {
-	ID     string `json:"-"`
-	Name   string `json:"name,omitempty"`
-	PType  string `json:"p_type,omitempty"`
-	PValue int64  `json:"p_value,omitempty"`
+	ID           string             `json:"-"`
+	Name         string             `json:"name,omitempty"`
+	PType        string             `json:"p_type,omitempty"`
+	PValue       int64              `json:"p_value,omitempty"`
+	Restrictions []ProofRestriction `json:"restrictions,omitempty"`
+	NonRevoked   *NonRevocInterval  `json:"nonRevoked,omitempty"`
+	CredDefID    string             `json:"-"`
+	SchemaID     string             `json:"-"`
+}
+
+// ProofRestriction is the Indy restriction of the credential. All of the set
+// fields must match. AttrValues are the required raw values of the credential
+// attributes, and AttrMarkers are the attributes the credential must have.
+type ProofRestriction struct {
+	SchemaID        string            `json:"schemaId,omitempty"`
+	SchemaIssuerDID string            `json:"schemaIssuerDid,omitempty"`
+	SchemaName      string            `json:"schemaName,omitempty"`
+	SchemaVersion   string            `json:"schemaVersion,omitempty"`
+	IssuerDID       string            `json:"issuerDid,omitempty"`
+	CredDefID       string            `json:"credDefId,omitempty"`
+	AttrValues      map[string]string `json:"attrValues,omitempty"`
+	AttrMarkers     []string          `json:"attrMarkers,omitempty"`
+}
+
+// NonRevocInterval is the interval in Unix time when the credential must not
+// have been revoked.
+type NonRevocInterval struct {
+	From int64 `json:"from,omitempty"`
+	To   int64 `json:"to,omitempty"`
 }
 
 // ProofValue for proof values
@@ -167,6 +206,8 @@ type Msg interface {
 	SubLevelID() string
 
 	Ready() bool
+
+	Info() string
 }
 
 // MsgInit is a helper struct for factors to construct new message instances.
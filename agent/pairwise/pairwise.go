@@ -31,7 +31,12 @@ type Callee struct {
 
 func (p *Callee) startStore() {
 	p.Caller.Store(p.agent.ManagedWallet())
+	p.SavePairwise()
+}
 
+// SavePairwise saves the pairwise info of the connection, i.e. the connection
+// record of our and their DID.
+func (p *Callee) SavePairwise() {
 	_, storageH := p.agent.ManagedWallet()
 	p.Callee.SavePairwiseForDID(storageH, p.Caller, core.PairwiseMeta{
 		Name:  p.Name,
@@ -87,3 +92,14 @@ func (p *Callee) Store() (err error) {
 
 	return nil
 }
+
+// StoreCaller saves their DID only. The pairwise is saved with SavePairwise
+// when the connection is accepted.
+func (p *Callee) StoreCaller() (err error) {
+	defer err2.Handle(&err)
+
+	p.Caller.Store(p.agent.ManagedWallet())
+	try.To(p.storeResult())
+
+	return nil
+}
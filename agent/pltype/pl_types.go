@@ -53,6 +53,14 @@ const (
 	DIDOrgProblemReport             = DIDOrgAries + "/" + ProtocolNotification
 	DIDOrgNotificationProblemReport = DIDOrgProblemReport + "/1.0/" + HandlerProblemReport
 	DIDOrgNotificationAck           = DIDOrgProblemReport + "/1.0/" + HandlerAck
+
+	// RFC 0035 family, the notification family is what ACA-Py uses
+	ProtocolReportProblem      = "report-problem"
+	ReportProblem              = Aries + "/" + ProtocolReportProblem
+	ReportProblemProblemReport = ReportProblem + "/1.0/" + HandlerProblemReport
+
+	DIDOrgReportProblem              = DIDOrgAries + "/" + ProtocolReportProblem
+	DIDOrgReportProblemProblemReport = DIDOrgReportProblem + "/1.0/" + HandlerProblemReport
 )
 
 // Issue Credential protocol constants
@@ -75,6 +83,7 @@ const (
 	IssueCredentialACK               = IssueCredential + "/1.0/" + HandlerIssueCredentialACK
 	IssueCredentialNACK              = IssueCredential + "/1.0/" + HandlerIssueCredentialNACK
 	IssueCredentialCredentialPreview = IssueCredential + "/1.0/" + ObjectTypeCredentialPreview
+	IssueCredentialProblemReport     = IssueCredential + "/1.0/" + HandlerProblemReport
 
 	DIDOrgIssueCredential                  = DIDOrgAries + "/" + ProtocolIssueCredential
 	DIDOrgIssueCredentialPropose           = DIDOrgIssueCredential + "/1.0/" + HandlerIssueCredentialPropose
@@ -85,6 +94,7 @@ const (
 	DIDOrgIssueCredentialACK               = DIDOrgIssueCredential + "/1.0/" + HandlerIssueCredentialACK
 	DIDOrgIssueCredentialNACK              = DIDOrgIssueCredential + "/1.0/" + HandlerIssueCredentialNACK
 	DIDOrgIssueCredentialCredentialPreview = DIDOrgIssueCredential + "/1.0/" + ObjectTypeCredentialPreview
+	DIDOrgIssueCredentialProblemReport     = DIDOrgIssueCredential + "/1.0/" + HandlerProblemReport
 )
 
 // DID exchange aka Connection related constants
@@ -146,15 +156,31 @@ const (
 	PresentProofACK                 = PresentProof + "/1.0/" + HandlerPresentProofACK
 	PresentProofNACK                = PresentProof + "/1.0/" + HandlerPresentProofNACK
 	PresentationPreviewObj          = PresentProof + "/1.0/" + ObjectTypePresentationPreview
-
-	DIDOrgPresentProof             = DIDOrgAries + "/" + ProtocolPresentProof
-	DIDOrgPresentProofPropose      = DIDOrgPresentProof + "/1.0/" + HandlerPresentProofPropose
-	DIDOrgPresentProofRequest      = DIDOrgPresentProof + "/1.0/" + HandlerPresentProofRequest
-	DIDOrgPresentProofPresentation = DIDOrgPresentProof + "/1.0/" + HandlerPresentProofPresentation
-	DIDOrgPresentProofUserAction   = DIDOrgPresentProof + "/1.0/" + HandlerPresentUserAction
-	DIDOrgPresentProofACK          = DIDOrgPresentProof + "/1.0/" + HandlerPresentProofACK
-	DIDOrgPresentProofNACK         = DIDOrgPresentProof + "/1.0/" + HandlerPresentProofNACK
-	DIDOrgPresentationPreviewObj   = DIDOrgPresentProof + "/1.0/" + ObjectTypePresentationPreview
+	PresentProofProblemReport       = PresentProof + "/1.0/" + HandlerProblemReport
+
+	PresentProofV2Propose       = PresentProof + "/2.0/" + HandlerPresentProofPropose
+	PresentProofV2Request       = PresentProof + "/2.0/" + HandlerPresentProofRequest
+	PresentProofV2Presentation  = PresentProof + "/2.0/" + HandlerPresentProofPresentation
+	PresentProofV2UserAction    = PresentProof + "/2.0/" + HandlerPresentUserAction
+	PresentProofV2ACK           = PresentProof + "/2.0/" + HandlerPresentProofACK
+	PresentProofV2ProblemReport = PresentProof + "/2.0/" + HandlerProblemReport
+
+	DIDOrgPresentProof              = DIDOrgAries + "/" + ProtocolPresentProof
+	DIDOrgPresentProofPropose       = DIDOrgPresentProof + "/1.0/" + HandlerPresentProofPropose
+	DIDOrgPresentProofRequest       = DIDOrgPresentProof + "/1.0/" + HandlerPresentProofRequest
+	DIDOrgPresentProofPresentation  = DIDOrgPresentProof + "/1.0/" + HandlerPresentProofPresentation
+	DIDOrgPresentProofUserAction    = DIDOrgPresentProof + "/1.0/" + HandlerPresentUserAction
+	DIDOrgPresentProofACK           = DIDOrgPresentProof + "/1.0/" + HandlerPresentProofACK
+	DIDOrgPresentProofNACK          = DIDOrgPresentProof + "/1.0/" + HandlerPresentProofNACK
+	DIDOrgPresentationPreviewObj    = DIDOrgPresentProof + "/1.0/" + ObjectTypePresentationPreview
+	DIDOrgPresentProofProblemReport = DIDOrgPresentProof + "/1.0/" + HandlerProblemReport
+
+	DIDOrgPresentProofV2Propose       = DIDOrgPresentProof + "/2.0/" + HandlerPresentProofPropose
+	DIDOrgPresentProofV2Request       = DIDOrgPresentProof + "/2.0/" + HandlerPresentProofRequest
+	DIDOrgPresentProofV2Presentation  = DIDOrgPresentProof + "/2.0/" + HandlerPresentProofPresentation
+	DIDOrgPresentProofV2UserAction    = DIDOrgPresentProof + "/2.0/" + HandlerPresentUserAction
+	DIDOrgPresentProofV2ACK           = DIDOrgPresentProof + "/2.0/" + HandlerPresentProofACK
+	DIDOrgPresentProofV2ProblemReport = DIDOrgPresentProof + "/2.0/" + HandlerProblemReport
 )
 
 // Basic Message protocol constants
@@ -182,6 +208,65 @@ const (
 	DIDOrgTrustPingResponse = DIDOrgTrustPing + "/1.0/" + HandlerPingResponse
 )
 
+// Discover Features protocol constants
+const (
+	ProtocolDiscoverFeatures = "discover-features"
+	HandlerQuery             = "query"
+	HandlerDisclose          = "disclose"
+	HandlerQueries           = "queries"
+	HandlerDisclosures       = "disclosures"
+	DiscoverFeatures         = Aries + "/" + ProtocolDiscoverFeatures
+	DiscoverFeaturesQuery    = DiscoverFeatures + "/1.0/" + HandlerQuery
+	DiscoverFeaturesDisclose = DiscoverFeatures + "/1.0/" + HandlerDisclose
+
+	DiscoverFeaturesV2Queries     = DiscoverFeatures + "/2.0/" + HandlerQueries
+	DiscoverFeaturesV2Disclosures = DiscoverFeatures + "/2.0/" + HandlerDisclosures
+
+	DIDOrgDiscoverFeatures         = DIDOrgAries + "/" + ProtocolDiscoverFeatures
+	DIDOrgDiscoverFeaturesQuery    = DIDOrgDiscoverFeatures + "/1.0/" + HandlerQuery
+	DIDOrgDiscoverFeaturesDisclose = DIDOrgDiscoverFeatures + "/1.0/" + HandlerDisclose
+
+	DIDOrgDiscoverFeaturesV2Queries     = DIDOrgDiscoverFeatures + "/2.0/" + HandlerQueries
+	DIDOrgDiscoverFeaturesV2Disclosures = DIDOrgDiscoverFeatures + "/2.0/" + HandlerDisclosures
+)
+
+// Action Menu protocol constants
+const (
+	ProtocolActionMenu    = "action-menu"
+	HandlerMenu           = "menu"
+	HandlerMenuRequest    = "menu-request"
+	HandlerPerform        = "perform"
+	ActionMenu            = Aries + "/" + ProtocolActionMenu
+	ActionMenuMenu        = ActionMenu + "/1.0/" + HandlerMenu
+	ActionMenuMenuRequest = ActionMenu + "/1.0/" + HandlerMenuRequest
+	ActionMenuPerform     = ActionMenu + "/1.0/" + HandlerPerform
+
+	DIDOrgActionMenu            = DIDOrgAries + "/" + ProtocolActionMenu
+	DIDOrgActionMenuMenu        = DIDOrgActionMenu + "/1.0/" + HandlerMenu
+	DIDOrgActionMenuMenuRequest = DIDOrgActionMenu + "/1.0/" + HandlerMenuRequest
+	DIDOrgActionMenuPerform     = DIDOrgActionMenu + "/1.0/" + HandlerPerform
+)
+
+// Question Answer protocol constants
+const (
+	ProtocolQuestionAnswer   = "questionanswer"
+	HandlerQuestion          = "question"
+	HandlerAnswer            = "answer"
+	QuestionAnswer           = Aries + "/" + ProtocolQuestionAnswer
+	QuestionAnswerQuestion   = QuestionAnswer + "/1.0/" + HandlerQuestion
+	QuestionAnswerAnswer     = QuestionAnswer + "/1.0/" + HandlerAnswer
+	QuestionAnswerUserAction = QuestionAnswer + "/1.0/" + UserAction
+
+	// SignatureEd25519Sha512Single is the type of the signature decorator
+	// of the answer.
+	SignatureEd25519Sha512Single = DIDOrgAries + "/signature/1.0/ed25519Sha512_single"
+
+	DIDOrgQuestionAnswer           = DIDOrgAries + "/" + ProtocolQuestionAnswer
+	DIDOrgQuestionAnswerQuestion   = DIDOrgQuestionAnswer + "/1.0/" + HandlerQuestion
+	DIDOrgQuestionAnswerAnswer     = DIDOrgQuestionAnswer + "/1.0/" + HandlerAnswer
+	DIDOrgQuestionAnswerUserAction = DIDOrgQuestionAnswer + "/1.0/" + UserAction
+)
+
 // SA API msg types
 const (
 	SAPing                         = SA + "/ping/1.0/ping"
@@ -244,12 +329,23 @@ const (
 	// Protocol launcher - protocol string must match Aries protocol
 	CABasicMessage = CA + "/" + ProtocolBasicMessage + "/1.0/send"
 
+	// Protocol launcher - protocol string must match Aries protocol
+	CAActionMenu = CA + "/" + ProtocolActionMenu + "/1.0/menu"
+
+	// Protocol launcher - protocol string must match Aries protocol
+	CAQuestionAnswer = CA + "/" + ProtocolQuestionAnswer + "/1.0/question"
+
+	// Protocol launcher - protocol string must match Aries protocol
+	CADiscoverFeatures = CA + "/" + ProtocolDiscoverFeatures + "/2.0/queries"
+
 	CAProblemReport = CA + "/notification/1.0/problem_report"
 
 	CAPingOwnCA = CA + "/ping/1.0/own_ca"
 
 	CAContinuePresentProofProtocol    = CA + "/protocol/1.0/continue-present-proof"
 	CAContinueIssueCredentialProtocol = CA + "/protocol/1.0/continue-issue-credential"
+	CAContinueQuestionAnswerProtocol  = CA + "/protocol/1.0/continue-question-answer"
+	CAContinueConnectionProtocol      = CA + "/protocol/1.0/continue-connection"
 )
 
 var protocolType = map[string]pb.Protocol_Type{
@@ -284,4 +380,10 @@ var receiversRoleFromType = map[string]pb.Protocol_Role{
 	HandlerPresentProofPropose:    pb.Protocol_INITIATOR,
 	HandlerPing:                   pb.Protocol_ADDRESSEE,
 	HandlerMessage:                pb.Protocol_ADDRESSEE,
+	HandlerQuery:                  pb.Protocol_ADDRESSEE,
+	HandlerQueries:                pb.Protocol_ADDRESSEE,
+	HandlerMenu:                   pb.Protocol_ADDRESSEE,
+	HandlerMenuRequest:            pb.Protocol_ADDRESSEE,
+	HandlerPerform:                pb.Protocol_ADDRESSEE,
+	HandlerQuestion:               pb.Protocol_ADDRESSEE,
 }
@@ -0,0 +1,68 @@
+// Package policy decides which incoming requests the agent accepts without
+// asking its controller. The rules are in the auto-accept policy stored in the
+// agent's storage, see storage.Policy.
+package policy
+
+import (
+	"github.com/findy-network/findy-agent/agent/comm"
+	storage "github.com/findy-network/findy-agent/agent/storage/api"
+	"github.com/golang/glog"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/try"
+)
+
+// Accept tells if the request is accepted automatically. The permissive SA
+// accepts everything as before, and without the stored policy everything is
+// escalated to the controller. All errors escalate the request.
+func Accept(r comm.Receiver, connID string, req storage.PolicyRequest) (ok bool) {
+	defer err2.Catch(err2.Err(func(err error) {
+		glog.Errorln("auto-accept policy:", err)
+		ok = false
+	}))
+
+	if r.AutoPermission() {
+		return true
+	}
+	_, ms := r.ManagedWallet()
+	p := try.To1(ms.Storage().PolicyStorage().GetPolicy())
+	if p == nil {
+		return false
+	}
+	action, rule := p.Decide(req)
+	glog.V(1).Infof("%s request (%s): %s by rule '%s'",
+		req.Protocol, connID, action, rule)
+	return action == storage.PolicyAccept
+}
+
+// Selection returns the credential selection of the agent's policy. Without
+// the policy or on errors the first matching credential is used.
+func Selection(r comm.Receiver) (s storage.CredSelection) {
+	defer err2.Catch(err2.Err(func(err error) {
+		glog.Errorln("credential selection policy:", err)
+		s = storage.CredSelection{}
+	}))
+
+	_, ms := r.ManagedWallet()
+	p := try.To1(ms.Storage().PolicyStorage().GetPolicy())
+	if p == nil {
+		return storage.CredSelection{}
+	}
+	return p.Selection
+}
+
+// Get returns the auto-accept policy of the agent, or nil if not set.
+func Get(r comm.Receiver) (p *storage.Policy, err error) {
+	defer err2.Handle(&err, "get policy")
+
+	_, ms := r.WorkerEA().ManagedWallet()
+	return ms.Storage().PolicyStorage().GetPolicy()
+}
+
+// Set validates and saves the auto-accept policy of the agent.
+func Set(r comm.Receiver, p storage.Policy) (err error) {
+	defer err2.Handle(&err, "set policy")
+
+	try.To(p.Validate())
+	_, ms := r.WorkerEA().ManagedWallet()
+	return ms.Storage().PolicyStorage().SavePolicy(p)
+}
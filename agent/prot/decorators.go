@@ -0,0 +1,131 @@
+package prot
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/findy-network/findy-agent/agent/aries"
+	"github.com/findy-network/findy-agent/agent/comm"
+	"github.com/findy-network/findy-agent/agent/didcomm"
+	"github.com/findy-network/findy-agent/agent/pltype"
+	"github.com/findy-network/findy-agent/agent/sec"
+	"github.com/findy-network/findy-agent/agent/utils"
+	"github.com/findy-network/findy-agent/std/decorator"
+	"github.com/golang/glog"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/assert"
+	"github.com/lainio/err2/try"
+)
+
+// errExpired is returned for the messages which ~timing.expires_time has
+// passed. The other end gets a problem report with the expired code.
+var errExpired = errors.New("message expired")
+
+// Ack statuses of Aries RFC 0015. The receipt ack of RFC 0317 is pending,
+// because the outcome isn't known yet.
+const (
+	AckStatusOK      = "OK"
+	AckStatusFail    = "FAIL"
+	AckStatusPending = "PENDING"
+)
+
+// checkTiming rejects the message if it has expired or its decorators are
+// malformed.
+func checkTiming(pl didcomm.Payload) error {
+	d, err := pl.Decorators()
+	if err != nil {
+		return fmt.Errorf("invalid decorators: %w", err)
+	}
+	if d.Timing.Expired(time.Now()) {
+		return fmt.Errorf("%w at %s", errExpired, d.Timing.ExpiresTime)
+	}
+	return nil
+}
+
+// expiresTime returns the expiration time of the message, zero if it
+// doesn't expire.
+func expiresTime(pl didcomm.Payload) time.Time {
+	d, _ := pl.Decorators()
+	if d.Timing == nil {
+		return time.Time{}
+	}
+	return d.Timing.ExpiresTime
+}
+
+// checkTaskTiming rejects the continuation of the task if the message we
+// waited the user action for has expired meanwhile.
+func checkTaskTiming(task comm.Task) error {
+	expires := task.ExpiresTime()
+	if !expires.IsZero() && time.Now().After(expires) {
+		return fmt.Errorf("%w at %s", errExpired, expires)
+	}
+	return nil
+}
+
+// wantsAck tells if the other end has asked the ack on the event with the
+// ~please_ack decorator.
+func wantsAck(pl didcomm.Payload, on string) bool {
+	d, _ := pl.Decorators()
+	return d.PleaseAck.Wants(on)
+}
+
+// sendAck sends the ack of Aries RFC 0317 if the other end has asked it on
+// the event with ~please_ack decorator. The receipt ack is sent when we have
+// got the message, and the outcome ack when we have processed it. It's the
+// best effort: the errors are only logged.
+func sendAck(ts Transition, task comm.Task, on, status string) {
+	defer err2.Catch(err2.Err(func(err error) {
+		glog.Warningln("cannot send ack:", err)
+	}))
+
+	if !wantsAck(ts.Payload, on) {
+		return
+	}
+	glog.V(3).Infof("sending %s ack (%s) for %s", on, status, ts.Payload.Type())
+
+	ep := try.To1(replyPipe(ts))
+	om := aries.MsgCreator.Create(didcomm.MsgInit{
+		Type:   pltype.NotificationAck,
+		Info:   status,
+		Thread: ts.Payload.Thread(),
+	})
+	opl := aries.PayloadCreator.NewMsg(utils.UUID(), pltype.NotificationAck, om)
+	task.SetReceiverEndp(try.To1(ep.EA()))
+	try.To(comm.SendPL(ep, task, opl))
+}
+
+// sendPL sends the protocol message with the ~please_ack decorator if the
+// agent asks acks for its messages.
+func sendPL(wa comm.Receiver, pipe sec.Pipe, task comm.Task, opl didcomm.Payload) error {
+	return comm.SendDecoratedPL(pipe, task, opl, decorator.Decorators{
+		PleaseAck: pleaseAck(wa),
+	})
+}
+
+// pleaseAck returns the ~please_ack decorator of the agent's settings, nil if
+// the agent doesn't ask acks.
+func pleaseAck(wa comm.Receiver) *decorator.PleaseAck {
+	defer err2.Catch(err2.Err(func(err error) {
+		glog.Warningln("cannot read please ack setting:", err)
+	}))
+
+	_, ms := wa.ManagedWallet()
+	settings := try.To1(ms.Storage().SettingStorage().GetSettings())
+	if len(settings.PleaseAck) == 0 {
+		return nil
+	}
+	return &decorator.PleaseAck{On: settings.PleaseAck}
+}
+
+// replyPipe returns the secure pipe to reply to the sender of the message.
+func replyPipe(ts Transition) (_ sec.Pipe, err error) {
+	defer err2.Handle(&err, "reply pipe")
+
+	pairwise := try.To1(ts.Receiver.FindPWByID(ts.Address.ConnID))
+	assert.That(pairwise != nil, "pairwise should not be nil")
+	outDID := ts.Receiver.LoadTheirDID(*pairwise)
+	_, storageH := ts.Receiver.ManagedWallet()
+	outDID.StartEndp(storageH, pairwise.ID)
+	return sec.Pipe{In: ts.Receiver.LoadDID(pairwise.MyDID), Out: outDID}, nil
+}
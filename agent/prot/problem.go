@@ -0,0 +1,177 @@
+package prot
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/findy-network/findy-agent/agent/aries"
+	"github.com/findy-network/findy-agent/agent/comm"
+	"github.com/findy-network/findy-agent/agent/didcomm"
+	"github.com/findy-network/findy-agent/agent/pltype"
+	"github.com/findy-network/findy-agent/agent/psm"
+	"github.com/findy-network/findy-agent/agent/utils"
+	"github.com/findy-network/findy-agent/std/common"
+	"github.com/golang/glog"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/assert"
+	"github.com/lainio/err2/try"
+)
+
+// HandleProblemReport is the handler of the problem reports of all protocol
+// families. The report is matched to the PSM it refers by the thread ID or the
+// parent thread ID, and the PSM moves to the Failure state with the reason of
+// the report. The report which doesn't refer to any running protocol of the
+// connection is processed as its own protocol.
+func HandleProblemReport(packet comm.Packet) (err error) {
+	defer err2.Handle(&err, "handle problem report")
+
+	pr, ok := packet.Payload.MsgHdr().FieldObj().(*common.ProblemReport)
+	assert.That(ok, "problem report type mismatch")
+
+	meDID := packet.Receiver.MyDID().Did()
+	connID := packet.Address.ConnID
+	reason := pr.Reason()
+	glog.Warningf("problem report (%s) from connection %s: %s",
+		pr.Description.Code, connID, reason)
+
+	thread := packet.Payload.Thread()
+	for _, thID := range []string{thread.ID, thread.PID} {
+		if thID == "" {
+			continue
+		}
+		key := psm.StateKey{DID: meDID, Nonce: thID}
+		m := try.To1(psm.FindPSM(key))
+		if m == nil || m.ConnID != connID {
+			continue
+		}
+		if m.IsReady() {
+			glog.V(1).Infoln("problem report for ready protocol:", key)
+			return nil
+		}
+		return FailPSM(key, reason)
+	}
+
+	return ExecPSM(Transition{
+		Packet:      packet,
+		SendNext:    pltype.Terminate,
+		WaitingNext: pltype.Terminate,
+		InOut: func(_ string, _, _ didcomm.MessageHdr) (ack bool, err error) {
+			return true, nil
+		},
+	})
+}
+
+// endLock serialises the failing of the PSMs that the PSM is failed only once
+// even if it's cancelled and the other end reports a problem at the same time.
+var endLock sync.Mutex
+
+// FailPSM moves the PSM to the Failure state with the reason, e.g. when the
+// other end has sent a problem report. The controllers are notified as for
+// any other failed protocol. It's no-op if the PSM has already ended.
+func FailPSM(key psm.StateKey, reason string) (err error) {
+	defer err2.Handle(&err, "fail psm")
+
+	try.To1(endPSM(key, reason, func(m *psm.PSM) psm.PayloadInfo {
+		return m.LastState().PLInfo
+	}))
+	return nil
+}
+
+// CancelPSM moves the running PSM to the Failure state with the reason when
+// our controller has cancelled the protocol. The pending user action is
+// dropped, i.e. the PSM cannot be resumed any more. It returns false if the
+// PSM has already ended, and then only the first of the concurrent cancels
+// returns true.
+func CancelPSM(key psm.StateKey, reason string) (cancelled bool, err error) {
+	defer err2.Handle(&err, "cancel psm")
+
+	return endPSM(key, reason, func(m *psm.PSM) psm.PayloadInfo {
+		return psm.PayloadInfo{Type: m.FirstState().PLInfo.Type}
+	})
+}
+
+// endPSM fails the running PSM with the reason and the payload info of its
+// last state. It returns false if the PSM has already ended.
+func endPSM(
+	key psm.StateKey,
+	reason string,
+	plInfo func(m *psm.PSM) psm.PayloadInfo,
+) (
+	ended bool,
+	err error,
+) {
+	endLock.Lock()
+	defer endLock.Unlock()
+
+	m := try.To1(psm.GetPSM(key))
+	if m.IsReady() {
+		glog.V(1).Infoln("protocol already ended:", key)
+		return false, nil
+	}
+	try.To(failPSM(m, reason, plInfo(m)))
+	return true, nil
+}
+
+func failPSM(m *psm.PSM, reason string, plInfo psm.PayloadInfo) (err error) {
+	last := m.LastState()
+	timestamp := time.Now().UnixNano()
+	m.States = append(m.States, psm.State{
+		Timestamp: timestamp,
+		T:         last.T,
+		PLInfo:    plInfo,
+		Sub:       psm.Failure,
+		Info:      reason,
+	})
+	try.To(psm.AddPSM(m))
+
+	go triggerEnd(endingInfo{
+		timestamp:      timestamp,
+		subState:       psm.Failure,
+		nonce:          m.Key.Nonce,
+		meDID:          m.Key.DID,
+		pwName:         m.ConnID,
+		plType:         m.FirstState().PLInfo.Type,
+		startedByUs:    m.StartedByUs,
+		protocolFamily: m.Protocol(),
+		role:           m.Role,
+		silent:         isSilent(m),
+	})
+	return nil
+}
+
+// reportProblem sends a problem report to the other end when our handler has
+// failed to process its message. It's the best effort: the errors are only
+// logged, and we don't report the problems of the problem reports. The error
+// itself isn't sent because it's internal information.
+func reportProblem(ts Transition, task comm.Task, cause error) {
+	defer err2.Catch(err2.Err(func(err error) {
+		glog.Warningln("cannot send problem report:", err)
+	}))
+
+	if ts.Payload.ProtocolMsg() == pltype.HandlerProblemReport {
+		return
+	}
+	glog.V(1).Infof("reporting problem of %s: %v", ts.Payload.Type(), cause)
+
+	ep := try.To1(replyPipe(ts))
+	code, description := common.CodeRequestProcessingError, "message processing failed"
+	if errors.Is(cause, errExpired) {
+		code, description = common.CodeExpired, "message has expired"
+	}
+
+	om := aries.MsgCreator.Create(didcomm.MsgInit{
+		Type:   pltype.NotificationProblemReport,
+		Info:   code,
+		Thread: ts.Payload.Thread(),
+	})
+	pr := om.FieldObj().(*common.ProblemReport)
+	pr.Description.En = description
+	pr.Impact = common.ImpactThread
+	pr.WhoRetries = common.WhoRetriesNone
+	pr.NoticedTime = time.Now().UTC().Format(time.RFC3339)
+
+	opl := aries.PayloadCreator.NewMsg(utils.UUID(), pltype.NotificationProblemReport, om)
+	task.SetReceiverEndp(try.To1(ep.EA()))
+	try.To(comm.SendPL(ep, task, opl))
+}
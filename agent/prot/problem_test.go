@@ -0,0 +1,105 @@
+package prot
+
+import (
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/findy-network/findy-agent/agent/pltype"
+	"github.com/findy-network/findy-agent/agent/psm"
+	"github.com/lainio/err2/assert"
+	"github.com/lainio/err2/try"
+)
+
+const dbPath = "prot_test.bolt"
+
+func TestMain(m *testing.M) {
+	try.To(psm.Open(dbPath))
+	code := m.Run()
+	psm.Close()
+	os.Remove(dbPath)
+	os.Exit(code)
+}
+
+func addRunningPSM(nonce string) psm.StateKey {
+	key := psm.StateKey{DID: "agentDID", Nonce: nonce}
+	try.To(psm.AddPSM(&psm.PSM{
+		Key:         key,
+		ConnID:      "connID",
+		StartedByUs: true,
+		States: []psm.State{
+			{PLInfo: psm.PayloadInfo{Type: pltype.CACredOffer}, Sub: psm.Sending},
+			{PLInfo: psm.PayloadInfo{Type: pltype.CACredOffer}, Sub: psm.Waiting},
+		},
+	}))
+	return key
+}
+
+func failures(key psm.StateKey) (n int) {
+	m := try.To1(psm.GetPSM(key))
+	for _, s := range m.States {
+		if s.Sub.Pure() == psm.Failure {
+			n++
+		}
+	}
+	return n
+}
+
+func TestCancelPSM(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	key := addRunningPSM("cancel")
+
+	const count = 10
+	var (
+		wg        sync.WaitGroup
+		lock      sync.Mutex
+		cancelled int
+	)
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := CancelPSM(key, "cancelled by controller")
+			if err != nil {
+				t.Error(err)
+			}
+			if ok {
+				lock.Lock()
+				cancelled++
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(cancelled, 1)
+	assert.Equal(failures(key), 1)
+	m := try.To1(psm.GetPSM(key))
+	assert.That(m.IsReady())
+	assert.Equal(m.Problem(), "cancelled by controller")
+	assert.Equal(m.LastState().PLInfo.Type, pltype.CACredOffer)
+
+	ok, err := CancelPSM(key, "again")
+	assert.NoError(err)
+	assert.That(!ok)
+
+	_, err = CancelPSM(psm.StateKey{DID: "agentDID", Nonce: "not-found"}, "")
+	assert.Error(err)
+}
+
+func TestFailPSM(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	key := addRunningPSM("fail")
+	assert.NoError(FailPSM(key, "problem reported"))
+	assert.Equal(failures(key), 1)
+
+	// the other end's report after our cancel is no-op
+	assert.NoError(FailPSM(key, "problem reported again"))
+	assert.Equal(failures(key), 1)
+	m := try.To1(psm.GetPSM(key))
+	assert.Equal(m.Problem(), "problem reported")
+}
@@ -1,6 +1,8 @@
 package prot
 
 import (
+	"fmt"
+
 	"github.com/findy-network/findy-agent/agent/aries"
 	"github.com/findy-network/findy-agent/agent/comm"
 	"github.com/findy-network/findy-agent/agent/didcomm"
@@ -90,7 +92,7 @@ func StartPSM(ts Initial) (err error) {
 	opl := aries.PayloadCreator.NewMsg(ts.T.ID(), ts.SendNext, msg)
 
 	try.To(UpdatePSM(wDID, connID, ts.T, opl, psm.Sending))
-	try.To(comm.SendPL(pipe, ts.T, opl))
+	try.To(sendPL(wa, pipe, ts.T, opl))
 
 	// sending went OK, update PSM for what we are doing next: waiting a
 	// message from other side or we are ready.
@@ -125,7 +127,14 @@ func ContinuePSM(shift Again) (err error) {
 		Nonce: shift.InMsg.SubLevelID(),
 	}))
 
+	if PSM.IsReady() { // e.g. cancelled while waiting the user action
+		return fmt.Errorf("protocol %s has already ended", PSM.Key.Nonce)
+	}
 	presentTask := PSM.PresentTask()
+	if err := checkTaskTiming(presentTask); err != nil {
+		try.To(failPSM(PSM, err.Error(), PSM.LastState().PLInfo))
+		return err
+	}
 
 	connID := PSM.ConnID
 	meDID := PSM.Key.DID
@@ -146,8 +155,9 @@ func ContinuePSM(shift Again) (err error) {
 
 	im := aries.MsgCreator.Create(didcomm.MsgInit{
 		Nonce: shift.InMsg.SubLevelID(), // Continue Task ID comes in as Msg.ID
-		Ready: shift.InMsg.Ready()},     // How we continue comes in Ready field
-	)
+		Ready: shift.InMsg.Ready(),      // How we continue comes in Ready field
+		Info:  shift.InMsg.Info(),       // and possible answer in Info field
+	})
 	om := aries.MsgCreator.Create(didcomm.MsgInit{
 		Type:   plType,
 		Thread: decorator.NewThread(shift.InMsg.SubLevelID(), ""),
@@ -168,7 +178,7 @@ func ContinuePSM(shift Again) (err error) {
 		presentTask.SetReceiverEndp(agentEndp)
 
 		try.To(UpdatePSM(meDID, connID, presentTask, opl, psm.Sending))
-		try.To(comm.SendPL(pipe, presentTask, opl))
+		try.To(sendPL(wa, pipe, presentTask, opl))
 	}
 	if isLast {
 		wpl := aries.PayloadCreator.New(didcomm.PayloadInit{ID: presentTask.ID(), Type: plType})
@@ -204,16 +214,20 @@ func ExecPSM(ts Transition) (err error) {
 	}
 	ts.TaskHeader.TaskID = ts.Payload.ThreadID()
 	ts.TaskHeader.TypeID = ts.Payload.Type()
+	ts.TaskHeader.Expires = expiresTime(ts.Payload)
 
 	// Create protocol task in protocol implementation
 	task := try.To1(CreateTask(ts.TaskHeader, nil))
 
 	defer err2.Handle(&err, func(err error) error {
 		_ = UpdatePSM(meDID, connID, task, ts.Payload, psm.Failure)
+		reportProblem(ts, task, err)
 		return err
 	})
 
 	try.To(UpdatePSM(meDID, connID, task, ts.Payload, psm.Received))
+	try.To(checkTiming(ts.Payload))
+	sendAck(ts, task, decorator.AckOnReceipt, AckStatusPending)
 
 	var om didcomm.MessageHdr
 	var ep sec.Pipe
@@ -257,7 +271,7 @@ func ExecPSM(ts Transition) (err error) {
 		task.SetReceiverEndp(agentEndp)
 
 		try.To(UpdatePSM(meDID, connID, task, opl, psm.Sending))
-		try.To(comm.SendPL(ep, task, opl))
+		try.To(sendPL(ts.Receiver, ep, task, opl))
 	}
 
 	if isLast {
@@ -267,6 +281,13 @@ func ExecPSM(ts Transition) (err error) {
 		wpl := aries.PayloadCreator.New(didcomm.PayloadInit{ID: task.ID(), Type: ts.WaitingNext})
 		try.To(UpdatePSM(meDID, connID, task, wpl, psm.Waiting))
 	}
+	if !sendBack { // our reply is the outcome if we sent one
+		status := AckStatusOK
+		if ackFlag != psm.ACK {
+			status = AckStatusFail
+		}
+		sendAck(ts, task, decorator.AckOnOutcome, status)
+	}
 	return nil
 }
 
@@ -339,7 +360,7 @@ func FindAndStartTask(receiver comm.Receiver, task comm.Task) {
 	go proc.Starter(receiver, task)
 }
 
-func Resume(rcvr comm.Receiver, typeID, protocolID string, ack bool) {
+func Resume(rcvr comm.Receiver, typeID, protocolID string, ack bool, info string) {
 	proc, ok := continuators[typeID]
 	if !ok {
 		glog.Error("!!No prot continuator for:", typeID)
@@ -350,6 +371,7 @@ func Resume(rcvr comm.Receiver, typeID, protocolID string, ack bool) {
 		Ready: ack,
 		ID:    protocolID, // This Has the SubLevelID() Getter
 		Nonce: protocolID, // This makes the Thread decorator
+		Info:  info,
 	}).(didcomm.Msg)
 
 	go proc.Continuator(rcvr, om)
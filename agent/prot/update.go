@@ -150,6 +150,7 @@ func UpdatePSM(
 		userActionType:    task.UserActionType(),
 		protocolFamily:    currentPSM.Protocol(),
 		role:              currentPSM.Role,
+		silent:            isSilent(currentPSM),
 	})
 
 	return nil
@@ -236,6 +237,14 @@ type endingInfo struct {
 	userActionType    string
 	protocolFamily    string
 	role              pb.Protocol_Role
+	silent            bool // no controller notifications, only bus
+}
+
+// isSilent tells if the task which started the PSM doesn't want the
+// controllers to be notified about it.
+func isSilent(m *psm.PSM) bool {
+	t := m.FirstState().T
+	return t != nil && t.Silent()
 }
 
 func triggerEnd(info endingInfo) {
@@ -252,7 +261,7 @@ func triggerEnd(info endingInfo) {
 	case psm.Ready:
 		// Do broadcasts and cleanup, this machine is ready
 		ack := info.subState&psm.ACK != 0
-		if ack {
+		if ack && !info.silent {
 			if info.plType == pltype.Nothing {
 				glog.Warning("PL type is empty on Notify")
 			}
@@ -277,7 +286,7 @@ func triggerEnd(info endingInfo) {
 		if info.subState&psm.Failure != 0 {
 			plType = pltype.CANotifyStatus
 		}
-		if plType != pltype.Nothing {
+		if plType != pltype.Nothing && !info.silent {
 			bus.WantUserActions.Broadcast(key, info.subState)
 			NotifyEdge(notifyEdge{
 				did:         info.meDID,
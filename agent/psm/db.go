@@ -3,13 +3,16 @@ package psm
 import (
 	"crypto/md5"
 	"fmt"
+	"sync"
 
 	"github.com/findy-network/findy-agent/agent/endp"
-	"github.com/findy-network/findy-agent/agent/pltype"
 	"github.com/findy-network/findy-common-go/crypto"
 	"github.com/findy-network/findy-common-go/crypto/db"
+	"github.com/findy-network/findy-common-go/dto"
 	"github.com/golang/glog"
+	"github.com/lainio/err2"
 	"github.com/lainio/err2/assert"
+	"github.com/lainio/err2/try"
 )
 
 const (
@@ -19,6 +22,13 @@ const (
 	BucketBasicMessage
 	BucketIssueCred
 	BucketPresentProof
+	BucketActionMenu
+	BucketQuestionAnswer
+	BucketBatch
+	BucketBatchItem
+	BucketProofTemplate
+	BucketProofTemplateVersion
+	BucketConnPSM // PSM index of the connections
 )
 
 var (
@@ -29,11 +39,21 @@ var (
 		{BucketBasicMessage},
 		{BucketIssueCred},
 		{BucketPresentProof},
+		{BucketActionMenu},
+		{BucketQuestionAnswer},
+		{BucketBatch},
+		{BucketBatchItem},
+		{BucketProofTemplate},
+		{BucketProofTemplateVersion},
+		{BucketConnPSM},
 	}
 
 	theCipher *crypto.Cipher
 
 	mgdDB db.Handle
+
+	// connIndexLock serializes the updates of the connection index.
+	connIndexLock sync.Mutex
 )
 
 type Rep interface {
@@ -55,14 +75,18 @@ func (f *Factor) Add(t byte, factor CreatorFunc) {
 }
 
 // Open opens the database by name of the file. If it is already open it returns
-// it, but it doesn't check the database name it isn't thread safe!
+// it, but it doesn't check the database name it isn't thread safe! The
+// connection index is built if the database has PSMs from the time before the
+// index.
 func Open(filename string) (err error) {
+	defer err2.Handle(&err, "open PSM DB")
+
 	mgdDB = db.New(db.Cfg{
 		Filename:   filename,
 		Buckets:    buckets,
 		BackupName: filename + "_backup",
 	})
-	return nil
+	return buildConnIndex()
 }
 
 func Close() {
@@ -130,8 +154,16 @@ func RmRawPL(addr *endp.Addr) (err error) {
 		})
 }
 
+// AddPSM saves the PSM. The PSM of the connection is added to the connection
+// index as well, see RmConnectionPSMs.
 func AddPSM(p *PSM) (err error) {
-	return addData(p.Key.Data(), p.Data(), BucketPSM)
+	defer err2.Handle(&err, "add PSM")
+
+	try.To(addData(p.Key.Data(), p.Data(), BucketPSM))
+	if p.ConnID != "" {
+		try.To(indexConnPSM(p.Key, p.ConnID))
+	}
+	return nil
 }
 
 // GetPSM get existing PSM from DB. If the PSM doesn't exist it returns error.
@@ -173,24 +205,142 @@ func GetRep(repType byte, k StateKey) (m Rep, err error) {
 	return m, err
 }
 
+// RmRep removes the rep of the type, e.g. the rep which isn't bound to any
+// PSM. See RmPSM for the reps of the PSMs.
+func RmRep(repType byte, k StateKey) (err error) {
+	return rm(k, repType)
+}
+
+// GetAllReps returns all of the reps of the type. The caller filters them,
+// e.g. by the agent DID of the key.
+func GetAllReps(repType byte) (reps []Rep, err error) {
+	defer err2.Handle(&err, "get all reps (%d)", repType)
+
+	factor, ok := Creator.factors[repType]
+	if !ok {
+		return nil, fmt.Errorf("no factor found for rep type %d", repType)
+	}
+	values := try.To1(mgdDB.GetAllValuesFromBucket(buckets[repType], decrypt))
+	reps = make([]Rep, 0, len(values))
+	for _, d := range values {
+		reps = append(reps, factor(d))
+	}
+	return reps, nil
+}
+
+// RmPSM removes the PSM and the reps of its protocol. All of the reps of the
+// protocols, e.g. the present proof v1 and v2 reps, are keyed by the PSM key,
+// and the protocol IDs are unique. That's why the key is removed from every
+// rep bucket, and no protocol can be missed.
 func RmPSM(p *PSM) (err error) {
+	defer err2.Handle(&err, "rm PSM %s", p.Key.Nonce)
+
 	glog.V(1).Infoln("--- rm PSM:", p.Key)
-	switch p.Protocol() {
-	case pltype.ProtocolBasicMessage:
-		err = rm(p.Key, BucketBasicMessage)
-	case pltype.ProtocolConnection:
-		err = rm(p.Key, BucketPairwise)
-	case pltype.ProtocolIssueCredential:
-		err = rm(p.Key, BucketIssueCred)
-	case pltype.ProtocolPresentProof:
-		err = rm(p.Key, BucketPresentProof)
-	}
-	if err != nil {
-		return err
+	for _, bucketID := range repBuckets {
+		try.To(rm(p.Key, bucketID))
+	}
+	if p.ConnID != "" {
+		try.To(unindexConnPSM(p.Key, p.ConnID))
 	}
 	return rm(p.Key, BucketPSM)
 }
 
+// repBuckets are the buckets of the reps, i.e. all except the PSMs, the raw
+// payloads and the index.
+var repBuckets = []byte{
+	BucketPairwise,
+	BucketBasicMessage,
+	BucketIssueCred,
+	BucketPresentProof,
+	BucketActionMenu,
+	BucketQuestionAnswer,
+	BucketBatch,
+	BucketBatchItem,
+	BucketProofTemplate,
+	BucketProofTemplateVersion,
+}
+
+// RmConnectionPSMs removes all of the PSMs of the agent's connection, the
+// running ones as well, and returns the count of the removed PSMs. The PSMs
+// are found by the connection index.
+func RmConnectionPSMs(agentDID, connID string) (count int, err error) {
+	defer err2.Handle(&err, "rm connection PSMs")
+
+	key := connIndexKey(agentDID, connID)
+	for _, nonce := range try.To1(getConnIndex(key)) {
+		m := try.To1(FindPSM(StateKey{DID: agentDID, Nonce: nonce}))
+		if m == nil {
+			continue
+		}
+		try.To(RmPSM(m))
+		count++
+	}
+	connIndexLock.Lock()
+	defer connIndexLock.Unlock()
+	return count, rm(key, BucketConnPSM)
+}
+
+// connIndexKey is the key of the connection's PSM nonces in the index.
+func connIndexKey(agentDID, connID string) StateKey {
+	return StateKey{DID: agentDID, Nonce: connID}
+}
+
+func getConnIndex(key StateKey) (nonces []string, err error) {
+	_, err = get(key, BucketConnPSM, func(d []byte) {
+		dto.FromGOB(d, &nonces)
+	})
+	return nonces, err
+}
+
+func indexConnPSM(k StateKey, connID string) (err error) {
+	connIndexLock.Lock()
+	defer connIndexLock.Unlock()
+
+	key := connIndexKey(k.DID, connID)
+	nonces := try.To1(getConnIndex(key))
+	for _, nonce := range nonces {
+		if nonce == k.Nonce {
+			return nil
+		}
+	}
+	return addData(key.Data(), dto.ToGOB(append(nonces, k.Nonce)), BucketConnPSM)
+}
+
+func unindexConnPSM(k StateKey, connID string) (err error) {
+	connIndexLock.Lock()
+	defer connIndexLock.Unlock()
+
+	key := connIndexKey(k.DID, connID)
+	nonces := try.To1(getConnIndex(key))
+	left := make([]string, 0, len(nonces))
+	for _, nonce := range nonces {
+		if nonce != k.Nonce {
+			left = append(left, nonce)
+		}
+	}
+	if len(left) == 0 {
+		return rm(key, BucketConnPSM)
+	}
+	return addData(key.Data(), dto.ToGOB(left), BucketConnPSM)
+}
+
+// buildConnIndex indexes the PSMs of the connections if the index is empty,
+// i.e. the PSMs are from the time before the index.
+func buildConnIndex() (err error) {
+	defer err2.Handle(&err, "build connection index")
+
+	if len(try.To1(mgdDB.GetAllValuesFromBucket(buckets[BucketConnPSM], decrypt))) > 0 {
+		return nil
+	}
+	for _, d := range try.To1(mgdDB.GetAllValuesFromBucket(buckets[BucketPSM], decrypt)) {
+		m := NewPSM(d)
+		if m.ConnID != "" {
+			try.To(indexConnPSM(m.Key, m.ConnID))
+		}
+	}
+	return nil
+}
+
 // all of the following has same signature. They also panic on error
 
 // hash makes the cryptographic hash of the map key value. This prevents us to
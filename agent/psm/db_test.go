@@ -70,6 +70,7 @@ func Test_addPSM(t *testing.T) {
 
 type testRep struct {
 	StateKey
+	bucket byte
 }
 
 func (t *testRep) Key() StateKey {
@@ -81,7 +82,7 @@ func (t *testRep) Data() []byte {
 }
 
 func (t *testRep) Type() byte {
-	return BucketPSM // just use any type
+	return t.bucket // BucketPSM by default, just use any type
 }
 
 func NewTestRep(d []byte) Rep {
@@ -115,10 +116,60 @@ func Test_addBaseRep(t *testing.T) {
 			got, err := GetRep(msgRep.Type(), msgRep.StateKey)
 			assert.NoError(err)
 			assert.DeepEqual(msgRep, got)
+
+			all, err := GetAllReps(msgRep.Type())
+			assert.NoError(err)
+			assert.SLen(all, 1)
+			assert.DeepEqual(msgRep, all[0])
 		})
 	}
 }
 
+func TestRmConnectionPSMs(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	const agentDID = "RM-TEST"
+	newPSM := func(nonce, connID string) *PSM {
+		m := testPSM(1)
+		m.Key = StateKey{DID: agentDID, Nonce: nonce}
+		m.ConnID = connID
+		assert.NoError(AddPSM(m))
+		assert.NoError(AddPSM(m)) // the next state doesn't duplicate the index
+		return m
+	}
+	newPSM("1", "conn1")
+	newPSM("2", "conn1")
+	other := newPSM("3", "conn2")
+
+	Creator.Add(BucketPresentProof, NewTestRep)
+	rep := &testRep{StateKey: StateKey{DID: agentDID, Nonce: "2"}, bucket: BucketPresentProof}
+	assert.NoError(AddRep(rep))
+
+	count, err := RmConnectionPSMs(agentDID, "conn1")
+	assert.NoError(err)
+	assert.Equal(count, 2)
+
+	m, err := FindPSM(StateKey{DID: agentDID, Nonce: "1"})
+	assert.NoError(err)
+	assert.That(m == nil)
+	got, err := GetRep(BucketPresentProof, rep.StateKey)
+	assert.NoError(err)
+	assert.That(got == nil, "rep of the PSM isn't removed")
+	m, err = FindPSM(other.Key)
+	assert.NoError(err)
+	assert.NotNil(m)
+
+	count, err = RmConnectionPSMs(agentDID, "conn1")
+	assert.NoError(err)
+	assert.Equal(count, 0)
+
+	assert.NoError(RmPSM(other))
+	nonces, err := getConnIndex(connIndexKey(agentDID, "conn2"))
+	assert.NoError(err)
+	assert.SLen(nonces, 0)
+}
+
 func Test_Close(t *testing.T) {
 	assert.PushTester(t)
 	defer assert.PopTester()
@@ -128,4 +179,5 @@ func Test_Close(t *testing.T) {
 	assert.NoError(err)
 
 	Close()
+	os.Remove(path)
 }
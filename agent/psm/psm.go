@@ -169,6 +169,7 @@ type State struct {
 	T         comm.Task
 	PLInfo    PayloadInfo
 	Sub       SubState
+	Info      string // the reason of the failure if known
 }
 
 // PSM is Protocol State Machine that works in event sourcing principle, i.e.
@@ -212,6 +213,17 @@ func (p *PSM) IsReady() bool {
 	return false
 }
 
+// Problem returns the reason why the PSM failed, e.g. the description of the
+// problem report the other end sent. It's empty if the reason isn't known.
+func (p *PSM) Problem() string {
+	for i := len(p.States) - 1; i >= 0; i-- {
+		if s := p.States[i]; s.Sub&Failure != 0 && s.Info != "" {
+			return s.Info
+		}
+	}
+	return ""
+}
+
 func (p *PSM) Timestamp() int64 {
 	if state := p.LastState(); state != nil {
 		return state.Timestamp
@@ -186,3 +186,17 @@ func TestAccept(t *testing.T) {
 	accept = p.Accept(ReadyACK)
 	assert.That(accept)
 }
+
+func TestPSM_Problem(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	p := PSM{States: []State{{Sub: Waiting}}}
+	assert.Equal(p.Problem(), "")
+
+	p.States = append(p.States,
+		State{Sub: Failure, Info: "request not accepted"},
+		State{Sub: Failure | Archiving},
+	)
+	assert.Equal(p.Problem(), "request not accepted")
+}
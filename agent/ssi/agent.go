@@ -104,8 +104,9 @@ type DIDAgent struct {
 
 	sync.Mutex // Currently saImplID makes the agent mutable
 
-	saImplID string        // SA implementation ID, used mostly for tests
-	EAEndp   *service.Addr // EA endpoint if set, used for SA API and notifications
+	saImplID           string        // SA implementation ID, used mostly for tests
+	manualConnApproval bool          // connection requests wait controller's approval
+	EAEndp             *service.Addr // EA endpoint if set, used for SA API and notifications
 }
 
 func (a *DIDAgent) SAImplID() string {
@@ -120,10 +121,29 @@ func (a *DIDAgent) SetSAImplID(id string) {
 	a.saImplID = id
 }
 
+// ManualConnApproval tells if the incoming connection requests must be
+// approved by the controller before we respond to them.
+func (a *DIDAgent) ManualConnApproval() bool {
+	a.Lock()
+	defer a.Unlock()
+	return a.manualConnApproval
+}
+
+func (a *DIDAgent) SetManualConnApproval(on bool) {
+	a.Lock()
+	defer a.Unlock()
+	a.manualConnApproval = on
+}
+
 func (a *DIDAgent) AddDIDCache(DID *DID) {
 	a.DidCache.Add(DID)
 }
 
+// RmDIDCache removes the DID from the DID cache of the agent.
+func (a *DIDAgent) RmDIDCache(did string) {
+	a.DidCache.Remove(did)
+}
+
 func (a *DIDAgent) IsCA() bool {
 	// Our default agent type is Cloud DIDAgent but we don't want to set zero to
 	// Cloud type. Instead we state that if we are not EA we are CA.
@@ -361,6 +381,10 @@ func (a *DIDAgent) KMS() kms.KeyManager {
 	return a.Storage().KMS()
 }
 
+func (a *DIDAgent) CredentialStorage() storage.CredentialStorage {
+	return a.Storage().CredentialStorage()
+}
+
 // localKey returns a future to the verkey of the DID from a local wallet.
 func (a *DIDAgent) localKey(didName string) (f *async.Future) {
 	defer err2.Catch(err2.Err(func(err error) {
@@ -50,6 +50,14 @@ func (c *Cache) Get(s string, sure bool) *DID {
 	return c.cache[s]
 }
 
+// Remove removes the DID from the cache, e.g. when the connection is deleted.
+func (c *Cache) Remove(s string) {
+	c.Lock()
+	defer c.Unlock()
+
+	delete(c.cache, s)
+}
+
 func (c *Cache) Clone() Cache {
 	c.Lock()
 	defer c.Unlock()
@@ -64,3 +64,18 @@ func TestCache_get(t *testing.T) {
 		})
 	}
 }
+
+func TestCache_remove(t *testing.T) {
+	c := Cache{}
+	c.Add(NewDid("DID_STRING", "VER_KEY"))
+	c.Add(NewDid("DID_STRING1", "VER_KEY1"))
+
+	c.Remove("DID_STRING")
+	c.Remove("NOT_EXIST")
+	if got := c.Get("DID_STRING", true); got != nil {
+		t.Errorf("Cache.Remove() left %v", got)
+	}
+	if got := c.Get("DID_STRING1", true); got == nil {
+		t.Errorf("Cache.Remove() removed wrong DID")
+	}
+}
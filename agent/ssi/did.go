@@ -232,19 +232,15 @@ func (d *DID) SavePairwiseForDID(mStorage managed.Wallet, tDID core.DID, pw core
 	// check that DIDs are ready
 	ok := d.data.Result().Err() == nil && theirDID.stored.Result().Err() == nil
 	if ok {
-		connection, _ := mStorage.Storage().ConnectionStorage().GetConnection(pw.Name)
-		if connection == nil {
-			connection = &api.Connection{
-				ID: pw.Name,
-			}
-		}
-		connection.MyDID = d.Did()
-		connection.TheirDID = theirDID.Did()
-		connection.TheirRoute = pw.Route
-		glog.V(7).Infoln("=== save connection:",
-			connection.ID, connection.MyDID, connection.TheirDID)
-
-		err := mStorage.Storage().ConnectionStorage().SaveConnection(*connection)
+		_, err := mStorage.Storage().ConnectionStorage().UpdateConnection(pw.Name,
+			func(connection *api.Connection, _ bool) error {
+				connection.MyDID = d.Did()
+				connection.TheirDID = theirDID.Did()
+				connection.TheirRoute = pw.Route
+				glog.V(7).Infoln("=== save connection:",
+					connection.ID, connection.MyDID, connection.TheirDID)
+				return nil
+			})
 		errStr := ""
 		if err != nil {
 			ok = false
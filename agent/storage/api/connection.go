@@ -0,0 +1,64 @@
+package api
+
+import "strings"
+
+// States of the connections. The connection is invited until the other end
+// has responded, and it's unreachable when the health monitor cannot ping it.
+const (
+	ConnectionStateInvited     = "invited"
+	ConnectionStateReady       = "ready"
+	ConnectionStateUnreachable = "unreachable"
+)
+
+// State returns the state of the connection.
+func (c Connection) State() string {
+	switch {
+	case c.TheirDID == "":
+		return ConnectionStateInvited
+	case c.Health.Unreachable:
+		return ConnectionStateUnreachable
+	}
+	return ConnectionStateReady
+}
+
+// HasTag tells if the connection has the tag.
+func (c Connection) HasTag(tag string) bool {
+	return contains(c.Tags, tag)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ConnectionFilter selects connections. Empty fields match all. The connection
+// must have all of the tags. Search matches the connections which label or
+// note include all of its words in any case.
+type ConnectionFilter struct {
+	State  string
+	Tags   []string
+	Search string
+}
+
+// Match tells if the connection matches to the filter.
+func (f ConnectionFilter) Match(c Connection) bool {
+	if !matchStr(f.State, c.State()) {
+		return false
+	}
+	for _, tag := range f.Tags {
+		if !c.HasTag(tag) {
+			return false
+		}
+	}
+	text := strings.ToLower(c.TheirLabel + " " + c.Note)
+	for _, word := range strings.Fields(strings.ToLower(f.Search)) {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/lainio/err2/assert"
+)
+
+func TestConnectionFilter_Match(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	conn := Connection{
+		ID:         "conn-1",
+		TheirDID:   "did:peer:1",
+		TheirLabel: "Acme Bank",
+		Tags:       []string{"customer", "vip"},
+		Note:       "met at the fair",
+	}
+	tests := []struct {
+		name   string
+		filter ConnectionFilter
+		want   bool
+	}{
+		{"empty", ConnectionFilter{}, true},
+		{"state", ConnectionFilter{State: ConnectionStateReady}, true},
+		{"wrong state", ConnectionFilter{State: ConnectionStateInvited}, false},
+		{"tags", ConnectionFilter{Tags: []string{"vip", "customer"}}, true},
+		{"missing tag", ConnectionFilter{Tags: []string{"vip", "partner"}}, false},
+		{"search", ConnectionFilter{Search: "acme FAIR"}, true},
+		{"wrong search", ConnectionFilter{Search: "acme insurance"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.PushTester(t)
+			defer assert.PopTester()
+			assert.Equal(tt.filter.Match(conn), tt.want)
+		})
+	}
+}
+
+func TestConnection_State(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	conn := Connection{ID: "conn-1"}
+	assert.Equal(conn.State(), ConnectionStateInvited)
+	conn.TheirDID = "did:peer:1"
+	assert.Equal(conn.State(), ConnectionStateReady)
+	conn.Health.Unreachable = true
+	assert.Equal(conn.State(), ConnectionStateUnreachable)
+}
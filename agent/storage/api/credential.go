@@ -0,0 +1,79 @@
+package api
+
+import "strings"
+
+// Formats of the credentials we hold.
+const (
+	FormatIndy   = "anoncreds"
+	FormatW3CLDP = "ldp_vc"
+	FormatW3CJWT = "jwt_vc"
+)
+
+// Credential is a verifiable credential we hold. Data is in the wire format
+// of the credential, e.g. JSON for Indy and W3C JSON-LD credentials, and
+// compact JWS for JWT credentials. For Indy credentials ID is the referent
+// of the credential in the anoncreds wallet.
+type Credential struct {
+	ID         string
+	Format     string
+	Issuer     string
+	SchemaID   string
+	CredDefID  string
+	Attributes map[string]string
+	Data       []byte
+
+	// Created is the Unix time in nanoseconds when we received the
+	// credential, like the Created of the connection. It's zero for the
+	// credentials received before it was recorded.
+	Created int64
+
+	// Deleted marks the deleted Indy credential. The anoncreds wallet cannot
+	// delete credentials, so the record is kept to leave the credential out
+	// of the proofs.
+	Deleted bool
+}
+
+// IsW3C tells if the credential is W3C verifiable credential.
+func (c Credential) IsW3C() bool {
+	return c.Format == FormatW3CLDP || c.Format == FormatW3CJWT
+}
+
+// CredentialFilter selects credentials. Empty fields match all. Every given
+// attribute must have the given value. Deleted credentials match only if
+// WithDeleted is set.
+type CredentialFilter struct {
+	Format     string
+	Issuer     string
+	SchemaID   string
+	CredDefID  string
+	Attributes map[string]string
+
+	WithDeleted bool
+}
+
+// Match tells if the credential matches to the filter.
+func (f CredentialFilter) Match(c Credential) bool {
+	if c.Deleted && !f.WithDeleted {
+		return false
+	}
+	if !matchStr(f.Format, c.Format) || !matchStr(f.Issuer, c.Issuer) ||
+		!matchStr(f.SchemaID, c.SchemaID) || !matchStr(f.CredDefID, c.CredDefID) {
+		return false
+	}
+	for name, value := range f.Attributes {
+		if v, ok := c.Attributes[name]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// IndyIssuer returns the issuer DID from the Indy cred def ID, which format is
+// <issuer_did>:3:CL:<schema_seq_no>:<tag>.
+func IndyIssuer(credDefID string) string {
+	return strings.SplitN(credDefID, ":", 2)[0]
+}
+
+func matchStr(filter, value string) bool {
+	return filter == "" || filter == value
+}
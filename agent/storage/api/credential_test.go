@@ -0,0 +1,46 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/lainio/err2/assert"
+)
+
+func TestCredentialFilter_Match(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	cred := Credential{
+		Format:     FormatIndy,
+		Issuer:     "Th7MpTaRZVRYnPiabds81Y",
+		SchemaID:   "Th7MpTaRZVRYnPiabds81Y:2:email:1.0",
+		CredDefID:  "Th7MpTaRZVRYnPiabds81Y:3:CL:1:T1",
+		Attributes: map[string]string{"email": "alice@example.com"},
+	}
+	tests := []struct {
+		name   string
+		filter CredentialFilter
+		want   bool
+	}{
+		{"empty", CredentialFilter{}, true},
+		{"format", CredentialFilter{Format: FormatIndy}, true},
+		{"wrong format", CredentialFilter{Format: FormatW3CLDP}, false},
+		{"cred def", CredentialFilter{CredDefID: cred.CredDefID}, true},
+		{"attr", CredentialFilter{Attributes: map[string]string{"email": "alice@example.com"}}, true},
+		{"wrong attr", CredentialFilter{Attributes: map[string]string{"email": "bob@example.com"}}, false},
+		{"missing attr", CredentialFilter{Attributes: map[string]string{"name": ""}}, false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.PushTester(t)
+			defer assert.PopTester()
+			assert.Equal(tt.filter.Match(cred), tt.want)
+		})
+	}
+	assert.Equal(IndyIssuer(cred.CredDefID), cred.Issuer)
+
+	cred.Deleted = true
+	assert.That(!CredentialFilter{}.Match(cred))
+	assert.That(CredentialFilter{WithDeleted: true}.Match(cred))
+}
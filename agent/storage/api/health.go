@@ -0,0 +1,48 @@
+package api
+
+import "time"
+
+// HealthMonitorConfig is the saved configuration of the connection health
+// monitor. MaxPings is the count of the pings sent at the same time.
+type HealthMonitorConfig struct {
+	Interval     time.Duration
+	Timeout      time.Duration
+	FailureLimit int
+	MaxPings     int
+}
+
+// ConnectionHealth is the result of the periodic trust pings of the
+// connection. The times are Unix nano, and Latency is the round trip time of
+// the last answered ping.
+type ConnectionHealth struct {
+	LastPing    int64
+	LastSeen    int64
+	Latency     time.Duration
+	Failures    int
+	Unreachable bool
+}
+
+// Update records the result of the ping sent at the given time. The
+// connection is unreachable after failureLimit successive failures, zero
+// means no limit. Update returns true if the reachability changed.
+func (h *ConnectionHealth) Update(
+	replied bool,
+	at time.Time,
+	latency time.Duration,
+	failureLimit int,
+) bool {
+	wasUnreachable := h.Unreachable
+	h.LastPing = at.UnixNano()
+	if replied {
+		h.LastSeen = at.Add(latency).UnixNano()
+		h.Latency = latency
+		h.Failures = 0
+		h.Unreachable = false
+	} else {
+		h.Failures++
+		if failureLimit > 0 && h.Failures >= failureLimit {
+			h.Unreachable = true
+		}
+	}
+	return wasUnreachable != h.Unreachable
+}
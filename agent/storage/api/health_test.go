@@ -0,0 +1,37 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lainio/err2/assert"
+)
+
+func TestConnectionHealth_Update(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	now := time.Now()
+	var h ConnectionHealth
+
+	assert.That(!h.Update(true, now, time.Millisecond, 2))
+	assert.Equal(h.Latency, time.Millisecond)
+	assert.Equal(h.LastSeen, now.Add(time.Millisecond).UnixNano())
+
+	assert.That(!h.Update(false, now, 0, 2))
+	assert.Equal(h.Failures, 1)
+	assert.That(!h.Unreachable)
+
+	assert.That(h.Update(false, now, 0, 2))
+	assert.That(h.Unreachable)
+	assert.That(!h.Update(false, now, 0, 2))
+	assert.Equal(h.Failures, 3)
+
+	assert.That(h.Update(true, now, time.Millisecond, 2))
+	assert.That(!h.Unreachable)
+	assert.Equal(h.Failures, 0)
+
+	h = ConnectionHealth{}
+	assert.That(!h.Update(false, now, 0, 0))
+	assert.That(!h.Unreachable)
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/findy-network/findy-agent/std/decorator"
+)
+
+// Message is a basic message of the connection's history. ID is the protocol
+// ID of the message. The timestamps are Unix nano: SendTimestamp is the send
+// time the sender has given, and Timestamp the time we sent or received the
+// message.
+type Message struct {
+	ID            string
+	ConnectionID  string
+	Message       string
+	SentByMe      bool
+	Delivered     bool
+	Read          bool
+	SendTimestamp int64
+	Timestamp     int64
+	Attachments   []decorator.Attachment
+}
+
+// MessageFilter selects messages. Empty fields match all. Search matches the
+// messages which include all of its words in any case.
+type MessageFilter struct {
+	ConnectionID string
+	Search       string
+	Unread       bool
+}
+
+// Match tells if the message matches to the filter.
+func (f MessageFilter) Match(m Message) bool {
+	if !matchStr(f.ConnectionID, m.ConnectionID) || (f.Unread && m.Read) {
+		return false
+	}
+	text := strings.ToLower(m.Message)
+	for _, word := range strings.Fields(strings.ToLower(f.Search)) {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// PageMessages sorts the messages by time, the oldest first, and returns the
+// page of them starting from offset. All the rest are returned if the limit
+// is zero. The negative offset and limit are handled as zero.
+func PageMessages(msgs []Message, offset, limit int) []Message {
+	if offset < 0 {
+		offset = 0
+	}
+	sort.SliceStable(msgs, func(i, j int) bool {
+		return msgs[i].Timestamp < msgs[j].Timestamp
+	})
+	if offset >= len(msgs) {
+		return []Message{}
+	}
+	msgs = msgs[offset:]
+	if limit > 0 && limit < len(msgs) {
+		msgs = msgs[:limit]
+	}
+	return msgs
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/lainio/err2/assert"
+)
+
+func TestMessageFilter_Match(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	msg := Message{ConnectionID: "conn-1", Message: "Do you authorize the payment?"}
+	tests := []struct {
+		name   string
+		filter MessageFilter
+		want   bool
+	}{
+		{"empty", MessageFilter{}, true},
+		{"connection", MessageFilter{ConnectionID: "conn-1"}, true},
+		{"wrong connection", MessageFilter{ConnectionID: "conn-2"}, false},
+		{"search", MessageFilter{Search: "PAYMENT authorize"}, true},
+		{"wrong search", MessageFilter{Search: "payment refund"}, false},
+		{"unread", MessageFilter{Unread: true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.PushTester(t)
+			defer assert.PopTester()
+			assert.Equal(tt.filter.Match(msg), tt.want)
+		})
+	}
+	msg.Read = true
+	assert.That(!MessageFilter{Unread: true}.Match(msg))
+}
+
+func TestPageMessages(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	msgs := []Message{{ID: "3", Timestamp: 3}, {ID: "1", Timestamp: 1}, {ID: "2", Timestamp: 2}}
+	page := PageMessages(msgs, 1, 1)
+	assert.SLen(page, 1)
+	assert.Equal(page[0].ID, "2")
+	assert.SLen(PageMessages(msgs, 0, 0), 3)
+	assert.SLen(PageMessages(msgs, 5, 1), 0)
+	assert.SLen(PageMessages(msgs, -1, -1), 3)
+}
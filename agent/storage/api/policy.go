@@ -0,0 +1,190 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Actions of the policy rules. Ask escalates the request to the controller,
+// i.e. the protocol waits the user action.
+const (
+	PolicyAccept = "accept"
+	PolicyAsk    = "ask"
+)
+
+// Protocols of the policy rules.
+const (
+	PolicyConnection = "connection"
+	PolicyIssue      = "issue"
+	PolicyProof      = "proof"
+)
+
+// Orders of the credential selection. The empty order keeps the order the
+// wallet finds the credentials.
+const (
+	SelectNewest = "newest"
+	SelectOldest = "oldest"
+)
+
+// Policy is the auto-accept policy of the agent. The first matching rule
+// decides the action, and the Default is used when none of the rules match.
+// The empty Default is Ask. Selection tells which credentials the proofs are
+// made of when the controller hasn't selected them.
+type Policy struct {
+	Rules     []PolicyRule
+	Default   string
+	Selection CredSelection
+}
+
+// CredSelection selects the credential of the proof from the ones matching
+// the proof request. The credentials of the Issuers are preferred in the
+// listed order, and the Order decides between the equal ones.
+type CredSelection struct {
+	Order   string
+	Issuers []string
+}
+
+// PolicyRule matches the incoming requests of the protocol. Empty fields match
+// all. The request matches only if all of its cred defs, issuers and
+// attributes are listed in the rule. LabelPattern isn't supported anymore:
+// the label is chosen by the other end, and it cannot tell who they are.
+// Validate rejects it, and the old saved rules with it don't match.
+type PolicyRule struct {
+	Name         string
+	Protocol     string
+	Action       string
+	CredDefIDs   []string
+	Issuers      []string
+	Attributes   []string
+	LabelPattern string
+}
+
+// PolicyRequest is the incoming request to decide: the connection request,
+// the credential offer or the proof request. CredDefIDs and Issuers of the
+// proof request are its restrictions. Issuers are the issuer DIDs of the
+// restrictions which don't have the cred def. Unrestricted tells that some
+// of the requested attributes or predicates can come from any issuer.
+type PolicyRequest struct {
+	Protocol     string
+	CredDefIDs   []string
+	Issuers      []string
+	Attributes   []string
+	Unrestricted bool
+}
+
+// Validate checks that the actions and protocols of the policy are known and
+// the rules don't have label patterns.
+func (p Policy) Validate() error {
+	if !validAction(p.Default) {
+		return fmt.Errorf("unknown default action: %s", p.Default)
+	}
+	switch p.Selection.Order {
+	case "", SelectNewest, SelectOldest:
+	default:
+		return fmt.Errorf("unknown selection order: %s", p.Selection.Order)
+	}
+	for i, r := range p.Rules {
+		switch r.Protocol {
+		case PolicyConnection, PolicyIssue, PolicyProof:
+		default:
+			return fmt.Errorf("rule %d (%s): unknown protocol: %s", i, r.Name, r.Protocol)
+		}
+		if r.Action == "" || !validAction(r.Action) {
+			return fmt.Errorf("rule %d (%s): unknown action: %s", i, r.Name, r.Action)
+		}
+		if r.LabelPattern != "" {
+			return fmt.Errorf("rule %d (%s): label isn't a trust criterion", i, r.Name)
+		}
+	}
+	return nil
+}
+
+// Decide returns the action for the request and the name of the rule which
+// decided it. The rule name is empty when the default is used.
+func (p Policy) Decide(req PolicyRequest) (action, rule string) {
+	for _, r := range p.Rules {
+		if r.Match(req) {
+			return r.Action, r.Name
+		}
+	}
+	if p.Default == "" {
+		return PolicyAsk, ""
+	}
+	return p.Default, ""
+}
+
+// Match tells if the rule matches to the request. The rules of cred defs and
+// issuers don't match the unrestricted requests, and the rules of cred defs
+// don't match the requests restricted by the issuers only.
+func (r PolicyRule) Match(req PolicyRequest) bool {
+	if r.Protocol != req.Protocol || r.LabelPattern != "" {
+		return false
+	}
+	if len(r.CredDefIDs) > 0 && (req.Unrestricted || len(req.Issuers) > 0 ||
+		!allListed(req.CredDefIDs, r.CredDefIDs)) {
+		return false
+	}
+	if len(r.Issuers) > 0 {
+		issuers := append([]string{}, req.Issuers...)
+		for _, id := range req.CredDefIDs {
+			issuers = append(issuers, CredDefIssuer(id))
+		}
+		if req.Unrestricted || !allListed(issuers, r.Issuers) {
+			return false
+		}
+	}
+	return len(r.Attributes) == 0 || allListed(req.Attributes, r.Attributes)
+}
+
+// Select returns the index of the selected credential, or -1 if there are no
+// candidates. The candidates are in the wallet's order.
+func (s CredSelection) Select(candidates []Credential) int {
+	selected := -1
+	rank := func(c Credential) int {
+		for i, issuer := range s.Issuers {
+			if c.Issuer == issuer {
+				return i
+			}
+		}
+		return len(s.Issuers)
+	}
+	for i, c := range candidates {
+		if selected == -1 {
+			selected = i
+			continue
+		}
+		best := candidates[selected]
+		switch r, bestR := rank(c), rank(best); {
+		case r < bestR:
+			selected = i
+		case r > bestR:
+		case s.Order == SelectNewest && c.Created > best.Created,
+			s.Order == SelectOldest && c.Created < best.Created:
+			selected = i
+		}
+	}
+	return selected
+}
+
+// CredDefIssuer returns the issuer DID of the Indy cred def ID.
+func CredDefIssuer(credDefID string) string {
+	return strings.SplitN(credDefID, ":", 2)[0]
+}
+
+// allListed tells if all of the values are in the list. Empty values don't
+// match, i.e. the request must tell what it's about.
+func allListed(values, list []string) bool {
+	if len(values) == 0 {
+		return false
+	}
+	for _, v := range values {
+		if !contains(list, v) {
+			return false
+		}
+	}
+	return true
+}
+
+func validAction(a string) bool {
+	return a == "" || a == PolicyAccept || a == PolicyAsk
+}
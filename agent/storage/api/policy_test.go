@@ -0,0 +1,132 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/lainio/err2/assert"
+)
+
+func TestPolicy_Decide(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	const employerCredDef = "EMPLOYERDID:3:CL:12:employee"
+	policy := Policy{
+		Rules: []PolicyRule{
+			{Name: "employer", Protocol: PolicyIssue, Action: PolicyAccept,
+				Issuers: []string{"EMPLOYERDID"}},
+			{Name: "employee proofs", Protocol: PolicyProof, Action: PolicyAccept,
+				CredDefIDs: []string{employerCredDef}, Attributes: []string{"name", "title"}},
+			{Name: "employer proofs", Protocol: PolicyProof, Action: PolicyAccept,
+				Issuers: []string{"EMPLOYERDID"}, Attributes: []string{"title"}},
+		},
+	}
+	assert.NoError(policy.Validate())
+
+	tests := []struct {
+		name   string
+		req    PolicyRequest
+		action string
+		rule   string
+	}{
+		{"employer offer",
+			PolicyRequest{Protocol: PolicyIssue, CredDefIDs: []string{employerCredDef}},
+			PolicyAccept, "employer"},
+		{"other offer",
+			PolicyRequest{Protocol: PolicyIssue, CredDefIDs: []string{"OTHERDID:3:CL:1:t"}},
+			PolicyAsk, ""},
+		{"offer without cred def",
+			PolicyRequest{Protocol: PolicyIssue},
+			PolicyAsk, ""},
+		{"listed attributes",
+			PolicyRequest{Protocol: PolicyProof, CredDefIDs: []string{employerCredDef},
+				Attributes: []string{"title"}},
+			PolicyAccept, "employee proofs"},
+		{"unlisted attribute",
+			PolicyRequest{Protocol: PolicyProof, CredDefIDs: []string{employerCredDef},
+				Attributes: []string{"title", "salary"}},
+			PolicyAsk, ""},
+		{"unrestricted proof",
+			PolicyRequest{Protocol: PolicyProof, Attributes: []string{"title"}},
+			PolicyAsk, ""},
+		{"partly unrestricted proof",
+			PolicyRequest{Protocol: PolicyProof, CredDefIDs: []string{employerCredDef},
+				Attributes: []string{"title"}, Unrestricted: true},
+			PolicyAsk, ""},
+		{"issuer restricted proof",
+			PolicyRequest{Protocol: PolicyProof, Issuers: []string{"EMPLOYERDID"},
+				Attributes: []string{"title"}},
+			PolicyAccept, "employer proofs"},
+		{"connection",
+			PolicyRequest{Protocol: PolicyConnection},
+			PolicyAsk, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.PushTester(t)
+			defer assert.PopTester()
+			action, rule := policy.Decide(tt.req)
+			assert.Equal(action, tt.action)
+			assert.Equal(rule, tt.rule)
+		})
+	}
+
+	// the old saved rule with the label doesn't match anymore
+	policy.Rules = append(policy.Rules, PolicyRule{Name: "acme",
+		Protocol: PolicyConnection, Action: PolicyAccept, LabelPattern: "^Acme "})
+	action, rule := policy.Decide(PolicyRequest{Protocol: PolicyConnection})
+	assert.Equal(action, PolicyAsk)
+	assert.Equal(rule, "")
+
+	policy.Default = PolicyAccept
+	action, _ = policy.Decide(PolicyRequest{Protocol: PolicyIssue})
+	assert.Equal(action, PolicyAccept)
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	assert.Error(Policy{Default: "maybe"}.Validate())
+	assert.Error(Policy{Rules: []PolicyRule{
+		{Protocol: "unknown", Action: PolicyAccept}}}.Validate())
+	assert.Error(Policy{Rules: []PolicyRule{
+		{Protocol: PolicyIssue}}}.Validate())
+	assert.Error(Policy{Rules: []PolicyRule{
+		{Protocol: PolicyConnection, Action: PolicyAccept, LabelPattern: "^Acme "}}}.Validate())
+	assert.Error(Policy{Selection: CredSelection{Order: "random"}}.Validate())
+	assert.NoError(Policy{Selection: CredSelection{Order: SelectOldest}}.Validate())
+}
+
+func TestCredSelection_Select(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	candidates := []Credential{
+		{ID: "1", Issuer: "BANK", Created: 200},
+		{ID: "2", Issuer: "GOV", Created: 100},
+		{ID: "3", Issuer: "GOV", Created: 300},
+		{ID: "4", Issuer: "SHOP", Created: 400},
+	}
+	tests := []struct {
+		name string
+		sel  CredSelection
+		want int
+	}{
+		{"first", CredSelection{}, 0},
+		{"newest", CredSelection{Order: SelectNewest}, 3},
+		{"oldest", CredSelection{Order: SelectOldest}, 1},
+		{"issuer", CredSelection{Issuers: []string{"GOV"}}, 1},
+		{"newest of issuer", CredSelection{Order: SelectNewest, Issuers: []string{"GOV"}}, 2},
+		{"issuer order", CredSelection{Order: SelectOldest, Issuers: []string{"SHOP", "GOV"}}, 3},
+		{"unknown issuer", CredSelection{Order: SelectOldest, Issuers: []string{"NONE"}}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.PushTester(t)
+			defer assert.PopTester()
+			assert.Equal(tt.sel.Select(candidates), tt.want)
+		})
+	}
+	assert.Equal(CredSelection{}.Select(nil), -1)
+}
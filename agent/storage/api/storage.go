@@ -23,6 +23,9 @@ type AgentStorage interface {
 	DIDStorage() DIDStorage
 	ConnectionStorage() ConnectionStorage
 	CredentialStorage() CredentialStorage
+	MessageStorage() MessageStorage
+	PolicyStorage() PolicyStorage
+	SettingStorage() SettingStorage
 
 	OurPackager() Packager
 
@@ -43,16 +46,49 @@ type Connection struct {
 	TheirDID      string
 	TheirEndpoint string
 	TheirRoute    []string
+	Health        ConnectionHealth
+
+	TheirLabel string
+	Created    int64 // Unix nano, when the connection was established
+	Tags       []string
+	Note       string
 }
 
 type ConnectionStorage interface {
 	SaveConnection(conn Connection) error
 	GetConnection(id string) (*Connection, error)
 	ListConnections() ([]Connection, error)
+	DeleteConnection(id string) error
+
+	// UpdateConnection reads, updates and saves the connection so that the
+	// concurrent updates don't overwrite each other. The update gets a new
+	// connection with the ID and found false if it isn't stored yet. The
+	// connection isn't saved if the update returns an error.
+	UpdateConnection(id string, update func(conn *Connection, found bool) error) (*Connection, error)
 }
 
 type CredentialStorage interface {
-	// Similar than DIDStorage, relevant functionality for storing credential data
+	SaveCredential(cred Credential) error
+	GetCredential(id string) (*Credential, error)
+	ListCredentials(filter CredentialFilter) ([]Credential, error)
+	DeleteCredential(id string) error
+
+	// W3CCredentials returns the W3C verifiable credentials we hold in their
+	// wire format.
+	W3CCredentials() ([][]byte, error)
+}
+
+type MessageStorage interface {
+	SaveMessage(msg Message) error
+	GetMessage(id string) (*Message, error)
+
+	// ListMessages returns the page of the messages matching to the filter,
+	// and the count of all of the matching messages.
+	ListMessages(filter MessageFilter, offset, limit int) ([]Message, int, error)
+
+	// MarkMessagesRead marks the received messages of the connection read
+	// until the timestamp, and returns the count of the marked messages.
+	MarkMessagesRead(connectionID string, until int64) (int, error)
 }
 
 type Packager interface {
@@ -63,3 +99,36 @@ type Packager interface {
 	PackMessage(messageEnvelope *transport.Envelope) ([]byte, error)
 	StorageProvider() storage.Provider
 }
+
+// PolicyStorage stores the auto-accept policy of the agent. GetPolicy returns
+// nil if the policy isn't set.
+type PolicyStorage interface {
+	SavePolicy(p Policy) error
+	GetPolicy() (*Policy, error)
+}
+
+// Settings are the agent's own settings, which aren't bound to any
+// connection.
+type Settings struct {
+	// HealthMonitor is the configuration of the connection health monitor,
+	// nil if the monitor isn't running.
+	HealthMonitor *HealthMonitorConfig
+
+	// PleaseAck tells on which events, RECEIPT and/or OUTCOME, we ask acks
+	// for the protocol messages we send. Empty means no acks.
+	PleaseAck []string
+
+	// ManualConnApproval tells if the incoming connection requests wait for
+	// the controller's approval.
+	ManualConnApproval bool
+}
+
+// SettingStorage stores the settings of the agent. GetSettings returns the
+// zero settings if they aren't saved. UpdateSettings reads, updates and saves
+// the settings so that the concurrent updates don't overwrite each other,
+// and nothing is saved if the update returns an error.
+type SettingStorage interface {
+	SaveSettings(s Settings) error
+	GetSettings() (*Settings, error)
+	UpdateSettings(update func(s *Settings) error) (*Settings, error)
+}
@@ -1,8 +1,10 @@
 package mgddb
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"sync"
 
 	"github.com/findy-network/findy-agent/agent/storage/api"
 	"github.com/findy-network/findy-agent/agent/storage/wrapper"
@@ -21,16 +23,25 @@ const (
 	NameDID        = "did"
 	NameConnection = "connection"
 	NameCredential = "credential"
+	NameMessage    = "message"
+	NamePolicy     = "policy"
+	NameSetting    = "setting"
 
 	NameVDRPeer = "peer"
 )
 
+// bucketIDs are in the order of their bucket keys, which are the indexes of
+// the list. New buckets must be added to the end to keep the keys of the
+// existing ones.
 var bucketIDs = []string{
 	NameKey,
 	NameDID,
 	NameConnection,
 	NameCredential,
 	NameVDRPeer,
+	NameMessage,
+	NamePolicy,
+	NameSetting,
 }
 
 type Storage struct {
@@ -38,7 +49,14 @@ type Storage struct {
 	keyStorage *kmsStorage
 	didStore   wrapper.Store
 	connStore  wrapper.Store
+	credStore  wrapper.Store
+	msgStore   wrapper.Store
+	polStore   wrapper.Store
+	setStore   wrapper.Store
 	packager   api.Packager
+
+	connLock sync.Mutex // serialises the writes of the connections
+	setLock  sync.Mutex // and the settings
 }
 
 func New(config api.AgentStorageConfig) (a *Storage, err error) {
@@ -57,6 +75,12 @@ func New(config api.AgentStorageConfig) (a *Storage, err error) {
 		nil,
 		nil,
 		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		sync.Mutex{},
+		sync.Mutex{},
 	}
 
 	try.To(me.Init())
@@ -73,6 +97,22 @@ func New(config api.AgentStorageConfig) (a *Storage, err error) {
 	me.connStore, ok = connStore.(wrapper.Store)
 	assert.That(ok, "conn store should always be wrapper store")
 
+	credStore := try.To1(me.OpenStore(NameCredential))
+	me.credStore, ok = credStore.(wrapper.Store)
+	assert.That(ok, "cred store should always be wrapper store")
+
+	msgStore := try.To1(me.OpenStore(NameMessage))
+	me.msgStore, ok = msgStore.(wrapper.Store)
+	assert.That(ok, "msg store should always be wrapper store")
+
+	polStore := try.To1(me.OpenStore(NamePolicy))
+	me.polStore, ok = polStore.(wrapper.Store)
+	assert.That(ok, "policy store should always be wrapper store")
+
+	setStore := try.To1(me.OpenStore(NameSetting))
+	me.setStore, ok = setStore.(wrapper.Store)
+	assert.That(ok, "setting store should always be wrapper store")
+
 	vdr := try.To1(vdr.New(me))
 
 	me.packager = try.To1(NewPackager(me, vdr.Registry()))
@@ -103,8 +143,19 @@ func (s *Storage) ConnectionStorage() api.ConnectionStorage {
 }
 
 func (s *Storage) CredentialStorage() api.CredentialStorage {
-	// TODO
-	return nil
+	return s
+}
+
+func (s *Storage) MessageStorage() api.MessageStorage {
+	return s
+}
+
+func (s *Storage) PolicyStorage() api.PolicyStorage {
+	return s
+}
+
+func (s *Storage) SettingStorage() api.SettingStorage {
+	return s
 }
 
 func (s *Storage) OurPackager() api.Packager {
@@ -128,9 +179,39 @@ func (s *Storage) GetDID(id string) (did *api.DID, err error) {
 
 // ConnectionStorage
 func (s *Storage) SaveConnection(conn api.Connection) error {
+	s.connLock.Lock()
+	defer s.connLock.Unlock()
+
 	return s.connStore.Put(conn.ID, dto.ToGOB(conn))
 }
 
+func (s *Storage) UpdateConnection(
+	id string,
+	update func(conn *api.Connection, found bool) error,
+) (conn *api.Connection, err error) {
+	defer err2.Handle(&err, fmt.Sprintf("conn storage update conn %s", id))
+
+	assert.That(id != "", "connection ID is empty")
+
+	s.connLock.Lock()
+	defer s.connLock.Unlock()
+
+	conn = &api.Connection{ID: id}
+	found := true
+	bytes, err := s.connStore.Get(id)
+	switch {
+	case errors.Is(err, storage.ErrDataNotFound):
+		found = false
+	case err != nil:
+		return nil, err
+	default:
+		dto.FromGOB(bytes, conn)
+	}
+	try.To(update(conn, found))
+	try.To(s.connStore.Put(id, dto.ToGOB(*conn)))
+	return conn, nil
+}
+
 func (s *Storage) GetConnection(id string) (conn *api.Connection, err error) {
 	defer err2.Handle(&err, fmt.Sprintf("conn storage get conn %s", id))
 
@@ -148,8 +229,9 @@ func (s *Storage) ListConnections() (res []api.Connection, err error) {
 	defer err2.Handle(&err, "conn storage list conn")
 
 	res = make([]api.Connection, 0)
-	conn := &api.Connection{}
 	try.To1(s.connStore.GetAll(func(bytes []byte) []byte {
+		// GOB doesn't decode zero values, a new struct for every record
+		conn := &api.Connection{}
 		dto.FromGOB(bytes, conn)
 		res = append(res, *conn)
 		return bytes
@@ -158,6 +240,189 @@ func (s *Storage) ListConnections() (res []api.Connection, err error) {
 	return res, nil
 }
 
+func (s *Storage) DeleteConnection(id string) (err error) {
+	defer err2.Handle(&err, fmt.Sprintf("conn storage delete conn %s", id))
+
+	assert.That(id != "", "connection ID is empty")
+
+	s.connLock.Lock()
+	defer s.connLock.Unlock()
+
+	return s.connStore.Delete(id)
+}
+
+// CredentialStorage
+func (s *Storage) SaveCredential(cred api.Credential) error {
+	return s.credStore.Put(cred.ID, dto.ToGOB(cred))
+}
+
+func (s *Storage) GetCredential(id string) (cred *api.Credential, err error) {
+	defer err2.Handle(&err, fmt.Sprintf("cred storage get cred %s", id))
+
+	assert.That(id != "", "credential ID is empty")
+
+	bytes := try.To1(s.credStore.Get(id))
+
+	cred = &api.Credential{}
+	dto.FromGOB(bytes, cred)
+	return
+}
+
+func (s *Storage) W3CCredentials() (res [][]byte, err error) {
+	defer err2.Handle(&err, "cred storage list W3C creds")
+
+	res = make([][]byte, 0)
+	try.To1(s.credStore.GetAll(func(bytes []byte) []byte {
+		cred := &api.Credential{}
+		dto.FromGOB(bytes, cred)
+		if cred.IsW3C() {
+			res = append(res, cred.Data)
+		}
+		return bytes
+	}))
+
+	return res, nil
+}
+
+func (s *Storage) ListCredentials(filter api.CredentialFilter) (res []api.Credential, err error) {
+	defer err2.Handle(&err, "cred storage list creds")
+
+	res = make([]api.Credential, 0)
+	try.To1(s.credStore.GetAll(func(bytes []byte) []byte {
+		cred := &api.Credential{}
+		dto.FromGOB(bytes, cred)
+		if filter.Match(*cred) {
+			res = append(res, *cred)
+		}
+		return bytes
+	}))
+
+	return res, nil
+}
+
+func (s *Storage) DeleteCredential(id string) (err error) {
+	defer err2.Handle(&err, fmt.Sprintf("cred storage delete cred %s", id))
+
+	assert.That(id != "", "credential ID is empty")
+
+	return s.credStore.Delete(id)
+}
+
+// MessageStorage
+func (s *Storage) SaveMessage(msg api.Message) error {
+	return s.msgStore.Put(msg.ID, dto.ToGOB(msg))
+}
+
+func (s *Storage) GetMessage(id string) (msg *api.Message, err error) {
+	defer err2.Handle(&err, fmt.Sprintf("msg storage get msg %s", id))
+
+	assert.That(id != "", "message ID is empty")
+
+	bytes := try.To1(s.msgStore.Get(id))
+
+	msg = &api.Message{}
+	dto.FromGOB(bytes, msg)
+	return
+}
+
+func (s *Storage) ListMessages(
+	filter api.MessageFilter,
+	offset, limit int,
+) (
+	res []api.Message,
+	count int,
+	err error,
+) {
+	defer err2.Handle(&err, "msg storage list msgs")
+
+	res = make([]api.Message, 0)
+	try.To1(s.msgStore.GetAll(func(bytes []byte) []byte {
+		msg := &api.Message{}
+		dto.FromGOB(bytes, msg)
+		if filter.Match(*msg) {
+			res = append(res, *msg)
+		}
+		return bytes
+	}))
+
+	return api.PageMessages(res, offset, limit), len(res), nil
+}
+
+func (s *Storage) MarkMessagesRead(connectionID string, until int64) (count int, err error) {
+	defer err2.Handle(&err, fmt.Sprintf("msg storage mark read %s", connectionID))
+
+	unread, _ := try.To2(s.ListMessages(api.MessageFilter{
+		ConnectionID: connectionID,
+		Unread:       true,
+	}, 0, 0))
+	for _, msg := range unread {
+		if msg.SentByMe || msg.Timestamp > until {
+			continue
+		}
+		msg.Read = true
+		try.To(s.SaveMessage(msg))
+		count++
+	}
+	return count, nil
+}
+
+// PolicyStorage, the agent has only one policy
+const policyKey = "policy"
+
+func (s *Storage) SavePolicy(p api.Policy) error {
+	return s.polStore.Put(policyKey, dto.ToGOB(p))
+}
+
+func (s *Storage) GetPolicy() (p *api.Policy, err error) {
+	defer err2.Handle(&err, "policy storage get policy")
+
+	bytes, err := s.polStore.Get(policyKey)
+	if errors.Is(err, storage.ErrDataNotFound) {
+		return nil, nil
+	}
+	try.To(err)
+
+	p = &api.Policy{}
+	dto.FromGOB(bytes, p)
+	return p, nil
+}
+
+// SettingStorage, the agent has only one settings record
+const settingsKey = "settings"
+
+func (s *Storage) SaveSettings(set api.Settings) error {
+	s.setLock.Lock()
+	defer s.setLock.Unlock()
+
+	return s.setStore.Put(settingsKey, dto.ToGOB(set))
+}
+
+func (s *Storage) UpdateSettings(update func(set *api.Settings) error) (set *api.Settings, err error) {
+	defer err2.Handle(&err, "setting storage update settings")
+
+	s.setLock.Lock()
+	defer s.setLock.Unlock()
+
+	set = try.To1(s.GetSettings())
+	try.To(update(set))
+	try.To(s.setStore.Put(settingsKey, dto.ToGOB(*set)))
+	return set, nil
+}
+
+func (s *Storage) GetSettings() (set *api.Settings, err error) {
+	defer err2.Handle(&err, "setting storage get settings")
+
+	set = &api.Settings{}
+	bytes, err := s.setStore.Get(settingsKey)
+	if errors.Is(err, storage.ErrDataNotFound) {
+		return set, nil
+	}
+	try.To(err)
+
+	dto.FromGOB(bytes, set)
+	return set, nil
+}
+
 // AFGO StorageProvider placeholder implementations
 // We needed direct wrapping because Go couldn't keep on with transitive
 // type support of aggregated types.
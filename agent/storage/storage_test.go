@@ -2,6 +2,7 @@ package storage
 
 import (
 	"flag"
+	"fmt"
 	"os"
 	"reflect"
 	"sync"
@@ -163,6 +164,145 @@ func TestConnectionStore(t *testing.T) {
 				assert.DeepEqual(testConn, conns[1])
 				assert.DeepEqual(testConn2, conns[0])
 			}
+
+			gotConn, err = store.UpdateConnection(testConn.ID,
+				func(conn *api.Connection, found bool) error {
+					assert.That(found)
+					conn.Note = "note"
+					return nil
+				})
+			assert.NoError(err)
+			assert.Equal(gotConn.Note, "note")
+			assert.Equal(gotConn.TheirDID, testConn.TheirDID)
+
+			_, err = store.UpdateConnection("789-uid",
+				func(conn *api.Connection, found bool) error {
+					assert.That(!found)
+					assert.Equal(conn.ID, "789-uid")
+					return fmt.Errorf("not found")
+				})
+			assert.Error(err)
+			_, err = store.GetConnection("789-uid")
+			assert.Error(err)
+		})
+	}
+}
+
+func TestMessageStore(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+	for index := range kmsTestStorages {
+		testCase := kmsTestStorages[index]
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.PushTester(t)
+			defer assert.PopTester()
+			store := testCase.storage.MessageStorage()
+			msgs := []api.Message{
+				{ID: "msg-1", ConnectionID: "conn-1", Message: "Hello Alice", Timestamp: 1},
+				{ID: "msg-2", ConnectionID: "conn-1", Message: "Hi Bob", SentByMe: true, Read: true, Timestamp: 2},
+				{ID: "msg-3", ConnectionID: "conn-1", Message: "How are you, Alice?", Timestamp: 3},
+				{ID: "msg-4", ConnectionID: "conn-2", Message: "Hello Carol", Timestamp: 4},
+			}
+			for _, msg := range msgs {
+				assert.NoError(store.SaveMessage(msg))
+			}
+
+			gotMsg, err := store.GetMessage("msg-1")
+			assert.NoError(err)
+			assert.DeepEqual(msgs[0], *gotMsg)
+
+			page, count, err := store.ListMessages(api.MessageFilter{ConnectionID: "conn-1"}, 1, 1)
+			assert.NoError(err)
+			assert.Equal(count, 3)
+			assert.SLen(page, 1)
+			assert.Equal(page[0].ID, "msg-2")
+
+			page, count, err = store.ListMessages(api.MessageFilter{Search: "alice HOW"}, 0, 0)
+			assert.NoError(err)
+			assert.Equal(count, 1)
+			assert.Equal(page[0].ID, "msg-3")
+
+			marked, err := store.MarkMessagesRead("conn-1", 2)
+			assert.NoError(err)
+			assert.Equal(marked, 1)
+
+			page, _, err = store.ListMessages(api.MessageFilter{ConnectionID: "conn-1", Unread: true}, 0, 0)
+			assert.NoError(err)
+			assert.SLen(page, 1)
+			assert.Equal(page[0].ID, "msg-3")
+		})
+	}
+}
+
+func TestPolicyStore(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+	for index := range kmsTestStorages {
+		testCase := kmsTestStorages[index]
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.PushTester(t)
+			defer assert.PopTester()
+			store := testCase.storage.PolicyStorage()
+
+			policy, err := store.GetPolicy()
+			assert.NoError(err)
+			assert.That(policy == nil)
+
+			testPolicy := api.Policy{
+				Rules: []api.PolicyRule{{
+					Name:     "employer",
+					Protocol: api.PolicyIssue,
+					Action:   api.PolicyAccept,
+					Issuers:  []string{"did:test:123"},
+				}},
+				Default: api.PolicyAsk,
+			}
+			assert.NoError(store.SavePolicy(testPolicy))
+
+			policy, err = store.GetPolicy()
+			assert.NoError(err)
+			assert.DeepEqual(testPolicy, *policy)
+		})
+	}
+}
+
+func TestSettingStore(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+	for index := range kmsTestStorages {
+		testCase := kmsTestStorages[index]
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.PushTester(t)
+			defer assert.PopTester()
+			store := testCase.storage.SettingStorage()
+
+			settings, err := store.GetSettings()
+			assert.NoError(err)
+			assert.SLen(settings.PleaseAck, 0)
+
+			assert.NoError(store.SaveSettings(api.Settings{PleaseAck: []string{"RECEIPT"}}))
+			settings, err = store.GetSettings()
+			assert.NoError(err)
+			assert.DeepEqual(settings.PleaseAck, []string{"RECEIPT"})
+
+			settings, err = store.UpdateSettings(func(s *api.Settings) error {
+				s.HealthMonitor = &api.HealthMonitorConfig{FailureLimit: 2}
+				return nil
+			})
+			assert.NoError(err)
+			assert.DeepEqual(settings.PleaseAck, []string{"RECEIPT"})
+			settings, err = store.GetSettings()
+			assert.NoError(err)
+			assert.Equal(settings.HealthMonitor.FailureLimit, 2)
+
+			_, err = store.UpdateSettings(func(s *api.Settings) error {
+				s.HealthMonitor = nil
+				return fmt.Errorf("cancel")
+			})
+			assert.Error(err)
+			settings, err = store.GetSettings()
+			assert.NoError(err)
+			assert.That(settings.HealthMonitor != nil)
 		})
 	}
 }
@@ -0,0 +1,249 @@
+// Package trust checks the issuers of the credentials against the trust
+// registry of the CA. The registry is read from the CA's governance file in
+// the agency's governance directory, see utils.Settings.GovernancePath. The
+// file is read again when it changes, i.e. it can be updated without the
+// agency restart.
+package trust
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/findy-network/findy-agent/agent/utils"
+	"github.com/golang/glog"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/try"
+)
+
+// DefaultFile is the governance file of the CAs which don't have their own.
+const DefaultFile = "default.json"
+
+// Reserved attribute names of the protocol statuses and the questions which
+// tell the result of the check: the trusted attribute's value is "true" or
+// "false", and there is one reason attribute for each of the reasons. The
+// attributes are added only if the CA has the registry. The '~' prefix of the
+// names is reserved for the agency: the credential offers with such attribute
+// names are rejected, and the controllers must not use it in their attribute
+// names.
+const (
+	AttrIssuerTrusted     = "~issuer_trusted"
+	AttrIssuerTrustReason = "~issuer_trust_reason"
+)
+
+// Reserved tells if the attribute name is reserved for the agency.
+func Reserved(name string) bool {
+	return strings.HasPrefix(name, "~")
+}
+
+// Registry is the governance file. The issuer is trusted for the schemas of
+// its roles and its own schemas, or for all of the schemas if it has
+// neither. If Schemas are listed, other schemas aren't trusted at all.
+type Registry struct {
+	Schemas []Schema `json:"schemas,omitempty"`
+	Roles   []Role   `json:"roles,omitempty"`
+	Issuers []Issuer `json:"issuers"`
+}
+
+type Schema struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+type Role struct {
+	Name    string   `json:"name"`
+	Schemas []string `json:"schemas"`
+}
+
+type Issuer struct {
+	DID     string   `json:"did"`
+	Name    string   `json:"name,omitempty"`
+	Roles   []string `json:"roles,omitempty"`
+	Schemas []string `json:"schemas,omitempty"`
+}
+
+// Credential is the issuer and the schema of the checked credential. The
+// schema is empty for the credentials which don't have it, e.g. W3C
+// credentials, and only their issuer is checked.
+type Credential struct {
+	Issuer   string
+	SchemaID string
+}
+
+// Result is the outcome of the check. Reasons tell why the credentials
+// aren't trusted.
+type Result struct {
+	Trusted bool
+	Reasons []string
+}
+
+// Validate checks that the issuers have DIDs and their roles are defined.
+func (reg *Registry) Validate() error {
+	roles := make(map[string]bool, len(reg.Roles))
+	for _, r := range reg.Roles {
+		roles[r.Name] = true
+	}
+	for i, issuer := range reg.Issuers {
+		if issuer.DID == "" {
+			return fmt.Errorf("issuer %d (%s): DID is needed", i, issuer.Name)
+		}
+		for _, r := range issuer.Roles {
+			if !roles[r] {
+				return fmt.Errorf("issuer %s: unknown role: %s", issuer.DID, r)
+			}
+		}
+	}
+	return nil
+}
+
+// Check returns nil if the issuer is trusted for the schema, otherwise the
+// reason why it isn't.
+func (reg *Registry) Check(c Credential) error {
+	if c.SchemaID != "" && len(reg.Schemas) > 0 && !reg.knownSchema(c.SchemaID) {
+		return fmt.Errorf("schema %s isn't trusted", c.SchemaID)
+	}
+	issuer := reg.issuer(c.Issuer)
+	if issuer == nil {
+		return fmt.Errorf("issuer %s isn't trusted", c.Issuer)
+	}
+	if c.SchemaID == "" {
+		return nil
+	}
+	schemas := reg.issuerSchemas(issuer)
+	if len(schemas) > 0 && !schemas[c.SchemaID] {
+		return fmt.Errorf("issuer %s isn't trusted for schema %s", c.Issuer, c.SchemaID)
+	}
+	return nil
+}
+
+// CheckAll checks all of the credentials, e.g. the ones of the proof.
+func (reg *Registry) CheckAll(creds []Credential) Result {
+	res := Result{Trusted: true}
+	for _, c := range creds {
+		if err := reg.Check(c); err != nil {
+			res.Trusted = false
+			res.Reasons = append(res.Reasons, err.Error())
+		}
+	}
+	return res
+}
+
+func (reg *Registry) knownSchema(id string) bool {
+	for _, s := range reg.Schemas {
+		if s.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (reg *Registry) issuer(did string) *Issuer {
+	for i := range reg.Issuers {
+		if reg.Issuers[i].DID == did {
+			return &reg.Issuers[i]
+		}
+	}
+	return nil
+}
+
+func (reg *Registry) issuerSchemas(issuer *Issuer) map[string]bool {
+	schemas := make(map[string]bool)
+	for _, id := range issuer.Schemas {
+		schemas[id] = true
+	}
+	for _, name := range issuer.Roles {
+		for _, r := range reg.Roles {
+			if r.Name != name {
+				continue
+			}
+			for _, id := range r.Schemas {
+				schemas[id] = true
+			}
+		}
+	}
+	return schemas
+}
+
+// loaded is the governance file read at the modification time.
+type loaded struct {
+	modTime time.Time
+	reg     *Registry
+	err     error
+}
+
+var registries = struct {
+	sync.Mutex
+	files map[string]loaded
+}{
+	files: make(map[string]loaded),
+}
+
+// CheckCA checks the credentials against the CA's registry. It returns nil if
+// the CA doesn't have the registry. The broken governance file doesn't trust
+// anyone.
+func CheckCA(caDID string, creds []Credential) *Result {
+	reg, err := Get(caDID)
+	if err != nil {
+		glog.Errorln("trust check:", err)
+		return &Result{Reasons: []string{err.Error()}}
+	}
+	if reg == nil {
+		return nil
+	}
+	res := reg.CheckAll(creds)
+	return &res
+}
+
+// Get returns the trust registry of the CA, or nil if the governance
+// directory isn't set or the CA doesn't have the governance file nor is
+// there the default file.
+func Get(caDID string) (reg *Registry, err error) {
+	defer err2.Handle(&err, "trust registry of %s", caDID)
+
+	dir := utils.Settings.GovernancePath()
+	if dir == "" {
+		return nil, nil
+	}
+	for _, name := range []string{caDID + ".json", DefaultFile} {
+		reg, found := try.To2(Load(filepath.Join(dir, name)))
+		if found {
+			return reg, nil
+		}
+	}
+	return nil, nil
+}
+
+// Load returns the governance file. It's read again only if it's modified
+// after the previous read. Found is false if there isn't the file.
+func Load(filename string) (reg *Registry, found bool, err error) {
+	info, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	registries.Lock()
+	defer registries.Unlock()
+
+	if l, ok := registries.files[filename]; ok && l.modTime.Equal(info.ModTime()) {
+		return l.reg, true, l.err
+	}
+	glog.V(1).Infoln("loading governance file:", filename)
+	reg, err = read(filename)
+	registries.files[filename] = loaded{modTime: info.ModTime(), reg: reg, err: err}
+	return reg, true, err
+}
+
+func read(filename string) (reg *Registry, err error) {
+	defer err2.Handle(&err, "governance file %s", filename)
+
+	reg = new(Registry)
+	try.To(json.Unmarshal(try.To1(os.ReadFile(filename)), reg))
+	try.To(reg.Validate())
+	return reg, nil
+}
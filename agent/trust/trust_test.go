@@ -0,0 +1,131 @@
+package trust
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/findy-network/findy-agent/agent/utils"
+	"github.com/lainio/err2/assert"
+)
+
+const governance = `{
+  "schemas": [
+    {"id": "GOV:2:employee:1.0", "name": "Employee"},
+    {"id": "GOV:2:email:1.0"}
+  ],
+  "roles": [{"name": "employer", "schemas": ["GOV:2:employee:1.0"]}],
+  "issuers": [
+    {"did": "ACME", "name": "Acme", "roles": ["employer"]},
+    {"did": "MAIL", "schemas": ["GOV:2:email:1.0"]},
+    {"did": "ANY"}
+  ]
+}`
+
+func TestRegistry_Check(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "ca.json")
+	assert.NoError(os.WriteFile(filename, []byte(governance), 0o600))
+	reg, found, err := Load(filename)
+	assert.NoError(err)
+	assert.That(found)
+
+	tests := []struct {
+		name    string
+		cred    Credential
+		trusted bool
+	}{
+		{"role schema", Credential{"ACME", "GOV:2:employee:1.0"}, true},
+		{"not role schema", Credential{"ACME", "GOV:2:email:1.0"}, false},
+		{"own schema", Credential{"MAIL", "GOV:2:email:1.0"}, true},
+		{"all schemas", Credential{"ANY", "GOV:2:email:1.0"}, true},
+		{"unknown schema", Credential{"ANY", "GOV:2:other:1.0"}, false},
+		{"unknown issuer", Credential{"OTHER", "GOV:2:email:1.0"}, false},
+		{"issuer only", Credential{"ACME", ""}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.PushTester(t)
+			defer assert.PopTester()
+			assert.Equal(reg.Check(tt.cred) == nil, tt.trusted)
+		})
+	}
+
+	res := reg.CheckAll([]Credential{{"ACME", "GOV:2:employee:1.0"}, {"OTHER", ""}})
+	assert.That(!res.Trusted)
+	assert.SLen(res.Reasons, 1)
+	assert.That(reg.CheckAll(nil).Trusted)
+}
+
+func TestGet(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	dir := t.TempDir()
+	defer utils.Settings.SetGovernancePath("")
+
+	reg, err := Get("CA")
+	assert.NoError(err)
+	assert.That(reg == nil, "governance path isn't set")
+
+	utils.Settings.SetGovernancePath(dir)
+	reg, err = Get("CA")
+	assert.NoError(err)
+	assert.That(reg == nil, "no governance files")
+
+	defaultFile := filepath.Join(dir, DefaultFile)
+	assert.NoError(os.WriteFile(defaultFile, []byte(`{"issuers": [{"did": "ACME"}]}`), 0o600))
+	reg, err = Get("CA")
+	assert.NoError(err)
+	assert.SLen(reg.Issuers, 1)
+
+	caFile := filepath.Join(dir, "CA.json")
+	assert.NoError(os.WriteFile(caFile, []byte(governance), 0o600))
+	reg, err = Get("CA")
+	assert.NoError(err)
+	assert.SLen(reg.Issuers, 3)
+
+	// the modified file is read again
+	assert.NoError(os.WriteFile(caFile, []byte(`{"issuers": [{"did": "X", "roles": ["none"]}]}`), 0o600))
+	modTime := time.Now().Add(time.Minute)
+	assert.NoError(os.Chtimes(caFile, modTime, modTime))
+	_, err = Get("CA")
+	assert.Error(err)
+}
+
+func TestCheckCA(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	dir := t.TempDir()
+	defer utils.Settings.SetGovernancePath("")
+	creds := []Credential{{Issuer: "ACME", SchemaID: "GOV:2:employee:1.0"}}
+
+	assert.That(CheckCA("CA", creds) == nil, "governance path isn't set")
+
+	utils.Settings.SetGovernancePath(dir)
+	caFile := filepath.Join(dir, "CA.json")
+	assert.NoError(os.WriteFile(caFile, []byte(governance), 0o600))
+	res := CheckCA("CA", creds)
+	assert.That(res != nil && res.Trusted)
+
+	res = CheckCA("CA", append(creds, Credential{Issuer: "OTHER"}))
+	assert.That(!res.Trusted)
+	assert.SLen(res.Reasons, 1)
+
+	// the broken file doesn't trust anyone
+	assert.NoError(os.WriteFile(caFile, []byte(`{"issuers": [{}]}`), 0o600))
+	modTime := time.Now().Add(time.Minute)
+	assert.NoError(os.Chtimes(caFile, modTime, modTime))
+	res = CheckCA("CA", creds)
+	assert.That(!res.Trusted)
+	assert.SLen(res.Reasons, 1)
+
+	assert.That(Reserved(AttrIssuerTrusted))
+	assert.That(Reserved(AttrIssuerTrustReason))
+	assert.That(!Reserved("issuer_trusted"))
+}
@@ -10,6 +10,10 @@ import (
 
 const HTTPReqTimeout = 1 * time.Minute
 
+// SAPingTimeout is the default time to wait the controller's answer to the
+// synchronous SA ping.
+const SAPingTimeout = 10 * time.Second
+
 var Settings = &Hub{}
 
 type Hub struct {
@@ -28,6 +32,10 @@ type Hub struct {
 	timeout     time.Duration // timeout setting for http requests and connections
 	exportPath  string        // wallet export path
 
+	governancePath string // directory of the CAs' governance files
+
+	saPingTimeout time.Duration // time to wait controller's answer to SA ping
+
 	localTestMode bool // tells if are running unit tests, will be obsolete
 
 	didMethod method.Type // the DID method to use as a default
@@ -101,6 +109,12 @@ func (h *Hub) SetTimeout(to time.Duration) {
 	h.timeout = to
 }
 
+// SetSAPingTimeout sets the time to wait the controller's answer when the
+// agent's controller is pinged.
+func (h *Hub) SetSAPingTimeout(to time.Duration) {
+	h.saPingTimeout = to
+}
+
 // SetServiceName sets the service name for a2a communication
 func (h *Hub) SetServiceName(n string) {
 	h.serviceName = n
@@ -144,10 +158,27 @@ func (h *Hub) Timeout() time.Duration {
 	return h.timeout
 }
 
+func (h *Hub) SAPingTimeout() time.Duration {
+	if h.saPingTimeout == 0 {
+		return SAPingTimeout
+	}
+	return h.saPingTimeout
+}
+
 func (h *Hub) ExportPath() string {
 	return h.exportPath
 }
 
+// SetGovernancePath sets the directory of the governance files. The empty
+// path disables the trust registries.
+func (h *Hub) SetGovernancePath(path string) {
+	h.governancePath = path
+}
+
+func (h *Hub) GovernancePath() string {
+	return h.governancePath
+}
+
 func (h *Hub) WalletExportPath(filename string) (exportPath, url string) {
 	return filepath.Join(h.exportPath, filename),
 		h.hostAddr + filepath.Join("/static", filename)
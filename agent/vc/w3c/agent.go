@@ -0,0 +1,63 @@
+package w3c
+
+import (
+	"time"
+
+	"github.com/findy-network/findy-agent/agent/storage/api"
+	"github.com/findy-network/findy-agent/agent/utils"
+	"github.com/findy-network/findy-agent/core"
+	"github.com/hyperledger/aries-framework-go/component/models/verifiable"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/assert"
+	"github.com/lainio/err2/try"
+	"github.com/mr-tron/base58"
+)
+
+// DIDSigner returns the signer of the agent's DID. It signs with the existing
+// key of the DID, and its verification method is the did:key of the DID's
+// verkey, i.e. the same key which the DID has in the ledger.
+func DIDSigner(d core.DID) (s *Signer, err error) {
+	defer err2.Handle(&err, "DID W3C signer")
+
+	pk := try.To1(base58.Decode(d.VerKey()))
+	return NewSigner(d.Packager().KMS(), d.Packager().Crypto(), d.KID(), pk)
+}
+
+// IssueCredential signs the credential given in JSON with the key of the
+// agent's DID. See Issue for the formats.
+func IssueCredential(d core.DID, credJSON []byte, format string) (data []byte, err error) {
+	defer err2.Handle(&err, "agent issue W3C credential")
+
+	vc := try.To1(verifiable.ParseCredential(credJSON, credentialOpts()...))
+	return Issue(vc, format, try.To1(DIDSigner(d)))
+}
+
+// SaveCredential verifies the credential given in its wire format and stores
+// it to the agent's credential storage.
+func SaveCredential(as api.AgentStorage, data []byte) (cred *api.Credential, err error) {
+	defer err2.Handle(&err, "agent save W3C credential")
+
+	cs := as.CredentialStorage()
+	assert.That(cs != nil, "credential storage not available")
+
+	vc := try.To1(VerifyCredential(data, as.OurPackager().VDRegistry()))
+	id := vc.ID
+	if id == "" {
+		id = utils.UUID()
+	}
+	var schemaID string
+	if len(vc.Schemas) > 0 {
+		schemaID = vc.Schemas[0].ID
+	}
+	cred = &api.Credential{
+		ID:         id,
+		Format:     CredentialFormat(data),
+		Issuer:     vc.Issuer.ID,
+		SchemaID:   schemaID,
+		Attributes: subjectFields(vc.Subject),
+		Data:       data,
+		Created:    time.Now().UnixNano(),
+	}
+	try.To(cs.SaveCredential(*cred))
+	return cred, nil
+}
@@ -0,0 +1,99 @@
+package w3c
+
+import (
+	"testing"
+
+	"github.com/findy-network/findy-agent/agent/managed"
+	"github.com/findy-network/findy-agent/agent/storage/api"
+	"github.com/findy-network/findy-agent/core"
+	"github.com/findy-network/findy-agent/method"
+	"github.com/lainio/err2/assert"
+	"github.com/lainio/err2/try"
+)
+
+// testWallet is the managed wallet of the test DIDs.
+type testWallet struct {
+	managed.Wallet
+}
+
+func (testWallet) Storage() api.AgentStorage { return testStorage }
+
+// testDID returns the agent DID of the tests. Its key is in the KMS of the
+// test storage.
+func testDID() core.DID {
+	testSigner() // init storage
+	return try.To1(method.NewKey(testWallet{}))
+}
+
+func TestDIDSigner(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	d := testDID()
+	s, err := DIDSigner(d)
+	assert.NoError(err)
+	// the verification method is the DID's own key
+	assert.Equal(s.DID, d.URI())
+
+	data, err := IssueCredential(d, []byte(testCredential), api.FormatW3CLDP)
+	assert.NoError(err)
+	vc, err := VerifyCredential(data, testStorage.OurPackager().VDRegistry())
+	assert.NoError(err)
+	assert.Equal(vc.Issuer.ID, d.URI())
+}
+
+func TestSaveCredential(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	d := testDID()
+	before, err := testStorage.W3CCredentials()
+	assert.NoError(err)
+
+	for _, format := range []string{api.FormatW3CLDP, api.FormatW3CJWT} {
+		data, err := IssueCredential(d, []byte(testCredential), format)
+		assert.NoError(err)
+
+		cred, err := SaveCredential(testStorage, data)
+		assert.NoError(err)
+		assert.Equal(cred.Format, format)
+
+		got, err := testStorage.GetCredential(cred.ID)
+		assert.NoError(err)
+		assert.Equal(string(got.Data), string(data))
+	}
+	creds, err := testStorage.W3CCredentials()
+	assert.NoError(err)
+	// same credential ID in both of the formats
+	assert.SLen(creds, len(before)+1)
+
+	_, err = SaveCredential(testStorage, []byte(testCredential))
+	assert.Error(err)
+}
+
+func TestListCredentials(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	d := testDID()
+
+	data, err := IssueCredential(d, []byte(testCredential), api.FormatW3CLDP)
+	assert.NoError(err)
+	cred, err := SaveCredential(testStorage, data)
+	assert.NoError(err)
+	assert.Equal(cred.Attributes["email"], "alice@example.com")
+
+	creds, err := testStorage.ListCredentials(api.CredentialFilter{
+		Attributes: map[string]string{"email": "alice@example.com"},
+	})
+	assert.NoError(err)
+	assert.SNotEmpty(creds)
+
+	creds, err = testStorage.ListCredentials(api.CredentialFilter{Issuer: d.URI() + "x"})
+	assert.NoError(err)
+	assert.SLen(creds, 0)
+
+	assert.NoError(testStorage.DeleteCredential(cred.ID))
+	_, err = testStorage.GetCredential(cred.ID)
+	assert.Error(err)
+}
@@ -0,0 +1,70 @@
+/*
+Package w3c includes helpers for W3C verifiable credentials and presentations.
+All of the JSON-LD processing is done offline. The contexts we need are
+bundled to the binary and we never fetch them from the network.
+*/
+package w3c
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+
+	ldcontext "github.com/hyperledger/aries-framework-go/component/models/ld/context"
+	"github.com/hyperledger/aries-framework-go/component/models/ld/context/embed"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/try"
+	"github.com/piprate/json-gold/ld"
+)
+
+// Loader is a JSON-LD document loader which serves only the contexts bundled
+// to it. It doesn't have network access by design.
+type Loader struct {
+	lk   sync.RWMutex
+	docs map[string]*ld.RemoteDocument
+}
+
+var defaultLoader = newLoader()
+
+// DocumentLoader returns the offline JSON-LD document loader of the agency.
+func DocumentLoader() *Loader {
+	return defaultLoader
+}
+
+func newLoader() *Loader {
+	l := &Loader{docs: make(map[string]*ld.RemoteDocument, len(embed.Contexts))}
+	try.To(l.Add(embed.Contexts...))
+	return l
+}
+
+// Add adds contexts to the loader. Already existing URLs are replaced.
+func (l *Loader) Add(contexts ...ldcontext.Document) (err error) {
+	defer err2.Handle(&err, "add JSON-LD contexts")
+
+	l.lk.Lock()
+	defer l.lk.Unlock()
+
+	for _, c := range contexts {
+		doc := try.To1(ld.DocumentFromReader(bytes.NewReader(c.Content)))
+		rd := &ld.RemoteDocument{
+			DocumentURL: c.DocumentURL,
+			Document:    doc,
+		}
+		l.docs[c.URL] = rd
+		if c.DocumentURL != "" {
+			l.docs[c.DocumentURL] = rd
+		}
+	}
+	return nil
+}
+
+// LoadDocument implements ld.DocumentLoader interface.
+func (l *Loader) LoadDocument(u string) (*ld.RemoteDocument, error) {
+	l.lk.RLock()
+	defer l.lk.RUnlock()
+
+	if doc, ok := l.docs[u]; ok {
+		return doc, nil
+	}
+	return nil, fmt.Errorf("JSON-LD context not bundled: %s", u)
+}
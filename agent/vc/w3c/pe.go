@@ -0,0 +1,158 @@
+package w3c
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/golang/glog"
+	"github.com/hyperledger/aries-framework-go/component/models/presexch"
+	"github.com/hyperledger/aries-framework-go/component/models/verifiable"
+	vdrapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/try"
+)
+
+// Attribute is a credential subject value from the credential matched to the
+// input descriptor of DIF presentation definition.
+type Attribute struct {
+	DescriptorID string
+	Issuer       string
+	Name         string
+	Value        string
+}
+
+// ParseDefinition parses and validates DIF presentation definition.
+func ParseDefinition(data []byte) (pd *presexch.PresentationDefinition, err error) {
+	defer err2.Handle(&err, "parse presentation definition")
+
+	pd = new(presexch.PresentationDefinition)
+	try.To(json.Unmarshal(data, pd))
+	try.To(pd.ValidateSchema())
+	return pd, nil
+}
+
+// CreateSubmission builds a verifiable presentation with a presentation
+// submission for the definition from the credentials given in wire format.
+// Credentials which we cannot parse are skipped. The presentation is signed
+// with the challenge and the domain if the signer is given.
+func CreateSubmission(
+	pd *presexch.PresentationDefinition,
+	credentials [][]byte,
+	s *Signer,
+	challenge, domain string,
+) (
+	vp *verifiable.Presentation,
+	err error,
+) {
+	defer err2.Handle(&err, "create presentation submission")
+
+	vcs := make([]*verifiable.Credential, 0, len(credentials))
+	for _, c := range credentials {
+		vc, err := verifiable.ParseCredential(c, credentialOpts()...)
+		if err != nil {
+			glog.Warningln("skipping unparseable credential:", err)
+			continue
+		}
+		vcs = append(vcs, vc)
+	}
+	vp = try.To1(pd.CreateVP(vcs, defaultLoader, credentialOpts()...))
+	if s != nil {
+		try.To(SignPresentation(vp, s, challenge, domain))
+	}
+	return vp, nil
+}
+
+// VerifySubmission checks that the presentation given in JSON satisfies the
+// definition, and verifies the proofs of the presentation and its
+// credentials. The keys are resolved with the VDR registry. If the challenge
+// is given, the presentation must be signed with it. It returns the matched
+// credentials by the input descriptor IDs.
+func VerifySubmission(
+	pd *presexch.PresentationDefinition,
+	vpData []byte,
+	reg vdrapi.Registry,
+	challenge string,
+) (
+	matched map[string]presexch.MatchValue,
+	err error,
+) {
+	defer err2.Handle(&err, "verify presentation submission")
+
+	vp := try.To1(verifiable.ParsePresentation(vpData,
+		verifiable.WithPresJSONLDDocumentLoader(defaultLoader),
+		verifiable.WithPresPublicKeyFetcher(KeyFetcher(reg)),
+		verifiable.WithPresEmbeddedSignatureSuites(signatureSuites()...),
+	))
+	if challenge != "" && (len(vp.Proofs) == 0 || vp.Proofs[0]["challenge"] != challenge) {
+		return nil, fmt.Errorf("presentation isn't signed with the challenge")
+	}
+	matched = try.To1(pd.Match([]*verifiable.Presentation{vp}, defaultLoader,
+		presexch.WithCredentialOptions(verifyingCredentialOpts(reg)...)))
+	for id, m := range matched {
+		if m.Credential == nil || !isSigned(m.Credential) {
+			return nil, fmt.Errorf("credential not signed: %s", id)
+		}
+	}
+	return matched, nil
+}
+
+// SubjectAttributes returns the credential subject values of the matched
+// credentials sorted by the descriptor ID and the attribute name.
+func SubjectAttributes(matched map[string]presexch.MatchValue) []Attribute {
+	attrs := make([]Attribute, 0, len(matched))
+	for descID, m := range matched {
+		if m.Credential == nil {
+			continue
+		}
+		for name, value := range subjectFields(m.Credential.Subject) {
+			attrs = append(attrs, Attribute{
+				DescriptorID: descID,
+				Issuer:       m.Credential.Issuer.ID,
+				Name:         name,
+				Value:        value,
+			})
+		}
+	}
+	sort.Slice(attrs, func(i, j int) bool {
+		if attrs[i].DescriptorID != attrs[j].DescriptorID {
+			return attrs[i].DescriptorID < attrs[j].DescriptorID
+		}
+		return attrs[i].Name < attrs[j].Name
+	})
+	return attrs
+}
+
+func subjectFields(subject interface{}) map[string]string {
+	fields := make(map[string]string)
+	add := func(custom map[string]interface{}) {
+		for k, v := range custom {
+			if s, ok := v.(string); ok {
+				fields[k] = s
+			} else {
+				fields[k] = fmt.Sprint(v)
+			}
+		}
+	}
+	switch s := subject.(type) {
+	case []verifiable.Subject:
+		for _, sub := range s {
+			add(sub.CustomFields)
+		}
+	case verifiable.Subject:
+		add(s.CustomFields)
+	case map[string]interface{}:
+		add(s)
+		delete(fields, "id")
+	}
+	return fields
+}
+
+// credentialOpts are for the credentials we hold. They are verified already
+// when we store them.
+func credentialOpts() []verifiable.CredentialOpt {
+	return []verifiable.CredentialOpt{
+		verifiable.WithJSONLDDocumentLoader(defaultLoader),
+		verifiable.WithDisabledProofCheck(),
+	}
+}
@@ -0,0 +1,140 @@
+package w3c
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/findy-network/findy-agent/agent/storage/api"
+	"github.com/lainio/err2/assert"
+	"github.com/lainio/err2/try"
+)
+
+const testCredential = `{
+  "@context": [
+    "https://www.w3.org/2018/credentials/v1",
+    {"email": "https://schema.org/email"}
+  ],
+  "id": "urn:uuid:3978344f-8596-4c3a-a978-8fcaba3903c5",
+  "type": ["VerifiableCredential"],
+  "issuer": "did:key:z6MkjRagNiMu91DduvCvgEsqLZDVzrJzFrwahc4tXLt9DoHd",
+  "issuanceDate": "2022-01-01T19:23:24Z",
+  "credentialSubject": {
+    "id": "did:example:ebfeb1f712ebc6f1c276e12ec21",
+    "email": "alice@example.com"
+  }
+}`
+
+const testDefinition = `{
+  "id": "32f54163-7166-48f1-93d8-ff217bdb0653",
+  "input_descriptors": [
+    {
+      "id": "email_input",
+      "schema": [{"uri": "https://www.w3.org/2018/credentials#VerifiableCredential"}],
+      "constraints": {
+        "fields": [{"path": ["$.credentialSubject.email"]}]
+      }
+    }
+  ]
+}`
+
+func TestLoader_LoadDocument(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	doc, err := DocumentLoader().LoadDocument("https://www.w3.org/2018/credentials/v1")
+	assert.NoError(err)
+	assert.NotNil(doc)
+
+	_, err = DocumentLoader().LoadDocument("https://example.com/not/bundled")
+	assert.Error(err)
+}
+
+func TestParseDefinition(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	pd, err := ParseDefinition([]byte(testDefinition))
+	assert.NoError(err)
+	assert.Equal(pd.ID, "32f54163-7166-48f1-93d8-ff217bdb0653")
+	assert.SLen(pd.InputDescriptors, 1)
+
+	_, err = ParseDefinition([]byte(`{"id": "no-descriptors"}`))
+	assert.Error(err)
+}
+
+func TestSubmission(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	pd := try.To1(ParseDefinition([]byte(testDefinition)))
+	issuer := testSigner()
+	holder := testSigner()
+	reg := testStorage.OurPackager().VDRegistry()
+	cred := try.To1(Issue(testVC(), api.FormatW3CLDP, issuer))
+
+	vp, err := CreateSubmission(pd, [][]byte{cred, []byte("{}")},
+		holder, "test-challenge", "")
+	assert.NoError(err)
+	assert.SLen(vp.Credentials(), 1)
+	assert.Equal(vp.Holder, holder.DID)
+
+	vpData := try.To1(json.Marshal(vp))
+	matched, err := VerifySubmission(pd, vpData, reg, "test-challenge")
+	assert.NoError(err)
+	assert.MLen(matched, 1)
+
+	_, err = VerifySubmission(pd, vpData, reg, "other-challenge")
+	assert.Error(err)
+
+	attrs := SubjectAttributes(matched)
+	assert.SLen(attrs, 1)
+	assert.Equal(attrs[0].DescriptorID, "email_input")
+	assert.Equal(attrs[0].Name, "email")
+	assert.Equal(attrs[0].Value, "alice@example.com")
+	assert.Equal(attrs[0].Issuer, issuer.DID)
+}
+
+func TestVerifySubmission_Unsigned(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	pd := try.To1(ParseDefinition([]byte(testDefinition)))
+	testSigner()
+	reg := testStorage.OurPackager().VDRegistry()
+
+	vp := try.To1(CreateSubmission(pd, [][]byte{[]byte(testCredential)}, nil, "", ""))
+	vpData := try.To1(json.Marshal(vp))
+	_, err := VerifySubmission(pd, vpData, reg, "")
+	assert.Error(err)
+}
+
+func TestCreateSubmission_NoMatch(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	pd := try.To1(ParseDefinition([]byte(testDefinition)))
+
+	_, err := CreateSubmission(pd, nil, nil, "", "")
+	assert.Error(err)
+}
+
+func TestKeyFetcher(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	const (
+		issuer = "did:key:z6MkjRagNiMu91DduvCvgEsqLZDVzrJzFrwahc4tXLt9DoHd"
+		kid    = "z6MkjRagNiMu91DduvCvgEsqLZDVzrJzFrwahc4tXLt9DoHd"
+	)
+	testSigner() // init storage
+	fetch := KeyFetcher(testStorage.OurPackager().VDRegistry())
+	for _, keyID := range []string{"#" + kid, issuer + "#" + kid, kid} {
+		_, err := fetch(issuer, keyID)
+		assert.NoError(err)
+	}
+	for _, keyID := range []string{"#" + kid[:10], "#", kid[5:],
+		"did:key:other#" + kid} {
+		_, err := fetch(issuer, keyID)
+		assert.Error(err)
+	}
+}
@@ -0,0 +1,160 @@
+package w3c
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/findy-network/findy-agent/agent/storage/api"
+	"github.com/hyperledger/aries-framework-go/component/models/ld/processor"
+	"github.com/hyperledger/aries-framework-go/component/models/signature/suite"
+	"github.com/hyperledger/aries-framework-go/component/models/signature/suite/ed25519signature2020"
+	afgotime "github.com/hyperledger/aries-framework-go/component/models/util/time"
+	"github.com/hyperledger/aries-framework-go/component/models/verifiable"
+	cryptoapi "github.com/hyperledger/aries-framework-go/pkg/crypto"
+	vdrapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/pkg/vdr/fingerprint"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/assert"
+	"github.com/lainio/err2/try"
+)
+
+const ed25519Context = "https://w3id.org/security/suites/ed25519-2020/v1"
+
+// Signer signs credentials and presentations with the Ed25519 key of our
+// KMS. The signer's DID is the did:key of the key, which means that anyone
+// can resolve it offline.
+type Signer struct {
+	DID   string
+	KeyID string
+
+	kh     interface{}
+	crypto cryptoapi.Crypto
+}
+
+// NewSigner returns signer for the KMS key.
+func NewSigner(
+	keys kms.KeyManager,
+	crypto cryptoapi.Crypto,
+	kid string,
+	pubKey []byte,
+) (
+	s *Signer,
+	err error,
+) {
+	defer err2.Handle(&err, "new W3C signer")
+
+	assert.NotEmpty(kid, "key ID is needed for signing")
+
+	kh := try.To1(keys.Get(kid))
+	didKey, keyID := fingerprint.CreateDIDKey(pubKey)
+	return &Signer{DID: didKey, KeyID: keyID, kh: kh, crypto: crypto}, nil
+}
+
+// Sign signs the data.
+func (s *Signer) Sign(data []byte) ([]byte, error) {
+	return s.crypto.Sign(data, s.kh)
+}
+
+// Alg returns JWS algorithm of the signer.
+func (s *Signer) Alg() string {
+	return "EdDSA"
+}
+
+// Issue signs the credential in the format, which is either
+// api.FormatW3CLDP or api.FormatW3CJWT. The issuer of the credential is set to
+// the signer's DID. It returns the credential in its wire format: JSON for
+// LDP, and compact JWS for JWT.
+func Issue(vc *verifiable.Credential, format string, s *Signer) (data []byte, err error) {
+	defer err2.Handle(&err, "issue W3C credential")
+
+	vc.Issuer = verifiable.Issuer{ID: s.DID}
+	vc.Context = withContext(vc.Context, ed25519Context)
+	if vc.Issued == nil {
+		vc.Issued = utcNow()
+	}
+
+	switch format {
+	case api.FormatW3CLDP:
+		// all of the fields must be defined in the contexts, because the
+		// proof covers only those
+		try.To1(verifiable.ParseCredential(try.To1(vc.MarshalJSON()),
+			append(credentialOpts(), strictOpts()...)...))
+		try.To(vc.AddLinkedDataProof(&verifiable.LinkedDataProofContext{
+			SignatureType:           ed25519signature2020.SignatureType,
+			Suite:                   ed25519signature2020.New(suite.WithSigner(s)),
+			SignatureRepresentation: verifiable.SignatureProofValue,
+			Created:                 &vc.Issued.Time,
+			VerificationMethod:      s.KeyID,
+			Purpose:                 "assertionMethod",
+		}, ldOpts()...))
+		return vc.MarshalJSON()
+	case api.FormatW3CJWT:
+		claims := try.To1(vc.JWTClaims(false))
+		jws := try.To1(claims.MarshalJWS(verifiable.EdDSA, s, s.KeyID))
+		return []byte(jws), nil
+	}
+	return nil, fmt.Errorf("unsupported credential format: %s", format)
+}
+
+// SignPresentation adds the holder's linked data proof to the presentation.
+// The challenge and the domain come from the verifier's request.
+func SignPresentation(
+	vp *verifiable.Presentation,
+	s *Signer,
+	challenge, domain string,
+) (err error) {
+	defer err2.Handle(&err, "sign presentation")
+
+	vp.Holder = s.DID
+	vp.Context = withContext(vp.Context, ed25519Context)
+	created := time.Now().UTC()
+	return vp.AddLinkedDataProof(&verifiable.LinkedDataProofContext{
+		SignatureType:           ed25519signature2020.SignatureType,
+		Suite:                   ed25519signature2020.New(suite.WithSigner(s)),
+		SignatureRepresentation: verifiable.SignatureProofValue,
+		Created:                 &created,
+		VerificationMethod:      s.KeyID,
+		Purpose:                 "authentication",
+		Challenge:               challenge,
+		Domain:                  domain,
+	}, ldOpts()...)
+}
+
+// VerifyCredential parses the credential given in JSON or JWT format and
+// verifies its proof. The keys are resolved with the VDR registry.
+func VerifyCredential(data []byte, reg vdrapi.Registry) (vc *verifiable.Credential, err error) {
+	defer err2.Handle(&err, "verify W3C credential")
+
+	vc = try.To1(verifiable.ParseCredential(data, verifyingCredentialOpts(reg)...))
+	if !isSigned(vc) {
+		return nil, fmt.Errorf("credential proof missing")
+	}
+	return vc, nil
+}
+
+// CredentialFormat returns the format of the credential in wire format.
+func CredentialFormat(data []byte) string {
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "{") {
+		return api.FormatW3CLDP
+	}
+	return api.FormatW3CJWT
+}
+
+func withContext(contexts []string, ctx string) []string {
+	for _, c := range contexts {
+		if c == ctx {
+			return contexts
+		}
+	}
+	return append(contexts, ctx)
+}
+
+func ldOpts() []processor.Opts {
+	return []processor.Opts{processor.WithDocumentLoader(defaultLoader)}
+}
+
+func utcNow() *afgotime.TimeWrapper {
+	return afgotime.NewTime(time.Now().UTC().Truncate(time.Second))
+}
@@ -0,0 +1,89 @@
+package w3c
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/findy-network/findy-agent/agent/storage/api"
+	"github.com/findy-network/findy-agent/agent/storage/mgddb"
+	"github.com/hyperledger/aries-framework-go/component/models/verifiable"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/lainio/err2/assert"
+	"github.com/lainio/err2/try"
+)
+
+var testStorage *mgddb.Storage
+
+func testSigner() *Signer {
+	if testStorage == nil {
+		testStorage = try.To1(mgddb.New(api.AgentStorageConfig{
+			AgentKey: mgddb.GenerateKey(),
+			AgentID:  "MEMORY_w3c-test",
+			FilePath: ".",
+		}))
+	}
+	keys := testStorage.KMS()
+	kid, pk := try.To2(keys.CreateAndExportPubKeyBytes(kms.ED25519))
+	return try.To1(NewSigner(keys, testStorage.OurPackager().Crypto(), kid, pk))
+}
+
+func testVC() *verifiable.Credential {
+	return try.To1(verifiable.ParseCredential([]byte(testCredential), credentialOpts()...))
+}
+
+func TestIssue(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	s := testSigner()
+	reg := testStorage.OurPackager().VDRegistry()
+
+	for _, format := range []string{api.FormatW3CLDP, api.FormatW3CJWT} {
+		format := format
+		t.Run(format, func(t *testing.T) {
+			assert.PushTester(t)
+			defer assert.PopTester()
+
+			data, err := Issue(testVC(), format, s)
+			assert.NoError(err)
+			assert.Equal(CredentialFormat(data), format)
+
+			vc, err := VerifyCredential(data, reg)
+			assert.NoError(err)
+			assert.Equal(vc.Issuer.ID, s.DID)
+
+			// other key must not verify
+			other := testSigner()
+			other.DID, other.KeyID = s.DID, s.KeyID
+			forged := try.To1(Issue(testVC(), format, other))
+			_, err = VerifyCredential(forged, reg)
+			assert.Error(err)
+		})
+	}
+}
+
+func TestVerifyCredential_Unsigned(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	testSigner()
+	reg := testStorage.OurPackager().VDRegistry()
+
+	_, err := VerifyCredential([]byte(testCredential), reg)
+	assert.Error(err)
+
+	data := try.To1(Issue(testVC(), api.FormatW3CLDP, testSigner()))
+	tampered := strings.Replace(string(data), "alice@example.com", "eve@example.com", 1)
+	_, err = VerifyCredential([]byte(tampered), reg)
+	assert.Error(err)
+}
+
+func TestIssue_UndefinedTerm(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	vc := testVC()
+	vc.CustomContext = nil
+	_, err := Issue(vc, api.FormatW3CLDP, testSigner())
+	assert.Error(err)
+}
@@ -0,0 +1,83 @@
+package w3c
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/aries-framework-go/component/models/signature/suite"
+	"github.com/hyperledger/aries-framework-go/component/models/signature/suite/ed25519signature2018"
+	"github.com/hyperledger/aries-framework-go/component/models/signature/suite/ed25519signature2020"
+	sigverifier "github.com/hyperledger/aries-framework-go/component/models/signature/verifier"
+	"github.com/hyperledger/aries-framework-go/component/models/verifiable"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	vdrapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
+)
+
+// isSigned tells if the credential has a proof. Parsing checks the proofs,
+// but it accepts credentials without any.
+func isSigned(vc *verifiable.Credential) bool {
+	return len(vc.Proofs) > 0 || vc.JWT != ""
+}
+
+// KeyFetcher returns public key fetcher which resolves the keys with the VDR
+// registry. The key ID must be the ID of the issuer's verification method,
+// either the full DID URL or its fragment.
+func KeyFetcher(reg vdrapi.Registry) verifiable.PublicKeyFetcher {
+	return func(issuerID, keyID string) (*sigverifier.PublicKey, error) {
+		res, err := reg.Resolve(issuerID)
+		if err != nil {
+			return nil, fmt.Errorf("resolve DID %s: %w", issuerID, err)
+		}
+		keyURL := didURL(issuerID, keyID)
+		for _, vms := range res.DIDDocument.VerificationMethods() {
+			for _, vm := range vms {
+				if vm.Relationship == did.KeyAgreement ||
+					didURL(issuerID, vm.VerificationMethod.ID) != keyURL {
+					continue
+				}
+				return &sigverifier.PublicKey{
+					Type:  vm.VerificationMethod.Type,
+					Value: vm.VerificationMethod.Value,
+				}, nil
+			}
+		}
+		return nil, fmt.Errorf("public key %s not found for DID %s", keyID, issuerID)
+	}
+}
+
+// didURL returns the verification method ID as the full DID URL. The ID can
+// be relative to the DID, i.e. only the fragment.
+func didURL(didID, id string) string {
+	switch {
+	case strings.HasPrefix(id, "#"):
+		return didID + id
+	case !strings.Contains(id, "#"):
+		return didID + "#" + id
+	}
+	return id
+}
+
+func signatureSuites() []sigverifier.SignatureSuite {
+	return []sigverifier.SignatureSuite{
+		ed25519signature2020.New(suite.WithVerifier(ed25519signature2020.NewPublicKeyVerifier())),
+		ed25519signature2018.New(suite.WithVerifier(ed25519signature2018.NewPublicKeyVerifier())),
+	}
+}
+
+// verifyingCredentialOpts checks the proofs, and also that all of the fields
+// are defined in the contexts, i.e. they are covered by the LD proof.
+func verifyingCredentialOpts(reg vdrapi.Registry) []verifiable.CredentialOpt {
+	opts := []verifiable.CredentialOpt{
+		verifiable.WithJSONLDDocumentLoader(defaultLoader),
+		verifiable.WithPublicKeyFetcher(KeyFetcher(reg)),
+		verifiable.WithEmbeddedSignatureSuites(signatureSuites()...),
+	}
+	return append(opts, strictOpts()...)
+}
+
+func strictOpts() []verifiable.CredentialOpt {
+	return []verifiable.CredentialOpt{
+		verifiable.WithJSONLDValidation(),
+		verifiable.WithStrictValidation(),
+	}
+}
@@ -1,7 +1,13 @@
 package vdr
 
 import (
+	"crypto/ed25519"
+	"fmt"
+	"strings"
+
 	"github.com/findy-network/findy-agent/agent/storage/api"
+	"github.com/findy-network/findy-agent/std/decorator"
+	"github.com/golang/glog"
 	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
 	vdregistry "github.com/hyperledger/aries-framework-go/pkg/vdr"
 	"github.com/hyperledger/aries-framework-go/pkg/vdr/key"
@@ -51,3 +57,42 @@ func (v *VDR) Peer() vdr.VDR {
 func (v *VDR) Registry() vdr.Registry {
 	return v.registry
 }
+
+// Ed25519Key resolves the Ed25519 verification key of the key ID, e.g. the
+// signer of the attachment. The key ID is a DID URL of did:key or did:peer.
+func (v *VDR) Ed25519Key(kid string) (ed25519.PublicKey, error) {
+	return Ed25519Key(v.registry, kid)
+}
+
+// KeyResolver returns the key resolver for the signed attachments, which
+// resolves the keys thru the registry.
+func KeyResolver(registry vdr.Registry) decorator.KeyResolver {
+	return func(kid string) (ed25519.PublicKey, error) {
+		return Ed25519Key(registry, kid)
+	}
+}
+
+// Ed25519Key resolves the DID document of the key ID thru the registry and
+// returns the Ed25519 key of the document. If the key ID has a fragment, the
+// key must be the verification method of the fragment, otherwise the first
+// Ed25519 key of the document is used.
+func Ed25519Key(registry vdr.Registry, kid string) (_ ed25519.PublicKey, err error) {
+	defer err2.Handle(&err, "resolve key %s", kid)
+
+	DID, fragment, _ := strings.Cut(kid, "#")
+	res := try.To1(registry.Resolve(DID))
+	for _, vm := range res.DIDDocument.VerificationMethod {
+		if fragment != "" && vm.ID != kid && vm.ID != "#"+fragment {
+			continue
+		}
+		if !strings.HasPrefix(vm.Type, "Ed25519") {
+			continue
+		}
+		if len(vm.Value) != ed25519.PublicKeySize {
+			glog.Warningf("invalid key size %d in %s", len(vm.Value), vm.ID)
+			continue
+		}
+		return ed25519.PublicKey(vm.Value), nil
+	}
+	return nil, fmt.Errorf("no Ed25519 key found")
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/hyperledger/aries-framework-go/component/models/did/endpoint"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
 	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
+	"github.com/hyperledger/aries-framework-go/pkg/vdr/fingerprint"
 	"github.com/lainio/err2/assert"
 	"github.com/lainio/err2/try"
 )
@@ -32,6 +33,7 @@ var (
 	afgoTestStorage *mgddb.Storage
 	tests           []testCase
 	testKey         []byte
+	testVDR         *myvdr.VDR
 )
 
 func TestMain(m *testing.M) {
@@ -56,6 +58,7 @@ func setUp() {
 	testVdr, err := myvdr.New(afgoTestStorage)
 	assert.That(err == nil)
 	assert.That(testVdr != nil)
+	testVDR = testVdr
 
 	tests = append(
 		tests,
@@ -157,3 +160,34 @@ func TestVDRClose(t *testing.T) {
 		})
 	}
 }
+
+func TestVDREd25519Key(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	doc := &did.Doc{
+		VerificationMethod: []did.VerificationMethod{{
+			Type:  "Ed25519VerificationKey2018",
+			Value: testKey,
+		}},
+		Service: []did.Service{{
+			Type:            "DidcCommServiceType",
+			ServiceEndpoint: endpoint.NewDIDCommV1Endpoint("http://example.com"),
+		}},
+	}
+	peerDoc := try.To1(testVDR.Peer().Create(doc))
+	_, didKeyID := fingerprint.CreateDIDKey(testKey)
+
+	for _, kid := range []string{peerDoc.DIDDocument.ID, didKeyID} {
+		t.Run(kid, func(t *testing.T) {
+			assert.PushTester(t)
+			defer assert.PopTester()
+			key, err := testVDR.Ed25519Key(kid)
+			assert.NoError(err)
+			assert.DeepEqual([]byte(key), testKey)
+		})
+	}
+
+	_, err := testVDR.Ed25519Key("did:key:invalid")
+	assert.Error(err)
+}
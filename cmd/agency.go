@@ -55,6 +55,7 @@ var agencyStartEnvs = map[string]string{
 	"wallet-backup-time":       "WALLET_BACKUP_TIME",
 	"wallet-pool":              "WALLET_POOL",
 	"request-timeout":          "REQUEST_TIMEOUT",
+	"sa-ping-timeout":          "SA_PING_TIMEOUT",
 }
 
 // startAgencyCmd represents the agency start subcommand
@@ -167,6 +168,7 @@ func init() {
 	flags.StringVar(&aCmd.StewardSeed, "steward-seed", "000000000000000000000000Steward1", flagInfo("steward seed", AgencyCmd.Name(), agencyStartEnvs["steward-seed"]))
 	flags.StringVar(&aCmd.PsmDB, "psm-database-file", "findy.bolt", flagInfo("state machine database's filename", AgencyCmd.Name(), agencyStartEnvs["psm-database-file"]))
 	flags.DurationVar(&aCmd.HTTPReqTimeout, "request-timeout", utils.HTTPReqTimeout, flagInfo("HTTP client request timeout (a2a comms)", AgencyCmd.Name(), agencyStartEnvs["request-timeout"]))
+	flags.DurationVar(&aCmd.SAPingTimeout, "sa-ping-timeout", utils.SAPingTimeout, flagInfo("timeout for controller's answer to ping", AgencyCmd.Name(), agencyStartEnvs["sa-ping-timeout"]))
 	flags.BoolVar(&aCmd.ResetData, "reset-register", false, flagInfo("reset handshake register", AgencyCmd.Name(), agencyStartEnvs["reset-register"]))
 	flags.StringVar(&aCmd.HandshakeRegister, "register-file", "findy.json", flagInfo("handshake registry's filename", AgencyCmd.Name(), agencyStartEnvs["register-file"]))
 	flags.StringVar(&aCmd.WalletName, "steward-wallet-name", "", flagInfo("steward wallet name", AgencyCmd.Name(), agencyStartEnvs["steward-wallet-name"]))
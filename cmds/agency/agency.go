@@ -22,11 +22,14 @@ import (
 	"github.com/findy-network/findy-agent/enclave"
 	grpcserver "github.com/findy-network/findy-agent/grpc/server"
 	"github.com/findy-network/findy-agent/method"
-	_ "github.com/findy-network/findy-agent/protocol/basicmessage" // protocols needed
+	_ "github.com/findy-network/findy-agent/protocol/actionmenu" // protocols needed
+	_ "github.com/findy-network/findy-agent/protocol/basicmessage"
 	_ "github.com/findy-network/findy-agent/protocol/connection"
+	_ "github.com/findy-network/findy-agent/protocol/discoverfeatures"
 	_ "github.com/findy-network/findy-agent/protocol/issuecredential"
 	_ "github.com/findy-network/findy-agent/protocol/notification"
 	_ "github.com/findy-network/findy-agent/protocol/presentproof"
+	_ "github.com/findy-network/findy-agent/protocol/questionanswer"
 	_ "github.com/findy-network/findy-agent/protocol/trustping"
 	"github.com/findy-network/findy-agent/server"
 	"github.com/findy-network/findy-common-go/crypto/db"
@@ -53,11 +56,13 @@ type Cmd struct {
 	HostPort          uint
 	ServerPort        uint
 	ExportPath        string
+	GovernancePath    string
 	EnclavePath       string
 	StewardDid        string
 	HandshakeRegister string
 	PsmDB             string
 	HTTPReqTimeout    time.Duration
+	SAPingTimeout     time.Duration
 	ResetData         bool
 	URL               string
 	VersionInfo       string
@@ -97,11 +102,13 @@ var (
 		HostPort:               8080,
 		ServerPort:             8080,
 		ExportPath:             "",
+		GovernancePath:         "",
 		EnclavePath:            "",
 		StewardDid:             "",
 		HandshakeRegister:      "findy.json",
 		PsmDB:                  "findy.bolt",
 		HTTPReqTimeout:         utils.HTTPReqTimeout,
+		SAPingTimeout:          utils.SAPingTimeout,
 		ResetData:              false,
 		URL:                    "",
 		VersionInfo:            "",
@@ -306,7 +313,9 @@ func (c *Cmd) setRuntimeSettings() {
 	utils.Settings.SetServiceName(c.ServiceName)
 	utils.Settings.SetHostAddr(c.HostAddr)
 	utils.Settings.SetTimeout(c.HTTPReqTimeout)
+	utils.Settings.SetSAPingTimeout(c.SAPingTimeout)
 	utils.Settings.SetExportPath(c.ExportPath)
+	utils.Settings.SetGovernancePath(c.GovernancePath)
 	utils.Settings.SetWalletBackupPath(c.WalletBackupPath)
 	utils.Settings.SetWalletBackupTime(c.WalletBackupTime)
 	utils.Settings.SetRegisterBackupName(c.RegisterBackupName)
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/findy-network/findy-agent/agent/comm"
+	storage "github.com/findy-network/findy-agent/agent/storage/api"
+	"github.com/findy-network/findy-agent/std/decorator"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/try"
+)
+
+func init() {
+	addExtMethod("SetPleaseAck", setPleaseAck)
+	addExtMethod("GetPleaseAck", getPleaseAck)
+}
+
+// pleaseAckMsg tells on which events, RECEIPT and/or OUTCOME, the agent asks
+// acks of Aries RFC 0317 for the protocol messages it sends. Empty means no
+// acks.
+type pleaseAckMsg struct {
+	On []string `json:"on"`
+}
+
+// setPleaseAck saves the ack setting of the agent. The acks of the other end
+// are handled by the notification protocol.
+func setPleaseAck(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "set please ack")
+
+	var req pleaseAckMsg
+	try.To(json.Unmarshal(in, &req))
+	for _, on := range req.On {
+		if on != decorator.AckOnReceipt && on != decorator.AckOnOutcome {
+			return nil, fmt.Errorf("unknown ack event: %s", on)
+		}
+	}
+
+	_, ms := r.WorkerEA().ManagedWallet()
+	settings := try.To1(ms.Storage().SettingStorage().UpdateSettings(
+		func(s *storage.Settings) error {
+			s.PleaseAck = req.On
+			return nil
+		}))
+	return pleaseAckMsg{On: settings.PleaseAck}, nil
+}
+
+func getPleaseAck(_ context.Context, r comm.Receiver, _ []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "get please ack")
+
+	_, ms := r.WorkerEA().ManagedWallet()
+	settings := try.To1(ms.Storage().SettingStorage().GetSettings())
+	return pleaseAckMsg{On: settings.PleaseAck}, nil
+}
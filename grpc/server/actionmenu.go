@@ -0,0 +1,107 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/findy-network/findy-agent/agent/comm"
+	"github.com/findy-network/findy-agent/agent/prot"
+	"github.com/findy-network/findy-agent/protocol/actionmenu"
+	stdmenu "github.com/findy-network/findy-agent/std/actionmenu"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/try"
+)
+
+func init() {
+	addExtMethod("SendActionMenu", sendActionMenu)
+	addExtMethod("GetActionMenu", getActionMenu)
+	addExtMethod("GetActionMenuPerform", getActionMenuPerform)
+	addExtMethod("RequestActionMenu", requestActionMenu)
+	addExtMethod("PerformActionMenu", performActionMenu)
+}
+
+type actionMenuMsg struct {
+	ConnectionID string          `json:"connectionId"`
+	Menu         json.RawMessage `json:"menu,omitempty"`
+}
+
+type actionMenusMsg struct {
+	Menu      *stdmenu.Menu `json:"menu,omitempty"`
+	TheirMenu *stdmenu.Menu `json:"theirMenu,omitempty"`
+}
+
+type performMsg struct {
+	ConnectionID string            `json:"connectionId"`
+	Name         string            `json:"name"`
+	Params       map[string]string `json:"params,omitempty"`
+	Timestamp    int64             `json:"timestamp,string"` // Unix nano
+}
+
+// sendActionMenu publishes the menu to the connection and sends it to the
+// peer. The perform of the peer is notified thru Listen, and the controller
+// answers with the next menu.
+func sendActionMenu(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "send action menu")
+
+	var req actionMenuMsg
+	try.To(json.Unmarshal(in, &req))
+
+	task := try.To1(actionmenu.NewTask(req.ConnectionID, req.Menu))
+	prot.FindAndStartTask(r, task)
+	return protocolIDMsg{ID: task.ID()}, nil
+}
+
+// getActionMenu returns our menu of the connection and the menu the peer has
+// sent us.
+func getActionMenu(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "get action menu")
+
+	var req actionMenuMsg
+	try.To(json.Unmarshal(in, &req))
+
+	menu, theirMenu := try.To2(actionmenu.Menus(r.WorkerEA().MyDID().Did(), req.ConnectionID))
+	return actionMenusMsg{Menu: menu, TheirMenu: theirMenu}, nil
+}
+
+// getActionMenuPerform returns the perform of the protocol the peer has
+// notified us.
+func getActionMenuPerform(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "get action menu perform")
+
+	var req protocolIDMsg
+	try.To(json.Unmarshal(in, &req))
+
+	p := try.To1(actionmenu.GetPerform(r.WorkerEA().MyDID().Did(), req.ID))
+	return performMsg{
+		ConnectionID: p.ConnectionID,
+		Name:         p.Name,
+		Params:       p.Params,
+		Timestamp:    p.Timestamp,
+	}, nil
+}
+
+// requestActionMenu requests the menu of the peer. The controller reads the
+// menu with GetActionMenu when the protocol is ready.
+func requestActionMenu(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "request action menu")
+
+	var req actionMenuMsg
+	try.To(json.Unmarshal(in, &req))
+
+	task := try.To1(actionmenu.NewRequestTask(req.ConnectionID))
+	prot.FindAndStartTask(r, task)
+	return protocolIDMsg{ID: task.ID()}, nil
+}
+
+// performActionMenu performs the option of the menu the peer has sent us.
+func performActionMenu(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "perform action menu")
+
+	var req performMsg
+	try.To(json.Unmarshal(in, &req))
+
+	task := try.To1(actionmenu.NewPerformTask(r.WorkerEA().MyDID().Did(),
+		req.ConnectionID, req.Name, req.Params))
+	prot.FindAndStartTask(r, task)
+	return protocolIDMsg{ID: task.ID()}, nil
+}
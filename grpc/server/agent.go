@@ -121,13 +121,7 @@ func (a *agentServer) Ping(
 		if receiver.AutoPermission() {
 			saReply = true
 		} else {
-			// TODO:
-			// we cannot return ping answer currently synchronously
-			// if needed:
-			// 1) create task with user action handling for ping
-			// 2) block while answer is received
-			// 3) add ping support to Resume-API
-			glog.Warning("SA ping not implemented")
+			saReply = pingController(ctx, receiver, utils.Settings.SAPingTimeout())
 		}
 	}
 	return &pb.PingMsg{ID: pm.ID, PingController: saReply}, nil
@@ -177,6 +171,7 @@ func (a *agentServer) CreateCredDef(
 
 	sch := &vc.Schema{ID: cdc.SchemaID}
 	try.To(sch.FromLedger(ca.RootDid().Did()))
+
 	rCA := <-anoncreds.IssuerCreateAndStoreCredentialDef(
 		ca.Wallet(), ca.RootDid().Did(), sch.Stored.Str2(),
 		cdc.Tag, findy.NullString, findy.NullString)
@@ -336,15 +331,19 @@ func (a *agentServer) Give(ctx context.Context, answer *pb.Answer) (cid *pb.Clie
 		answer.Ack,
 	)
 
-	state := try.To1(psm.GetPSM(psm.StateKey{
+	if resumeSAPing(receiver, answer.ID, answer.Ack) {
+		return &pb.ClientID{ID: answer.ClientID.ID}, nil
+	}
+
+	key := psm.StateKey{
 		DID:   caDID,
 		Nonce: answer.ID,
-	}))
+	}
+	state := try.To1(psm.GetPSM(key))
 
-	prot.Resume(
-		receiver,
-		uniqueTypeID(pb.Protocol_RESUMER, state.FirstState().T.ProtocolType()),
-		answer.ID, answer.Ack)
+	typeID := try.To1(resumeTypeID(key, pb.Protocol_RESUMER,
+		state.FirstState().T.ProtocolType()))
+	prot.Resume(receiver, typeID, answer.ID, answer.Ack, answer.Info)
 
 	return &pb.ClientID{ID: answer.ClientID.ID}, nil
 }
@@ -495,6 +494,10 @@ func processQuestion(ctx context.Context, notify bus.AgentNotify) (as *pb.Questi
 		},
 	}
 
+	if notify.NotificationType == pltype.SAPing {
+		return &q2send, nil // no PSM, the ping is answered with Give
+	}
+
 	id := &pb.ProtocolID{
 		TypeID: pltype.ProtocolTypeForFamily(notify.ProtocolFamily),
 		Role:   notify.Role,
@@ -509,34 +512,47 @@ func processQuestion(ctx context.Context, notify bus.AgentNotify) (as *pb.Questi
 	switch notificationProtocolType {
 	case pb.Protocol_ISSUE_CREDENTIAL:
 		glog.V(1).Infoln("issue propose handling")
-		q2send.Question = &pb.Question_IssuePropose{
-			IssuePropose: &pb.Question_IssueProposeMsg{
-				CredDefID:  ps.GetIssueCredential().GetCredDefID(),
-				ValuesJSON: ps.GetIssueCredential().GetAttributes().String(), // TODO?
-			},
-		}
+		q2send.Question = issueProposeQuestion(ps)
 	case pb.Protocol_PRESENT_PROOF:
 		glog.V(1).Infoln("proof verify handling")
-		attrs := make([]*pb.Question_ProofVerifyMsg_Attribute, 0,
-			len(ps.GetPresentProof().GetProof().Attributes))
-		for _, attr := range ps.GetPresentProof().GetProof().Attributes {
-			attrs = append(attrs, &pb.Question_ProofVerifyMsg_Attribute{
-				Value:     attr.Value,
-				Name:      attr.Name,
-				CredDefID: attr.CredDefID,
-			})
-		}
-		q2send.Question = &pb.Question_ProofVerify{
-			ProofVerify: &pb.Question_ProofVerifyMsg{
-				Attributes: attrs,
-			},
-		}
-
+		q2send.Question = proofVerifyQuestion(ps)
 	}
 
 	return &q2send, nil
 }
 
+// issueProposeQuestion returns the question of the issuing protocol. The
+// holder's values have the reserved attributes of the trust registry's check
+// of the offer, see trust.AttrIssuerTrusted.
+func issueProposeQuestion(ps *pb.ProtocolStatus) *pb.Question_IssuePropose {
+	return &pb.Question_IssuePropose{
+		IssuePropose: &pb.Question_IssueProposeMsg{
+			CredDefID:  ps.GetIssueCredential().GetCredDefID(),
+			ValuesJSON: ps.GetIssueCredential().GetAttributes().String(), // TODO?
+		},
+	}
+}
+
+// proofVerifyQuestion returns the verifier's question of the proof. The
+// attributes have the reserved ones of the trust registry's check, see
+// trust.AttrIssuerTrusted.
+func proofVerifyQuestion(ps *pb.ProtocolStatus) *pb.Question_ProofVerify {
+	attrs := make([]*pb.Question_ProofVerifyMsg_Attribute, 0,
+		len(ps.GetPresentProof().GetProof().GetAttributes()))
+	for _, attr := range ps.GetPresentProof().GetProof().GetAttributes() {
+		attrs = append(attrs, &pb.Question_ProofVerifyMsg_Attribute{
+			Value:     attr.Value,
+			Name:      attr.Name,
+			CredDefID: attr.CredDefID,
+		})
+	}
+	return &pb.Question_ProofVerify{
+		ProofVerify: &pb.Question_ProofVerifyMsg{
+			Attributes: attrs,
+		},
+	}
+}
+
 func processNofity(notify bus.AgentNotify) (as *pb.AgentStatus) {
 	agentStatus := pb.AgentStatus{
 		ClientID: &pb.ClientID{ID: notify.AgentDID},
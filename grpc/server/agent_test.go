@@ -0,0 +1,64 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/findy-network/findy-agent/agent/trust"
+	pb "github.com/findy-network/findy-common-go/grpc/agency/v1"
+	"github.com/lainio/err2/assert"
+)
+
+func TestProofVerifyQuestion(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	ps := &pb.ProtocolStatus{
+		Status: &pb.ProtocolStatus_PresentProof{
+			PresentProof: &pb.ProtocolStatus_PresentProofStatus{
+				Proof: &pb.Protocol_Proof{
+					Attributes: []*pb.Protocol_Proof_Attribute{
+						{ID: "1", Name: "email", CredDefID: "credDefID", Value: "alice@example.com"},
+						{ID: trust.AttrIssuerTrusted, Name: trust.AttrIssuerTrusted, Value: "false"},
+						{ID: trust.AttrIssuerTrustReason, Name: trust.AttrIssuerTrustReason, Value: "issuer isn't trusted"},
+					},
+				},
+			},
+		},
+	}
+
+	attrs := proofVerifyQuestion(ps).ProofVerify.GetAttributes()
+	assert.SLen(attrs, 3)
+	assert.Equal(attrs[0].Name, "email")
+	assert.Equal(attrs[0].CredDefID, "credDefID")
+	assert.Equal(attrs[1].Name, trust.AttrIssuerTrusted)
+	assert.Equal(attrs[1].Value, "false")
+	assert.Equal(attrs[2].Name, trust.AttrIssuerTrustReason)
+	assert.Equal(attrs[2].Value, "issuer isn't trusted")
+}
+
+func TestIssueProposeQuestion(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	ps := &pb.ProtocolStatus{
+		Status: &pb.ProtocolStatus_IssueCredential{
+			IssueCredential: &pb.ProtocolStatus_IssueCredentialStatus{
+				CredDefID: "credDefID",
+				Attributes: &pb.Protocol_IssuingAttributes{
+					Attributes: []*pb.Protocol_IssuingAttributes_Attribute{
+						{Name: "email", Value: "alice@example.com"},
+						{Name: trust.AttrIssuerTrusted, Value: "false"},
+						{Name: trust.AttrIssuerTrustReason, Value: "issuer isn't trusted"},
+					},
+				},
+			},
+		},
+	}
+
+	q := issueProposeQuestion(ps).IssuePropose
+	assert.Equal(q.CredDefID, "credDefID")
+	assert.That(strings.Contains(q.ValuesJSON, trust.AttrIssuerTrusted))
+	assert.That(strings.Contains(q.ValuesJSON, trust.AttrIssuerTrustReason))
+	assert.That(strings.Contains(q.ValuesJSON, "issuer isn't trusted"))
+}
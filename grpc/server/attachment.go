@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/findy-network/findy-agent/agent/comm"
+	"github.com/findy-network/findy-agent/agent/vdr"
+	"github.com/findy-network/findy-agent/core"
+	"github.com/findy-network/findy-agent/std/decorator"
+	"github.com/findy-network/findy-agent/std/didexchange/signature"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/assert"
+	"github.com/lainio/err2/try"
+)
+
+func init() {
+	addExtMethod("SignAttachment", signAttachment)
+	addExtMethod("VerifyAttachment", verifyAttachment)
+}
+
+// attachmentMsg is an Aries RFC 0017 attachment with inline data or links.
+// The attachment is signed with the key of the connection, or with the key of
+// the agent if the connection ID is empty. Content is the content of the
+// links, which is signed as the detached payload. Note! The OOB invitations
+// of the agency don't carry the signed attachments yet, because the
+// invitation model of findy-common-go doesn't have attachments.
+type attachmentMsg struct {
+	ConnectionID string               `json:"connectionId"`
+	Attachment   decorator.Attachment `json:"attachment"`
+	Content      []byte               `json:"content,omitempty"`
+}
+
+type verifiedAttachmentMsg struct {
+	Verified bool     `json:"verified"`
+	Signers  []string `json:"signers,omitempty"` // key IDs
+	Reason   string   `json:"reason,omitempty"`
+}
+
+// signAttachment signs the data of the attachment as JWS with detached
+// payload and returns the signed attachment. The linked content is signed if
+// it's given. Already signed attachment gets
+// an additional signature, e.g. for the delegated requests.
+func signAttachment(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "sign attachment")
+
+	var req attachmentMsg
+	try.To(json.Unmarshal(in, &req))
+
+	signer := signature.Signer{DID: try.To1(attachmentDID(r, req.ConnectionID, true))}
+	if req.Content != nil {
+		try.To(signer.SignLinkedAttachment(&req.Attachment.Data, req.Content))
+	} else {
+		try.To(signer.SignAttachment(&req.Attachment.Data))
+	}
+	return req.Attachment, nil
+}
+
+// verifyAttachment verifies the signatures of the attachment. The signer keys
+// are resolved thru the VDR. If the connection ID is given, one of the
+// signers must be the connection.
+func verifyAttachment(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "verify attachment")
+
+	var req attachmentMsg
+	try.To(json.Unmarshal(in, &req))
+	if req.Attachment.Data.JWS == nil {
+		return nil, fmt.Errorf("attachment isn't signed")
+	}
+
+	res := verifiedAttachmentMsg{}
+	if req.ConnectionID != "" {
+		verifier := signature.Verifier{DID: try.To1(attachmentDID(r, req.ConnectionID, false))}
+		verify := verifier.VerifyAttachment
+		if req.Content != nil {
+			verify = func(d *decorator.AttachmentData) error {
+				return verifier.VerifyLinkedAttachment(d, req.Content)
+			}
+		}
+		if err := verify(&req.Attachment.Data); err != nil {
+			res.Reason = err.Error()
+			return res, nil
+		}
+	}
+	resolve := vdr.KeyResolver(r.WorkerEA().MyDID().Packager().VDRegistry())
+	var signers []string
+	if req.Content != nil {
+		signers, err = req.Attachment.Data.VerifyDetached(req.Content, resolve)
+	} else {
+		signers, err = req.Attachment.Data.Verify(resolve)
+	}
+	if err != nil {
+		res.Reason = err.Error()
+		return res, nil
+	}
+	res.Verified, res.Signers = true, signers
+	return res, nil
+}
+
+// attachmentDID returns our or their DID of the connection, or the DID of
+// the agent if the connection ID is empty.
+func attachmentDID(r comm.Receiver, connID string, our bool) (_ core.DID, err error) {
+	defer err2.Handle(&err, "connection %s", connID)
+
+	wa := r.WorkerEA()
+	if connID == "" {
+		return wa.MyDID(), nil
+	}
+	pw := try.To1(wa.FindPWByID(connID))
+	assert.That(pw != nil, "connection not found")
+	if our {
+		return wa.LoadDID(pw.MyDID), nil
+	}
+	return wa.LoadTheirDID(*pw), nil
+}
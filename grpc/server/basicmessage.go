@@ -0,0 +1,189 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/findy-network/findy-agent/agent/comm"
+	"github.com/findy-network/findy-agent/agent/prot"
+	storage "github.com/findy-network/findy-agent/agent/storage/api"
+	"github.com/findy-network/findy-agent/protocol/basicmessage"
+	"github.com/findy-network/findy-agent/std/decorator"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/try"
+)
+
+func init() {
+	addExtMethod("SendBasicMessage", sendBasicMessage)
+	addExtMethod("ListBasicMessages", listBasicMessages)
+	addExtMethod("GetBasicMessage", getBasicMessage)
+	addExtMethod("MarkBasicMessagesRead", markBasicMessagesRead)
+	addExtMethod("BasicMessageStatus", basicMessageStatus)
+}
+
+type basicMessageFilterMsg struct {
+	ConnectionID string `json:"connectionId"`
+	Search       string `json:"search"`
+	Unread       bool   `json:"unread"`
+	Offset       int    `json:"offset"`
+	// Limit of the page size, all the rest if zero.
+	Limit int `json:"limit"`
+}
+
+type sendBasicMessageMsg struct {
+	ConnectionID string                 `json:"connectionId"`
+	Content      string                 `json:"content"`
+	Attachments  []decorator.Attachment `json:"attachments"`
+}
+
+type basicMessageMsg struct {
+	ID            string `json:"id"`
+	ConnectionID  string `json:"connectionId"`
+	Message       string `json:"message"`
+	SentByMe      bool   `json:"sentByMe"`
+	Delivered     bool   `json:"delivered"`
+	Read          bool   `json:"read"`
+	SendTimestamp int64  `json:"sendTimestamp,omitempty,string"`
+	Timestamp     int64  `json:"timestamp,string"`
+
+	Attachments []decorator.Attachment `json:"attachments,omitempty"`
+}
+
+// basicMessageStatusMsg is the protocol status of the basic message with
+// the attachments, which the gRPC API's status doesn't have.
+type basicMessageStatusMsg struct {
+	Content       string `json:"content"`
+	SentByMe      bool   `json:"sentByMe"`
+	Delivered     bool   `json:"delivered"`
+	SentTimestamp int64  `json:"sentTimestamp,omitempty,string"`
+
+	Attachments []decorator.Attachment `json:"attachments,omitempty"`
+}
+
+type basicMessagesMsg struct {
+	Messages []basicMessageMsg `json:"messages"`
+	// Count of all the messages matching to the filter.
+	Count int `json:"count"`
+}
+
+type markReadMsg struct {
+	ConnectionID string `json:"connectionId"`
+	// Until is the Unix nano timestamp until the received messages are
+	// marked read, now if zero. It's a string like the other timestamps, and
+	// the timestamp of the message can be given as it is.
+	Until int64 `json:"until,string"`
+}
+
+type countMsg struct {
+	Count int `json:"count"`
+}
+
+// sendBasicMessage sends the basic message with the attachments, which the
+// gRPC API's basic message doesn't have.
+func sendBasicMessage(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "send basic message")
+
+	var req sendBasicMessageMsg
+	try.To(json.Unmarshal(in, &req))
+
+	task := try.To1(basicmessage.NewTask(req.ConnectionID, req.Content, req.Attachments))
+	prot.FindAndStartTask(r, task)
+	return protocolIDMsg{ID: task.ID()}, nil
+}
+
+// listBasicMessages returns the page of the message history, the oldest
+// first.
+func listBasicMessages(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "list basic messages")
+
+	var req basicMessageFilterMsg
+	try.To(json.Unmarshal(in, &req))
+	if req.Offset < 0 || req.Limit < 0 {
+		return nil, fmt.Errorf("negative offset (%d) or limit (%d)", req.Offset, req.Limit)
+	}
+
+	store := try.To1(messageStorage(r))
+	msgs, count := try.To2(store.ListMessages(storage.MessageFilter{
+		ConnectionID: req.ConnectionID,
+		Search:       req.Search,
+		Unread:       req.Unread,
+	}, req.Offset, req.Limit))
+
+	res := basicMessagesMsg{
+		Messages: make([]basicMessageMsg, 0, len(msgs)),
+		Count:    count,
+	}
+	for _, m := range msgs {
+		res.Messages = append(res.Messages, newBasicMessageMsg(m))
+	}
+	return res, nil
+}
+
+func getBasicMessage(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "get basic message")
+
+	var req protocolIDMsg
+	try.To(json.Unmarshal(in, &req))
+
+	store := try.To1(messageStorage(r))
+	return newBasicMessageMsg(*try.To1(store.GetMessage(req.ID))), nil
+}
+
+// basicMessageStatus returns the protocol status of the basic message with
+// the attachments.
+func basicMessageStatus(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "basic message status")
+
+	var req protocolIDMsg
+	try.To(json.Unmarshal(in, &req))
+
+	st := try.To1(basicmessage.GetStatus(r.WorkerEA().MyDID().Did(), req.ID))
+	return basicMessageStatusMsg{
+		Content:       st.Content,
+		SentByMe:      st.SentByMe,
+		Delivered:     st.Delivered,
+		SentTimestamp: st.SentTimestamp,
+		Attachments:   st.Attachments,
+	}, nil
+}
+
+// markBasicMessagesRead sets the read marker of the connection's history.
+func markBasicMessagesRead(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "mark basic messages read")
+
+	var req markReadMsg
+	try.To(json.Unmarshal(in, &req))
+	if req.Until == 0 {
+		req.Until = time.Now().UnixNano()
+	}
+
+	store := try.To1(messageStorage(r))
+	return countMsg{Count: try.To1(store.MarkMessagesRead(req.ConnectionID, req.Until))}, nil
+}
+
+func newBasicMessageMsg(m storage.Message) basicMessageMsg {
+	return basicMessageMsg{
+		ID:            m.ID,
+		ConnectionID:  m.ConnectionID,
+		Message:       m.Message,
+		SentByMe:      m.SentByMe,
+		Delivered:     m.Delivered,
+		Read:          m.Read,
+		SendTimestamp: m.SendTimestamp,
+		Timestamp:     m.Timestamp,
+		Attachments:   m.Attachments,
+	}
+}
+
+// messageStorage returns the message storage of the CA's worker agent, which
+// sends and receives the messages.
+func messageStorage(r comm.Receiver) (store storage.MessageStorage, err error) {
+	_, ms := r.WorkerEA().ManagedWallet()
+	store = ms.Storage().MessageStorage()
+	if store == nil {
+		return nil, fmt.Errorf("message storage not available")
+	}
+	return store, nil
+}
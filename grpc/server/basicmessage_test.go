@@ -0,0 +1,50 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/findy-network/findy-agent/agent/storage/api"
+	"github.com/lainio/err2/assert"
+)
+
+func TestMarkBasicMessagesRead_timestamp(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	ca := newTestCA("basicMessagesCA")
+	ms := ca.storage.MessageStorage()
+	// float64 would round both of the timestamps to the same value
+	const ts = int64(1700000000123456789)
+	assert.NoError(ms.SaveMessage(api.Message{ID: "msg1", ConnectionID: "conn", Timestamp: ts}))
+	assert.NoError(ms.SaveMessage(api.Message{ID: "msg2", ConnectionID: "conn", Timestamp: ts + 1}))
+
+	var msg map[string]interface{}
+	assert.NoError(callExt(ca, "GetBasicMessage", `{"id": "msg1"}`, &msg))
+	until, ok := msg["timestamp"].(string)
+	assert.That(ok, "timestamp must be a string")
+	assert.Equal(until, "1700000000123456789")
+
+	var count countMsg
+	assert.NoError(callExt(ca, "MarkBasicMessagesRead",
+		`{"connectionId": "conn", "until": "`+until+`"}`, &count))
+	assert.Equal(count.Count, 1)
+
+	var res basicMessagesMsg
+	assert.NoError(callExt(ca, "ListBasicMessages",
+		`{"connectionId": "conn", "unread": true}`, &res))
+	assert.SLen(res.Messages, 1)
+	assert.Equal(res.Messages[0].ID, "msg2")
+	assert.Equal(res.Messages[0].Timestamp, ts+1)
+}
+
+func TestListBasicMessages_negative(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	ca := newTestCA("negativePageCA")
+
+	var res basicMessagesMsg
+	assert.Error(callExt(ca, "ListBasicMessages", `{"offset": -1}`, &res))
+	assert.Error(callExt(ca, "ListBasicMessages", `{"limit": -1}`, &res))
+	assert.NoError(callExt(ca, "ListBasicMessages", `{}`, &res))
+}
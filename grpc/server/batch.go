@@ -0,0 +1,193 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/findy-network/findy-agent/agent/comm"
+	"github.com/findy-network/findy-agent/agent/didcomm"
+	"github.com/findy-network/findy-agent/protocol/issuecredential/batch"
+	"github.com/golang/glog"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/try"
+)
+
+func init() {
+	addExtStream("IssueCredentialBatch", issueCredentialBatch)
+	addExtStream("ResumeCredentialBatch", resumeCredentialBatch)
+	addExtMethod("GetCredentialBatch", getCredentialBatch)
+	addExtMethod("ListCredentialBatches", listCredentialBatches)
+}
+
+// credentialBatchMsg issues the credential of the cred def to the connections.
+// At most Concurrency protocols run at the same time, and at most Rate of
+// them are started per second (zero is unlimited). The item timeout is in
+// seconds.
+type credentialBatchMsg struct {
+	CredDefID   string                `json:"credDefId"`
+	Items       []credentialBatchItem `json:"items"`
+	Concurrency int                   `json:"concurrency,omitempty"`
+	Rate        int                   `json:"rate,omitempty"`
+	ItemTimeout int                   `json:"itemTimeout,omitempty"`
+}
+
+type credentialBatchItem struct {
+	ConnectionID string                        `json:"connectionId"`
+	Attributes   []didcomm.CredentialAttribute `json:"attributes"`
+	ProtocolID   string                        `json:"protocolId,omitempty"`
+	State        string                        `json:"state,omitempty"`
+	Info         string                        `json:"info,omitempty"`
+}
+
+type batchIDMsg struct {
+	BatchID string `json:"batchId"`
+}
+
+// batchProgressMsg is sent when the state of the item changes. The last one
+// has Finished set and the index is -1. The counts are by item states:
+// pending, running, ok, nack, error, timeout and released.
+type batchProgressMsg struct {
+	BatchID  string               `json:"batchId"`
+	Index    int                  `json:"index"`
+	Item     *credentialBatchItem `json:"item,omitempty"`
+	Counts   map[string]int       `json:"counts"`
+	Total    int                  `json:"total"`
+	Finished bool                 `json:"finished"`
+}
+
+type batchStatusMsg struct {
+	BatchID     string                `json:"batchId"`
+	CredDefID   string                `json:"credDefId"`
+	Created     int64                 `json:"created,string"` // Unix nano
+	Running     bool                  `json:"running"`
+	Concurrency int                   `json:"concurrency"`
+	Rate        int                   `json:"rate"`
+	ItemTimeout int                   `json:"itemTimeout"`
+	Counts      map[string]int        `json:"counts"`
+	Items       []credentialBatchItem `json:"items,omitempty"`
+}
+
+type batchesMsg struct {
+	Batches []batchStatusMsg `json:"batches"`
+}
+
+// issueCredentialBatch starts the batch and streams its progress. The batch
+// continues even if the stream is closed, and it can be followed again with
+// ResumeCredentialBatch.
+func issueCredentialBatch(ctx context.Context, r comm.Receiver, in []byte, send func(interface{}) error) (err error) {
+	defer err2.Handle(&err, "issue credential batch")
+
+	var req credentialBatchMsg
+	try.To(json.Unmarshal(in, &req))
+
+	items := make([]batch.Item, 0, len(req.Items))
+	for _, item := range req.Items {
+		items = append(items, batch.Item{
+			ConnectionID: item.ConnectionID,
+			Attributes:   item.Attributes,
+		})
+	}
+	id := try.To1(batch.Start(r, req.CredDefID, items, batch.Config{
+		Concurrency: req.Concurrency,
+		Rate:        req.Rate,
+		ItemTimeout: time.Duration(req.ItemTimeout) * time.Second,
+	}))
+	return followBatch(ctx, r, id, send)
+}
+
+// resumeCredentialBatch resumes the unfinished items of the batch, e.g. to
+// retry the failed ones, and streams its progress. If the batch is running,
+// its progress is only followed. The running batches are resumed
+// automatically after the agency restart.
+func resumeCredentialBatch(ctx context.Context, r comm.Receiver, in []byte, send func(interface{}) error) (err error) {
+	defer err2.Handle(&err, "resume credential batch")
+
+	var req batchIDMsg
+	try.To(json.Unmarshal(in, &req))
+
+	try.To(batch.Resume(r, req.BatchID))
+	return followBatch(ctx, r, req.BatchID, send)
+}
+
+func followBatch(ctx context.Context, r comm.Receiver, id string, send func(interface{}) error) (err error) {
+	progress, unsubscribe, ok := batch.Subscribe(id)
+	if !ok { // already finished
+		b := try.To1(batch.Get(r, id))
+		return send(batchProgressMsg{BatchID: id, Index: -1, Counts: b.Counts,
+			Total: len(b.Items), Finished: true})
+	}
+	defer unsubscribe()
+
+	for {
+		select {
+		case p, ok := <-progress:
+			if !ok {
+				return nil
+			}
+			msg := batchProgressMsg{
+				BatchID:  p.BatchID,
+				Index:    p.Index,
+				Counts:   p.Counts,
+				Total:    p.Total,
+				Finished: p.Finished,
+			}
+			if !p.Finished {
+				item := newCredentialBatchItem(p.Item)
+				msg.Item = &item
+			}
+			try.To(send(msg))
+		case <-ctx.Done():
+			glog.V(1).Infoln("batch stream closed, batch continues:", id)
+			return nil
+		}
+	}
+}
+
+func getCredentialBatch(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "get credential batch")
+
+	var req batchIDMsg
+	try.To(json.Unmarshal(in, &req))
+
+	b := try.To1(batch.Get(r, req.BatchID))
+	res := newBatchStatusMsg(*b)
+	res.Items = make([]credentialBatchItem, 0, len(b.Items))
+	for _, item := range b.Items {
+		res.Items = append(res.Items, newCredentialBatchItem(item))
+	}
+	return res, nil
+}
+
+func listCredentialBatches(_ context.Context, r comm.Receiver, _ []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "list credential batches")
+
+	res := batchesMsg{Batches: make([]batchStatusMsg, 0)}
+	for _, b := range try.To1(batch.List(r)) {
+		res.Batches = append(res.Batches, newBatchStatusMsg(b))
+	}
+	return res, nil
+}
+
+func newBatchStatusMsg(b batch.Batch) batchStatusMsg {
+	return batchStatusMsg{
+		BatchID:     b.ID,
+		CredDefID:   b.CredDefID,
+		Created:     b.Created,
+		Running:     b.Running,
+		Concurrency: b.Config.Concurrency,
+		Rate:        b.Config.Rate,
+		ItemTimeout: int(b.Config.ItemTimeout / time.Second),
+		Counts:      b.Counts,
+	}
+}
+
+func newCredentialBatchItem(item batch.Item) credentialBatchItem {
+	return credentialBatchItem{
+		ConnectionID: item.ConnectionID,
+		Attributes:   item.Attributes,
+		ProtocolID:   item.ProtocolID,
+		State:        item.State,
+		Info:         item.Info,
+	}
+}
@@ -0,0 +1,19 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/lainio/err2/assert"
+)
+
+func TestListCredentialBatches(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	ca := newTestCA("listBatchesCA")
+
+	var res batchesMsg
+	assert.NoError(callExt(ca, "ListCredentialBatches", `{}`, &res))
+	assert.That(res.Batches != nil, "batches must be listed even if empty")
+	assert.SLen(res.Batches, 0)
+}
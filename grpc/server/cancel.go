@@ -0,0 +1,50 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/findy-network/findy-agent/agent/comm"
+	"github.com/findy-network/findy-agent/agent/psm"
+	"github.com/findy-network/findy-agent/protocol/notification"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/try"
+)
+
+func init() {
+	addExtMethod("CancelProtocol", cancelProtocol)
+}
+
+// cancelProtocolMsg cancels our running protocol. Release only archives the
+// protocol which has ended.
+type cancelProtocolMsg struct {
+	ProtocolID string `json:"protocolId"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+type cancelProtocolResult struct {
+	ProtocolID string `json:"protocolId"`
+	Cancelled  bool   `json:"cancelled"` // false if already ended
+	State      string `json:"state"`
+	Info       string `json:"info,omitempty"`
+}
+
+// cancelProtocol moves the PSM to the Failure state, drops its pending user
+// action, and sends the problem report to the other end. The listeners get
+// the status notification as for any other failed protocol. Cancelling the
+// protocol which has already ended does nothing.
+func cancelProtocol(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "cancel protocol")
+
+	var req cancelProtocolMsg
+	try.To(json.Unmarshal(in, &req))
+
+	cancelled := try.To1(notification.Cancel(r, req.ProtocolID, req.Reason))
+	ps, _ := tryProtocolStatus(psm.NewStateKey(r.WorkerEA(), req.ProtocolID))
+	return cancelProtocolResult{
+		ProtocolID: req.ProtocolID,
+		Cancelled:  cancelled,
+		State:      ps.State.State.String(),
+		Info:       ps.State.Info,
+	}, nil
+}
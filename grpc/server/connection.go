@@ -0,0 +1,197 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/findy-network/findy-agent/agent/comm"
+	"github.com/findy-network/findy-agent/agent/psm"
+	storage "github.com/findy-network/findy-agent/agent/storage/api"
+	"github.com/findy-network/findy-agent/protocol/actionmenu"
+	"github.com/golang/glog"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/try"
+)
+
+func init() {
+	addExtMethod("SetConnectionApproval", setConnectionApproval)
+	addExtMethod("GetConnectionApproval", getConnectionApproval)
+	addExtMethod("ListConnections", listConnections)
+	addExtMethod("GetConnection", getConnection)
+	addExtMethod("UpdateConnection", updateConnection)
+	addExtMethod("DeleteConnection", deleteConnection)
+}
+
+// connectionApprovalMsg is the connection approval mode of the agent. In the
+// manual mode the incoming connection requests are paused until the
+// controller resumes them: ACK sends the response and NACK the problem report.
+// The requester's label and DID are in the status of the protocol.
+type connectionApprovalMsg struct {
+	Manual bool `json:"manual"`
+}
+
+// setConnectionApproval sets the approval mode of the agent. The mode is
+// saved, and it stays after the agency restarts.
+func setConnectionApproval(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "set connection approval")
+
+	var req connectionApprovalMsg
+	try.To(json.Unmarshal(in, &req))
+
+	try.To(r.SetManualConnApproval(req.Manual))
+	return connectionApprovalMsg{Manual: r.ManualConnApproval()}, nil
+}
+
+func getConnectionApproval(_ context.Context, r comm.Receiver, _ []byte) (_ interface{}, err error) {
+	return connectionApprovalMsg{Manual: r.ManualConnApproval()}, nil
+}
+
+// connectionMsg is the connection (pairwise) of the agent. Created is Unix
+// time in nanoseconds, and it's a string like the other timestamps of the ext
+// API.
+type connectionMsg struct {
+	ConnectionID  string   `json:"connectionId"`
+	MyDID         string   `json:"myDid"`
+	TheirDID      string   `json:"theirDid"`
+	TheirEndpoint string   `json:"theirEndpoint"`
+	TheirLabel    string   `json:"theirLabel"`
+	Created       int64    `json:"created,omitempty,string"`
+	State         string   `json:"state"`
+	Tags          []string `json:"tags"`
+	Note          string   `json:"note,omitempty"`
+}
+
+// connectionFilterMsg selects the listed connections. Empty fields match all.
+// Search words are matched to the labels and notes.
+type connectionFilterMsg struct {
+	State  string   `json:"state"`
+	Tags   []string `json:"tags"`
+	Search string   `json:"search"`
+}
+
+// updateConnectionMsg updates the tags and the note of the connection. The
+// omitted fields aren't changed, and the empty ones are cleared.
+type updateConnectionMsg struct {
+	ConnectionID string   `json:"connectionId"`
+	Tags         []string `json:"tags"`
+	Note         *string  `json:"note"`
+}
+
+type connectionsMsg struct {
+	Connections []connectionMsg `json:"connections"`
+}
+
+type deletedConnectionMsg struct {
+	ConnectionID string `json:"connectionId"`
+	Protocols    int    `json:"protocols"` // count of the removed protocol states
+}
+
+func listConnections(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "list connections")
+
+	var req connectionFilterMsg
+	if len(in) > 0 {
+		try.To(json.Unmarshal(in, &req))
+	}
+	filter := storage.ConnectionFilter{State: req.State, Tags: req.Tags, Search: req.Search}
+
+	res := connectionsMsg{Connections: make([]connectionMsg, 0)}
+	for _, conn := range try.To1(connectionStorage(r).ListConnections()) {
+		if filter.Match(conn) {
+			res.Connections = append(res.Connections, newConnectionMsg(conn))
+		}
+	}
+	return res, nil
+}
+
+func getConnection(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "get connection")
+
+	var req connectionIDMsg
+	try.To(json.Unmarshal(in, &req))
+
+	conn := try.To1(connectionStorage(r).GetConnection(req.ConnectionID))
+	return newConnectionMsg(*conn), nil
+}
+
+func updateConnection(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "update connection")
+
+	var req updateConnectionMsg
+	try.To(json.Unmarshal(in, &req))
+
+	conn := try.To1(connectionStorage(r).UpdateConnection(req.ConnectionID,
+		func(conn *storage.Connection, found bool) error {
+			if !found {
+				return fmt.Errorf("connection %s not found", req.ConnectionID)
+			}
+			if req.Tags != nil {
+				conn.Tags = uniqueTags(req.Tags)
+			}
+			if req.Note != nil {
+				conn.Note = *req.Note
+			}
+			return nil
+		}))
+	return newConnectionMsg(*conn), nil
+}
+
+// deleteConnection deletes the connection and everything we have for it: the
+// pairwise pipe, the protocol states, the running ones as well, the action
+// menus, and their DID from the DID cache. The DIDs stay in the wallet.
+func deleteConnection(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "delete connection")
+
+	var req connectionIDMsg
+	try.To(json.Unmarshal(in, &req))
+
+	store := connectionStorage(r)
+	conn := try.To1(store.GetConnection(req.ConnectionID))
+
+	wa := r.WorkerEA()
+	count := try.To1(psm.RmConnectionPSMs(wa.MyDID().Did(), conn.ID))
+	try.To(actionmenu.RmConnectionMenus(wa.MyDID().Did(), conn.ID))
+	r.RmPWFromMap(conn.ID)
+	wa.RmPWFromMap(conn.ID)
+	if conn.TheirDID != "" {
+		wa.RmDIDCache(conn.TheirDID)
+	}
+	try.To(store.DeleteConnection(conn.ID))
+
+	glog.V(1).Infof("connection %s deleted with %d protocols", conn.ID, count)
+	return deletedConnectionMsg{ConnectionID: conn.ID, Protocols: count}, nil
+}
+
+func connectionStorage(r comm.Receiver) storage.ConnectionStorage {
+	_, ms := r.WorkerEA().ManagedWallet()
+	return ms.Storage().ConnectionStorage()
+}
+
+func newConnectionMsg(conn storage.Connection) connectionMsg {
+	tags := conn.Tags
+	if tags == nil {
+		tags = make([]string, 0)
+	}
+	return connectionMsg{
+		ConnectionID:  conn.ID,
+		MyDID:         conn.MyDID,
+		TheirDID:      conn.TheirDID,
+		TheirEndpoint: conn.TheirEndpoint,
+		TheirLabel:    conn.TheirLabel,
+		Created:       conn.Created,
+		State:         conn.State(),
+		Tags:          tags,
+		Note:          conn.Note,
+	}
+}
+
+func uniqueTags(tags []string) []string {
+	res := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if tag != "" && !(storage.Connection{Tags: res}).HasTag(tag) {
+			res = append(res, tag)
+		}
+	}
+	return res
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/findy-network/findy-agent/agent/storage/api"
+	"github.com/lainio/err2/assert"
+)
+
+func TestListConnections(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	ca := newTestCA("listConnectionsCA")
+	cs := ca.storage.ConnectionStorage()
+	assert.NoError(cs.SaveConnection(api.Connection{ID: "conn1", TheirLabel: "Alice"}))
+	assert.NoError(cs.SaveConnection(api.Connection{ID: "conn2", TheirLabel: "Bob"}))
+
+	var res connectionsMsg
+	assert.NoError(callExt(ca, "ListConnections", `{}`, &res))
+	assert.SLen(res.Connections, 2)
+
+	assert.NoError(callExt(ca, "ListConnections", `{"search": "alice"}`, &res))
+	assert.SLen(res.Connections, 1)
+	assert.Equal(res.Connections[0].ConnectionID, "conn1")
+}
+
+func TestGetConnection_created(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	ca := newTestCA("getConnectionCA")
+	const created = int64(1700000000123456789)
+	assert.NoError(ca.storage.ConnectionStorage().SaveConnection(
+		api.Connection{ID: "conn", Created: created}))
+
+	var msg map[string]interface{}
+	assert.NoError(callExt(ca, "GetConnection", `{"connectionId": "conn"}`, &msg))
+	assert.Equal(msg["created"], "1700000000123456789")
+
+	var conn connectionMsg
+	assert.NoError(callExt(ca, "GetConnection", `{"connectionId": "conn"}`, &conn))
+	assert.Equal(conn.Created, created)
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/findy-network/findy-agent/agent/comm"
+	storage "github.com/findy-network/findy-agent/agent/storage/api"
+	"github.com/findy-network/findy-agent/protocol/presentproof/data"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/try"
+)
+
+func init() {
+	addExtMethod("GetCredential", getCredential)
+	addExtMethod("ListCredentials", listCredentials)
+	addExtMethod("DeleteCredential", deleteCredential)
+	addExtMethod("IndexWalletCredentials", indexWalletCredentials)
+}
+
+// credentialMsg is the JSON presentation of the credential in the ext API.
+// Data is the credential in its wire format, which is text in all of the
+// formats.
+type credentialMsg struct {
+	ID         string            `json:"id"`
+	Format     string            `json:"format"`
+	Issuer     string            `json:"issuer,omitempty"`
+	SchemaID   string            `json:"schemaId,omitempty"`
+	CredDefID  string            `json:"credDefId,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Data       string            `json:"data,omitempty"`
+}
+
+type credentialIDMsg struct {
+	ID string `json:"id"`
+}
+
+type credentialFilterMsg struct {
+	Format     string            `json:"format"`
+	Issuer     string            `json:"issuer"`
+	SchemaID   string            `json:"schemaId"`
+	CredDefID  string            `json:"credDefId"`
+	Attributes map[string]string `json:"attributes"`
+	// WithData tells if the credential data is included to the list.
+	WithData bool `json:"withData"`
+}
+
+// indexCredentialsMsg has the attribute names which the anoncreds wallet is
+// searched with. Count is the number of the credentials indexed.
+type indexCredentialsMsg struct {
+	Names []string `json:"names,omitempty"`
+	Count int      `json:"count"`
+}
+
+type credentialsMsg struct {
+	Credentials []credentialMsg `json:"credentials"`
+}
+
+func newCredentialMsg(c storage.Credential, withData bool) credentialMsg {
+	msg := credentialMsg{
+		ID:         c.ID,
+		Format:     c.Format,
+		Issuer:     c.Issuer,
+		SchemaID:   c.SchemaID,
+		CredDefID:  c.CredDefID,
+		Attributes: c.Attributes,
+	}
+	if withData {
+		msg.Data = string(c.Data)
+	}
+	return msg
+}
+
+// credentialStorage returns the credential storage of the CA's worker agent,
+// which is the holder of the credentials.
+func credentialStorage(r comm.Receiver) (cs storage.CredentialStorage, err error) {
+	_, ms := r.WorkerEA().ManagedWallet()
+	cs = ms.Storage().CredentialStorage()
+	if cs == nil {
+		return nil, fmt.Errorf("credential storage not available")
+	}
+	return cs, nil
+}
+
+func getCredential(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "get credential")
+
+	var req credentialIDMsg
+	try.To(json.Unmarshal(in, &req))
+
+	cs := try.To1(credentialStorage(r))
+	cred := try.To1(cs.GetCredential(req.ID))
+	if cred.Deleted {
+		return nil, fmt.Errorf("credential %s is deleted", req.ID)
+	}
+	return newCredentialMsg(*cred, true), nil
+}
+
+func listCredentials(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "list credentials")
+
+	var req credentialFilterMsg
+	try.To(json.Unmarshal(in, &req))
+
+	cs := try.To1(credentialStorage(r))
+	creds := try.To1(cs.ListCredentials(storage.CredentialFilter{
+		Format:     req.Format,
+		Issuer:     req.Issuer,
+		SchemaID:   req.SchemaID,
+		CredDefID:  req.CredDefID,
+		Attributes: req.Attributes,
+	}))
+
+	res := credentialsMsg{Credentials: make([]credentialMsg, 0, len(creds))}
+	for _, c := range creds {
+		res.Credentials = append(res.Credentials, newCredentialMsg(c, req.WithData))
+	}
+	return res, nil
+}
+
+// deleteCredential deletes the credential. Indy credentials cannot be deleted
+// from the anoncreds wallet, which is why their records are marked deleted
+// instead, and the prover doesn't use them in the proofs.
+func deleteCredential(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "delete credential")
+
+	var req credentialIDMsg
+	try.To(json.Unmarshal(in, &req))
+
+	cs := try.To1(credentialStorage(r))
+	cred := try.To1(cs.GetCredential(req.ID))
+	if cred.Format != storage.FormatIndy {
+		try.To(cs.DeleteCredential(req.ID))
+		return req, nil
+	}
+	try.To(cs.SaveCredential(storage.Credential{
+		ID:        cred.ID,
+		Format:    cred.Format,
+		Issuer:    cred.Issuer,
+		SchemaID:  cred.SchemaID,
+		CredDefID: cred.CredDefID,
+		Deleted:   true,
+	}))
+	return req, nil
+}
+
+// indexWalletCredentials adds the credentials of the anoncreds wallet, which
+// were received before the credential storage, to the storage. The wallet
+// can be searched only by the attribute names, so the controller gives them,
+// e.g. one attribute of each schema it has received credentials of.
+func indexWalletCredentials(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "index wallet credentials")
+
+	var req indexCredentialsMsg
+	try.To(json.Unmarshal(in, &req))
+
+	wa := r.WorkerEA()
+	cs := try.To1(credentialStorage(r))
+	count := try.To1(data.IndexWalletCredentials(wa.Wallet(), cs, req.Names))
+	return indexCredentialsMsg{Count: count}, nil
+}
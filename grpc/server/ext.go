@@ -0,0 +1,157 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/findy-network/findy-agent/agent/comm"
+	"github.com/findy-network/findy-common-go/jwt"
+	"github.com/golang/glog"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/try"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// ExtServiceName is the full name of the agent extension service. It
+// includes the agent API methods which aren't in the findy-common-go IDL yet.
+// Requests and responses are google.protobuf.Struct messages, i.e. JSON
+// objects, and the methods are called as /<ExtServiceName>/<method>. The
+// numbers of the Struct are float64, which would round the Unix nano
+// timestamps, and they are JSON strings.
+//
+// TODO: gRPC API change. Move the methods to AgentService when they are
+// stable.
+const ExtServiceName = "findy.agency.ext.v1.AgentExtService"
+
+// extHandler serves an extension method for the CA. The in is the JSON of the
+// request, and out must marshal to a JSON object.
+type extHandler func(ctx context.Context, r comm.Receiver, in []byte) (out interface{}, err error)
+
+// extStreamHandler serves a server streaming extension method for the CA. The
+// send marshals the out to a JSON object and sends it to the stream.
+type extStreamHandler func(ctx context.Context, r comm.Receiver, in []byte, send func(out interface{}) error) error
+
+// protocolIDMsg is the ID of the protocol the extension method has started.
+type protocolIDMsg struct {
+	ID string `json:"id"`
+}
+
+var (
+	extMethods = make(map[string]extHandler)
+	extStreams = make(map[string]extStreamHandler)
+)
+
+// addExtMethod adds the method to the extension service. It must be called
+// from init functions, because the service is registered at startup.
+func addExtMethod(name string, h extHandler) {
+	if _, ok := extMethods[name]; ok {
+		panic("ext method already added: " + name)
+	}
+	extMethods[name] = h
+}
+
+// addExtStream adds the server streaming method to the extension service. The
+// request is a single JSON object like in the normal methods.
+func addExtStream(name string, h extStreamHandler) {
+	if _, ok := extStreams[name]; ok {
+		panic("ext stream already added: " + name)
+	}
+	extStreams[name] = h
+}
+
+func registerExtService(s *grpc.Server) {
+	desc := grpc.ServiceDesc{
+		ServiceName: ExtServiceName,
+		HandlerType: (*interface{})(nil),
+		Metadata:    "ext",
+	}
+	for name, h := range extMethods {
+		desc.Methods = append(desc.Methods, grpc.MethodDesc{
+			MethodName: name,
+			Handler:    extMethodHandler(name, h),
+		})
+	}
+	for name, h := range extStreams {
+		desc.Streams = append(desc.Streams, grpc.StreamDesc{
+			StreamName:    name,
+			Handler:       extStreamHandlerFunc(name, h),
+			ServerStreams: true,
+		})
+	}
+	s.RegisterService(&desc, struct{}{})
+}
+
+func extStreamHandlerFunc(name string, h extStreamHandler) grpc.StreamHandler {
+	return func(_ interface{}, stream grpc.ServerStream) (err error) {
+		defer err2.Handle(&err, "ext %s", name)
+
+		in := new(structpb.Struct)
+		try.To(stream.RecvMsg(in))
+
+		ctx := try.To1(jwt.CheckTokenValidity(stream.Context()))
+		caDID, receiver := try.To2(ca(ctx))
+		glog.V(1).Infoln(caDID, "-agent ext stream:", name)
+
+		send := func(res interface{}) (err error) {
+			defer err2.Handle(&err)
+
+			out := new(structpb.Struct)
+			try.To(protojson.Unmarshal(try.To1(json.Marshal(res)), out))
+			return stream.SendMsg(out)
+		}
+		return h(ctx, receiver, try.To1(protojson.Marshal(in)), send)
+	}
+}
+
+func extMethodHandler(name string, h extHandler) func(
+	srv interface{},
+	ctx context.Context,
+	dec func(interface{}) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (interface{}, error) {
+	call := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return serveExt(ctx, name, h, req.(*structpb.Struct))
+	}
+	return func(
+		srv interface{},
+		ctx context.Context,
+		dec func(interface{}) error,
+		interceptor grpc.UnaryServerInterceptor,
+	) (interface{}, error) {
+		in := new(structpb.Struct)
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+		if interceptor == nil {
+			return call(ctx, in)
+		}
+		info := &grpc.UnaryServerInfo{
+			Server:     srv,
+			FullMethod: "/" + ExtServiceName + "/" + name,
+		}
+		return interceptor(ctx, in, info, call)
+	}
+}
+
+func serveExt(
+	ctx context.Context,
+	name string,
+	h extHandler,
+	in *structpb.Struct,
+) (
+	out *structpb.Struct,
+	err error,
+) {
+	defer err2.Handle(&err, "ext %s", name)
+
+	caDID, receiver := try.To2(ca(ctx))
+	glog.V(1).Infoln(caDID, "-agent ext:", name)
+
+	res := try.To1(h(ctx, receiver, try.To1(protojson.Marshal(in))))
+
+	out = new(structpb.Struct)
+	try.To(protojson.Unmarshal(try.To1(json.Marshal(res)), out))
+	return out, nil
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/findy-network/findy-agent/agent/agency"
+	"github.com/findy-network/findy-agent/agent/comm"
+	"github.com/findy-network/findy-agent/agent/managed"
+	"github.com/findy-network/findy-agent/agent/psm"
+	"github.com/findy-network/findy-agent/agent/ssi"
+	"github.com/findy-network/findy-agent/agent/storage/api"
+	"github.com/findy-network/findy-agent/agent/storage/mgddb"
+	"github.com/findy-network/findy-agent/core"
+	"github.com/findy-network/findy-agent/method"
+	"github.com/findy-network/findy-common-go/jwt"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/try"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const dbPath = "server_test.bolt"
+
+func TestMain(m *testing.M) {
+	try.To(psm.Open(dbPath))
+	code := m.Run()
+	psm.Close()
+	os.Remove(dbPath)
+	os.Exit(code)
+}
+
+// testCA is the CA of the ext method tests. It's its own worker, and it has
+// the in-memory agent storage. Only the methods which the tested handlers
+// call are implemented, the others panic thru the nil Receiver.
+type testCA struct {
+	comm.Receiver
+
+	did     core.DID
+	storage api.AgentStorage
+}
+
+// newTestCA returns the CA which is added to the agency's handlers like the
+// on-boarded CAs.
+func newTestCA(did string) *testCA {
+	ca := &testCA{
+		did: ssi.NewDid(did, "verkey"),
+		storage: try.To1(mgddb.New(api.AgentStorageConfig{
+			AgentKey: mgddb.GenerateKey(),
+			AgentID:  "MEMORY_" + did,
+			FilePath: ".",
+		})),
+	}
+	agency.AddHandler(did, ca)
+	return ca
+}
+
+func (ca *testCA) IsCA() bool { return true }
+func (ca *testCA) IsEA() bool { return false }
+
+func (ca *testCA) NewDID(method.Type, ...string) (core.DID, error) {
+	return nil, fmt.Errorf("not supported in tests")
+}
+
+func (ca *testCA) AddDIDCache(*ssi.DID) {}
+
+func (ca *testCA) MyDID() core.DID         { return ca.did }
+func (ca *testCA) MyCA() comm.Receiver     { return ca }
+func (ca *testCA) WorkerEA() comm.Receiver { return ca }
+func (ca *testCA) WDID() string            { return ca.did.Did() }
+
+func (ca *testCA) ManagedWallet() (managed.Wallet, managed.Wallet) {
+	return nil, testWallet{storage: ca.storage}
+}
+
+type testWallet struct {
+	managed.Wallet
+	storage api.AgentStorage
+}
+
+func (w testWallet) Storage() api.AgentStorage { return w.storage }
+
+// callExt calls the ext method as the CA thru serveExt like the gRPC service
+// does. The in and the out are JSON objects.
+func callExt(ca *testCA, name, in string, out interface{}) (err error) {
+	defer err2.Handle(&err, "call %s", name)
+
+	req := new(structpb.Struct)
+	try.To(protojson.Unmarshal([]byte(in), req))
+
+	ctx := jwt.NewContextWithUser(context.Background(), ca.did.Did())
+	res := try.To1(serveExt(ctx, name, extMethods[name], req))
+	return json.Unmarshal(try.To1(protojson.Marshal(res)), out)
+}
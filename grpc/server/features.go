@@ -0,0 +1,115 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/findy-network/findy-agent/agent/bus"
+	"github.com/findy-network/findy-agent/agent/comm"
+	"github.com/findy-network/findy-agent/agent/prot"
+	"github.com/findy-network/findy-agent/agent/psm"
+	"github.com/findy-network/findy-agent/protocol/discoverfeatures"
+	"github.com/golang/glog"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/try"
+)
+
+const (
+	defaultFeatureQuery   = "https://didcomm.org/*"
+	defaultFeatureTimeout = 30 * time.Second
+)
+
+func init() {
+	addExtMethod("DiscoverFeatures", discoverFeatures)
+}
+
+type discoverFeaturesMsg struct {
+	ConnectionID string `json:"connectionId"`
+	Query        string `json:"query"`
+	Version      string `json:"version"`
+	// Refresh queries the peer even if we have the result in the cache.
+	Refresh bool `json:"refresh"`
+	// Timeout in seconds to wait the disclosures.
+	Timeout int `json:"timeout"`
+}
+
+// disclosedMsg is the disclosed protocols of the peer. Timestamp is Unix time
+// in nanoseconds as a string like the other timestamps of the ext API.
+type disclosedMsg struct {
+	Protocols []string `json:"protocols"`
+	Timestamp int64    `json:"timestamp,string"`
+	Cached    bool     `json:"cached"`
+}
+
+// discoverFeatures queries the protocols the peer of the connection supports,
+// and waits for the disclosures. The results are cached per connection and
+// query.
+func discoverFeatures(ctx context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "discover features")
+
+	req := discoverFeaturesMsg{
+		Query:   defaultFeatureQuery,
+		Version: discoverfeatures.VersionV2,
+	}
+	try.To(json.Unmarshal(in, &req))
+
+	workerDID := r.WorkerEA().MyDID().Did()
+	if d, ok := discoverfeatures.Cached(workerDID, req.ConnectionID, req.Query); ok && !req.Refresh {
+		return newDisclosedMsg(d, true), nil
+	}
+
+	task := try.To1(discoverfeatures.NewTask(req.ConnectionID, req.Version, req.Query))
+	key := psm.NewStateKey(r.WorkerEA(), task.ID())
+	statusChan := bus.WantAll.AddListener(key)
+	defer bus.WantAll.RmListener(key)
+
+	prot.FindAndStartTask(r, task)
+
+	timeout := defaultFeatureTimeout
+	if req.Timeout > 0 {
+		timeout = time.Duration(req.Timeout) * time.Second
+	}
+	try.To(waitReady(ctx, statusChan, timeout))
+
+	d, ok := discoverfeatures.Cached(workerDID, req.ConnectionID, req.Query)
+	if !ok {
+		return nil, fmt.Errorf("no disclosures from connection %s", req.ConnectionID)
+	}
+	return newDisclosedMsg(d, false), nil
+}
+
+// waitReady waits until the protocol is ready. It returns error if the
+// protocol doesn't succeed in time.
+func waitReady(ctx context.Context, statusChan bus.StateChan, timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case status := <-statusChan:
+			glog.V(3).Infoln("protocol state:", status)
+			switch status {
+			case psm.ReadyACK, psm.ACK:
+				return nil
+			case psm.ReadyNACK, psm.NACK, psm.Failure:
+				return fmt.Errorf("protocol failed: %s", status)
+			case psm.SystemReboot:
+				return fmt.Errorf("system reboot")
+			}
+		case <-timer.C:
+			return fmt.Errorf("timeout after %s", timeout)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func newDisclosedMsg(d discoverfeatures.Disclosed, cached bool) disclosedMsg {
+	return disclosedMsg{
+		Protocols: d.Protocols,
+		Timestamp: d.Timestamp.UnixNano(),
+		Cached:    cached,
+	}
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/findy-network/findy-agent/agent/comm"
+	"github.com/findy-network/findy-agent/protocol/discoverfeatures"
+	"github.com/findy-network/findy-common-go/jwt"
+	"github.com/lainio/err2/assert"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestDisclosedMsg_timestamp(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	ca := newTestCA("disclosedCA")
+	ts := time.Unix(0, 1700000000123456789)
+	disclosed := func(context.Context, comm.Receiver, []byte) (interface{}, error) {
+		return newDisclosedMsg(discoverfeatures.Disclosed{
+			Protocols: []string{"https://didcomm.org/trust_ping/1.0"},
+			Timestamp: ts,
+		}, true), nil
+	}
+
+	ctx := jwt.NewContextWithUser(context.Background(), ca.did.Did())
+	out, err := serveExt(ctx, "DiscoverFeatures", disclosed, new(structpb.Struct))
+	assert.NoError(err)
+	assert.Equal(out.Fields["timestamp"].GetStringValue(), "1700000000123456789")
+
+	var msg disclosedMsg
+	data, err := protojson.Marshal(out)
+	assert.NoError(err)
+	assert.NoError(json.Unmarshal(data, &msg))
+	assert.Equal(msg.Timestamp, ts.UnixNano())
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/findy-network/findy-agent/agent/comm"
+	storage "github.com/findy-network/findy-agent/agent/storage/api"
+	"github.com/findy-network/findy-agent/protocol/trustping"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/try"
+)
+
+func init() {
+	addExtMethod("StartHealthMonitor", startHealthMonitor)
+	addExtMethod("StopHealthMonitor", stopHealthMonitor)
+	addExtMethod("GetConnectionHealth", getConnectionHealth)
+}
+
+// healthMonitorMsg is the monitor configuration. The times are in seconds,
+// and the defaults are used for zero values.
+type healthMonitorMsg struct {
+	Interval     int `json:"interval"`
+	Timeout      int `json:"timeout"`
+	FailureLimit int `json:"failureLimit"`
+	MaxPings     int `json:"maxPings"`
+}
+
+type healthMonitorStatusMsg struct {
+	Running bool             `json:"running"`
+	Config  healthMonitorMsg `json:"config"`
+}
+
+type connectionHealthMsg struct {
+	ConnectionID string `json:"connectionId"`
+	LastPing     int64  `json:"lastPing,omitempty,string"` // Unix nano
+	LastSeen     int64  `json:"lastSeen,omitempty,string"` // Unix nano
+	Latency      int64  `json:"latency,omitempty"`         // nanoseconds
+	Failures     int    `json:"failures"`
+	Unreachable  bool   `json:"unreachable"`
+}
+
+type connectionHealthsMsg struct {
+	Monitor     healthMonitorStatusMsg `json:"monitor"`
+	Connections []connectionHealthMsg  `json:"connections"`
+}
+
+type connectionIDMsg struct {
+	ConnectionID string `json:"connectionId"`
+}
+
+// startHealthMonitor starts or restarts the connection health monitor of the
+// agent. The changes in connections' reachability are notified thru Listen as
+// trust ping status notifications.
+func startHealthMonitor(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "start health monitor")
+
+	var req healthMonitorMsg
+	try.To(json.Unmarshal(in, &req))
+
+	cfg := try.To1(trustping.StartMonitor(r, trustping.MonitorConfig{
+		Interval:     time.Duration(req.Interval) * time.Second,
+		Timeout:      time.Duration(req.Timeout) * time.Second,
+		FailureLimit: req.FailureLimit,
+		MaxPings:     req.MaxPings,
+	}))
+	return healthMonitorStatusMsg{Running: true, Config: newHealthMonitorMsg(cfg)}, nil
+}
+
+func stopHealthMonitor(_ context.Context, r comm.Receiver, _ []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "stop health monitor")
+
+	if !try.To1(trustping.StopMonitor(r)) {
+		return nil, fmt.Errorf("health monitor isn't running")
+	}
+	return healthMonitorStatusMsg{Running: false}, nil
+}
+
+// getConnectionHealth returns the health of the connection, or all the
+// connections if the connection ID is empty.
+func getConnectionHealth(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "get connection health")
+
+	var req connectionIDMsg
+	if len(in) > 0 {
+		try.To(json.Unmarshal(in, &req))
+	}
+
+	cfg, running := trustping.Monitor(r.WorkerEA().MyDID().Did())
+	res := connectionHealthsMsg{
+		Monitor:     healthMonitorStatusMsg{Running: running, Config: newHealthMonitorMsg(cfg)},
+		Connections: make([]connectionHealthMsg, 0),
+	}
+
+	_, ms := r.WorkerEA().ManagedWallet()
+	store := ms.Storage().ConnectionStorage()
+	if req.ConnectionID != "" {
+		conn := try.To1(store.GetConnection(req.ConnectionID))
+		res.Connections = append(res.Connections, newConnectionHealthMsg(*conn))
+		return res, nil
+	}
+	for _, conn := range try.To1(store.ListConnections()) {
+		res.Connections = append(res.Connections, newConnectionHealthMsg(conn))
+	}
+	return res, nil
+}
+
+func newHealthMonitorMsg(cfg trustping.MonitorConfig) healthMonitorMsg {
+	return healthMonitorMsg{
+		Interval:     int(cfg.Interval / time.Second),
+		Timeout:      int(cfg.Timeout / time.Second),
+		FailureLimit: cfg.FailureLimit,
+		MaxPings:     cfg.MaxPings,
+	}
+}
+
+func newConnectionHealthMsg(conn storage.Connection) connectionHealthMsg {
+	return connectionHealthMsg{
+		ConnectionID: conn.ID,
+		LastPing:     conn.Health.LastPing,
+		LastSeen:     conn.Health.LastSeen,
+		Latency:      int64(conn.Health.Latency),
+		Failures:     conn.Health.Failures,
+		Unreachable:  conn.Health.Unreachable,
+	}
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/findy-network/findy-agent/agent/comm"
+	"github.com/findy-network/findy-agent/agent/didcomm"
+	"github.com/findy-network/findy-agent/agent/pltype"
+	"github.com/findy-network/findy-agent/agent/prot"
+	"github.com/findy-network/findy-agent/agent/psm"
+	"github.com/findy-network/findy-agent/protocol/issuecredential"
+	"github.com/findy-network/findy-agent/protocol/issuecredential/data"
+	"github.com/findy-network/findy-agent/protocol/issuecredential/issuer"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/assert"
+	"github.com/lainio/err2/try"
+)
+
+func init() {
+	addExtMethod("ProposeCredential", proposeCredential)
+	addExtMethod("GetCredentialProposal", getCredentialProposal)
+	addExtMethod("CounterOfferCredential", counterOfferCredential)
+}
+
+// credentialProposalMsg is the RFC 0036 credential proposal. The holder sends
+// the cred def ID, the schema and issuer filter, or both. The attributes are
+// the credential preview, and their MIME type is text/plain by default.
+type credentialProposalMsg struct {
+	ConnectionID    string                        `json:"connectionId,omitempty"`
+	ProtocolID      string                        `json:"protocolId,omitempty"`
+	CredDefID       string                        `json:"credDefId,omitempty"`
+	SchemaIssuerDID string                        `json:"schemaIssuerDid,omitempty"`
+	SchemaID        string                        `json:"schemaId,omitempty"`
+	SchemaName      string                        `json:"schemaName,omitempty"`
+	SchemaVersion   string                        `json:"schemaVersion,omitempty"`
+	IssuerDID       string                        `json:"issuerDid,omitempty"`
+	Attributes      []didcomm.CredentialAttribute `json:"attributes"`
+	Comment         string                        `json:"comment,omitempty"`
+}
+
+// counterOfferMsg answers the proposal question of the issuer. If the cred
+// def ID or the attributes are given, they are offered instead of the
+// proposed ones.
+type counterOfferMsg struct {
+	ProtocolID string                        `json:"protocolId"`
+	CredDefID  string                        `json:"credDefId,omitempty"`
+	Attributes []didcomm.CredentialAttribute `json:"attributes,omitempty"`
+}
+
+// proposeCredential starts the issuing protocol as the holder by sending the
+// credential proposal.
+func proposeCredential(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "propose credential")
+
+	var req credentialProposalMsg
+	try.To(json.Unmarshal(in, &req))
+
+	task := try.To1(issuecredential.NewProposeTask(req.ConnectionID,
+		req.CredDefID, data.CredFilter{
+			SchemaIssuerDID: req.SchemaIssuerDID,
+			SchemaID:        req.SchemaID,
+			SchemaName:      req.SchemaName,
+			SchemaVersion:   req.SchemaVersion,
+			IssuerDID:       req.IssuerDID,
+		}, req.Attributes, req.Comment))
+	prot.FindAndStartTask(r, task)
+	return protocolIDMsg{ID: task.ID()}, nil
+}
+
+// getCredentialProposal returns the proposal of the issuing protocol, e.g.
+// for the issuer's controller to decide what to offer.
+func getCredentialProposal(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "get credential proposal")
+
+	var req protocolIDMsg
+	try.To(json.Unmarshal(in, &req))
+
+	key := psm.NewStateKey(r.WorkerEA(), req.ID)
+	m := try.To1(psm.GetPSM(key))
+	rep := try.To1(data.GetIssueCredRep(key))
+	assert.That(rep != nil, "credential proposal not found")
+
+	return credentialProposalMsg{
+		ConnectionID:    m.ConnID,
+		ProtocolID:      req.ID,
+		CredDefID:       rep.CredDefID,
+		SchemaIssuerDID: rep.Filter.SchemaIssuerDID,
+		SchemaID:        rep.Filter.SchemaID,
+		SchemaName:      rep.Filter.SchemaName,
+		SchemaVersion:   rep.Filter.SchemaVersion,
+		IssuerDID:       rep.Filter.IssuerDID,
+		Attributes:      rep.Attributes,
+		Comment:         rep.Comment,
+	}, nil
+}
+
+// counterOfferCredential accepts the credential proposal with the changes of
+// the issuer, and the protocol continues with the offer. The holder can
+// accept or decline the counter offer as any other offer.
+func counterOfferCredential(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "counter offer credential")
+
+	var req counterOfferMsg
+	try.To(json.Unmarshal(in, &req))
+
+	try.To(issuer.CounterOffer(r, req.ProtocolID, req.CredDefID, req.Attributes))
+	prot.Resume(r, pltype.CAContinueIssueCredentialProtocol, req.ProtocolID, true, "")
+	return protocolIDMsg{ID: req.ProtocolID}, nil
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/findy-network/findy-agent/agent/comm"
+	"github.com/findy-network/findy-agent/agent/policy"
+	storage "github.com/findy-network/findy-agent/agent/storage/api"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/try"
+)
+
+func init() {
+	addExtMethod("SetAcceptPolicy", setAcceptPolicy)
+	addExtMethod("GetAcceptPolicy", getAcceptPolicy)
+}
+
+// acceptPolicyMsg is the auto-accept policy of the agent. The first matching
+// rule decides if the credential offer, proof request or connection request is
+// accepted or asked from the controller. The default action is used when
+// none of the rules match. Connection requests are decided only in the manual
+// connection approval mode. The permissive SA (AUTO_ACCEPT mode) still accepts
+// everything. The selection tells which credentials are used for the proofs
+// the controller hasn't selected, see SelectProofCredentials.
+type acceptPolicyMsg struct {
+	Rules     []policyRuleMsg  `json:"rules"`
+	Default   string           `json:"default,omitempty"` // accept or ask (default)
+	Selection credSelectionMsg `json:"selection"`
+}
+
+// credSelectionMsg prefers the credentials of the issuers in the listed
+// order, and the order (newest or oldest) decides between the rest. By
+// default the first matching credential is used.
+type credSelectionMsg struct {
+	Order   string   `json:"order,omitempty"`
+	Issuers []string `json:"issuers,omitempty"`
+}
+
+// policyRuleMsg matches the requests of the protocol: connection, issue or
+// proof. Empty fields match all. The request must have only the listed cred
+// defs, issuers and attributes, and the proof request must restrict all of its
+// attributes to them. LabelPattern is rejected: their label isn't a trust
+// criterion.
+type policyRuleMsg struct {
+	Name         string   `json:"name,omitempty"`
+	Protocol     string   `json:"protocol"`
+	Action       string   `json:"action"`
+	CredDefIDs   []string `json:"credDefIds,omitempty"`
+	Issuers      []string `json:"issuers,omitempty"`
+	Attributes   []string `json:"attributes,omitempty"`
+	LabelPattern string   `json:"labelPattern,omitempty"`
+}
+
+func setAcceptPolicy(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "set accept policy")
+
+	var req acceptPolicyMsg
+	try.To(json.Unmarshal(in, &req))
+
+	p := storage.Policy{
+		Rules:     make([]storage.PolicyRule, 0, len(req.Rules)),
+		Default:   req.Default,
+		Selection: storage.CredSelection(req.Selection),
+	}
+	for _, rule := range req.Rules {
+		p.Rules = append(p.Rules, storage.PolicyRule(rule))
+	}
+	try.To(policy.Set(r, p))
+	return req, nil
+}
+
+func getAcceptPolicy(_ context.Context, r comm.Receiver, _ []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "get accept policy")
+
+	res := acceptPolicyMsg{Rules: make([]policyRuleMsg, 0), Default: storage.PolicyAsk}
+	p := try.To1(policy.Get(r))
+	if p == nil {
+		return res, nil
+	}
+	for _, rule := range p.Rules {
+		res.Rules = append(res.Rules, policyRuleMsg(rule))
+	}
+	if p.Default != "" {
+		res.Default = p.Default
+	}
+	res.Selection = credSelectionMsg(p.Selection)
+	return res, nil
+}
@@ -0,0 +1,248 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/findy-network/findy-agent/agent/comm"
+	"github.com/findy-network/findy-agent/agent/psm"
+	storage "github.com/findy-network/findy-agent/agent/storage/api"
+	"github.com/findy-network/findy-agent/protocol/presentproof/data"
+	v2 "github.com/findy-network/findy-agent/std/presentproof/v2"
+	"github.com/findy-network/findy-wrapper-go/anoncreds"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/assert"
+	"github.com/lainio/err2/try"
+)
+
+func init() {
+	addExtMethod("GetProofPresentation", getProofPresentation)
+	addExtMethod("GetProofCandidates", getProofCandidates)
+	addExtMethod("SelectProofCredentials", selectProofCredentials)
+}
+
+// proofPresentationMsg is the result of the Indy proof. The identifiers tell
+// which credential the attribute or the predicate was proven with. The
+// protocol status and the verifier's question have only the attributes of the
+// credentials, and the predicates and the self-attested attributes are here.
+// The template is the verification template the proof was requested with.
+// IssuerTrusted is set if the verifier has the trust registry, and the
+// reasons tell why the issuers aren't trusted.
+type proofPresentationMsg struct {
+	ProtocolID      string              `json:"protocolId"`
+	Attributes      []proofAttributeMsg `json:"attributes"`
+	Predicates      []proofPredicateMsg `json:"predicates"`
+	TemplateID      string              `json:"templateId,omitempty"`
+	TemplateVersion int                 `json:"templateVersion,omitempty"`
+	IssuerTrusted   *bool               `json:"issuerTrusted,omitempty"`
+	TrustReasons    []string            `json:"trustReasons,omitempty"`
+}
+
+type proofAttributeMsg struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Value        string `json:"value,omitempty"`
+	SelfAttested bool   `json:"selfAttested,omitempty"`
+	SchemaID     string `json:"schemaId,omitempty"`
+	CredDefID    string `json:"credDefId,omitempty"`
+}
+
+type proofPredicateMsg struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	PType     string `json:"pType"`
+	PValue    int64  `json:"pValue"`
+	SchemaID  string `json:"schemaId,omitempty"`
+	CredDefID string `json:"credDefId,omitempty"`
+}
+
+func getProofPresentation(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "get proof presentation")
+
+	var req protocolIDMsg
+	try.To(json.Unmarshal(in, &req))
+
+	rep := try.To1(data.GetPresentProofRep(psm.NewStateKey(r.WorkerEA(), req.ID)))
+	assert.That(rep != nil, "proof presentation not found")
+
+	res := proofPresentationMsg{
+		ProtocolID:      req.ID,
+		Attributes:      make([]proofAttributeMsg, 0, len(rep.Attributes)),
+		Predicates:      make([]proofPredicateMsg, 0, len(rep.Predicates)),
+		TemplateID:      rep.TemplateID,
+		TemplateVersion: rep.TemplateVersion,
+	}
+	if rep.Trust != nil {
+		res.IssuerTrusted = &rep.Trust.Trusted
+		res.TrustReasons = rep.Trust.Reasons
+	}
+	for _, attr := range rep.Attributes {
+		res.Attributes = append(res.Attributes, proofAttributeMsg{
+			ID:           attr.ID,
+			Name:         attr.Name,
+			Value:        attr.Value,
+			SelfAttested: attr.SelfAttested,
+			SchemaID:     attr.SchemaID,
+			CredDefID:    attr.CredDefID,
+		})
+	}
+	for _, pred := range rep.Predicates {
+		res.Predicates = append(res.Predicates, proofPredicateMsg{
+			ID:        pred.ID,
+			Name:      pred.Name,
+			PType:     pred.PType,
+			PValue:    pred.PValue,
+			SchemaID:  pred.SchemaID,
+			CredDefID: pred.CredDefID,
+		})
+	}
+	return res, nil
+}
+
+// proofCandidatesMsg has the credentials of the prover which match the
+// referents of the Indy proof request. The referents are the requested
+// attributes and predicates.
+type proofCandidatesMsg struct {
+	ProtocolID string                 `json:"protocolId"`
+	Referents  []proofCandidateRefMsg `json:"referents"`
+}
+
+type proofCandidateRefMsg struct {
+	Referent   string              `json:"referent"`
+	Name       string              `json:"name,omitempty"`
+	Names      []string            `json:"names,omitempty"`
+	Predicate  bool                `json:"predicate,omitempty"`
+	Candidates []proofCandidateMsg `json:"candidates"`
+}
+
+type proofCandidateMsg struct {
+	CredentialID string            `json:"credentialId"`
+	Issuer       string            `json:"issuer"`
+	SchemaID     string            `json:"schemaId"`
+	CredDefID    string            `json:"credDefId"`
+	Attributes   map[string]string `json:"attributes"`
+	Created      int64             `json:"created,omitempty,string"` // Unix nano
+	Selected     bool              `json:"selected,omitempty"`
+}
+
+// proofSelectionMsg selects the credentials of the prover for the referents.
+// The attributes of the unrevealed referents are proven without their values,
+// and the self-attested values are given for the attributes without
+// restrictions. The attribute groups cannot be unrevealed. The policy selects
+// the credentials of the other referents.
+// The proof is made when the protocol is resumed.
+type proofSelectionMsg struct {
+	ProtocolID   string            `json:"protocolId"`
+	Credentials  map[string]string `json:"credentials"` // by referents
+	Unrevealed   []string          `json:"unrevealed,omitempty"`
+	SelfAttested map[string]string `json:"selfAttested,omitempty"`
+}
+
+// getProofCandidates lists the credentials for the proof request which waits
+// for the controller.
+func getProofCandidates(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "get proof candidates")
+
+	var req protocolIDMsg
+	try.To(json.Unmarshal(in, &req))
+
+	wa := r.WorkerEA()
+	rep := try.To1(proverRep(wa, req.ID))
+	_, ms := wa.ManagedWallet()
+	cs := ms.Storage().CredentialStorage()
+	cands := try.To1(rep.Candidates(wa.Wallet(), cs))
+
+	var proofReq anoncreds.ProofRequest
+	try.To(json.Unmarshal([]byte(rep.ProofReq), &proofReq))
+
+	newRef := func(ref string) proofCandidateRefMsg {
+		res := proofCandidateRefMsg{
+			Referent:   ref,
+			Candidates: make([]proofCandidateMsg, 0, len(cands[ref])),
+		}
+		for _, c := range cands[ref] {
+			cand := proofCandidateMsg{
+				CredentialID: c.Referent,
+				Issuer:       storage.IndyIssuer(c.CredDefID),
+				SchemaID:     c.SchemaID,
+				CredDefID:    c.CredDefID,
+				Attributes:   c.Attrs,
+				Selected:     rep.Selection.Credentials[ref] == c.Referent,
+			}
+			if stored, _ := cs.GetCredential(c.Referent); stored != nil {
+				cand.Created = stored.Created
+			}
+			res.Candidates = append(res.Candidates, cand)
+		}
+		return res
+	}
+	res := proofCandidatesMsg{
+		ProtocolID: req.ID,
+		Referents: make([]proofCandidateRefMsg, 0,
+			len(proofReq.RequestedAttributes)+len(proofReq.RequestedPredicates)),
+	}
+	for ref, attr := range proofReq.RequestedAttributes {
+		msg := newRef(ref)
+		msg.Name, msg.Names = attr.Name, attr.Names
+		res.Referents = append(res.Referents, msg)
+	}
+	for ref, pred := range proofReq.RequestedPredicates {
+		msg := newRef(ref)
+		msg.Name, msg.Predicate = pred.Name, true
+		res.Referents = append(res.Referents, msg)
+	}
+	sort.Slice(res.Referents, func(i, j int) bool {
+		return res.Referents[i].Referent < res.Referents[j].Referent
+	})
+	return res, nil
+}
+
+// selectProofCredentials saves the selection of the controller for the proof
+// request which waits for it. The selected credentials must be candidates.
+func selectProofCredentials(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "select proof credentials")
+
+	var req proofSelectionMsg
+	try.To(json.Unmarshal(in, &req))
+
+	wa := r.WorkerEA()
+	rep := try.To1(proverRep(wa, req.ProtocolID))
+	_, ms := wa.ManagedWallet()
+	cands := try.To1(rep.Candidates(wa.Wallet(), ms.Storage().CredentialStorage()))
+	for ref, id := range req.Credentials {
+		found := false
+		for _, c := range cands[ref] {
+			found = found || c.Referent == id
+		}
+		assert.That(found, "credential %s doesn't match referent %s", id, ref)
+	}
+
+	sel := data.ProofSelection{
+		Credentials:  req.Credentials,
+		Unrevealed:   req.Unrevealed,
+		SelfAttested: req.SelfAttested,
+	}
+	var proofReq anoncreds.ProofRequest
+	try.To(json.Unmarshal([]byte(rep.ProofReq), &proofReq))
+	try.To(sel.Validate(proofReq))
+
+	rep.Selection = sel
+	try.To(psm.AddRep(rep))
+	return req, nil
+}
+
+// proverRep returns the rep of the Indy proof request which waits for the
+// prover's controller.
+func proverRep(wa comm.Receiver, protocolID string) (rep *data.PresentProofRep, err error) {
+	defer err2.Handle(&err, "proof request %s", protocolID)
+
+	key := psm.NewStateKey(wa, protocolID)
+	m := try.To1(psm.GetPSM(key))
+	assert.That(m.PendingUserAction(), "proof request isn't waiting for our answer")
+	rep = try.To1(data.GetPresentProofRep(key))
+	assert.That(rep != nil && rep.ProofReq != "", "proof request not found")
+	assert.That(rep.Format == "" || rep.Format == v2.FormatIndyProofReq,
+		"only Indy proof requests have candidates")
+	return rep, nil
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/findy-network/findy-agent/agent/comm"
+	"github.com/findy-network/findy-agent/agent/prot"
+	"github.com/findy-network/findy-agent/protocol/notification"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/try"
+)
+
+func init() {
+	addExtMethod("SendProblemReport", sendProblemReport)
+}
+
+// problemReportMsg is the RFC 0035 problem report. ProtocolID is the
+// protocol the report is about, and it's sent as the parent thread ID.
+type problemReportMsg struct {
+	ConnectionID string `json:"connectionId"`
+	ProtocolID   string `json:"protocolId"`
+	Code         string `json:"code"`
+	Description  string `json:"description"`
+	FixHint      string `json:"fixHint"`
+	Impact       string `json:"impact"`     // message, thread, or connection
+	WhoRetries   string `json:"whoRetries"` // me, you, both, or none
+}
+
+// sendProblemReport sends the problem report to the connection. If the impact
+// is the thread, our protocol of the thread fails as well.
+func sendProblemReport(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "send problem report")
+
+	var req problemReportMsg
+	try.To(json.Unmarshal(in, &req))
+
+	task := try.To1(notification.NewTask(req.ConnectionID, notification.Report{
+		ParentThreadID: req.ProtocolID,
+		Code:           req.Code,
+		Description:    req.Description,
+		FixHint:        req.FixHint,
+		Impact:         req.Impact,
+		WhoRetries:     req.WhoRetries,
+	}))
+	prot.FindAndStartTask(r, task)
+	return protocolIDMsg{ID: task.ID()}, nil
+}
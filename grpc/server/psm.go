@@ -90,6 +90,11 @@ loop:
 		ProtocolID: &pb.ProtocolID{ID: task.ID()},
 		State:      statusCode,
 	}
+	if statusCode == pb.ProtocolState_ERR {
+		if m, _ := psm.FindPSM(key); m != nil {
+			status.Info = m.Problem()
+		}
+	}
 	try.To(server.Send(status))
 
 	return nil
@@ -101,8 +106,13 @@ func (s *didCommServer) Resume(ctx context.Context, state *pb.ProtocolState) (pi
 	caDID, receiver := try.To2(ca(ctx))
 	glog.V(1).Infoln(caDID, "-agent Resume protocol:", state.ProtocolID.TypeID, state.ProtocolID.ID)
 
-	prot.Resume(receiver, uniqueTypeID(state.ProtocolID.Role, state.ProtocolID.TypeID),
-		state.ProtocolID.ID, state.GetState() == pb.ProtocolState_ACK)
+	if resumeSAPing(receiver, state.ProtocolID.ID, state.GetState() == pb.ProtocolState_ACK) {
+		return state.ProtocolID, nil
+	}
+	key := psm.NewStateKey(receiver.WorkerEA(), state.ProtocolID.ID)
+	typeID := try.To1(resumeTypeID(key, state.ProtocolID.Role, state.ProtocolID.TypeID))
+	prot.Resume(receiver, typeID, state.ProtocolID.ID,
+		state.GetState() == pb.ProtocolState_ACK, state.Info)
 
 	return state.ProtocolID, nil
 }
@@ -133,6 +143,9 @@ func (s *didCommServer) Status(ctx context.Context, id *pb.ProtocolID) (ps *pb.P
 	defer err2.Handle(&err)
 
 	caDID, receiver := try.To2(ca(ctx))
+	if ps, ok := saPingStatus(receiver, id.ID); ok {
+		return ps, nil
+	}
 	key := psm.NewStateKey(receiver.WorkerEA(), id.ID)
 	ps, _ = tryProtocolStatus(key)
 
@@ -153,6 +166,7 @@ func tryProtocolStatus(key psm.StateKey) (ps *pb.ProtocolStatus, connID string)
 	if m != nil {
 		connID = m.ConnID
 		state.ProtocolID.Role = m.Role
+		state.Info = m.Problem()
 	} else {
 		glog.Warningf("cannot get protocol role for %s", key)
 		state.ProtocolID.Role = pb.Protocol_UNKNOWN
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/findy-network/findy-agent/agent/comm"
+	"github.com/findy-network/findy-agent/agent/prot"
+	"github.com/findy-network/findy-agent/protocol/questionanswer"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/try"
+)
+
+func init() {
+	addExtMethod("AskQuestion", askQuestion)
+	addExtMethod("GetQuestion", getQuestion)
+}
+
+type askQuestionMsg struct {
+	ConnectionID      string   `json:"connectionId"`
+	QuestionText      string   `json:"questionText"`
+	QuestionDetail    string   `json:"questionDetail"`
+	ValidResponses    []string `json:"validResponses"`
+	SignatureRequired bool     `json:"signatureRequired"`
+	// ExpiresIn is the time in seconds the responder has to answer, no
+	// expiration if zero.
+	ExpiresIn int `json:"expiresIn"`
+	// Attachments is the array of Aries RFC 0017 attachments, which are
+	// signed with the key of the connection if SignAttachments is set.
+	Attachments     json.RawMessage `json:"attachments,omitempty"`
+	SignAttachments bool            `json:"signAttachments"`
+}
+
+type questionMsg struct {
+	ConnectionID      string   `json:"connectionId"`
+	QuestionText      string   `json:"questionText"`
+	QuestionDetail    string   `json:"questionDetail,omitempty"`
+	ValidResponses    []string `json:"validResponses"`
+	SignatureRequired bool     `json:"signatureRequired"`
+	ExpiresTime       int64    `json:"expiresTime,omitempty,string"` // Unix nano
+	Nonce             string   `json:"nonce"`
+	SentByMe          bool     `json:"sentByMe"`
+	Timestamp         int64    `json:"timestamp,string"` // Unix nano
+	Response          string   `json:"response,omitempty"`
+	Signature         string   `json:"signature,omitempty"`
+	SigData           string   `json:"sigData,omitempty"`
+	Signer            string   `json:"signer,omitempty"`
+	Verified          bool     `json:"verified"`
+	Expired           bool     `json:"expired"`
+
+	Attachments         json.RawMessage `json:"attachments,omitempty"`
+	AttachmentsVerified bool            `json:"attachmentsVerified"`
+}
+
+// askQuestion starts question answer protocol. The responder's controller
+// answers by resuming the protocol with the response in the info field, and
+// the verified answer is available thru GetQuestion after the protocol is
+// ready.
+func askQuestion(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "ask question")
+
+	var req askQuestionMsg
+	try.To(json.Unmarshal(in, &req))
+
+	q := questionanswer.Question{
+		ConnectionID:      req.ConnectionID,
+		QuestionText:      req.QuestionText,
+		QuestionDetail:    req.QuestionDetail,
+		ValidResponses:    req.ValidResponses,
+		SignatureRequired: req.SignatureRequired,
+		Attachments:       req.Attachments,
+		SignAttachments:   req.SignAttachments,
+	}
+	if req.ExpiresIn > 0 {
+		q.ExpiresTime = time.Now().Add(time.Duration(req.ExpiresIn) * time.Second).UnixNano()
+	}
+	task := try.To1(questionanswer.NewTask(q))
+	prot.FindAndStartTask(r, task)
+	return protocolIDMsg{ID: task.ID()}, nil
+}
+
+// getQuestion returns the question and the answer of the protocol for the
+// both sides.
+func getQuestion(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "get question")
+
+	var req protocolIDMsg
+	try.To(json.Unmarshal(in, &req))
+
+	q := try.To1(questionanswer.GetQuestion(r.WorkerEA().MyDID().Did(), req.ID))
+	return questionMsg{
+		ConnectionID:      q.ConnectionID,
+		QuestionText:      q.QuestionText,
+		QuestionDetail:    q.QuestionDetail,
+		ValidResponses:    q.ValidResponses,
+		SignatureRequired: q.SignatureRequired,
+		ExpiresTime:       q.ExpiresTime,
+		Nonce:             q.Nonce,
+		SentByMe:          q.SentByMe,
+		Timestamp:         q.Timestamp,
+		Response:          q.Response,
+		Signature:         q.Signature,
+		SigData:           q.SigData,
+		Signer:            q.Signer,
+		Verified:          q.Verified,
+		Expired:           q.Expired,
+
+		Attachments:         q.Attachments,
+		AttachmentsVerified: q.AttachmentsVerified,
+	}, nil
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/findy-network/findy-agent/agent/bus"
+	"github.com/findy-network/findy-agent/agent/comm"
+	"github.com/findy-network/findy-agent/agent/pltype"
+	"github.com/findy-network/findy-agent/agent/utils"
+	pb "github.com/findy-network/findy-common-go/grpc/agency/v1"
+	"github.com/golang/glog"
+)
+
+// saPings are the SA pings waiting for the controller's answer. The ping ID
+// is the protocol ID which the controller uses in Resume or Give. The pings
+// are keyed by the agent as well that only the pinged agent's controllers can
+// see and answer them.
+var saPings = struct {
+	sync.Mutex
+	m map[saPingKey]chan bool
+}{m: make(map[saPingKey]chan bool)}
+
+// saPingKey is the key of the pending SA ping: the agent's worker DID and the
+// ping ID.
+type saPingKey struct {
+	agentDID string
+	id       string
+}
+
+// pingController pings the agent's controllers and blocks until one of them
+// answers thru Resume, or the timeout expires. The controllers get the ping as
+// a paused trust ping, or as PING_WAITS question in Wait. It returns false
+// immediately if no controller is listening.
+func pingController(ctx context.Context, r comm.Receiver, timeout time.Duration) bool {
+	id := utils.UUID()
+	key := saPingKey{agentDID: r.WDID(), id: id}
+	answer := make(chan bool, 1)
+
+	saPings.Lock()
+	saPings.m[key] = answer
+	saPings.Unlock()
+	defer func() {
+		saPings.Lock()
+		delete(saPings.m, key)
+		saPings.Unlock()
+	}()
+
+	sent := bus.WantAllAgentActions.AgentTryBroadcast(bus.AgentNotify{
+		AgentKeyType:     bus.AgentKeyType{AgentDID: r.WDID()},
+		ID:               utils.UUID(),
+		NotificationType: pltype.SAPing,
+		ProtocolID:       id,
+		ProtocolFamily:   pltype.ProtocolTrustPing,
+		Timestamp:        time.Now().UnixNano(),
+		Role:             pb.Protocol_ADDRESSEE,
+	})
+	if !sent {
+		glog.V(1).Infoln(r.WDID(), "no controller listening for SA ping")
+		return false
+	}
+
+	select {
+	case ack := <-answer:
+		glog.V(1).Infoln(r.WDID(), "SA ping answered:", id, ack)
+		return ack
+	case <-time.After(timeout):
+		glog.Warningln(r.WDID(), "SA ping timeout:", id)
+	case <-ctx.Done():
+		glog.V(1).Infoln(r.WDID(), "SA ping canceled:", id)
+	}
+	return false
+}
+
+// resumeSAPing delivers the controller's answer to the agent's waiting SA
+// ping. It returns false if the ID isn't the agent's pending SA ping.
+func resumeSAPing(r comm.Receiver, id string, ack bool) bool {
+	saPings.Lock()
+	defer saPings.Unlock()
+
+	answer, ok := saPings.m[saPingKey{agentDID: r.WDID(), id: id}]
+	if ok {
+		select {
+		case answer <- ack:
+		default: // already answered by other controller
+		}
+	}
+	return ok
+}
+
+// saPingStatus returns the status of the agent's pending SA ping, which is
+// waiting the controller's action.
+func saPingStatus(r comm.Receiver, id string) (ps *pb.ProtocolStatus, ok bool) {
+	saPings.Lock()
+	_, ok = saPings.m[saPingKey{agentDID: r.WDID(), id: id}]
+	saPings.Unlock()
+	if !ok {
+		return nil, false
+	}
+	return &pb.ProtocolStatus{
+		State: &pb.ProtocolState{
+			ProtocolID: &pb.ProtocolID{
+				TypeID: pb.Protocol_TRUST_PING,
+				Role:   pb.Protocol_ADDRESSEE,
+				ID:     id,
+			},
+			State: pb.ProtocolState_WAIT_ACTION,
+		},
+	}, true
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/findy-network/findy-agent/agent/bus"
+	"github.com/lainio/err2/assert"
+)
+
+func TestPingController_otherCA(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	ca := newTestCA("saPingCA")
+	other := newTestCA("saPingOtherCA")
+
+	listenKey := bus.AgentKeyType{AgentDID: ca.WDID(), ClientID: "saPingClient"}
+	notifyChan := bus.WantAllAgentActions.AgentAddListener(listenKey)
+	defer bus.WantAllAgentActions.AgentRmListener(listenKey)
+
+	acked := make(chan bool, 1)
+	go func() {
+		acked <- pingController(context.Background(), ca, time.Minute)
+	}()
+	notify := <-notifyChan
+	id := notify.ProtocolID
+
+	_, ok := saPingStatus(other, id)
+	assert.That(!ok, "other CA sees the SA ping")
+	assert.That(!resumeSAPing(other, id, true), "other CA answers the SA ping")
+
+	ps, ok := saPingStatus(ca, id)
+	assert.That(ok)
+	assert.Equal(ps.State.ProtocolID.ID, id)
+	assert.That(resumeSAPing(ca, id, true))
+	assert.That(<-acked)
+
+	_, ok = saPingStatus(ca, id)
+	assert.That(!ok, "answered SA ping is still pending")
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/findy-network/findy-agent/agent/comm"
 	"github.com/findy-network/findy-agent/agent/pltype"
 	"github.com/findy-network/findy-agent/agent/prot"
+	"github.com/findy-network/findy-agent/agent/psm"
 	"github.com/findy-network/findy-agent/agent/utils"
 	pb "github.com/findy-network/findy-common-go/grpc/agency/v1"
 	ops "github.com/findy-network/findy-common-go/grpc/ops/v1"
@@ -36,6 +37,7 @@ func Serve(conf *rpc.ServerCfg) {
 	conf.Register = func(s *grpc.Server) error {
 		pb.RegisterProtocolServiceServer(s, &didCommServer{})
 		pb.RegisterAgentServiceServer(s, &agentServer{})
+		registerExtService(s)
 
 		root := utils.Settings.GRPCAdmin()
 		ops.RegisterAgencyServiceServer(s, &agencyService{Root: root})
@@ -91,6 +93,31 @@ func uniqueTypeID(role pb.Protocol_Role, id pb.Protocol_Type) string {
 	return s
 }
 
+// resumeTypeID returns agency's internal type ID for protocol resuming. The
+// protocols which don't have a type in the gRPC API are resumed with
+// Protocol_NONE, and they are found by the protocol family of their PSM.
+func resumeTypeID(key psm.StateKey, role pb.Protocol_Role, id pb.Protocol_Type) (s string, err error) {
+	defer err2.Handle(&err, "resume type ID")
+
+	if id != pb.Protocol_NONE {
+		return uniqueTypeID(role, id), nil
+	}
+	state := try.To1(psm.GetPSM(key))
+	s, ok := continuatorTypeID[state.Protocol()]
+	if !ok {
+		return "", fmt.Errorf("cannot resume protocol (%s)", state.Protocol())
+	}
+	return s, nil
+}
+
+// continuatorTypeID is look up table for the protocols which aren't in the
+// gRPC API's protocol types.
+var continuatorTypeID = map[string]string{
+	pltype.ProtocolQuestionAnswer:   pltype.CAContinueQuestionAnswerProtocol,
+	pltype.AriesProtocolConnection:  pltype.CAContinueConnectionProtocol,
+	pltype.AriesProtocolDIDExchange: pltype.CAContinueConnectionProtocol,
+}
+
 // TODO: Should we shift for `role` and consider what happens when w3c protocols
 // come along
 
@@ -104,6 +131,7 @@ var typeID = map[int32]string{
 	int32(10*pb.Protocol_ADDRESSEE) + int32(pb.Protocol_PRESENT_PROOF):    pltype.CAProofPropose,
 	int32(10*pb.Protocol_INITIATOR) + int32(pb.Protocol_TRUST_PING):       pltype.CATrustPing,
 	int32(10*pb.Protocol_INITIATOR) + int32(pb.Protocol_BASIC_MESSAGE):    pltype.CABasicMessage,
+	int32(10*pb.Protocol_RESUMER) + int32(pb.Protocol_DIDEXCHANGE):        pltype.CAContinueConnectionProtocol,
 	int32(10*pb.Protocol_RESUMER) + int32(pb.Protocol_ISSUE_CREDENTIAL):   pltype.CAContinueIssueCredentialProtocol,
 	int32(10*pb.Protocol_RESUMER) + int32(pb.Protocol_PRESENT_PROOF):      pltype.CAContinuePresentProofProtocol,
 }
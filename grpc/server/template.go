@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/findy-network/findy-agent/agent/comm"
+	"github.com/findy-network/findy-agent/agent/didcomm"
+	"github.com/findy-network/findy-agent/agent/prot"
+	"github.com/findy-network/findy-agent/protocol/presentproof"
+	"github.com/findy-network/findy-agent/protocol/presentproof/template"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/try"
+)
+
+func init() {
+	addExtMethod("SaveProofTemplate", saveProofTemplate)
+	addExtMethod("GetProofTemplate", getProofTemplate)
+	addExtMethod("ListProofTemplates", listProofTemplates)
+	addExtMethod("DeleteProofTemplate", deleteProofTemplate)
+	addExtMethod("RequestProofByTemplate", requestProofByTemplate)
+}
+
+// proofTemplateMsg is the verification template. The attributes and the
+// predicates are in the same JSON format as in the proof request. The
+// credentials must be issued by one of the issuers if they are given.
+// NonRevoked and the non-revocation intervals are rejected, because the
+// revocation isn't supported. Saving the template returns its new version.
+type proofTemplateMsg struct {
+	ID          string                   `json:"id"`
+	Version     int                      `json:"version,omitempty"`
+	Description string                   `json:"description,omitempty"`
+	Attributes  []didcomm.ProofAttribute `json:"attributes"`
+	Predicates  []didcomm.ProofPredicate `json:"predicates,omitempty"`
+	Issuers     []string                 `json:"issuers,omitempty"`
+	NonRevoked  bool                     `json:"nonRevoked,omitempty"`
+	Created     int64                    `json:"created,omitempty,string"` // Unix nano
+}
+
+type proofTemplatesMsg struct {
+	Templates []proofTemplateMsg `json:"templates"`
+}
+
+// proofTemplateIDMsg is the template ID and the version. The zero version is
+// the latest.
+type proofTemplateIDMsg struct {
+	ID      string `json:"id"`
+	Version int    `json:"version,omitempty"`
+}
+
+// templateProofRequestMsg requests the proof of the template from the
+// connection. The zero version is the latest.
+type templateProofRequestMsg struct {
+	ConnectionID    string `json:"connectionId"`
+	TemplateID      string `json:"templateId"`
+	TemplateVersion int    `json:"templateVersion,omitempty"`
+}
+
+func saveProofTemplate(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "save proof template")
+
+	var req proofTemplateMsg
+	try.To(json.Unmarshal(in, &req))
+
+	version := try.To1(template.Save(r, template.Template{
+		ID:          req.ID,
+		Description: req.Description,
+		Attributes:  req.Attributes,
+		Predicates:  req.Predicates,
+		Issuers:     req.Issuers,
+		NonRevoked:  req.NonRevoked,
+	}))
+	return proofTemplateIDMsg{ID: req.ID, Version: version}, nil
+}
+
+func getProofTemplate(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "get proof template")
+
+	var req proofTemplateIDMsg
+	try.To(json.Unmarshal(in, &req))
+
+	t := try.To1(template.Get(r, req.ID, req.Version))
+	return newProofTemplateMsg(*t), nil
+}
+
+func listProofTemplates(_ context.Context, r comm.Receiver, _ []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "list proof templates")
+
+	res := proofTemplatesMsg{Templates: make([]proofTemplateMsg, 0)}
+	for _, t := range try.To1(template.List(r)) {
+		res.Templates = append(res.Templates, newProofTemplateMsg(t))
+	}
+	return res, nil
+}
+
+func deleteProofTemplate(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "delete proof template")
+
+	var req proofTemplateIDMsg
+	try.To(json.Unmarshal(in, &req))
+
+	try.To(template.Delete(r, req.ID))
+	return proofTemplateIDMsg{ID: req.ID}, nil
+}
+
+// requestProofByTemplate starts the present proof protocol as the verifier
+// with the proof request of the template. The protocol is followed like the
+// other proof requests, and GetProofPresentation tells the template version.
+func requestProofByTemplate(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "request proof by template")
+
+	var req templateProofRequestMsg
+	try.To(json.Unmarshal(in, &req))
+
+	t := try.To1(template.Get(r, req.TemplateID, req.TemplateVersion))
+	task := try.To1(presentproof.NewTemplateRequestTask(req.ConnectionID, t))
+	prot.FindAndStartTask(r, task)
+	return protocolIDMsg{ID: task.ID()}, nil
+}
+
+func newProofTemplateMsg(t template.Template) proofTemplateMsg {
+	return proofTemplateMsg{
+		ID:          t.ID,
+		Version:     t.Version,
+		Description: t.Description,
+		Attributes:  t.Attributes,
+		Predicates:  t.Predicates,
+		Issuers:     t.Issuers,
+		NonRevoked:  t.NonRevoked,
+		Created:     t.Created,
+	}
+}
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/lainio/err2/assert"
+)
+
+func TestListProofTemplates(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	ca := newTestCA("listTemplatesCA")
+
+	var res proofTemplatesMsg
+	assert.NoError(callExt(ca, "ListProofTemplates", `{}`, &res))
+	assert.SLen(res.Templates, 0)
+
+	var id proofTemplateIDMsg
+	for _, in := range []string{
+		`{"id": "email", "attributes": [{"name": "email"}]}`,
+		`{"id": "age", "predicates": [{"name": "age", "p_type": ">=", "p_value": 18}]}`,
+	} {
+		assert.NoError(callExt(ca, "SaveProofTemplate", in, &id))
+		assert.Equal(id.Version, 1)
+	}
+
+	assert.NoError(callExt(ca, "ListProofTemplates", `{}`, &res))
+	assert.SLen(res.Templates, 2)
+	assert.Equal(res.Templates[0].ID, "age")
+	assert.SLen(res.Templates[0].Predicates, 1)
+	assert.That(res.Templates[0].Created > 0)
+	assert.Equal(res.Templates[1].ID, "email")
+	assert.SLen(res.Templates[1].Attributes, 1)
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/findy-network/findy-agent/agent/comm"
+	storage "github.com/findy-network/findy-agent/agent/storage/api"
+	"github.com/findy-network/findy-agent/agent/trust"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/try"
+)
+
+func init() {
+	addExtMethod("GetTrustRegistry", getTrustRegistry)
+	addExtMethod("CheckIssuerTrust", checkIssuerTrust)
+}
+
+// trustRegistryMsg is the CA's trust registry as in its governance file.
+// Found is false if the CA doesn't have the registry, and then all of the
+// issuers are trusted.
+type trustRegistryMsg struct {
+	Found    bool            `json:"found"`
+	Registry *trust.Registry `json:"registry,omitempty"`
+}
+
+// issuerTrustMsg checks the issuer for the schema like the proof
+// verification and the credential offers do. The issuer is taken from the
+// cred def ID if not given.
+type issuerTrustMsg struct {
+	Issuer    string `json:"issuer,omitempty"`
+	CredDefID string `json:"credDefId,omitempty"`
+	SchemaID  string `json:"schemaId,omitempty"`
+	Trusted   bool   `json:"trusted"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+func getTrustRegistry(_ context.Context, r comm.Receiver, _ []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "get trust registry")
+
+	reg := try.To1(trust.Get(r.MyDID().Did()))
+	return trustRegistryMsg{Found: reg != nil, Registry: reg}, nil
+}
+
+func checkIssuerTrust(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "check issuer trust")
+
+	var req issuerTrustMsg
+	try.To(json.Unmarshal(in, &req))
+	if req.Issuer == "" {
+		req.Issuer = storage.IndyIssuer(req.CredDefID)
+	}
+
+	req.Trusted = true
+	reg := try.To1(trust.Get(r.MyDID().Did()))
+	if reg == nil {
+		return req, nil
+	}
+	if err := reg.Check(trust.Credential{Issuer: req.Issuer, SchemaID: req.SchemaID}); err != nil {
+		req.Trusted, req.Reason = false, err.Error()
+	}
+	return req, nil
+}
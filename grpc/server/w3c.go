@@ -0,0 +1,54 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/findy-network/findy-agent/agent/comm"
+	"github.com/findy-network/findy-agent/agent/vc/w3c"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/try"
+)
+
+func init() {
+	addExtMethod("IssueW3CCredential", issueW3CCredential)
+	addExtMethod("SaveW3CCredential", saveW3CCredential)
+}
+
+// issueW3CCredentialMsg is the unsigned W3C credential in JSON, and its
+// format, which is either storage.FormatW3CLDP or storage.FormatW3CJWT.
+type issueW3CCredentialMsg struct {
+	Credential json.RawMessage `json:"credential"`
+	Format     string          `json:"format"`
+}
+
+// w3cCredentialMsg is the W3C credential in its wire format: JSON for LDP,
+// and compact JWS for JWT.
+type w3cCredentialMsg struct {
+	Data string `json:"data"`
+}
+
+// issueW3CCredential signs the credential with the key of the agent's DID.
+// The controller delivers the signed credential to the holder.
+func issueW3CCredential(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "issue W3C credential")
+
+	var req issueW3CCredentialMsg
+	try.To(json.Unmarshal(in, &req))
+
+	data := try.To1(w3c.IssueCredential(r.WorkerEA().MyDID(), req.Credential, req.Format))
+	return w3cCredentialMsg{Data: string(data)}, nil
+}
+
+// saveW3CCredential verifies the credential and stores it to be used in the
+// DIF presentations.
+func saveW3CCredential(_ context.Context, r comm.Receiver, in []byte) (_ interface{}, err error) {
+	defer err2.Handle(&err, "save W3C credential")
+
+	var req w3cCredentialMsg
+	try.To(json.Unmarshal(in, &req))
+
+	_, ms := r.WorkerEA().ManagedWallet()
+	cred := try.To1(w3c.SaveCredential(ms.Storage(), []byte(req.Data)))
+	return newCredentialMsg(*cred, false), nil
+}
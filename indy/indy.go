@@ -54,6 +54,18 @@ func (i *Indy) CredentialStorage() api.CredentialStorage {
 	panic("not implemented") // TODO: Implement
 }
 
+func (i *Indy) MessageStorage() api.MessageStorage {
+	panic("not implemented") // TODO: Implement
+}
+
+func (i *Indy) PolicyStorage() api.PolicyStorage {
+	panic("not implemented") // TODO: Implement
+}
+
+func (i *Indy) SettingStorage() api.SettingStorage {
+	panic("not implemented") // TODO: Implement
+}
+
 func (i *Indy) OurPackager() api.Packager {
 	return i.packager
 }
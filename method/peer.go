@@ -201,20 +201,15 @@ func (p Peer) SavePairwiseForDID(mStorage managed.Wallet, theirDID core.DID,
 		glog.Warningf("save pairwise for DID error: %v", err)
 	}))
 
-	connection, _ := mStorage.Storage().ConnectionStorage().GetConnection(pw.Name)
-
-	if connection == nil {
-		connection = &api.Connection{
-			ID: pw.Name,
-		}
-	}
-	connection.MyDID = p.Did()
-	connection.TheirDID = theirDID.Did()
-	connection.TheirRoute = pw.Route
-	glog.V(7).Infoln("=== save connection:",
-		connection.ID, connection.MyDID, connection.TheirDID)
-
-	try.To(mStorage.Storage().ConnectionStorage().SaveConnection(*connection))
+	try.To1(mStorage.Storage().ConnectionStorage().UpdateConnection(pw.Name,
+		func(connection *api.Connection, _ bool) error {
+			connection.MyDID = p.Did()
+			connection.TheirDID = theirDID.Did()
+			connection.TheirRoute = pw.Route
+			glog.V(7).Infoln("=== save connection:",
+				connection.ID, connection.MyDID, connection.TheirDID)
+			return nil
+		}))
 }
 
 func NewDoc(pk, addr string) (d *did.Doc, err error) {
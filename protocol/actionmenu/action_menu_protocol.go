@@ -0,0 +1,315 @@
+/*
+Package actionmenu implements Aries RFC 0509 action menu protocol. The menu we
+publish to the connection is stored per connection, and it's sent to the peer
+when it requests it. Every menu waits the perform of the peer, which is
+checked against the menu, stored and notified to the controller that answers
+with the next menu. In the other direction, we can request the menu of the
+peer and perform an option of it.
+*/
+package actionmenu
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/findy-network/findy-agent/agent/comm"
+	"github.com/findy-network/findy-agent/agent/didcomm"
+	"github.com/findy-network/findy-agent/agent/pltype"
+	"github.com/findy-network/findy-agent/agent/prot"
+	"github.com/findy-network/findy-agent/agent/psm"
+	"github.com/findy-network/findy-agent/agent/utils"
+	"github.com/findy-network/findy-agent/std/actionmenu"
+	pb "github.com/findy-network/findy-common-go/grpc/agency/v1"
+	"github.com/golang/glog"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/assert"
+	"github.com/lainio/err2/try"
+)
+
+type taskActionMenu struct {
+	comm.TaskBase
+	Menu actionmenu.Menu
+
+	// Request is set when we request the menu of the peer, and Name when we
+	// perform an option of it.
+	Request bool
+	Name    string
+	Params  map[string]string
+}
+
+// Perform is the action the peer has selected from our menu.
+type Perform struct {
+	ConnectionID string
+	Name         string
+	Params       map[string]string
+	Timestamp    int64
+}
+
+var actionMenuProcessor = comm.ProtProc{
+	Creator: createActionMenuTask,
+	Starter: startActionMenu,
+	Handlers: map[string]comm.HandlerFunc{
+		pltype.HandlerMenu:        handleMenu,
+		pltype.HandlerMenuRequest: handleMenuRequest,
+		pltype.HandlerPerform:     handlePerform,
+	},
+	FillStatus: fillActionMenuStatus,
+}
+
+func init() {
+	gob.Register(&taskActionMenu{})
+	prot.AddCreator(pltype.ProtocolActionMenu, actionMenuProcessor)
+	prot.AddStarter(pltype.CAActionMenu, actionMenuProcessor)
+	prot.AddStatusProvider(pltype.ProtocolActionMenu, actionMenuProcessor)
+	comm.Proc.Add(pltype.ProtocolActionMenu, actionMenuProcessor)
+}
+
+// NewTask returns the task which publishes the menu to the connection. The
+// menu is the JSON of the menu message fields, and it's sent to the peer
+// when it requests the menu later.
+func NewTask(connID string, menuJSON []byte) (t comm.Task, err error) {
+	defer err2.Handle(&err, "action menu task")
+
+	if connID == "" {
+		return nil, fmt.Errorf("connection is needed for action menu")
+	}
+	var menu actionmenu.Menu
+	try.To(json.Unmarshal(menuJSON, &menu))
+
+	return &taskActionMenu{
+		TaskBase: newTaskBase(connID),
+		Menu:     menu,
+	}, nil
+}
+
+// NewRequestTask returns the task which requests the menu of the peer of the
+// connection. The menu is available thru Menus when the peer has sent it.
+func NewRequestTask(connID string) (t comm.Task, err error) {
+	if connID == "" {
+		return nil, fmt.Errorf("connection is needed for action menu request")
+	}
+	return &taskActionMenu{
+		TaskBase: newTaskBase(connID),
+		Request:  true,
+	}, nil
+}
+
+// NewPerformTask returns the task which performs the option of the menu the
+// peer of the connection has sent us. The option and its params are checked
+// against that menu.
+func NewPerformTask(workerDID, connID, name string, params map[string]string) (t comm.Task, err error) {
+	defer err2.Handle(&err, "action menu perform task")
+
+	if connID == "" || name == "" {
+		return nil, fmt.Errorf("connection and option are needed for action menu perform")
+	}
+	_, theirMenu := try.To2(Menus(workerDID, connID))
+	if theirMenu == nil {
+		return nil, fmt.Errorf("no action menu from connection %s", connID)
+	}
+	try.To(theirMenu.CheckPerform(name, params))
+
+	return &taskActionMenu{
+		TaskBase: newTaskBase(connID),
+		Name:     name,
+		Params:   params,
+	}, nil
+}
+
+func newTaskBase(connID string) comm.TaskBase {
+	return comm.TaskBase{TaskHeader: comm.TaskHeader{
+		TaskID:       utils.UUID(),
+		TypeID:       pltype.CAActionMenu,
+		ProtocolRole: pb.Protocol_INITIATOR,
+		ConnID:       connID,
+		Method:       utils.Settings.DIDMethod(),
+	}}
+}
+
+// Menus returns the menu we have published to the connection of the worker
+// agent, and the last menu the connection has sent us. They are nil if there
+// is no menu.
+func Menus(workerDID, connID string) (menu, theirMenu *actionmenu.Menu, err error) {
+	defer err2.Handle(&err, "action menus")
+
+	rep := try.To1(getActionMenuRep(menuKey(workerDID, connID)))
+	if rep == nil {
+		return nil, nil, nil
+	}
+	return rep.Menu, rep.TheirMenu, nil
+}
+
+// GetPerform returns the perform the peer has sent to the protocol.
+func GetPerform(workerDID, protocolID string) (p *Perform, err error) {
+	defer err2.Handle(&err, "action menu perform")
+
+	rep := try.To1(getActionMenuRep(psm.StateKey{DID: workerDID, Nonce: protocolID}))
+	if rep == nil || rep.Name == "" {
+		return nil, fmt.Errorf("no perform for protocol %s", protocolID)
+	}
+	return &Perform{
+		ConnectionID: rep.PwName,
+		Name:         rep.Name,
+		Params:       rep.Params,
+		Timestamp:    rep.Timestamp,
+	}, nil
+}
+
+func createActionMenuTask(header *comm.TaskHeader, _ *pb.Protocol) (t comm.Task, err error) {
+	glog.V(1).Infof("Create task for ActionMenu with connection id %s", header.ConnID)
+
+	return &taskActionMenu{
+		TaskBase: comm.TaskBase{TaskHeader: *header},
+	}, nil
+}
+
+func startActionMenu(ca comm.Receiver, t comm.Task) {
+	defer err2.Catch()
+
+	amTask, ok := t.(*taskActionMenu)
+	assert.That(ok)
+
+	switch {
+	case amTask.Request:
+		try.To(prot.StartPSM(prot.Initial{
+			SendNext:    pltype.ActionMenuMenuRequest,
+			WaitingNext: pltype.ActionMenuMenu,
+			Ca:          ca,
+			T:           t,
+			Setup: func(psm.StateKey, didcomm.MessageHdr) error {
+				return nil
+			},
+		}))
+		return
+	case amTask.Name != "":
+		// the peer answers with a new menu in a new thread, if at all
+		try.To(prot.StartPSM(prot.Initial{
+			SendNext:    pltype.ActionMenuPerform,
+			WaitingNext: pltype.Terminate,
+			Ca:          ca,
+			T:           t,
+			Setup: func(_ psm.StateKey, om didcomm.MessageHdr) error {
+				msg := om.FieldObj().(*actionmenu.ActionMenu)
+				msg.Name = amTask.Name
+				msg.Params = amTask.Params
+				return nil
+			},
+		}))
+		return
+	}
+	try.To(prot.StartPSM(prot.Initial{
+		SendNext:    pltype.ActionMenuMenu,
+		WaitingNext: pltype.ActionMenuPerform,
+		Ca:          ca,
+		T:           t,
+		Setup: func(key psm.StateKey, om didcomm.MessageHdr) (err error) {
+			defer err2.Handle(&err)
+
+			rep := try.To1(getMenuRep(key.DID, amTask.ConnectionID()))
+			rep.Menu = &amTask.Menu
+			rep.Timestamp = time.Now().UnixNano()
+			try.To(psm.AddRep(rep))
+
+			msg := om.FieldObj().(*actionmenu.ActionMenu)
+			msg.Menu = amTask.Menu
+			return nil
+		},
+	}))
+}
+
+// handleMenuRequest answers with the menu we have published to the
+// connection, and waits the perform of it.
+func handleMenuRequest(packet comm.Packet) (err error) {
+	defer err2.Handle(&err, "action menu request")
+
+	workerDID := packet.Receiver.MyDID().Did()
+	rep := try.To1(getActionMenuRep(menuKey(workerDID, packet.Address.ConnID)))
+
+	if rep == nil || rep.Menu == nil {
+		glog.Warningf("no action menu for connection %s", packet.Address.ConnID)
+		return prot.ExecPSM(prot.Transition{
+			Packet:      packet,
+			SendNext:    pltype.Terminate,
+			WaitingNext: pltype.Terminate,
+			InOut: func(string, didcomm.MessageHdr, didcomm.MessageHdr) (bool, error) {
+				return false, nil
+			},
+		})
+	}
+	return prot.ExecPSM(prot.Transition{
+		Packet:      packet,
+		SendNext:    pltype.ActionMenuMenu,
+		WaitingNext: pltype.ActionMenuPerform,
+		InOut: func(_ string, _, om didcomm.MessageHdr) (ack bool, err error) {
+			msg := om.FieldObj().(*actionmenu.ActionMenu)
+			msg.Menu = *rep.Menu
+			return true, nil
+		},
+	})
+}
+
+// handlePerform checks the perform of the peer against the menu we have
+// published to the connection, and stores it by the protocol ID for the
+// controller. The peer gets a problem report of the invalid perform.
+func handlePerform(packet comm.Packet) (err error) {
+	return prot.ExecPSM(prot.Transition{
+		Packet:      packet,
+		SendNext:    pltype.Terminate,
+		WaitingNext: pltype.Terminate,
+		InOut: func(connID string, im, _ didcomm.MessageHdr) (ack bool, err error) {
+			defer err2.Handle(&err, "action menu perform")
+
+			msg := im.FieldObj().(*actionmenu.ActionMenu)
+			glog.V(3).Infof("perform %s from connection %s", msg.Name, connID)
+
+			workerDID := packet.Receiver.MyDID().Did()
+			menuRep := try.To1(getActionMenuRep(menuKey(workerDID, connID)))
+			if menuRep == nil || menuRep.Menu == nil {
+				return false, fmt.Errorf("no action menu for connection %s", connID)
+			}
+			try.To(menuRep.Menu.CheckPerform(msg.Name, msg.Params))
+
+			try.To(psm.AddRep(&actionMenuRep{
+				StateKey: psm.StateKey{
+					DID:   workerDID,
+					Nonce: im.Thread().ID,
+				},
+				PwName:    connID,
+				Timestamp: time.Now().UnixNano(),
+				Name:      msg.Name,
+				Params:    msg.Params,
+			}))
+			return true, nil
+		},
+	})
+}
+
+// handleMenu stores the menu the peer has sent us.
+func handleMenu(packet comm.Packet) (err error) {
+	return prot.ExecPSM(prot.Transition{
+		Packet:      packet,
+		SendNext:    pltype.Terminate,
+		WaitingNext: pltype.Terminate,
+		InOut: func(connID string, im, _ didcomm.MessageHdr) (ack bool, err error) {
+			defer err2.Handle(&err, "action menu")
+
+			msg := im.FieldObj().(*actionmenu.ActionMenu)
+			rep := try.To1(getMenuRep(packet.Receiver.MyDID().Did(), connID))
+			menu := msg.Menu
+			rep.TheirMenu = &menu
+			rep.Timestamp = time.Now().UnixNano()
+			try.To(psm.AddRep(rep))
+			return true, nil
+		},
+	})
+}
+
+// fillActionMenuStatus returns the common status only. The menus of the
+// connection are read with Menus, and the perform of the protocol with
+// GetPerform.
+func fillActionMenuStatus(_ string, _ string, ps *pb.ProtocolStatus) *pb.ProtocolStatus {
+	assert.That(ps != nil)
+	return ps
+}
@@ -0,0 +1,84 @@
+package actionmenu
+
+import (
+	"os"
+	"testing"
+
+	"github.com/findy-network/findy-agent/agent/psm"
+	"github.com/findy-network/findy-agent/std/actionmenu"
+	"github.com/lainio/err2/assert"
+	"github.com/lainio/err2/try"
+)
+
+const dbPath = "action_menu_test.bolt"
+
+func TestMain(m *testing.M) {
+	try.To(psm.Open(dbPath))
+	code := m.Run()
+	psm.Close()
+	os.Remove(dbPath)
+	os.Exit(code)
+}
+
+func TestNewTask(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	task, err := NewTask("conn-id", []byte(`{"title":"Welcome","options":[{"name":"a","title":"A"}]}`))
+	assert.NoError(err)
+	assert.Equal(task.ConnectionID(), "conn-id")
+	amTask, ok := task.(*taskActionMenu)
+	assert.That(ok)
+	assert.Equal(amTask.Menu.Title, "Welcome")
+	assert.SLen(amTask.Menu.Options, 1)
+
+	_, err = NewTask("", []byte(`{}`))
+	assert.Error(err)
+	_, err = NewTask("conn-id", []byte(`{`))
+	assert.Error(err)
+}
+
+func TestNewRequestTask(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	task, err := NewRequestTask("conn-id")
+	assert.NoError(err)
+	amTask, ok := task.(*taskActionMenu)
+	assert.That(ok)
+	assert.That(amTask.Request)
+
+	_, err = NewRequestTask("")
+	assert.Error(err)
+}
+
+func TestNewPerformTask(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	const workerDID = "worker-did"
+
+	// no menu from the peer yet
+	_, err := NewPerformTask(workerDID, "conn-id", "a", nil)
+	assert.Error(err)
+
+	rep := try.To1(getMenuRep(workerDID, "conn-id"))
+	rep.TheirMenu = &actionmenu.Menu{Options: []actionmenu.Option{
+		{Name: "a", Title: "A"},
+		{Name: "b", Title: "B", Disabled: true},
+	}}
+	try.To(psm.AddRep(rep))
+
+	task, err := NewPerformTask(workerDID, "conn-id", "a", nil)
+	assert.NoError(err)
+	amTask, ok := task.(*taskActionMenu)
+	assert.That(ok)
+	assert.Equal(amTask.Name, "a")
+
+	_, err = NewPerformTask(workerDID, "conn-id", "b", nil)
+	assert.Error(err)
+	_, err = NewPerformTask(workerDID, "conn-id", "c", nil)
+	assert.Error(err)
+	_, err = NewPerformTask(workerDID, "conn-id", "a", map[string]string{"x": "1"})
+	assert.Error(err)
+}
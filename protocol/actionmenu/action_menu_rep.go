@@ -0,0 +1,94 @@
+package actionmenu
+
+import (
+	"github.com/findy-network/findy-agent/agent/psm"
+	"github.com/findy-network/findy-agent/std/actionmenu"
+	"github.com/findy-network/findy-common-go/dto"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/assert"
+	"github.com/lainio/err2/try"
+)
+
+const bucketType = psm.BucketActionMenu
+
+// menuPrefix separates the menu reps of the connections from the perform
+// reps of the protocols in the same bucket.
+const menuPrefix = "menu|"
+
+// actionMenuRep is either the menus of the connection, keyed by the
+// connection ID, or the perform we received, keyed by the protocol ID.
+type actionMenuRep struct {
+	psm.StateKey
+	PwName    string
+	Timestamp int64
+
+	// Menu is the menu we have published to the connection and TheirMenu
+	// the last menu we have received from it.
+	Menu      *actionmenu.Menu
+	TheirMenu *actionmenu.Menu
+
+	Name   string
+	Params map[string]string
+}
+
+func init() {
+	psm.Creator.Add(bucketType, NewActionMenuRep)
+}
+
+func NewActionMenuRep(d []byte) psm.Rep {
+	p := &actionMenuRep{}
+	dto.FromGOB(d, p)
+	return p
+}
+
+func (p *actionMenuRep) Key() psm.StateKey {
+	return p.StateKey
+}
+
+func (p *actionMenuRep) Data() []byte {
+	return dto.ToGOB(p)
+}
+
+func (p *actionMenuRep) Type() byte {
+	return bucketType
+}
+
+func menuKey(workerDID, connID string) psm.StateKey {
+	return psm.StateKey{DID: workerDID, Nonce: menuPrefix + connID}
+}
+
+// RmConnectionMenus removes the menus of the connection, e.g. when the
+// connection is deleted. The perform reps are removed with their PSMs.
+func RmConnectionMenus(workerDID, connID string) error {
+	return psm.RmRep(bucketType, menuKey(workerDID, connID))
+}
+
+func getActionMenuRep(key psm.StateKey) (rep *actionMenuRep, err error) {
+	defer err2.Handle(&err)
+
+	res := try.To1(psm.GetRep(bucketType, key))
+
+	// Allow not found
+	if res == nil {
+		return nil, nil
+	}
+
+	var ok bool
+	rep, ok = res.(*actionMenuRep)
+	assert.That(ok, "action menu type mismatch")
+
+	return rep, nil
+}
+
+// getMenuRep returns the menu rep of the connection. A new one is returned if
+// it doesn't exist yet.
+func getMenuRep(workerDID, connID string) (rep *actionMenuRep, err error) {
+	defer err2.Handle(&err)
+
+	key := menuKey(workerDID, connID)
+	rep = try.To1(getActionMenuRep(key))
+	if rep == nil {
+		rep = &actionMenuRep{StateKey: key, PwName: connID}
+	}
+	return rep, nil
+}
@@ -2,6 +2,7 @@ package basicmessage
 
 import (
 	"encoding/gob"
+	"fmt"
 	"time"
 
 	"github.com/findy-network/findy-agent/agent/comm"
@@ -9,7 +10,10 @@ import (
 	"github.com/findy-network/findy-agent/agent/pltype"
 	"github.com/findy-network/findy-agent/agent/prot"
 	"github.com/findy-network/findy-agent/agent/psm"
+	storage "github.com/findy-network/findy-agent/agent/storage/api"
+	"github.com/findy-network/findy-agent/agent/utils"
 	"github.com/findy-network/findy-agent/std/basicmessage"
+	"github.com/findy-network/findy-agent/std/decorator"
 	pb "github.com/findy-network/findy-common-go/grpc/agency/v1"
 	"github.com/golang/glog"
 	"github.com/lainio/err2"
@@ -19,7 +23,8 @@ import (
 
 type taskBasicMessage struct {
 	comm.TaskBase
-	Content string
+	Content     string
+	Attachments []decorator.Attachment
 }
 
 // basicMessageProcessor is a protocol processor for Basic Message protocol.
@@ -40,6 +45,29 @@ func init() {
 	comm.Proc.Add(pltype.ProtocolBasicMessage, basicMessageProcessor)
 }
 
+// NewTask returns the task which sends the message with the attachments to
+// the connection. The gRPC API's basic message has the content only.
+func NewTask(connID, content string, atts []decorator.Attachment) (t comm.Task, err error) {
+	defer err2.Handle(&err, "basic message task")
+
+	if connID == "" {
+		return nil, fmt.Errorf("connection is needed for basic message")
+	}
+	try.To(basicmessage.CheckAttachments(atts))
+
+	return &taskBasicMessage{
+		TaskBase: comm.TaskBase{TaskHeader: comm.TaskHeader{
+			TaskID:       utils.UUID(),
+			TypeID:       pltype.CABasicMessage,
+			ProtocolRole: pb.Protocol_INITIATOR,
+			ConnID:       connID,
+			Method:       utils.Settings.DIDMethod(),
+		}},
+		Content:     content,
+		Attachments: atts,
+	}, nil
+}
+
 func createBasicMessageTask(header *comm.TaskHeader, protocol *pb.Protocol) (t comm.Task, err error) {
 	defer err2.Handle(&err, "createBasicMessageTask")
 
@@ -60,9 +88,14 @@ func createBasicMessageTask(header *comm.TaskHeader, protocol *pb.Protocol) (t c
 	}, nil
 }
 
+// startBasicMessage sends the message. It's stored undelivered first, and
+// marked delivered when the peer's endpoint has accepted it.
 func startBasicMessage(ca comm.Receiver, t comm.Task) {
-	defer err2.Catch()
+	defer err2.Catch(err2.Err(func(err error) {
+		glog.Error("Failed to send basic message: ", err)
+	}))
 
+	var rep *basicMessageRep
 	try.To(prot.StartPSM(prot.Initial{
 		SendNext:    pltype.BasicMessageSend,
 		WaitingNext: pltype.Terminate,
@@ -74,21 +107,28 @@ func startBasicMessage(ca comm.Receiver, t comm.Task) {
 			bmTask, ok := t.(*taskBasicMessage)
 			assert.That(ok)
 
-			rep := &basicMessageRep{
+			rep = &basicMessageRep{
 				StateKey:  key,
 				PwName:    bmTask.ConnectionID(),
 				Message:   bmTask.Content,
 				Timestamp: time.Now().UnixNano(),
 				SentByMe:  true,
-				Delivered: true,
+
+				Attachments: bmTask.Attachments,
 			}
 			try.To(psm.AddRep(rep))
+			try.To(saveHistory(ca.WorkerEA(), rep))
 
 			msg := om.FieldObj().(*basicmessage.Basicmessage)
 			msg.Content = bmTask.Content
+			msg.Attachments = bmTask.Attachments
 			return nil
 		},
 	}))
+
+	rep.Delivered = true
+	try.To(psm.AddRep(rep))
+	try.To(saveHistory(ca.WorkerEA(), rep))
 }
 
 func handleBasicMessage(packet comm.Packet) (err error) {
@@ -107,11 +147,18 @@ func handleBasicMessage(packet comm.Packet) (err error) {
 			glog.Info("Content: ", bm.Content)
 		}
 
+		atts := bm.Attachments
+		if err := basicmessage.CheckAttachments(atts); err != nil {
+			glog.Warningln("dropping attachments:", err)
+			atts = nil
+		}
+
 		key := psm.StateKey{
 			DID:   packet.Receiver.MyDID().Did(),
 			Nonce: im.Thread().ID,
 		}
 
+		// the message we have received is delivered by definition
 		rep := &basicMessageRep{
 			StateKey:      key,
 			PwName:        pw.ID,
@@ -120,8 +167,10 @@ func handleBasicMessage(packet comm.Packet) (err error) {
 			Timestamp:     time.Now().UnixNano(),
 			SentByMe:      false,
 			Delivered:     true,
+			Attachments:   atts,
 		}
 		try.To(psm.AddRep(rep))
+		try.To(saveHistory(packet.Receiver, rep))
 
 		return true, nil
 	}
@@ -133,6 +182,62 @@ func handleBasicMessage(packet comm.Packet) (err error) {
 	})
 }
 
+// saveHistory saves the message to the message history of the connection.
+// Unlike the rep, the history isn't removed with the PSM.
+func saveHistory(wa comm.Receiver, rep *basicMessageRep) (err error) {
+	defer err2.Handle(&err, "save message history")
+
+	_, ms := wa.ManagedWallet()
+	store := ms.Storage().MessageStorage()
+	assert.That(store != nil, "message storage not available")
+
+	return store.SaveMessage(storage.Message{
+		ID:            rep.StateKey.Nonce,
+		ConnectionID:  rep.PwName,
+		Message:       rep.Message,
+		SentByMe:      rep.SentByMe,
+		Delivered:     rep.Delivered,
+		Read:          rep.SentByMe,
+		SendTimestamp: rep.SendTimestamp,
+		Timestamp:     rep.Timestamp,
+		Attachments:   rep.Attachments,
+	})
+}
+
+// Status is the status of the basic message protocol. Unlike the gRPC API's
+// status, it has the attachments of the message.
+type Status struct {
+	Content       string
+	SentByMe      bool
+	Delivered     bool
+	SentTimestamp int64
+	Attachments   []decorator.Attachment
+}
+
+// GetStatus returns the status of the basic message protocol.
+func GetStatus(workerDID, protocolID string) (st *Status, err error) {
+	defer err2.Handle(&err, "basic message status")
+
+	msg := try.To1(getBasicMessageRep(workerDID, protocolID))
+
+	// the PSM is ready before the rep is marked delivered
+	delivered := msg.Delivered
+	if msg.SentByMe && !delivered {
+		m := try.To1(psm.FindPSM(psm.StateKey{DID: workerDID, Nonce: protocolID}))
+		delivered = m != nil && m.LastState().Sub&psm.ACK != 0
+	}
+	return &Status{
+		Content:       msg.Message,
+		SentByMe:      msg.SentByMe,
+		Delivered:     delivered,
+		SentTimestamp: msg.SendTimestamp,
+		Attachments:   msg.Attachments,
+	}, nil
+}
+
+// fillBasicMessageStatus fills the status of the message. The gRPC API's
+// status has no room for the attachments, and the controller gets the whole
+// status with them thru GetStatus.
 func fillBasicMessageStatus(workerDID string, taskID string, ps *pb.ProtocolStatus) *pb.ProtocolStatus {
 	defer err2.Catch(err2.Err(func(err error) {
 		glog.Error("Failed to fill basic message status: ", err)
@@ -142,13 +247,13 @@ func fillBasicMessageStatus(workerDID string, taskID string, ps *pb.ProtocolStat
 
 	status := ps
 
-	msg := try.To1(getBasicMessageRep(workerDID, taskID))
+	msg := try.To1(GetStatus(workerDID, taskID))
 
 	status.Status = &pb.ProtocolStatus_BasicMessage{BasicMessage: &pb.ProtocolStatus_BasicMessageStatus{
-		Content:       msg.Message,
+		Content:       msg.Content,
 		SentByMe:      msg.SentByMe,
 		Delivered:     msg.Delivered,
-		SentTimestamp: msg.SendTimestamp,
+		SentTimestamp: msg.SentTimestamp,
 	}}
 
 	return status
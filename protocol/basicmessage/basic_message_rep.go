@@ -2,6 +2,7 @@ package basicmessage
 
 import (
 	"github.com/findy-network/findy-agent/agent/psm"
+	"github.com/findy-network/findy-agent/std/decorator"
 	"github.com/findy-network/findy-common-go/dto"
 	"github.com/lainio/err2"
 	"github.com/lainio/err2/assert"
@@ -18,6 +19,7 @@ type basicMessageRep struct {
 	Timestamp     int64
 	SentByMe      bool
 	Delivered     bool
+	Attachments   []decorator.Attachment
 }
 
 func init() {
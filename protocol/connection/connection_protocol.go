@@ -4,6 +4,7 @@ import (
 	"encoding/gob"
 	"encoding/json"
 	"strings"
+	"time"
 
 	"github.com/findy-network/findy-agent/agent/aries"
 	"github.com/findy-network/findy-agent/agent/comm"
@@ -12,6 +13,7 @@ import (
 	"github.com/findy-network/findy-agent/agent/managed"
 	"github.com/findy-network/findy-agent/agent/pairwise"
 	"github.com/findy-network/findy-agent/agent/pltype"
+	"github.com/findy-network/findy-agent/agent/policy"
 	"github.com/findy-network/findy-agent/agent/prot"
 	"github.com/findy-network/findy-agent/agent/psm"
 	"github.com/findy-network/findy-agent/agent/sec"
@@ -21,6 +23,8 @@ import (
 	"github.com/findy-network/findy-agent/agent/utils"
 	"github.com/findy-network/findy-agent/core"
 	"github.com/findy-network/findy-agent/method"
+	"github.com/findy-network/findy-agent/std/common"
+	"github.com/findy-network/findy-agent/std/decorator"
 	"github.com/findy-network/findy-agent/std/didexchange"
 	pb "github.com/findy-network/findy-common-go/grpc/agency/v1"
 	"github.com/findy-network/findy-common-go/std/didexchange/invitation"
@@ -41,8 +45,9 @@ type taskDIDExchange struct {
 }
 
 var connectionProcessor = comm.ProtProc{
-	Creator: createConnectionTask,
-	Starter: startConnectionProtocol,
+	Creator:     createConnectionTask,
+	Starter:     startConnectionProtocol,
+	Continuator: continueConnectionRequest,
 	Handlers: map[string]comm.HandlerFunc{
 		pltype.HandlerResponse: handleConnectionResponse, // to Caller (sends the request)
 		pltype.HandlerRequest:  handleConnectionRequest,  // to Callee
@@ -59,6 +64,7 @@ func init() {
 	prot.AddCreator(pltype.AriesProtocolDIDExchange, connectionProcessor)
 	prot.AddStarter(pltype.CAPairwiseCreate, connectionProcessor)
 	prot.AddStarter(pltype.CAPairwiseInvitation, connectionProcessor)
+	prot.AddContinuator(pltype.CAContinueConnectionProtocol, connectionProcessor)
 	prot.AddStatusProvider(pltype.AriesProtocolConnection, connectionProcessor)
 	prot.AddStatusProvider(pltype.AriesProtocolDIDExchange, connectionProcessor)
 	comm.Proc.Add(pltype.AriesProtocolConnection, connectionProcessor)
@@ -200,7 +206,9 @@ func buildRouting(addr, rKey string, rKeys []string, didMethod method.Type) []st
 }
 
 // handleConnectionRequest is handled by 'responder' aka callee.
-// The party who receives conn_req.
+// The party who receives conn_req. If the agent is in the manual connection
+// approval mode, the protocol is paused until the controller resumes it, see
+// continueConnectionRequest.
 func handleConnectionRequest(packet comm.Packet) (err error) {
 	defer err2.Handle(&err, "connection req")
 
@@ -261,10 +269,11 @@ func handleConnectionRequest(packet comm.Packet) (err error) {
 		Key:  myEndp.VerKey,
 	})
 
-	try.To(calleePw.Store())
+	// NOTE: verify can be done only after their DID is stored to KMS. The
+	// pairwise is saved only when the request is accepted.
+	try.To(calleePw.StoreCaller())
 
 	// todo: send NACK here if fails
-	// NOTE: verify can be done only after their DID is stored to KMS
 	try.To(reqMsg.Verify(callerDID))
 
 	caller := calleePw.Caller // the other end, we're here the callee
@@ -274,24 +283,138 @@ func handleConnectionRequest(packet comm.Packet) (err error) {
 		StateKey:   psm.StateKey{DID: meDID, Nonce: safeThreadID}, // check if this really must be connection id
 		Name:       connectionID,
 		TheirLabel: reqMsg.Label(),
-		Callee:     didRep{DID: calleePw.Callee.Did(), VerKey: calleePw.Callee.VerKey(), My: true},
-		Caller:     didRep{DID: caller.Did(), VerKey: caller.VerKey(), Endp: callerAddress},
+		Callee: didRep{DID: calleePw.Callee.Did(), VerKey: calleePw.Callee.VerKey(),
+			Endp: myEndp.Address(), My: true},
+		Caller: didRep{DID: caller.Did(), VerKey: caller.VerKey(), Endp: callerAddress},
+	}
+	// in the manual approval mode the policy can still accept the request
+	manualApproval := receiver.ManualConnApproval() &&
+		!policy.Accept(receiver, connectionID, storage.PolicyRequest{
+			Protocol: storage.PolicyConnection,
+		})
+	if manualApproval {
+		pwr.Request = ipl.JSON()
 	}
 	try.To(psm.AddRep(pwr))
 
+	if manualApproval {
+		glog.V(1).Infof("connection request (%s) from %s waits approval",
+			connectionID, reqMsg.Label())
+		task.UserActionPLType = pltype.CANotifyUserAction
+		try.To(prot.UpdatePSM(meDID, connectionID, task, ipl, psm.Decrypted))
+		wpl := aries.PayloadCreator.New(didcomm.PayloadInit{
+			ID:   safeThreadID,
+			Type: userActionType(ipl.Type()),
+		})
+		return prot.UpdatePSM(meDID, connectionID, task, wpl, psm.Waiting)
+	}
+
+	pipe := try.To1(acceptConnectionRequest(receiver, calleePw, reqMsg))
+	return sendConnectionResponse(meDID, connectionID, task, reqMsg, pipe)
+}
+
+// acceptConnectionRequest saves the pairwise and the endpoint of the
+// connection, and maps the pipe of the connection.
+func acceptConnectionRequest(
+	receiver comm.Receiver,
+	calleePw *pairwise.Callee,
+	reqMsg didexchange.PwMsg,
+) (
+	pipe sec.Pipe,
+	err error,
+) {
+	defer err2.Handle(&err, "accept connection request")
+
+	calleePw.SavePairwise()
+
 	// SAVE ENDPOINT to wallet
-	try.To(saveConnectionEndpoint(managedStorage(receiver), connectionID, callerAddress))
+	callerEndp := endp.NewAddrFromPublic(reqMsg.Endpoint())
+	try.To(saveConnectionEndpoint(managedStorage(receiver), calleePw.Name,
+		callerEndp.Address(), reqMsg.Label()))
 
-	pipe := sec.Pipe{
+	pipe = sec.Pipe{
 		In:  calleePw.Callee, // This is us
-		Out: caller,          // This is the other end, who sent the Request
+		Out: calleePw.Caller, // This is the other end, who sent the Request
 	}
 
-	caller.SetAEndp(callerEP)
-	receiver.AddToPWMap(calleePw.Callee, caller, connectionID) // to access PW later, map it
+	calleePw.Caller.SetAEndp(reqMsg.Endpoint())
+	receiver.AddToPWMap(calleePw.Callee, calleePw.Caller, calleePw.Name) // to access PW later, map it
+	return pipe, nil
+}
+
+// continueConnectionRequest sends the connection response when the controller
+// has approved the request by resuming the protocol with ACK. The connection
+// is saved only then. With NACK the requester gets the problem report, the
+// protocol ends, and their DID is removed from the cache.
+func continueConnectionRequest(ca comm.Receiver, im didcomm.Msg) {
+	defer err2.Catch(err2.Err(func(err error) {
+		glog.Error("continue connection request: ", err)
+	}))
+
+	meDID := ca.WDID()
+	key := psm.StateKey{DID: meDID, Nonce: im.SubLevelID()}
+	m := try.To1(psm.GetPSM(key))
+	assert.That(m.PendingUserAction(), "connection request isn't waiting approval")
+
+	pwr := try.To1(getPairwiseRep(key))
+	connectionID := m.ConnID
+	task := m.PresentTask()
+
+	ipl := aries.PayloadCreator.NewFromData(pwr.Request)
+	reqMsg, ok := ipl.MsgHdr().(didexchange.PwMsg)
+	assert.That(ok, "connection request type mismatch")
+
+	wa := ca.WorkerEA()
+	calleePw := pendingPairwise(wa, connectionID, pwr, reqMsg)
+
+	if !im.Ready() {
+		glog.V(1).Infof("connection request (%s) from %s rejected",
+			connectionID, pwr.TheirLabel)
+		pipe := sec.Pipe{In: calleePw.Callee, Out: calleePw.Caller}
+		try.To(rejectConnectionRequest(meDID, connectionID, task, pipe))
+		wa.RmDIDCache(pwr.Caller.DID)
+		return
+	}
 
-	// build the response payload, update PSM, and send the PL with sec.Pipe
-	opl, state := try.To2(reqMsg.PayloadToSend("", calleePw.Callee))
+	pwr.Request = nil
+	try.To(psm.AddRep(pwr))
+	pipe := try.To1(acceptConnectionRequest(wa, calleePw, reqMsg))
+	try.To(sendConnectionResponse(meDID, connectionID, task, reqMsg, pipe))
+}
+
+// pendingPairwise loads the pairwise of the connection request waiting the
+// approval. Their DID is stored already, but the pairwise isn't.
+func pendingPairwise(
+	wa comm.Receiver,
+	connectionID string,
+	pwr *pairwiseRep,
+	reqMsg didexchange.PwMsg,
+) *pairwise.Callee {
+	caller := wa.LoadDID(pwr.Caller.DID)
+	caller.SetAEndp(reqMsg.Endpoint())
+	calleePw := pairwise.NewCalleePairwise(wa.(ssi.Agent), reqMsg.RoutingKeys(),
+		caller, connectionID, service.Addr{})
+	calleePw.Callee = wa.LoadDID(pwr.Callee.DID)
+	calleePw.Callee.SetAEndp(service.Addr{
+		Endp: pwr.Callee.Endp,
+		Key:  pwr.Callee.VerKey,
+	})
+	return calleePw
+}
+
+// sendConnectionResponse builds the response payload, updates PSM, and sends
+// the PL with the pipe of the connection.
+func sendConnectionResponse(
+	meDID, connectionID string,
+	task comm.Task,
+	reqMsg didexchange.PwMsg,
+	pipe sec.Pipe,
+) (
+	err error,
+) {
+	defer err2.Handle(&err, "send connection response")
+
+	opl, state := try.To2(reqMsg.PayloadToSend("", pipe.In))
 	try.To(prot.UpdatePSM(meDID, connectionID, task, opl, state))
 	try.To(comm.SendPL(pipe, task, opl))
 
@@ -303,6 +426,36 @@ func handleConnectionRequest(packet comm.Packet) (err error) {
 	return nil
 }
 
+// rejectConnectionRequest sends the problem report of the rejected request
+// and ends the PSM with NACK.
+func rejectConnectionRequest(meDID, connectionID string, task comm.Task, pipe sec.Pipe) (err error) {
+	defer err2.Handle(&err, "reject connection request")
+
+	om := aries.MsgCreator.Create(didcomm.MsgInit{
+		Type:   pltype.NotificationProblemReport,
+		Info:   common.CodeRequestNotAccepted,
+		Thread: decorator.NewThread(task.ID(), ""),
+	})
+	pr := om.FieldObj().(*common.ProblemReport)
+	pr.Description.En = "connection request was not approved"
+	pr.Impact = common.ImpactThread
+	pr.WhoRetries = common.WhoRetriesNone
+	pr.NoticedTime = time.Now().UTC().Format(time.RFC3339)
+
+	opl := aries.PayloadCreator.NewMsg(utils.UUID(), pltype.NotificationProblemReport, om)
+	try.To(prot.UpdatePSM(meDID, connectionID, task, opl, psm.Sending))
+	try.To(comm.SendPL(pipe, task, opl))
+	try.To(prot.UpdatePSM(meDID, connectionID, task, opl, psm.ReadyNACK))
+
+	return nil
+}
+
+// userActionType returns the user action type of the protocol family of the
+// request type.
+func userActionType(requestType string) string {
+	return strings.TrimSuffix(requestType, pltype.HandlerRequest) + pltype.UserAction
+}
+
 func handleConnectionResponse(packet comm.Packet) (err error) {
 	defer err2.Handle(&err, "connection response")
 
@@ -354,7 +507,8 @@ func handleConnectionResponse(packet comm.Packet) (err error) {
 
 	// SAVE ENDPOINT to wallet
 	calleeEndp := endp.NewAddrFromPublic(respEndp)
-	try.To(saveConnectionEndpoint(managedStorage(receiver), pwName, calleeEndp.Address()))
+	try.To(saveConnectionEndpoint(managedStorage(receiver), pwName,
+		calleeEndp.Address(), pwr.TheirLabel))
 
 	// Save Rep and PSM
 	newPwr := &pairwiseRep{
@@ -389,16 +543,18 @@ func handleConnectionResponse(packet comm.Packet) (err error) {
 	return nil
 }
 
-func saveConnectionEndpoint(mgdStorage managed.Wallet, connectionID, theirEndpoint string) error {
+func saveConnectionEndpoint(mgdStorage managed.Wallet, connectionID, theirEndpoint, theirLabel string) error {
 	store := mgdStorage.Storage().ConnectionStorage()
-	connection, _ := store.GetConnection(connectionID)
-	if connection == nil {
-		connection = &storage.Connection{
-			ID: connectionID,
-		}
-	}
-	connection.TheirEndpoint = theirEndpoint
-	return store.SaveConnection(*connection)
+	_, err := store.UpdateConnection(connectionID,
+		func(connection *storage.Connection, _ bool) error {
+			connection.TheirEndpoint = theirEndpoint
+			connection.TheirLabel = theirLabel
+			if connection.Created == 0 {
+				connection.Created = time.Now().UnixNano()
+			}
+			return nil
+		})
+	return err
 }
 
 func fillPairwiseStatus(workerDID string, taskID string, ps *pb.ProtocolStatus) *pb.ProtocolStatus {
@@ -11,6 +11,7 @@ import (
 
 	"github.com/findy-network/findy-agent/agent/aries"
 	"github.com/findy-network/findy-agent/agent/comm"
+	"github.com/findy-network/findy-agent/agent/didcomm"
 	"github.com/findy-network/findy-agent/agent/endp"
 	"github.com/findy-network/findy-agent/agent/pltype"
 	"github.com/findy-network/findy-agent/agent/psm"
@@ -19,6 +20,7 @@ import (
 	"github.com/findy-network/findy-agent/agent/ssi"
 	storage "github.com/findy-network/findy-agent/agent/storage/api"
 	"github.com/findy-network/findy-agent/agent/utils"
+	"github.com/findy-network/findy-agent/core"
 	"github.com/findy-network/findy-agent/method"
 	"github.com/findy-network/findy-agent/std/didexchange"
 	v1 "github.com/findy-network/findy-common-go/grpc/agency/v1"
@@ -319,6 +321,7 @@ func TestConnectionInvitor(t *testing.T) {
 			mockReceiver.EXPECT().AddDIDCache(outDID).Return()
 			mockReceiver.EXPECT().ManagedWallet().AnyTimes().Return(theirAgent.WalletH, theirAgent.StorageH)
 			mockReceiver.EXPECT().AddToPWMap(theirDID, outDID, endpointConnID).Return(sec.Pipe{In: outDID, Out: theirDID})
+			mockReceiver.EXPECT().ManualConnApproval().Return(false)
 
 			err := handleConnectionRequest(packet)
 			assert.NoError(err)
@@ -345,3 +348,123 @@ func TestConnectionInvitor(t *testing.T) {
 	}
 
 }
+
+// Simulates invitor role in the manual approval mode
+func TestConnectionInvitorManualApproval(t *testing.T) {
+	tests := []struct {
+		name                string
+		requestPayload      []byte
+		responsePayloadType string
+		approve             bool
+	}{
+		{
+			name:                "approve",
+			requestPayload:      readJSONFromFile("./test_data/v1/request-findy.json"),
+			responsePayloadType: pltype.DIDOrgAriesDIDExchangeResponse,
+			approve:             true,
+		},
+		{
+			name:                "reject",
+			requestPayload:      readJSONFromFile("./test_data/v0/request-findy.json"),
+			responsePayloadType: pltype.NotificationProblemReport,
+			approve:             false,
+		},
+	}
+	const (
+		ourSeed      = "000000000000000000000000Steward1"
+		theirSeed    = "000000000000000000000000Steward2"
+		invitationID = "d3dbb3af-63d4-4c88-85a4-36f0a0b889e0"
+	)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.PushTester(t)
+			defer assert.PopTester()
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			ourAgent := createAgent("our-manual" + tt.name)
+			theirAgent := createAgent("their-manual" + tt.name)
+
+			ourDID := try.To1(ourAgent.NewDID(method.TypeSov, ourSeed))
+			ourDID.SetAEndp(service.Addr{Endp: "http://example.com", Key: ourDID.VerKey()})
+			theirDID := try.To1(theirAgent.NewDID(method.TypeSov, theirSeed))
+			mockReceiver := NewMockReceiverMock(ctrl)
+
+			payload := aries.PayloadCreator.NewFromData(tt.requestPayload)
+			outDID := try.To1(theirAgent.NewOutDID(ourDID.String(), ourDID.VerKey()))
+			mockReceiver.EXPECT().MyDID().Return(theirDID)
+			mockReceiver.EXPECT().WDID().Return(theirDID.Did())
+			mockReceiver.EXPECT().WorkerEA().Return(mockReceiver)
+			mockReceiver.EXPECT().FindPWByID(endpointConnID).Return(&storage.Connection{
+				MyDID: theirDID.String(),
+			}, nil)
+			mockReceiver.EXPECT().LoadDID(gomock.Any()).AnyTimes().DoAndReturn(
+				func(did string) core.DID {
+					if did == outDID.Did() || did == outDID.String() {
+						return outDID
+					}
+					return theirDID
+				})
+			mockReceiver.EXPECT().NewOutDID(ourDID.String(), ourDID.VerKey()).Return(outDID, nil)
+			mockReceiver.EXPECT().AddDIDCache(outDID).Return()
+			mockReceiver.EXPECT().ManagedWallet().AnyTimes().Return(theirAgent.WalletH, theirAgent.StorageH)
+			mockReceiver.EXPECT().ManualConnApproval().Return(true)
+			mockReceiver.EXPECT().AutoPermission().Return(false)
+
+			// the request waits the approval: nothing is sent or saved,
+			// and the pipe isn't mapped (AddToPWMap isn't expected yet)
+			httpPayload = []byte{}
+			assert.NoError(handleConnectionRequest(comm.Packet{
+				Payload:  payload,
+				Receiver: mockReceiver,
+				Address:  endpoint,
+			}))
+			assert.Equal(len(httpPayload), 0)
+
+			key := psm.StateKey{DID: theirDID.Did(), Nonce: invitationID}
+			m := try.To1(psm.GetPSM(key))
+			assert.That(m.PendingUserAction())
+			connStore := theirAgent.StorageH.Storage().ConnectionStorage()
+			conn, _ := connStore.GetConnection(endpointConnID)
+			assert.That(conn == nil || conn.TheirDID == "", "saved before approval")
+
+			if tt.approve {
+				mockReceiver.EXPECT().AddToPWMap(theirDID, outDID, endpointConnID).
+					Return(sec.Pipe{In: theirDID, Out: outDID})
+			} else {
+				mockReceiver.EXPECT().RmDIDCache(outDID.Did()).Return()
+			}
+			continueConnectionRequest(mockReceiver, aries.MsgCreator.Create(didcomm.MsgInit{
+				Ready: tt.approve,
+				ID:    invitationID,
+				Nonce: invitationID,
+			}).(didcomm.Msg))
+
+			pipe := sec.Pipe{In: ourDID, Out: theirDID}
+			unpacked, _, _ := pipe.Unpack(httpPayload)
+			httpPayload = []byte{}
+			responsePl := aries.PayloadCreator.NewFromData(unpacked)
+			assert.Equal(responsePl.Type(), tt.responsePayloadType)
+
+			m = try.To1(psm.GetPSM(key))
+			assert.That(!m.PendingUserAction())
+			assert.That(m.IsReady() != tt.approve)
+			conn, _ = connStore.GetConnection(endpointConnID)
+			if tt.approve {
+				assert.That(conn != nil && conn.TheirDID != "", "not saved")
+			} else {
+				assert.That(conn == nil || conn.TheirDID == "", "saved after reject")
+			}
+		})
+	}
+}
+
+func TestUserActionType(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	assert.Equal(userActionType(pltype.AriesConnectionRequest),
+		pltype.AriesConnection+"/1.0/"+pltype.UserAction)
+	assert.Equal(userActionType(pltype.DIDOrgAriesDIDExchangeRequest),
+		pltype.DIDOrgAriesDIDExchange+"/1.0/"+pltype.UserAction)
+}
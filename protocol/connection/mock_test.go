@@ -65,6 +65,30 @@ func (mr *MockReceiverMockMockRecorder) AddPipeToPWMap(p, name interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddPipeToPWMap", reflect.TypeOf((*MockReceiverMock)(nil).AddPipeToPWMap), p, name)
 }
 
+// RmPWFromMap mocks base method.
+func (m *MockReceiverMock) RmPWFromMap(name string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RmPWFromMap", name)
+}
+
+// RmPWFromMap indicates an expected call of RmPWFromMap.
+func (mr *MockReceiverMockMockRecorder) RmPWFromMap(name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RmPWFromMap", reflect.TypeOf((*MockReceiverMock)(nil).RmPWFromMap), name)
+}
+
+// RmDIDCache mocks base method.
+func (m *MockReceiverMock) RmDIDCache(did string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RmDIDCache", did)
+}
+
+// RmDIDCache indicates an expected call of RmDIDCache.
+func (mr *MockReceiverMockMockRecorder) RmDIDCache(did interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RmDIDCache", reflect.TypeOf((*MockReceiverMock)(nil).RmDIDCache), did)
+}
+
 // AddToPWMap mocks base method.
 func (m *MockReceiverMock) AddToPWMap(me, you core.DID, name string) sec.Pipe {
 	m.ctrl.T.Helper()
@@ -105,6 +129,34 @@ func (mr *MockReceiverMockMockRecorder) AutoPermission() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AutoPermission", reflect.TypeOf((*MockReceiverMock)(nil).AutoPermission))
 }
 
+// ManualConnApproval mocks base method.
+func (m *MockReceiverMock) ManualConnApproval() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ManualConnApproval")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// ManualConnApproval indicates an expected call of ManualConnApproval.
+func (mr *MockReceiverMockMockRecorder) ManualConnApproval() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ManualConnApproval", reflect.TypeOf((*MockReceiverMock)(nil).ManualConnApproval))
+}
+
+// SetManualConnApproval mocks base method.
+func (m *MockReceiverMock) SetManualConnApproval(on bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetManualConnApproval", on)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetManualConnApproval indicates an expected call of SetManualConnApproval.
+func (mr *MockReceiverMockMockRecorder) SetManualConnApproval(on interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetManualConnApproval", reflect.TypeOf((*MockReceiverMock)(nil).SetManualConnApproval), on)
+}
+
 // CAEndp mocks base method.
 func (m *MockReceiverMock) CAEndp(connID string) *endp.Addr {
 	m.ctrl.T.Helper()
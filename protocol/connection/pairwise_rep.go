@@ -16,6 +16,7 @@ type pairwiseRep struct {
 	TheirLabel string
 	Caller     didRep
 	Callee     didRep
+	Request    []byte // the request payload while it waits the approval
 }
 
 func init() {
@@ -0,0 +1,282 @@
+/*
+Package discoverfeatures implements Aries RFC 0031 discover features v1 and
+RFC 0557 v2 protocols. We answer to the queries with the protocol families of
+the protocol processor, and the disclosures we get are cached per connection.
+*/
+package discoverfeatures
+
+import (
+	"encoding/gob"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/findy-network/findy-agent/agent/aries"
+	"github.com/findy-network/findy-agent/agent/comm"
+	"github.com/findy-network/findy-agent/agent/didcomm"
+	"github.com/findy-network/findy-agent/agent/pltype"
+	"github.com/findy-network/findy-agent/agent/prot"
+	"github.com/findy-network/findy-agent/agent/psm"
+	"github.com/findy-network/findy-agent/agent/utils"
+	"github.com/findy-network/findy-agent/std/discoverfeatures"
+	pb "github.com/findy-network/findy-common-go/grpc/agency/v1"
+	"github.com/golang/glog"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/assert"
+	"github.com/lainio/err2/try"
+)
+
+// Versions of the protocol.
+const (
+	VersionV1 = "1.0"
+	VersionV2 = "2.0"
+)
+
+const defaultProtocolVersion = "1.0"
+
+type taskDiscoverFeatures struct {
+	comm.TaskBase
+	Version string
+	Query   string
+}
+
+// Disclosed is the result of the query.
+type Disclosed struct {
+	Protocols []string
+	Timestamp time.Time
+}
+
+type cacheKey struct {
+	DID    string // worker agent DID
+	ConnID string
+	Query  string
+}
+
+var cache = struct {
+	sync.RWMutex
+	disclosed map[cacheKey]Disclosed
+}{
+	disclosed: make(map[cacheKey]Disclosed),
+}
+
+var discoverFeaturesProcessor = comm.ProtProc{
+	Creator: createDiscoverFeaturesTask,
+	Starter: startDiscoverFeatures,
+	Handlers: map[string]comm.HandlerFunc{
+		pltype.HandlerQuery:       handleQuery,
+		pltype.HandlerQueries:     handleQuery,
+		pltype.HandlerDisclose:    handleDisclose,
+		pltype.HandlerDisclosures: handleDisclose,
+	},
+	FillStatus: fillDiscoverFeaturesStatus,
+}
+
+func init() {
+	gob.Register(&taskDiscoverFeatures{})
+	prot.AddCreator(pltype.ProtocolDiscoverFeatures, discoverFeaturesProcessor)
+	prot.AddStarter(pltype.CADiscoverFeatures, discoverFeaturesProcessor)
+	prot.AddStatusProvider(pltype.ProtocolDiscoverFeatures, discoverFeaturesProcessor)
+	comm.Proc.Add(pltype.ProtocolDiscoverFeatures, discoverFeaturesProcessor)
+}
+
+// NewTask returns the task which queries the protocols of the connection.
+// The query is the protocol ID pattern, e.g. https://didcomm.org/*, and the
+// version is VersionV1 or VersionV2.
+func NewTask(connID, version, query string) (t comm.Task, err error) {
+	if version != VersionV1 && version != VersionV2 {
+		return nil, fmt.Errorf("unsupported discover features version: %s", version)
+	}
+	if connID == "" || query == "" {
+		return nil, fmt.Errorf("connection and query are needed for discover features")
+	}
+	return &taskDiscoverFeatures{
+		TaskBase: comm.TaskBase{TaskHeader: comm.TaskHeader{
+			TaskID:       utils.UUID(),
+			TypeID:       pltype.CADiscoverFeatures,
+			ProtocolRole: pb.Protocol_INITIATOR,
+			ConnID:       connID,
+			Method:       utils.Settings.DIDMethod(),
+		}},
+		Version: version,
+		Query:   query,
+	}, nil
+}
+
+// Cached returns the cached result of the query to the connection of the
+// worker agent.
+func Cached(workerDID, connID, query string) (d Disclosed, ok bool) {
+	cache.RLock()
+	defer cache.RUnlock()
+
+	d, ok = cache.disclosed[cacheKey{DID: workerDID, ConnID: connID, Query: query}]
+	return d, ok
+}
+
+func createDiscoverFeaturesTask(header *comm.TaskHeader, _ *pb.Protocol) (t comm.Task, err error) {
+	glog.V(1).Infof("Create task for DiscoverFeatures with connection id %s", header.ConnID)
+
+	return &taskDiscoverFeatures{
+		TaskBase: comm.TaskBase{TaskHeader: *header},
+	}, nil
+}
+
+func startDiscoverFeatures(ca comm.Receiver, t comm.Task) {
+	defer err2.Catch()
+
+	dfTask, ok := t.(*taskDiscoverFeatures)
+	assert.That(ok)
+
+	sendNext := pltype.DiscoverFeaturesV2Queries
+	waitingNext := pltype.DiscoverFeaturesV2Disclosures
+	if dfTask.Version == VersionV1 {
+		sendNext = pltype.DiscoverFeaturesQuery
+		waitingNext = pltype.DiscoverFeaturesDisclose
+	}
+	try.To(prot.StartPSM(prot.Initial{
+		SendNext:    sendNext,
+		WaitingNext: waitingNext,
+		Ca:          ca,
+		T:           t,
+		Setup: func(_ psm.StateKey, om didcomm.MessageHdr) error {
+			msg := om.FieldObj().(*discoverfeatures.Features)
+			if dfTask.Version == VersionV1 {
+				msg.Query = dfTask.Query
+			} else {
+				msg.Queries = []discoverfeatures.Query{{
+					FeatureType: discoverfeatures.FeatureTypeProtocol,
+					Match:       dfTask.Query,
+				}}
+			}
+			return nil
+		},
+	}))
+}
+
+// handleQuery answers to the both versions of the query.
+func handleQuery(packet comm.Packet) (err error) {
+	sendNext := pltype.DiscoverFeaturesV2Disclosures
+	if aries.ProtocolVersionForType(packet.Payload.Type()) == VersionV1 {
+		sendNext = pltype.DiscoverFeaturesDisclose
+	}
+	return prot.ExecPSM(prot.Transition{
+		Packet:      packet,
+		SendNext:    sendNext,
+		WaitingNext: pltype.Terminate,
+		InOut: func(_ string, im, om didcomm.MessageHdr) (ack bool, err error) {
+			query := im.FieldObj().(*discoverfeatures.Features)
+			disclose := om.FieldObj().(*discoverfeatures.Features)
+
+			protocols := supportedProtocols()
+			if query.Query != "" {
+				for _, pid := range protocols {
+					if discoverfeatures.Match(query.Query, pid) {
+						disclose.Protocols = append(disclose.Protocols,
+							discoverfeatures.Protocol{PID: pid})
+					}
+				}
+			}
+			for _, q := range query.Queries {
+				if q.FeatureType != discoverfeatures.FeatureTypeProtocol {
+					continue
+				}
+				for _, pid := range protocols {
+					if discoverfeatures.Match(q.Match, pid) {
+						disclose.Disclosures = append(disclose.Disclosures,
+							discoverfeatures.Disclosure{
+								FeatureType: discoverfeatures.FeatureTypeProtocol,
+								ID:          pid,
+							})
+					}
+				}
+			}
+			glog.V(3).Infof("disclosing %d+%d protocols",
+				len(disclose.Protocols), len(disclose.Disclosures))
+			return true, nil
+		},
+	})
+}
+
+// handleDisclose caches the disclosed protocols of the both versions.
+func handleDisclose(packet comm.Packet) (err error) {
+	return prot.ExecPSM(prot.Transition{
+		Packet:      packet,
+		SendNext:    pltype.Terminate,
+		WaitingNext: pltype.Terminate,
+		InOut: func(connID string, im, _ didcomm.MessageHdr) (ack bool, err error) {
+			defer err2.Handle(&err, "discover features disclose")
+
+			key := psm.NewStateKey(packet.Receiver, im.Thread().ID)
+			machine := try.To1(psm.GetPSM(key))
+			dfTask, ok := machine.FirstState().T.(*taskDiscoverFeatures)
+			assert.That(ok, "discover features task missing")
+
+			msg := im.FieldObj().(*discoverfeatures.Features)
+			d := Disclosed{
+				Protocols: make([]string, 0, len(msg.Protocols)+len(msg.Disclosures)),
+				Timestamp: time.Now(),
+			}
+			for _, p := range msg.Protocols {
+				d.Protocols = append(d.Protocols, p.PID)
+			}
+			for _, disclosure := range msg.Disclosures {
+				if disclosure.FeatureType == discoverfeatures.FeatureTypeProtocol {
+					d.Protocols = append(d.Protocols, disclosure.ID)
+				}
+			}
+
+			cache.Lock()
+			cache.disclosed[cacheKey{
+				DID:    key.DID,
+				ConnID: connID,
+				Query:  dfTask.Query,
+			}] = d
+			cache.Unlock()
+			return true, nil
+		},
+	})
+}
+
+// ariesFamilies are the protocol families of the processor which are Aries
+// RFC protocols. The others, like the notification family, are our own
+// internal message types, and we don't advertise them as didcomm.org
+// protocols.
+var ariesFamilies = map[string]bool{
+	pltype.AriesProtocolConnection:  true, // RFC 0160
+	pltype.AriesProtocolDIDExchange: true, // RFC 0023
+	pltype.ProtocolIssueCredential:  true, // RFC 0036
+	pltype.ProtocolPresentProof:     true, // RFC 0037, RFC 0454
+	pltype.ProtocolBasicMessage:     true, // RFC 0095
+	pltype.ProtocolTrustPing:        true, // RFC 0048
+	pltype.ProtocolReportProblem:    true, // RFC 0035
+	pltype.ProtocolDiscoverFeatures: true, // RFC 0031, RFC 0557
+	pltype.ProtocolActionMenu:       true, // RFC 0509
+	pltype.ProtocolQuestionAnswer:   true, // RFC 0113
+}
+
+// supportedProtocols returns the IDs of the Aries protocols of the
+// processor. The versions come from the registered message types, and the
+// protocols without them are version 1.0.
+func supportedProtocols() []string {
+	protocols := make([]string, 0)
+	for _, family := range comm.Proc.Families() {
+		if !ariesFamilies[family] {
+			continue
+		}
+		versions := aries.Creator.Versions(family)
+		if len(versions) == 0 {
+			versions = []string{defaultProtocolVersion}
+		}
+		for _, v := range versions {
+			protocols = append(protocols, pltype.DIDOrgAries+"/"+family+"/"+v)
+		}
+	}
+	return protocols
+}
+
+// fillDiscoverFeaturesStatus returns the common status only. The disclosed
+// protocols are cached per connection, and the controller reads them with the
+// DiscoverFeatures ext call instead of the protocol status.
+func fillDiscoverFeaturesStatus(_ string, _ string, ps *pb.ProtocolStatus) *pb.ProtocolStatus {
+	assert.That(ps != nil)
+	return ps
+}
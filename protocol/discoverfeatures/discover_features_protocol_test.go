@@ -0,0 +1,45 @@
+package discoverfeatures
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/findy-network/findy-agent/agent/comm"
+	"github.com/findy-network/findy-agent/agent/pltype"
+	"github.com/lainio/err2/assert"
+)
+
+func TestSupportedProtocols(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	// the notification family is our internal one
+	comm.Proc.Add(pltype.ProtocolNotification, comm.ProtProc{})
+
+	found := make(map[string]bool)
+	for _, pid := range supportedProtocols() {
+		found[pid] = true
+	}
+	assert.That(found[pltype.DIDOrgDiscoverFeatures+"/1.0"])
+	assert.That(found[pltype.DIDOrgDiscoverFeatures+"/2.0"])
+	for pid := range found {
+		assert.That(!strings.HasPrefix(pid, pltype.DIDOrgProblemReport+"/"))
+	}
+}
+
+func TestNewTask(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	task, err := NewTask("conn-id", VersionV2, "https://didcomm.org/*")
+	assert.NoError(err)
+	assert.Equal(task.ConnectionID(), "conn-id")
+
+	_, err = NewTask("conn-id", "3.0", "*")
+	assert.Error(err)
+	_, err = NewTask("conn-id", VersionV1, "")
+	assert.Error(err)
+
+	_, ok := Cached("did", "conn-id", "*")
+	assert.That(!ok)
+}
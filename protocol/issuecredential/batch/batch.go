@@ -0,0 +1,498 @@
+// Package batch issues the same credential to many connections, e.g. the
+// annual membership cards. The issuing protocols are started with bounded
+// concurrency and rate, and the batch and its items are stored to the PSM
+// database that the unfinished batch is resumed after the agency restart.
+package batch
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/findy-network/findy-agent/agent/bus"
+	"github.com/findy-network/findy-agent/agent/comm"
+	"github.com/findy-network/findy-agent/agent/didcomm"
+	"github.com/findy-network/findy-agent/agent/pltype"
+	"github.com/findy-network/findy-agent/agent/prot"
+	"github.com/findy-network/findy-agent/agent/psm"
+	"github.com/findy-network/findy-agent/agent/utils"
+	pb "github.com/findy-network/findy-common-go/grpc/agency/v1"
+	"github.com/golang/glog"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/assert"
+	"github.com/lainio/err2/try"
+)
+
+// States of the batch items. Running items have the protocol ID, and after
+// the timeout the protocol still continues without the batch. The released
+// protocol was finished and released by the controller before the batch got
+// its result, e.g. during the agency restart.
+const (
+	ItemPending  = "pending"
+	ItemRunning  = "running"
+	ItemOK       = "ok"
+	ItemNACK     = "nack"
+	ItemError    = "error"
+	ItemTimeout  = "timeout"
+	ItemReleased = "released"
+)
+
+// Defaults of the batch configuration.
+const (
+	DefaultConcurrency = 10
+	DefaultItemTimeout = 5 * time.Minute
+)
+
+// Config of the batch. Rate is the maximum count of the protocols started per
+// second, and zero is unlimited. The item timeout is the time to wait the
+// holder to accept the credential.
+type Config struct {
+	Concurrency int
+	Rate        int
+	ItemTimeout time.Duration
+}
+
+// Item is the credential to issue to the connection. Started tells that the
+// PSM of the running protocol was created. Without it the protocol didn't
+// start, and it's started again when the batch is resumed.
+type Item struct {
+	ConnectionID string
+	Attributes   []didcomm.CredentialAttribute
+	ProtocolID   string
+	State        string
+	Info         string
+	Started      bool
+}
+
+// Batch is the status of the batch.
+type Batch struct {
+	ID        string
+	CredDefID string
+	Config    Config
+	Created   int64
+	Running   bool
+	Counts    map[string]int // item counts by state
+	Items     []Item
+}
+
+// Progress tells that the state of the item has changed. The counts are the
+// states of all the items at the moment. The batch is finished when all of the
+// items are done, i.e. they aren't pending or running anymore.
+type Progress struct {
+	BatchID  string
+	Index    int
+	Item     Item
+	Counts   map[string]int
+	Total    int
+	Finished bool
+}
+
+// Done tells if the item is processed. The timeout and the released items
+// aren't retried, because their protocols may have issued the credential, but
+// the errors are.
+func (i Item) Done() bool {
+	return i.State == ItemOK || i.State == ItemNACK ||
+		i.State == ItemTimeout || i.State == ItemReleased
+}
+
+// Start stores and starts the new batch. The progress is reported thru the
+// subscription, see Subscribe.
+func Start(ca comm.Receiver, credDefID string, items []Item, cfg Config) (id string, err error) {
+	defer err2.Handle(&err, "start batch")
+
+	assert.NotEmpty(credDefID, "cred def ID is needed")
+	assert.That(len(items) > 0, "batch doesn't have items")
+	for i, item := range items {
+		assert.NotEmpty(item.ConnectionID, "item %d: connection ID is needed", i)
+		assert.That(len(item.Attributes) > 0, "item %d: attributes are needed", i)
+	}
+	cfg = cfg.withDefaults()
+
+	rep := &batchRep{
+		StateKey:  psm.StateKey{DID: ca.WorkerEA().MyDID().Did(), Nonce: utils.UUID()},
+		CredDefID: credDefID,
+		Config:    cfg,
+		Total:     len(items),
+		Created:   time.Now().UnixNano(),
+	}
+	reps := make([]*itemRep, 0, len(items))
+	for i, item := range items {
+		item.ProtocolID, item.State, item.Info, item.Started = "", ItemPending, "", false
+		itemRep := &itemRep{StateKey: itemKey(rep.StateKey, i), Index: i, Item: item}
+		try.To(psm.AddRep(itemRep))
+		reps = append(reps, itemRep)
+	}
+	try.To(psm.AddRep(rep)) // the last, the batch is complete
+
+	glog.V(1).Infof("batch %s started with %d items", rep.Nonce, rep.Total)
+	run(ca, rep, reps)
+	return rep.Nonce, nil
+}
+
+// Resume starts the unfinished items of the batch again, e.g. after the
+// agency restart. The items which failed are retried. It's no-op if the batch
+// is already running.
+func Resume(ca comm.Receiver, id string) (err error) {
+	defer err2.Handle(&err, "resume batch")
+
+	if isRunning(id) {
+		return nil
+	}
+	rep := try.To1(getBatchRep(psm.StateKey{DID: ca.WorkerEA().MyDID().Did(), Nonce: id}))
+	reps := try.To1(getItemReps(rep))
+
+	if run(ca, rep, reps) {
+		glog.V(1).Infof("batch %s resumed", id)
+	}
+	return nil
+}
+
+func init() {
+	comm.AddOpener(resumeBatches)
+}
+
+// resumeBatches resumes the batches of the CA which were running when the
+// agency stopped, i.e. they have pending or running items. The batches with
+// the failed items only are left for the controller to resume.
+func resumeBatches(ca comm.Receiver) {
+	defer err2.Catch(err2.Err(func(err error) {
+		glog.Error("resume batches: ", err)
+	}))
+
+	for _, b := range try.To1(List(ca)) {
+		if b.Counts[ItemPending] > 0 || b.Counts[ItemRunning] > 0 {
+			try.To(Resume(ca, b.ID))
+		}
+	}
+}
+
+// Get returns the status of the batch with its items.
+func Get(ca comm.Receiver, id string) (b *Batch, err error) {
+	defer err2.Handle(&err, "get batch")
+
+	rep := try.To1(getBatchRep(psm.StateKey{DID: ca.WorkerEA().MyDID().Did(), Nonce: id}))
+	reps := try.To1(getItemReps(rep))
+	b = newBatch(rep, reps)
+	for _, item := range reps {
+		b.Items = append(b.Items, item.Item)
+	}
+	return b, nil
+}
+
+// List returns the batches of the agent without their items, the newest
+// first.
+func List(ca comm.Receiver) (batches []Batch, err error) {
+	defer err2.Handle(&err, "list batches")
+
+	agentDID := ca.WorkerEA().MyDID().Did()
+	batches = make([]Batch, 0)
+	for _, r := range try.To1(psm.GetAllReps(psm.BucketBatch)) {
+		rep, ok := r.(*batchRep)
+		if !ok || rep.DID != agentDID {
+			continue
+		}
+		batches = append(batches, *newBatch(rep, try.To1(getItemReps(rep))))
+	}
+	sort.Slice(batches, func(i, j int) bool {
+		return batches[i].Created > batches[j].Created
+	})
+	return batches, nil
+}
+
+func newBatch(rep *batchRep, items []*itemRep) *Batch {
+	return &Batch{
+		ID:        rep.Nonce,
+		CredDefID: rep.CredDefID,
+		Config:    rep.Config,
+		Created:   rep.Created,
+		Running:   isRunning(rep.Nonce),
+		Counts:    counts(items),
+		Items:     make([]Item, 0),
+	}
+}
+
+func counts(items []*itemRep) map[string]int {
+	c := make(map[string]int)
+	for _, item := range items {
+		c[item.State]++
+	}
+	return c
+}
+
+func (c Config) withDefaults() Config {
+	if c.Concurrency <= 0 {
+		c.Concurrency = DefaultConcurrency
+	}
+	if c.Rate < 0 {
+		c.Rate = 0
+	}
+	if c.ItemTimeout <= 0 {
+		c.ItemTimeout = DefaultItemTimeout
+	}
+	return c
+}
+
+// runners are the running batches by their IDs.
+var runners = struct {
+	sync.Mutex
+	m map[string]*runner
+}{m: make(map[string]*runner)}
+
+type runner struct {
+	ca    comm.Receiver
+	rep   *batchRep
+	items []*itemRep
+
+	// start starts the issuing protocol of the item with the protocol ID.
+	// It returns when the PSM of the protocol is created.
+	start func(item Item, protocolID string) error
+
+	sync.Mutex // for the items' states and the listeners
+	listeners  map[chan Progress]struct{}
+}
+
+func isRunning(id string) bool {
+	runners.Lock()
+	defer runners.Unlock()
+	_, ok := runners.m[id]
+	return ok
+}
+
+// Subscribe returns the progress channel of the running batch. The channel is
+// closed when the batch is finished, and the caller must call the returned
+// function when it stops reading. The ok is false if the batch isn't running.
+func Subscribe(id string) (ch <-chan Progress, unsubscribe func(), ok bool) {
+	runners.Lock()
+	r, ok := runners.m[id]
+	runners.Unlock()
+	if !ok {
+		return nil, nil, false
+	}
+
+	c := make(chan Progress, 1000)
+	r.Lock()
+	r.listeners[c] = struct{}{}
+	r.Unlock()
+	return c, func() {
+		r.Lock()
+		defer r.Unlock()
+		if _, ok := r.listeners[c]; ok {
+			delete(r.listeners, c)
+			close(c)
+		}
+	}, true
+}
+
+// run starts the runner of the batch if it isn't already running.
+func run(ca comm.Receiver, rep *batchRep, items []*itemRep) (started bool) {
+	runners.Lock()
+	defer runners.Unlock()
+
+	if _, ok := runners.m[rep.Nonce]; ok {
+		return false
+	}
+	r := newRunner(ca, rep, items)
+	runners.m[rep.Nonce] = r
+	go r.run()
+	return true
+}
+
+func newRunner(ca comm.Receiver, rep *batchRep, items []*itemRep) *runner {
+	r := &runner{
+		ca:        ca,
+		rep:       rep,
+		items:     items,
+		listeners: make(map[chan Progress]struct{}),
+	}
+	r.start = r.startTask
+	return r
+}
+
+func (r *runner) run() {
+	defer func() {
+		runners.Lock()
+		delete(runners.m, r.rep.Nonce)
+		runners.Unlock()
+
+		r.Lock()
+		for c := range r.listeners {
+			c <- Progress{BatchID: r.rep.Nonce, Index: -1, Counts: counts(r.items),
+				Total: r.rep.Total, Finished: true}
+			close(c)
+			delete(r.listeners, c)
+		}
+		r.Unlock()
+		glog.V(1).Infof("batch %s finished", r.rep.Nonce)
+	}()
+
+	cfg := r.rep.Config
+	var limiter <-chan time.Time
+	if cfg.Rate > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(cfg.Rate))
+		defer ticker.Stop()
+		limiter = ticker.C
+	}
+
+	sem := make(chan struct{}, cfg.Concurrency)
+	var wg sync.WaitGroup
+	for _, item := range r.items {
+		if item.Done() {
+			continue
+		}
+		sem <- struct{}{}
+		if limiter != nil && !item.Started {
+			<-limiter
+		}
+		wg.Add(1)
+		go func(item *itemRep) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			r.issue(item)
+		}(item)
+	}
+	wg.Wait()
+}
+
+// issue issues the credential of the item and waits until the protocol is
+// ready. The protocol which was started before the restart is waited only,
+// and the one which wasn't is started again.
+func (r *runner) issue(item *itemRep) {
+	defer err2.Catch(err2.Err(func(err error) {
+		glog.Errorf("batch %s item %d: %v", r.rep.Nonce, item.Index, err)
+		r.update(item, ItemError, err.Error())
+	}))
+
+	key := psm.StateKey{DID: r.rep.DID, Nonce: item.ProtocolID}
+	resume := false
+	if item.State == ItemRunning && item.ProtocolID != "" {
+		m, _ := psm.FindPSM(key)
+		switch {
+		case m == nil && item.Started:
+			r.update(item, ItemReleased, "protocol was released before its result")
+			return
+		case m != nil && m.IsReady():
+			state, info := readyState(m)
+			r.update(item, state, info)
+			return
+		}
+		resume = m != nil
+	}
+	if !resume {
+		key.Nonce = utils.UUID()
+	}
+	statusChan := bus.WantAll.AddListener(key)
+	defer bus.WantAll.RmListener(key)
+
+	if !resume {
+		item.ProtocolID, item.Started = key.Nonce, false
+		r.update(item, ItemRunning, "")
+		try.To(r.start(item.Item, key.Nonce))
+		item.Started = true
+		r.update(item, ItemRunning, "")
+	}
+
+	timeout := time.After(r.rep.Config.ItemTimeout)
+	for {
+		select {
+		case status := <-statusChan:
+			switch status {
+			case psm.SystemReboot:
+				return // the item stays running, resume waits it
+			case psm.ReadyACK, psm.ACK:
+				r.update(item, ItemOK, "")
+				return
+			case psm.ReadyNACK, psm.NACK:
+				r.update(item, ItemNACK, "")
+				return
+			case psm.Failure:
+				info := ""
+				if m, _ := psm.FindPSM(key); m != nil {
+					info = m.Problem()
+				}
+				r.update(item, ItemError, info)
+				return
+			}
+		case <-timeout:
+			r.update(item, ItemTimeout, "holder didn't answer, protocol continues")
+			return
+		}
+	}
+}
+
+// startTask starts the issuing protocol of the item, and checks that its PSM
+// was created.
+func (r *runner) startTask(item Item, protocolID string) (err error) {
+	defer err2.Handle(&err, "start protocol")
+
+	task := try.To1(r.newTask(item, protocolID))
+	prot.FindAndStartTask(r.ca, task)
+	m := try.To1(psm.FindPSM(psm.StateKey{DID: r.rep.DID, Nonce: protocolID}))
+	assert.That(m != nil, "protocol didn't start")
+	return nil
+}
+
+func (r *runner) newTask(item Item, protocolID string) (t comm.Task, err error) {
+	defer err2.Handle(&err, "new task")
+
+	attrs := try.To1(json.Marshal(item.Attributes))
+	header := &comm.TaskHeader{
+		TaskID:       protocolID,
+		TypeID:       pltype.CACredOffer,
+		ProtocolRole: pb.Protocol_INITIATOR,
+		ConnID:       item.ConnectionID,
+		Method:       utils.Settings.DIDMethod(),
+	}
+	return prot.CreateTask(header, &pb.Protocol{
+		ConnectionID: item.ConnectionID,
+		TypeID:       pb.Protocol_ISSUE_CREDENTIAL,
+		Role:         pb.Protocol_INITIATOR,
+		StartMsg: &pb.Protocol_IssueCredential{
+			IssueCredential: &pb.Protocol_IssueCredentialMsg{
+				CredDefID: r.rep.CredDefID,
+				AttrFmt: &pb.Protocol_IssueCredentialMsg_AttributesJSON{
+					AttributesJSON: string(attrs),
+				},
+			},
+		},
+	})
+}
+
+// update saves the new state of the item and notifies the listeners.
+func (r *runner) update(item *itemRep, state, info string) {
+	r.Lock()
+	defer r.Unlock()
+
+	item.State, item.Info = state, info
+	if err := psm.AddRep(item); err != nil {
+		glog.Errorf("batch %s item %d save: %v", r.rep.Nonce, item.Index, err)
+	}
+
+	p := Progress{
+		BatchID: r.rep.Nonce,
+		Index:   item.Index,
+		Item:    item.Item,
+		Counts:  counts(r.items),
+		Total:   r.rep.Total,
+	}
+	for c := range r.listeners {
+		select {
+		case c <- p:
+		default:
+			glog.Warningf("batch %s listener is too slow, progress dropped", r.rep.Nonce)
+		}
+	}
+}
+
+func readyState(m *psm.PSM) (state, info string) {
+	sub := m.LastState().Sub
+	switch {
+	case sub.Pure() == psm.Failure:
+		return ItemError, m.Problem()
+	case sub&psm.NACK != 0:
+		return ItemNACK, ""
+	}
+	return ItemOK, ""
+}
@@ -0,0 +1,102 @@
+package batch
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/findy-network/findy-agent/agent/psm"
+	"github.com/findy-network/findy-common-go/dto"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/assert"
+	"github.com/lainio/err2/try"
+)
+
+// batchRep is the batch without its items. The key is the worker agent's DID
+// and the batch ID.
+type batchRep struct {
+	psm.StateKey
+	CredDefID string
+	Config    Config
+	Total     int
+	Created   int64
+}
+
+// itemRep is the item of the batch. The nonce of the key is the batch ID and
+// the index of the item.
+type itemRep struct {
+	psm.StateKey
+	Index int
+	Item
+}
+
+func init() {
+	psm.Creator.Add(psm.BucketBatch, newBatchRep)
+	psm.Creator.Add(psm.BucketBatchItem, newItemRep)
+}
+
+func newBatchRep(d []byte) psm.Rep {
+	p := &batchRep{}
+	dto.FromGOB(d, p)
+	return p
+}
+
+func (p *batchRep) Key() psm.StateKey {
+	return p.StateKey
+}
+
+func (p *batchRep) Data() []byte {
+	return dto.ToGOB(p)
+}
+
+func (p *batchRep) Type() byte {
+	return psm.BucketBatch
+}
+
+func newItemRep(d []byte) psm.Rep {
+	p := &itemRep{}
+	dto.FromGOB(d, p)
+	return p
+}
+
+func (p *itemRep) Key() psm.StateKey {
+	return p.StateKey
+}
+
+func (p *itemRep) Data() []byte {
+	return dto.ToGOB(p)
+}
+
+func (p *itemRep) Type() byte {
+	return psm.BucketBatchItem
+}
+
+func itemKey(key psm.StateKey, index int) psm.StateKey {
+	return psm.StateKey{DID: key.DID, Nonce: key.Nonce + "#" + strconv.Itoa(index)}
+}
+
+func getBatchRep(key psm.StateKey) (rep *batchRep, err error) {
+	defer err2.Handle(&err, "batch %s", key.Nonce)
+
+	res := try.To1(psm.GetRep(psm.BucketBatch, key))
+	if res == nil {
+		return nil, fmt.Errorf("not found")
+	}
+	var ok bool
+	rep, ok = res.(*batchRep)
+	assert.That(ok, "batch type mismatch")
+	return rep, nil
+}
+
+func getItemReps(rep *batchRep) (items []*itemRep, err error) {
+	defer err2.Handle(&err, "batch %s items", rep.Nonce)
+
+	items = make([]*itemRep, 0, rep.Total)
+	for i := 0; i < rep.Total; i++ {
+		res := try.To1(psm.GetRep(psm.BucketBatchItem, itemKey(rep.StateKey, i)))
+		assert.That(res != nil, "item %d not found", i)
+		item, ok := res.(*itemRep)
+		assert.That(ok, "batch item type mismatch")
+		items = append(items, item)
+	}
+	return items, nil
+}
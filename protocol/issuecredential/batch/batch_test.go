@@ -0,0 +1,227 @@
+package batch
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/findy-network/findy-agent/agent/bus"
+	"github.com/findy-network/findy-agent/agent/didcomm"
+	"github.com/findy-network/findy-agent/agent/psm"
+	"github.com/lainio/err2/assert"
+	"github.com/lainio/err2/try"
+)
+
+const dbPath = "batch_test.bolt"
+
+func TestMain(m *testing.M) {
+	try.To(psm.Open(dbPath))
+	code := m.Run()
+	psm.Close()
+	os.Remove(dbPath)
+	os.Exit(code)
+}
+
+func TestBatchReps(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	rep := &batchRep{
+		StateKey:  psm.StateKey{DID: "agentDID", Nonce: "batch-1"},
+		CredDefID: "credDefID",
+		Config:    Config{}.withDefaults(),
+		Total:     3,
+	}
+	states := []string{ItemOK, ItemRunning, ItemPending}
+	for i, state := range states {
+		assert.NoError(psm.AddRep(&itemRep{
+			StateKey: itemKey(rep.StateKey, i),
+			Index:    i,
+			Item: Item{
+				ConnectionID: "conn",
+				Attributes:   []didcomm.CredentialAttribute{{Name: "name", Value: "Alice"}},
+				State:        state,
+			},
+		}))
+	}
+	assert.NoError(psm.AddRep(rep))
+
+	got, err := getBatchRep(rep.StateKey)
+	assert.NoError(err)
+	assert.DeepEqual(rep, got)
+	assert.Equal(got.Config.Concurrency, DefaultConcurrency)
+
+	items, err := getItemReps(got)
+	assert.NoError(err)
+	assert.SLen(items, 3)
+	assert.Equal(items[1].State, ItemRunning)
+	assert.Equal(items[2].Attributes[0].Value, "Alice")
+	assert.That(items[0].Done() && !items[1].Done() && !items[2].Done())
+
+	c := counts(items)
+	assert.Equal(c[ItemOK], 1)
+	assert.Equal(c[ItemRunning], 1)
+	assert.Equal(c[ItemPending], 1)
+
+	_, err = getBatchRep(psm.StateKey{DID: "agentDID", Nonce: "not-found"})
+	assert.Error(err)
+}
+
+// newTestRunner stores the batch of the items in the states and returns its
+// runner, which has the start function of the test.
+func newTestRunner(nonce string, cfg Config, states []string,
+	start func(item Item, protocolID string) error) *runner {
+	rep := &batchRep{
+		StateKey:  psm.StateKey{DID: "agentDID", Nonce: nonce},
+		CredDefID: "credDefID",
+		Config:    cfg.withDefaults(),
+		Total:     len(states),
+	}
+	items := make([]*itemRep, 0, len(states))
+	for i, state := range states {
+		item := &itemRep{
+			StateKey: itemKey(rep.StateKey, i),
+			Index:    i,
+			Item: Item{
+				ConnectionID: "conn",
+				Attributes:   []didcomm.CredentialAttribute{{Name: "name", Value: "Alice"}},
+				State:        state,
+			},
+		}
+		try.To(psm.AddRep(item))
+		items = append(items, item)
+	}
+	try.To(psm.AddRep(rep))
+
+	r := newRunner(nil, rep, items)
+	r.start = start
+	return r
+}
+
+// ack answers the protocol after the delay like the holder would.
+func ack(protocolID string, delay time.Duration) {
+	go func() {
+		time.Sleep(delay)
+		bus.WantAll.Broadcast(psm.StateKey{DID: "agentDID", Nonce: protocolID},
+			psm.ReadyACK)
+	}()
+}
+
+func TestRunner_concurrency(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	var (
+		lock            sync.Mutex
+		active, maxSeen int
+	)
+	states := []string{ItemPending, ItemPending, ItemPending, ItemPending,
+		ItemPending, ItemPending, ItemOK}
+	r := newTestRunner("concurrency", Config{Concurrency: 2}, states,
+		func(_ Item, protocolID string) error {
+			lock.Lock()
+			active++
+			if active > maxSeen {
+				maxSeen = active
+			}
+			lock.Unlock()
+			go func() {
+				time.Sleep(20 * time.Millisecond)
+				lock.Lock()
+				active--
+				lock.Unlock()
+				ack(protocolID, 0)
+			}()
+			return nil
+		})
+	r.run()
+
+	assert.Equal(maxSeen, 2)
+	c := counts(r.items)
+	assert.Equal(c[ItemOK], len(states))
+	items, err := getItemReps(r.rep)
+	assert.NoError(err)
+	for _, item := range items {
+		assert.Equal(item.State, ItemOK)
+	}
+	assert.That(items[0].Started)
+	assert.NotEmpty(items[0].ProtocolID)
+}
+
+func TestRunner_rate(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	const rate = 20
+	states := []string{ItemPending, ItemPending, ItemPending, ItemPending}
+	var (
+		lock    sync.Mutex
+		started []time.Time
+	)
+	r := newTestRunner("rate", Config{Concurrency: 10, Rate: rate}, states,
+		func(_ Item, protocolID string) error {
+			lock.Lock()
+			started = append(started, time.Now())
+			lock.Unlock()
+			ack(protocolID, 0)
+			return nil
+		})
+	r.run()
+
+	assert.SLen(started, len(states))
+	interval := time.Second / rate
+	for i := 1; i < len(started); i++ {
+		// the ticker may drop ticks, but it never sends them earlier
+		assert.That(started[i].Sub(started[i-1]) > interval/2,
+			"%d started too soon: %v", i, started[i].Sub(started[i-1]))
+	}
+	assert.Equal(counts(r.items)[ItemOK], len(states))
+}
+
+func TestRunner_timeout(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	r := newTestRunner("timeout", Config{ItemTimeout: 20 * time.Millisecond},
+		[]string{ItemPending, ItemPending},
+		func(_ Item, protocolID string) error {
+			return nil // the holder doesn't answer
+		})
+	r.run()
+
+	c := counts(r.items)
+	assert.Equal(c[ItemTimeout], 2)
+	assert.That(r.items[0].Done())
+}
+
+func TestRunner_resume(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	var (
+		lock    sync.Mutex
+		started []string
+	)
+	r := newTestRunner("resume", Config{ItemTimeout: time.Second},
+		[]string{ItemRunning, ItemRunning, ItemError},
+		func(_ Item, protocolID string) error {
+			lock.Lock()
+			started = append(started, protocolID)
+			lock.Unlock()
+			ack(protocolID, 0)
+			return nil
+		})
+	// the PSM of the first was never created, and the second was released
+	r.items[0].ProtocolID = "not-created"
+	r.items[1].ProtocolID, r.items[1].Started = "released", true
+	r.run()
+
+	assert.SLen(started, 2) // the first is retried with the failed one
+	for _, id := range started {
+		assert.NotEqual(id, "not-created")
+	}
+	assert.Equal(r.items[0].State, ItemOK)
+	assert.Equal(r.items[1].State, ItemReleased)
+	assert.Equal(r.items[2].State, ItemOK)
+}
@@ -1,9 +1,14 @@
 package data
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/findy-network/findy-agent/agent/comm"
 	"github.com/findy-network/findy-agent/agent/didcomm"
 	"github.com/findy-network/findy-agent/agent/psm"
+	"github.com/findy-network/findy-agent/agent/storage/api"
+	"github.com/findy-network/findy-agent/agent/trust"
 	"github.com/findy-network/findy-common-go/dto"
 	"github.com/findy-network/findy-wrapper-go"
 	"github.com/findy-network/findy-wrapper-go/anoncreds"
@@ -24,6 +29,25 @@ type IssueCredRep struct {
 	CredReqMeta string
 	Values      string
 	Attributes  []didcomm.CredentialAttribute
+
+	// Filter and Comment are from the credential proposal.
+	Filter  CredFilter
+	Comment string
+
+	// Trust is the trust registry's check of the offer's issuer at the
+	// holder. It's nil if the CA doesn't have the registry.
+	Trust *trust.Result
+}
+
+// CredFilter is the schema and issuer filter of the credential proposal. The
+// holder can send it instead of the cred def ID when it doesn't know which
+// cred def the issuer uses.
+type CredFilter struct {
+	SchemaIssuerDID string
+	SchemaID        string
+	SchemaName      string
+	SchemaVersion   string
+	IssuerDID       string
 }
 
 func init() {
@@ -86,12 +110,75 @@ func (rep *IssueCredRep) IssuerBuildCred(packet comm.Packet, credReq string) (c
 	return c, nil
 }
 
+// indyCred is the part of the Indy credential which we index in the
+// credential storage.
+type indyCred struct {
+	SchemaID  string `json:"schema_id"`
+	CredDefID string `json:"cred_def_id"`
+	Values    map[string]struct {
+		Raw string `json:"raw"`
+	} `json:"values"`
+}
+
 // StoreCred saves the credential to wallet which is prover/holder side action.
-func (rep *IssueCredRep) StoreCred(packet comm.Packet, cred string) error {
+// The credential is also saved to the agent's credential storage with the
+// wallet's credential ID, which makes it possible to query our credentials.
+func (rep *IssueCredRep) StoreCred(packet comm.Packet, cred string) (err error) {
+	defer err2.Handle(&err, "store cred")
+
 	a := packet.Receiver
 	w := a.Wallet()
 	r := <-anoncreds.ProverStoreCredential(w, findy.NullString, rep.CredReqMeta, cred, rep.CredDef, findy.NullString)
-	return r.Err()
+	try.To(r.Err())
+
+	_, ms := a.ManagedWallet()
+	cs := ms.Storage().CredentialStorage()
+	assert.That(cs != nil, "credential storage not available")
+	c := IndyCredential(r.Str1(), []byte(cred))
+	c.Created = time.Now().UnixNano()
+	return cs.SaveCredential(c)
+}
+
+// CheckIssued checks that the issued credential is the offered one: it must
+// be of the offered cred def and have the offered attribute values.
+func (rep *IssueCredRep) CheckIssued(cred []byte) error {
+	c := IndyCredential("", cred)
+	if c.CredDefID != rep.CredDefID {
+		return fmt.Errorf("credential cred def (%s) isn't the offered (%s)",
+			c.CredDefID, rep.CredDefID)
+	}
+	if len(c.Attributes) != len(rep.Attributes) {
+		return fmt.Errorf("credential has %d attributes, %d offered",
+			len(c.Attributes), len(rep.Attributes))
+	}
+	for _, attr := range rep.Attributes {
+		if v, ok := c.Attributes[attr.Name]; !ok || v != attr.Value {
+			return fmt.Errorf("credential attribute (%s) isn't the offered",
+				attr.Name)
+		}
+	}
+	return nil
+}
+
+// IndyCredential returns the credential storage presentation of the Indy
+// credential given in JSON.
+func IndyCredential(id string, cred []byte) api.Credential {
+	var ic indyCred
+	dto.FromJSON(cred, &ic)
+
+	attrs := make(map[string]string, len(ic.Values))
+	for name, v := range ic.Values {
+		attrs[name] = v.Raw
+	}
+	return api.Credential{
+		ID:         id,
+		Format:     api.FormatIndy,
+		Issuer:     api.IndyIssuer(ic.CredDefID),
+		SchemaID:   ic.SchemaID,
+		CredDefID:  ic.CredDefID,
+		Attributes: attrs,
+		Data:       cred,
+	}
 }
 
 func GetIssueCredRep(key psm.StateKey) (rep *IssueCredRep, err error) {
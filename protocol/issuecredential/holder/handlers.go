@@ -1,18 +1,27 @@
 package holder
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
 	"github.com/findy-network/findy-agent/agent/comm"
 	"github.com/findy-network/findy-agent/agent/didcomm"
 	"github.com/findy-network/findy-agent/agent/pltype"
+	"github.com/findy-network/findy-agent/agent/policy"
 	"github.com/findy-network/findy-agent/agent/prot"
 	"github.com/findy-network/findy-agent/agent/psm"
+	storage "github.com/findy-network/findy-agent/agent/storage/api"
+	"github.com/findy-network/findy-agent/agent/trust"
 	"github.com/findy-network/findy-agent/protocol/issuecredential/data"
 	"github.com/findy-network/findy-agent/protocol/issuecredential/preview"
 	"github.com/findy-network/findy-agent/std/common"
+	"github.com/findy-network/findy-agent/std/didexchange/signature"
 	"github.com/findy-network/findy-agent/std/issuecredential"
 	"github.com/findy-network/findy-common-go/dto"
 	"github.com/golang/glog"
 	"github.com/lainio/err2"
+	"github.com/lainio/err2/assert"
 	"github.com/lainio/err2/try"
 )
 
@@ -39,18 +48,26 @@ func HandleCredentialOffer(packet comm.Packet) (err error) {
 		try.To(psm.AddRep(rep))
 	}
 
-	sendNext, waitingNext := checkAutoPermission(packet)
+	credDefID, schemaID := offerIDs(packet)
+	offerTrust := trust.CheckCA(packet.Receiver.MyCA().MyDID().Did(),
+		[]trust.Credential{{
+			Issuer:   storage.IndyIssuer(credDefID),
+			SchemaID: schemaID,
+		}})
+	sendNext, waitingNext := checkAutoPermission(packet, credDefID, offerTrust)
 
 	return prot.ExecPSM(prot.Transition{
 		Packet:      packet,
 		SendNext:    sendNext,
 		WaitingNext: waitingNext,
 		TaskHeader:  &comm.TaskHeader{UserActionPLType: pltype.CANotifyUserAction},
-		InOut: func(_ string, im, om didcomm.MessageHdr) (ack bool, err error) {
+		InOut: func(connID string, im, om didcomm.MessageHdr) (ack bool, err error) {
 			defer err2.Handle(&err, "cred offer ask user (%v)",
 				packet.Receiver.RootDid().Did())
 
 			offer := im.FieldObj().(*issuecredential.Offer)
+			try.To(verifyOffer(packet.Receiver, connID, offer))
+			try.To(checkOfferAttributes(offer))
 			values := issuecredential.PreviewCredentialToValues(
 				offer.CredentialPreview)
 
@@ -71,6 +88,7 @@ func HandleCredentialOffer(packet comm.Packet) (err error) {
 
 			rep.Values = values
 			preview.StoreCredPreview(&offer.CredentialPreview, rep)
+			rep.Trust = offerTrust
 
 			req, autoAccept := om.FieldObj().(*issuecredential.Request)
 			if autoAccept {
@@ -88,6 +106,33 @@ func HandleCredentialOffer(packet comm.Packet) (err error) {
 	})
 }
 
+// verifyOffer verifies the signature of the offer attachment, if the issuer
+// has signed it. The offer must be signed by the connection.
+func verifyOffer(r comm.Receiver, connID string, offer *issuecredential.Offer) (err error) {
+	defer err2.Handle(&err, "verify offer")
+
+	if len(offer.OffersAttach) == 0 || offer.OffersAttach[0].Data.JWS == nil {
+		return nil
+	}
+	pw := try.To1(r.FindPWByID(connID))
+	assert.That(pw != nil, "connection not found")
+
+	verifier := signature.Verifier{DID: r.LoadTheirDID(*pw)}
+	return verifier.VerifyAttachment(&offer.OffersAttach[0].Data)
+}
+
+// checkOfferAttributes rejects the offers which use the attribute names
+// reserved for the agency, see trust.Reserved.
+func checkOfferAttributes(offer *issuecredential.Offer) error {
+	for _, attr := range offer.CredentialPreview.Attributes {
+		if trust.Reserved(attr.Name) {
+			return fmt.Errorf("offer attribute name (%s) is reserved",
+				attr.Name)
+		}
+	}
+	return nil
+}
+
 // todo lapi: im message is old legacy api type!!
 
 // userActionCredential is called when Holder has received a Cred_Offer and it's
@@ -128,8 +173,38 @@ func UserActionCredential(ca comm.Receiver, im didcomm.Msg) {
 	}))
 }
 
-func checkAutoPermission(packet comm.Packet) (next string, wait string) {
-	if packet.Receiver.AutoPermission() {
+// offerIDs returns the cred def and the schema of the credential offer.
+func offerIDs(packet comm.Packet) (credDefID, schemaID string) {
+	offer, ok := packet.Payload.MsgHdr().FieldObj().(*issuecredential.Offer)
+	if !ok {
+		return "", ""
+	}
+	attach, err := issuecredential.OfferAttach(offer)
+	if err != nil {
+		glog.Warningln("cred offer attachment:", err)
+		return "", ""
+	}
+	var subMsg struct {
+		CredDefID string `json:"cred_def_id"`
+		SchemaID  string `json:"schema_id"`
+	}
+	if err := json.Unmarshal(attach, &subMsg); err != nil {
+		glog.Warningln("cred offer attachment:", err)
+	}
+	return subMsg.CredDefID, subMsg.SchemaID
+}
+
+// checkAutoPermission returns the next states of the credential offer. The
+// offer is accepted automatically if the auto-accept policy allows it and
+// the trust registry trusts its issuer. The untrusted offers are escalated
+// to the controller with the trust result in the status.
+func checkAutoPermission(packet comm.Packet, credDefID string, res *trust.Result) (next string, wait string) {
+	req := storage.PolicyRequest{Protocol: storage.PolicyIssue}
+	if credDefID != "" {
+		req.CredDefIDs = []string{credDefID}
+	}
+	if policy.Accept(packet.Receiver, packet.Address.ConnID, req) &&
+		(res == nil || res.Trusted) {
 		next = pltype.IssueCredentialRequest
 		wait = pltype.IssueCredentialIssue
 	} else {
@@ -155,6 +230,10 @@ func HandleCredentialIssue(packet comm.Packet) (err error) {
 
 			rep := try.To1(data.GetIssueCredRep(repK))
 			cred := try.To1(issuecredential.CredentialAttach(issue))
+			c := data.IndyCredential("", cred)
+			res := trust.CheckCA(agent.MyCA().MyDID().Did(),
+				[]trust.Credential{{Issuer: c.Issuer, SchemaID: c.SchemaID}})
+			try.To(checkIssued(rep, cred, res))
 			try.To(rep.StoreCred(packet, string(cred)))
 
 			outAck := om.FieldObj().(*common.Ack)
@@ -164,3 +243,19 @@ func HandleCredentialIssue(packet comm.Packet) (err error) {
 		},
 	})
 }
+
+// checkIssued checks the issued credential before it's stored: it must be
+// the offered one, and its issuer must still be trusted if the offer's
+// issuer was. The untrusted offers are stored only when the controller has
+// accepted them.
+func checkIssued(rep *data.IssueCredRep, cred []byte, res *trust.Result) error {
+	if err := rep.CheckIssued(cred); err != nil {
+		return err
+	}
+	offerTrusted := rep.Trust == nil || rep.Trust.Trusted
+	if offerTrusted && res != nil && !res.Trusted {
+		return fmt.Errorf("credential issuer isn't trusted: %s",
+			strings.Join(res.Reasons, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package holder
+
+import (
+	"testing"
+
+	"github.com/findy-network/findy-agent/agent/didcomm"
+	"github.com/findy-network/findy-agent/agent/trust"
+	"github.com/findy-network/findy-agent/protocol/issuecredential/data"
+	"github.com/findy-network/findy-agent/std/issuecredential"
+	"github.com/lainio/err2/assert"
+)
+
+const issuedCred = `{
+	"schema_id": "schemaID",
+	"cred_def_id": "credDefID",
+	"values": {"email": {"raw": "alice@example.com", "encoded": "1"}}
+}`
+
+func TestCheckOfferAttributes(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	offer := &issuecredential.Offer{
+		CredentialPreview: issuecredential.PreviewCredential{
+			Attributes: []issuecredential.Attribute{
+				{Name: "email", Value: "alice@example.com"},
+			},
+		},
+	}
+	assert.NoError(checkOfferAttributes(offer))
+
+	offer.CredentialPreview.Attributes = append(
+		offer.CredentialPreview.Attributes,
+		issuecredential.Attribute{Name: trust.AttrIssuerTrusted, Value: "true"})
+	assert.Error(checkOfferAttributes(offer))
+}
+
+func TestCheckIssued(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	untrusted := &trust.Result{Reasons: []string{"issuer isn't trusted"}}
+	tests := []struct {
+		name      string
+		credDefID string
+		value     string
+		offer     *trust.Result
+		issue     *trust.Result
+		ok        bool
+	}{
+		{"no registry", "credDefID", "alice@example.com", nil, nil, true},
+		{"trusted", "credDefID", "alice@example.com",
+			&trust.Result{Trusted: true}, &trust.Result{Trusted: true}, true},
+		{"accepted untrusted", "credDefID", "alice@example.com",
+			untrusted, untrusted, true},
+		{"not trusted anymore", "credDefID", "alice@example.com",
+			&trust.Result{Trusted: true}, untrusted, false},
+		{"registry added", "credDefID", "alice@example.com",
+			nil, untrusted, false},
+		{"other cred def", "otherCredDefID", "alice@example.com",
+			nil, nil, false},
+		{"other value", "credDefID", "bob@example.com", nil, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.PushTester(t)
+			defer assert.PopTester()
+
+			rep := &data.IssueCredRep{
+				CredDefID: tt.credDefID,
+				Attributes: []didcomm.CredentialAttribute{
+					{Name: "email", Value: tt.value},
+				},
+				Trust: tt.offer,
+			}
+			err := checkIssued(rep, []byte(issuedCred), tt.issue)
+			if tt.ok {
+				assert.NoError(err)
+			} else {
+				assert.Error(err)
+			}
+		})
+	}
+}
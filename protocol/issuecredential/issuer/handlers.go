@@ -1,6 +1,8 @@
 package issuer
 
 import (
+	"fmt"
+
 	"github.com/findy-network/findy-agent/agent/comm"
 	"github.com/findy-network/findy-agent/agent/didcomm"
 	"github.com/findy-network/findy-agent/agent/pltype"
@@ -8,10 +10,14 @@ import (
 	"github.com/findy-network/findy-agent/agent/psm"
 	"github.com/findy-network/findy-agent/protocol/issuecredential/data"
 	"github.com/findy-network/findy-agent/protocol/issuecredential/preview"
+	"github.com/findy-network/findy-agent/std/decorator"
+	"github.com/findy-network/findy-agent/std/didexchange/signature"
 	"github.com/findy-network/findy-agent/std/issuecredential"
+	"github.com/findy-network/findy-common-go/dto"
 	"github.com/findy-network/findy-wrapper-go/anoncreds"
 	"github.com/golang/glog"
 	"github.com/lainio/err2"
+	"github.com/lainio/err2/assert"
 	"github.com/lainio/err2/try"
 )
 
@@ -34,7 +40,7 @@ func HandleCredentialPropose(packet comm.Packet) (err error) {
 		WaitingNext: waitingNext,
 		SendOnNACK:  pltype.IssueCredentialNACK,
 		TaskHeader:  &comm.TaskHeader{UserActionPLType: pltype.SAIssueCredentialAcceptPropose},
-		InOut: func(_ string, im, om didcomm.MessageHdr) (ack bool, err error) {
+		InOut: func(connID string, im, om didcomm.MessageHdr) (ack bool, err error) {
 			defer err2.Handle(&err, "credential propose handler")
 
 			wa := packet.Receiver
@@ -54,12 +60,12 @@ func HandleCredentialPropose(packet comm.Packet) (err error) {
 				})
 			}
 
-			// TODO: support changing values
-
-			r := <-anoncreds.IssuerCreateCredentialOffer(
-				wa.Wallet(), prop.CredDefID)
-			try.To(r.Err())
-			credOffer := r.Str1()
+			// the controller can change the values and the cred def before
+			// we offer, see CounterOffer
+			var credOffer string
+			if prop.CredDefID != "" {
+				credOffer = try.To1(createCredOffer(wa, prop.CredDefID))
+			}
 
 			rep := &data.IssueCredRep{
 				StateKey:   psm.StateKey{DID: meDID, Nonce: im.Thread().ID},
@@ -67,13 +73,25 @@ func HandleCredentialPropose(packet comm.Packet) (err error) {
 				CredOffer:  credOffer,
 				Values:     values, // important! saved for Req handling
 				Attributes: attributes,
+				Filter: data.CredFilter{
+					SchemaIssuerDID: prop.SchemaIssuerDid,
+					SchemaID:        prop.SchemaID,
+					SchemaName:      prop.SchemaName,
+					SchemaVersion:   prop.SchemaVersion,
+					IssuerDID:       prop.IssuerDid,
+				},
+				Comment: prop.Comment,
 			}
 			try.To(psm.AddRep(rep))
 
 			offer, autoAccept := om.FieldObj().(*issuecredential.Offer)
 			if autoAccept {
+				if credOffer == "" {
+					return false, fmt.Errorf("proposal without cred def " +
+						"cannot be offered automatically")
+				}
 				offer.OffersAttach =
-					issuecredential.NewOfferAttach([]byte(credOffer))
+					try.To1(OfferAttach(wa, connID, credOffer))
 				offer.CredentialPreview =
 					issuecredential.NewPreviewCredentialRaw(values)
 				offer.Comment = values // todo: for legacy tests
@@ -108,12 +126,16 @@ func ContinueCredentialPropose(ca comm.Receiver, im didcomm.Msg) {
 			repK := psm.NewStateKey(ca, im.Thread().ID)
 
 			rep := try.To1(data.GetIssueCredRep(repK))
+			if rep.CredOffer == "" {
+				return false, fmt.Errorf("cred def is needed for offer, " +
+					"see CounterOfferCredential")
+			}
+			m := try.To1(psm.GetPSM(repK))
 
 			offer := om.FieldObj().(*issuecredential.Offer)
 			offer.OffersAttach =
-				issuecredential.NewOfferAttach([]byte(rep.CredOffer))
-			offer.CredentialPreview =
-				issuecredential.NewPreviewCredentialRaw(rep.Values)
+				try.To1(OfferAttach(ca.WorkerEA(), m.ConnID, rep.CredOffer))
+			offer.CredentialPreview = previewCredential(rep)
 			offer.Comment = rep.Values // todo: for legacy tests
 			preview.StoreCredPreview(&offer.CredentialPreview, rep)
 
@@ -122,6 +144,81 @@ func ContinueCredentialPropose(ca comm.Receiver, im didcomm.Msg) {
 	}))
 }
 
+// CounterOffer changes the credential of the proposal we are offering. It's
+// called when our controller answers the proposal question, before the
+// protocol is resumed. If the cred def ID is given, the offer is made for it.
+// If the attributes are given, they replace the proposed ones.
+func CounterOffer(
+	ca comm.Receiver,
+	protocolID, credDefID string,
+	attrs []didcomm.CredentialAttribute,
+) (err error) {
+	wa := ca.WorkerEA()
+	return counterOffer(psm.NewStateKey(wa, protocolID), credDefID, attrs,
+		func(credDefID string) (string, error) {
+			return createCredOffer(wa, credDefID)
+		})
+}
+
+// counterOffer updates the proposal of the PSM. The new indy cred offer is
+// created with the function if the cred def changes.
+func counterOffer(
+	key psm.StateKey,
+	credDefID string,
+	attrs []didcomm.CredentialAttribute,
+	createOffer func(credDefID string) (string, error),
+) (err error) {
+	defer err2.Handle(&err, "counter offer")
+
+	m := try.To1(psm.GetPSM(key))
+	if m.StartedByUs {
+		return fmt.Errorf("counter offer only for received proposal")
+	}
+	if !m.PendingUserAction() {
+		return fmt.Errorf("proposal isn't waiting for our answer")
+	}
+	rep := try.To1(data.GetIssueCredRep(key))
+	if rep == nil {
+		return fmt.Errorf("proposal not found")
+	}
+
+	if credDefID != "" && credDefID != rep.CredDefID {
+		rep.CredOffer = try.To1(createOffer(credDefID))
+		rep.CredDefID = credDefID
+	}
+	if len(attrs) > 0 {
+		rep.Attributes = make([]didcomm.CredentialAttribute, len(attrs))
+		for i, attr := range attrs {
+			if attr.MimeType == "" {
+				attr.MimeType = "text/plain"
+			}
+			rep.Attributes[i] = attr
+		}
+		rep.Values = issuecredential.PreviewCredentialToCodedValues(
+			previewCredential(rep))
+	}
+	glog.V(1).Infof("counter offer %s: %s %v", key.Nonce, rep.CredDefID,
+		rep.Attributes)
+	return psm.AddRep(rep)
+}
+
+// previewCredential returns the credential preview of the offer. The
+// attributes are used when we have them, because they have the MIME types.
+func previewCredential(rep *data.IssueCredRep) issuecredential.PreviewCredential {
+	if len(rep.Attributes) == 0 {
+		return issuecredential.NewPreviewCredentialRaw(rep.Values)
+	}
+	return issuecredential.NewPreviewCredential(dto.ToJSON(rep.Attributes))
+}
+
+func createCredOffer(ca comm.Receiver, credDefID string) (offer string, err error) {
+	defer err2.Handle(&err, "create cred offer for %s", credDefID)
+
+	r := <-anoncreds.IssuerCreateCredentialOffer(ca.Wallet(), credDefID)
+	try.To(r.Err())
+	return r.Str1(), nil
+}
+
 // HandleCredentialRequest implements the handler for credential request protocol
 // msg. This is Issuer side action.
 func HandleCredentialRequest(packet comm.Packet) (err error) {
@@ -164,3 +261,21 @@ func HandleCredentialACK(packet comm.Packet) (err error) {
 		},
 	})
 }
+
+// OfferAttach returns the attachment of the Indy credential offer signed with
+// our key of the connection. The holder verifies the signature to know that
+// the offer is from the connection.
+func OfferAttach(
+	wa comm.Receiver,
+	connID, credOffer string,
+) (_ []decorator.Attachment, err error) {
+	defer err2.Handle(&err, "offer attachment")
+
+	pw := try.To1(wa.FindPWByID(connID))
+	assert.That(pw != nil, "connection not found")
+
+	attach := issuecredential.NewOfferAttach([]byte(credOffer))
+	signer := signature.Signer{DID: wa.LoadDID(pw.MyDID)}
+	try.To(signer.SignAttachment(&attach[0].Data))
+	return attach, nil
+}
@@ -0,0 +1,115 @@
+package issuer
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/findy-network/findy-agent/agent/didcomm"
+	"github.com/findy-network/findy-agent/agent/pltype"
+	"github.com/findy-network/findy-agent/agent/psm"
+	"github.com/findy-network/findy-agent/protocol/issuecredential/data"
+	"github.com/lainio/err2/assert"
+	"github.com/lainio/err2/try"
+)
+
+const dbPath = "issuer_test.bolt"
+
+func TestMain(m *testing.M) {
+	try.To(psm.Open(dbPath))
+	code := m.Run()
+	psm.Close()
+	os.Remove(dbPath)
+	os.Exit(code)
+}
+
+// addProposal stores the received proposal which waits for our answer.
+func addProposal(nonce string, startedByUs bool, lastType string) psm.StateKey {
+	key := psm.StateKey{DID: "issuerDID", Nonce: nonce}
+	try.To(psm.AddPSM(&psm.PSM{
+		Key:         key,
+		ConnID:      "connID",
+		StartedByUs: startedByUs,
+		States: []psm.State{
+			{PLInfo: psm.PayloadInfo{Type: pltype.IssueCredentialPropose}, Sub: psm.Received},
+			{PLInfo: psm.PayloadInfo{Type: lastType}, Sub: psm.Waiting},
+		},
+	}))
+	try.To(psm.AddRep(&data.IssueCredRep{
+		StateKey:  key,
+		CredDefID: "proposedCredDefID",
+		CredOffer: "proposedOffer",
+		Attributes: []didcomm.CredentialAttribute{
+			{Name: "email", Value: "alice@example.com", MimeType: "text/plain"},
+		},
+	}))
+	return key
+}
+
+func TestCounterOffer(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	offers := 0
+	createOffer := func(credDefID string) (string, error) {
+		offers++
+		return "offer of " + credDefID, nil
+	}
+
+	key := addProposal("counter", false, pltype.IssueCredentialUserAction)
+	assert.NoError(counterOffer(key, "proposedCredDefID", nil, createOffer))
+	assert.Equal(offers, 0) // same cred def, same offer
+
+	attrs := []didcomm.CredentialAttribute{
+		{Name: "email", Value: "alice@example.org"},
+		{Name: "photo", Value: "aGVsbG8=", MimeType: "image/png"},
+	}
+	assert.NoError(counterOffer(key, "ourCredDefID", attrs, createOffer))
+	assert.Equal(offers, 1)
+
+	rep, err := data.GetIssueCredRep(key)
+	assert.NoError(err)
+	assert.Equal(rep.CredDefID, "ourCredDefID")
+	assert.Equal(rep.CredOffer, "offer of ourCredDefID")
+	assert.SLen(rep.Attributes, 2)
+	assert.Equal(rep.Attributes[0].MimeType, "text/plain")
+	assert.Equal(rep.Attributes[1].MimeType, "image/png")
+	assert.NotEmpty(rep.Values)
+	assert.Equal(attrs[0].MimeType, "") // caller's attributes aren't changed
+
+	preview := previewCredential(rep)
+	assert.SLen(preview.Attributes, 2)
+	assert.Equal(preview.Attributes[0].Value, "alice@example.org")
+}
+
+func TestCounterOffer_errors(t *testing.T) {
+	// no assert tester: the assertions of counterOffer must return errors
+	failOffer := func(string) (string, error) {
+		return "", errors.New("no cred def")
+	}
+	tests := []struct {
+		name      string
+		ours      bool
+		lastType  string
+		credDefID string
+	}{
+		{"our proposal", true, pltype.IssueCredentialUserAction, ""},
+		{"answered", false, pltype.IssueCredentialOffer, ""},
+		{"unknown cred def", false, pltype.IssueCredentialUserAction, "unknownCredDefID"},
+	}
+	for _, tt := range tests {
+		key := addProposal(tt.name, tt.ours, tt.lastType)
+		if err := counterOffer(key, tt.credDefID, nil, failOffer); err == nil {
+			t.Errorf("%s: counter offer succeeded", tt.name)
+		}
+		rep, err := data.GetIssueCredRep(key)
+		if err != nil || rep.CredDefID != "proposedCredDefID" {
+			t.Errorf("%s: proposal changed: %v", tt.name, err)
+		}
+	}
+
+	key := psm.StateKey{DID: "issuerDID", Nonce: "not-found"}
+	if err := counterOffer(key, "", nil, failOffer); err == nil {
+		t.Error("counter offer of missing proposal succeeded")
+	}
+}
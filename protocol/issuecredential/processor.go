@@ -3,12 +3,16 @@ package issuecredential
 import (
 	"encoding/gob"
 	"encoding/json"
+	"fmt"
+	"strconv"
 
 	"github.com/findy-network/findy-agent/agent/comm"
 	"github.com/findy-network/findy-agent/agent/didcomm"
 	"github.com/findy-network/findy-agent/agent/pltype"
 	"github.com/findy-network/findy-agent/agent/prot"
 	"github.com/findy-network/findy-agent/agent/psm"
+	"github.com/findy-network/findy-agent/agent/trust"
+	"github.com/findy-network/findy-agent/agent/utils"
 	"github.com/findy-network/findy-agent/protocol/issuecredential/data"
 	"github.com/findy-network/findy-agent/protocol/issuecredential/holder"
 	"github.com/findy-network/findy-agent/protocol/issuecredential/issuer"
@@ -27,6 +31,7 @@ type taskIssueCredential struct {
 	Comment         string
 	CredentialAttrs []didcomm.CredentialAttribute
 	CredDefID       string
+	Filter          data.CredFilter
 }
 
 type continuatorFunc func(ca comm.Receiver, im didcomm.Msg)
@@ -42,6 +47,7 @@ var issueCredentialProcessor = comm.ProtProc{
 		pltype.HandlerIssueCredentialIssue:   holder.HandleCredentialIssue,
 		pltype.HandlerIssueCredentialACK:     issuer.HandleCredentialACK,
 		pltype.HandlerIssueCredentialNACK:    handleCredentialNACK,
+		pltype.HandlerProblemReport:          prot.HandleProblemReport,
 	},
 	FillStatus: fillIssueCredentialStatus,
 }
@@ -98,6 +104,44 @@ func createIssueCredentialTask(header *comm.TaskHeader, protocol *pb.Protocol) (
 	}, nil
 }
 
+// NewProposeTask creates a task for the holder to start the protocol with the
+// credential proposal. The proposal can have the cred def ID, the schema and
+// issuer filter, or both. The attributes are the credential preview, and they
+// can have MIME types, text/plain is the default.
+func NewProposeTask(
+	connID, credDefID string,
+	filter data.CredFilter,
+	attrs []didcomm.CredentialAttribute,
+	comment string,
+) (t comm.Task, err error) {
+	if connID == "" {
+		return nil, fmt.Errorf("connection is needed for credential proposal")
+	}
+	if credDefID == "" && filter == (data.CredFilter{}) {
+		return nil, fmt.Errorf("cred def ID or filter is needed for credential proposal")
+	}
+	credAttrs := make([]didcomm.CredentialAttribute, len(attrs))
+	for i, attr := range attrs {
+		if attr.MimeType == "" {
+			attr.MimeType = "text/plain"
+		}
+		credAttrs[i] = attr
+	}
+	return &taskIssueCredential{
+		TaskBase: comm.TaskBase{TaskHeader: comm.TaskHeader{
+			TaskID:       utils.UUID(),
+			TypeID:       pltype.CACredRequest,
+			ProtocolRole: pb.Protocol_INITIATOR,
+			ConnID:       connID,
+			Method:       utils.Settings.DIDMethod(),
+		}},
+		Comment:         comment,
+		CredentialAttrs: credAttrs,
+		CredDefID:       credDefID,
+		Filter:          filter,
+	}, nil
+}
+
 // startIssueCredentialByPropose starts the Issue Credential Protocol by sending
 // a Propose Message to pairwise identified by t.Message. It sends the protocol
 // message from cloud EA, and saves the received credentials to cloud EA's
@@ -146,7 +190,8 @@ func startIssueCredentialByPropose(ca comm.Receiver, t comm.Task) {
 				offer := msg.FieldObj().(*issuecredential.Offer)
 				offer.CredentialPreview = pc
 				offer.OffersAttach = // here we send the indy cred offer
-					issuecredential.NewOfferAttach([]byte(credOffer))
+					try.To1(issuer.OfferAttach(ca.WorkerEA(),
+						credTask.ConnectionID(), credOffer))
 
 				return nil
 			},
@@ -168,12 +213,19 @@ func startIssueCredentialByPropose(ca comm.Receiver, t comm.Task) {
 				propose.CredDefID = credTask.CredDefID
 				propose.CredentialProposal = pc
 				propose.Comment = credTask.Comment
+				propose.SchemaIssuerDid = credTask.Filter.SchemaIssuerDID
+				propose.SchemaID = credTask.Filter.SchemaID
+				propose.SchemaName = credTask.Filter.SchemaName
+				propose.SchemaVersion = credTask.Filter.SchemaVersion
+				propose.IssuerDid = credTask.Filter.IssuerDID
 
 				rep := &data.IssueCredRep{
 					StateKey:   key,
 					CredDefID:  credTask.CredDefID,
 					Attributes: credTask.CredentialAttrs,
 					Values:     issuecredential.PreviewCredentialToCodedValues(pc),
+					Filter:     credTask.Filter,
+					Comment:    credTask.Comment,
 				}
 				try.To(psm.AddRep(rep))
 				return nil
@@ -242,10 +294,13 @@ func fillIssueCredentialStatus(workerDID string, taskID string, ps *pb.ProtocolS
 	credRep := try.To1(data.GetIssueCredRep(key))
 
 	// TODO: save schema id parsed to db? copied from original implementation
-	var credOfferMap map[string]interface{}
-	dto.FromJSONStr(credRep.CredOffer, &credOfferMap)
-
-	schemaID := credOfferMap["schema_id"].(string)
+	// The proposal without the cred def doesn't have the offer yet.
+	schemaID := credRep.Filter.SchemaID
+	if credRep.CredOffer != "" {
+		var credOfferMap map[string]interface{}
+		dto.FromJSONStr(credRep.CredOffer, &credOfferMap)
+		schemaID = credOfferMap["schema_id"].(string)
+	}
 
 	attrs := make([]*pb.Protocol_IssuingAttributes_Attribute,
 		0, len(credRep.Attributes))
@@ -257,6 +312,21 @@ func fillIssueCredentialStatus(workerDID string, taskID string, ps *pb.ProtocolS
 		attrs = append(attrs, a)
 	}
 
+	// the holder's trust registry check is shown with the reserved
+	// attributes, see trust.AttrIssuerTrusted
+	if credRep.Trust != nil {
+		attrs = append(attrs, &pb.Protocol_IssuingAttributes_Attribute{
+			Name:  trust.AttrIssuerTrusted,
+			Value: strconv.FormatBool(credRep.Trust.Trusted),
+		})
+		for _, reason := range credRep.Trust.Reasons {
+			attrs = append(attrs, &pb.Protocol_IssuingAttributes_Attribute{
+				Name:  trust.AttrIssuerTrustReason,
+				Value: reason,
+			})
+		}
+	}
+
 	status.Status = &pb.ProtocolStatus_IssueCredential{
 		IssueCredential: &pb.ProtocolStatus_IssueCredentialStatus{
 			CredDefID: credRep.CredDefID,
@@ -0,0 +1,85 @@
+package issuecredential
+
+import (
+	"os"
+	"testing"
+
+	"github.com/findy-network/findy-agent/agent/didcomm"
+	"github.com/findy-network/findy-agent/agent/pltype"
+	"github.com/findy-network/findy-agent/agent/psm"
+	"github.com/findy-network/findy-agent/agent/trust"
+	"github.com/findy-network/findy-agent/protocol/issuecredential/data"
+	pb "github.com/findy-network/findy-common-go/grpc/agency/v1"
+	"github.com/lainio/err2/assert"
+	"github.com/lainio/err2/try"
+)
+
+const dbPath = "issuecredential_test.bolt"
+
+func TestMain(m *testing.M) {
+	try.To(psm.Open(dbPath))
+	code := m.Run()
+	psm.Close()
+	os.Remove(dbPath)
+	os.Exit(code)
+}
+
+func TestNewProposeTask(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	filter := data.CredFilter{SchemaName: "email", IssuerDID: "issuerDID"}
+	attrs := []didcomm.CredentialAttribute{
+		{Name: "email", Value: "alice@example.com"},
+		{Name: "photo", Value: "aGVsbG8=", MimeType: "image/png"},
+	}
+	task, err := NewProposeTask("connID", "", filter, attrs, "please")
+	assert.NoError(err)
+
+	credTask, ok := task.(*taskIssueCredential)
+	assert.That(ok)
+	assert.NotEmpty(credTask.ID())
+	assert.Equal(credTask.Type(), pltype.CACredRequest)
+	assert.Equal(credTask.Role(), pb.Protocol_INITIATOR)
+	assert.Equal(credTask.ConnectionID(), "connID")
+	assert.Equal(credTask.Filter, filter)
+	assert.Equal(credTask.Comment, "please")
+	assert.SLen(credTask.CredentialAttrs, 2)
+	assert.Equal(credTask.CredentialAttrs[0].MimeType, "text/plain")
+	assert.Equal(credTask.CredentialAttrs[1].MimeType, "image/png")
+	assert.Equal(attrs[0].MimeType, "") // caller's attributes aren't changed
+
+	task, err = NewProposeTask("connID", "credDefID", data.CredFilter{}, attrs, "")
+	assert.NoError(err)
+	assert.Equal(task.(*taskIssueCredential).CredDefID, "credDefID")
+
+	_, err = NewProposeTask("", "credDefID", filter, attrs, "")
+	assert.Error(err)
+	_, err = NewProposeTask("connID", "", data.CredFilter{}, attrs, "")
+	assert.Error(err)
+}
+
+func TestFillIssueCredentialStatus_trust(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	key := psm.StateKey{DID: "holderDID", Nonce: "untrustedOffer"}
+	try.To(psm.AddRep(&data.IssueCredRep{
+		StateKey:  key,
+		CredDefID: "credDefID",
+		Filter:    data.CredFilter{SchemaID: "schemaID"},
+		Attributes: []didcomm.CredentialAttribute{
+			{Name: "email", Value: "alice@example.com"},
+		},
+		Trust: &trust.Result{Reasons: []string{"issuer isn't trusted"}},
+	}))
+
+	status := fillIssueCredentialStatus(key.DID, key.Nonce, &pb.ProtocolStatus{})
+	attrs := status.GetIssueCredential().GetAttributes().GetAttributes()
+	assert.SLen(attrs, 3)
+	assert.Equal(attrs[0].Name, "email")
+	assert.Equal(attrs[1].Name, trust.AttrIssuerTrusted)
+	assert.Equal(attrs[1].Value, "false")
+	assert.Equal(attrs[2].Name, trust.AttrIssuerTrustReason)
+	assert.Equal(attrs[2].Value, "issuer isn't trusted")
+}
@@ -1,61 +1,190 @@
 package notification
 
 import (
+	"encoding/gob"
+	"fmt"
+	"time"
+
 	"github.com/findy-network/findy-agent/agent/comm"
 	"github.com/findy-network/findy-agent/agent/didcomm"
 	"github.com/findy-network/findy-agent/agent/pltype"
 	"github.com/findy-network/findy-agent/agent/prot"
 	"github.com/findy-network/findy-agent/agent/psm"
+	"github.com/findy-network/findy-agent/agent/utils"
 	"github.com/findy-network/findy-agent/std/common"
+	pb "github.com/findy-network/findy-common-go/grpc/agency/v1"
 	"github.com/golang/glog"
 	"github.com/lainio/err2"
+	"github.com/lainio/err2/assert"
 	"github.com/lainio/err2/try"
 )
 
-var processor = comm.ProtProc{Starter: startProtocol,
+// Report is the problem report we send. ParentThreadID is the thread of the
+// protocol the report is about. If the impact is the thread, our PSM of the
+// protocol fails as well.
+type Report struct {
+	ParentThreadID string
+	Code           string
+	Description    string
+	FixHint        string
+	Impact         string
+	WhoRetries     string
+}
+
+type taskProblemReport struct {
+	comm.TaskBase
+	Report Report
+}
+
+var processor = comm.ProtProc{
+	Creator: createProblemReportTask,
+	Starter: startProtocol,
 	Handlers: map[string]comm.HandlerFunc{
-		pltype.HandlerProblemReport: handleProblemReport,
+		pltype.HandlerProblemReport: prot.HandleProblemReport,
+		pltype.HandlerAck:           handleAck,
 	}}
 
 func init() {
+	gob.Register(&taskProblemReport{})
+	prot.AddCreator(pltype.ProtocolNotification, processor)
+	prot.AddCreator(pltype.ProtocolReportProblem, processor)
 	prot.AddStarter(pltype.CAProblemReport, processor)
 	comm.Proc.Add(pltype.ProtocolNotification, processor)
+	comm.Proc.Add(pltype.ProtocolReportProblem, processor)
+}
+
+// NewTask creates a task to send the problem report to the connection.
+func NewTask(connID string, r Report) (t comm.Task, err error) {
+	if connID == "" {
+		return nil, fmt.Errorf("connection is needed for problem report")
+	}
+	if r.Code == "" {
+		return nil, fmt.Errorf("problem code is needed")
+	}
+	return &taskProblemReport{
+		TaskBase: comm.TaskBase{TaskHeader: comm.TaskHeader{
+			TaskID:       utils.UUID(),
+			TypeID:       pltype.CAProblemReport,
+			ProtocolRole: pb.Protocol_INITIATOR,
+			ConnID:       connID,
+			Method:       utils.Settings.DIDMethod(),
+		}},
+		Report: r,
+	}, nil
+}
+
+func createProblemReportTask(header *comm.TaskHeader, _ *pb.Protocol) (t comm.Task, err error) {
+	return &taskProblemReport{
+		TaskBase: comm.TaskBase{TaskHeader: *header},
+	}, nil
 }
 
 func startProtocol(ca comm.Receiver, t comm.Task) {
-	defer err2.Catch()
+	defer err2.Catch(err2.Err(func(err error) {
+		glog.Error("send problem report: ", err)
+	}))
+
+	task, ok := t.(*taskProblemReport)
+	assert.That(ok)
+	r := task.Report
 
 	try.To(prot.StartPSM(prot.Initial{
-		SendNext:    pltype.BasicMessageSend,
+		SendNext:    pltype.NotificationProblemReport,
 		WaitingNext: pltype.Terminate,
 		Ca:          ca,
 		T:           t,
-		Setup: func(psm.StateKey, didcomm.MessageHdr) error {
-			// todo: fill the report data here
+		Setup: func(_ psm.StateKey, msg didcomm.MessageHdr) error {
+			pr := msg.FieldObj().(*common.ProblemReport)
+			pr.Description = common.Code{Code: r.Code, En: r.Description}
+			if r.FixHint != "" {
+				pr.FixHint = &common.Text{En: r.FixHint}
+			}
+			pr.Impact = r.Impact
+			pr.WhoRetries = r.WhoRetries
+			pr.NoticedTime = time.Now().UTC().Format(time.RFC3339)
+			pr.Thread.PID = r.ParentThreadID
 			return nil
 		},
 	}))
+
+	if r.ParentThreadID == "" || r.Impact != common.ImpactThread {
+		return
+	}
+	key := psm.StateKey{DID: ca.WDID(), Nonce: r.ParentThreadID}
+	m := try.To1(psm.FindPSM(key))
+	if m != nil && m.ConnID == t.ConnectionID() {
+		try.To(prot.FailPSM(key, fmt.Sprintf("problem reported: %s", r.Code)))
+	}
+}
+
+// Cancel cancels our running protocol: the PSM fails with the reason and the
+// other end is notified with the problem report of the abandoned thread. It
+// returns false if the protocol has already ended, and then nothing is sent.
+func Cancel(ca comm.Receiver, protocolID, reason string) (cancelled bool, err error) {
+	defer err2.Handle(&err, "cancel protocol %s", protocolID)
+
+	key := psm.StateKey{DID: ca.WDID(), Nonce: protocolID}
+	if reason == "" {
+		reason = "cancelled by controller"
+	}
+	if !try.To1(prot.CancelPSM(key, reason)) {
+		return false, nil
+	}
+	m := try.To1(psm.GetPSM(key))
+	if m.ConnID == "" {
+		return true, nil
+	}
+	task := try.To1(NewTask(m.ConnID, Report{
+		ParentThreadID: protocolID,
+		Code:           common.CodeAbandoned,
+		Description:    reason,
+		Impact:         common.ImpactThread,
+		WhoRetries:     common.WhoRetriesNone,
+	}))
+	prot.FindAndStartTask(ca, task)
+	return true, nil
 }
 
-func handleProblemReport(packet comm.Packet) (err error) {
-	tHandler := func(_ string, im, _ didcomm.MessageHdr) (ack bool, err error) {
-		defer err2.Handle(&err, "basic message")
+// handleAck handles the acks of Aries RFC 0317 the other end sends when we
+// have asked them with ~please_ack. The receipt ack is pending, and it only
+// tells that the message has arrived. The failed outcome ack tells that the
+// other end couldn't process our message, and our PSM of the thread fails if
+// it's still running.
+func handleAck(packet comm.Packet) (err error) {
+	defer err2.Handle(&err, "handle ack")
 
-		problemReport := im.FieldObj().(*common.ProblemReport)
+	ack, ok := packet.Payload.MsgHdr().FieldObj().(*common.Ack)
+	if !ok {
+		return fmt.Errorf("ack type mismatch: %s", packet.Payload.Type())
+	}
+	threadID := packet.Payload.ThreadID()
 
-		glog.Info("Sent time:", problemReport.ExplainLongTxt)
+	switch ack.Status {
+	case prot.AckStatusPending:
+		glog.V(1).Infof("receipt ack from connection %s for thread %s",
+			packet.Address.ConnID, threadID)
+	case prot.AckStatusFail:
+		glog.Warningf("failed outcome ack from connection %s for thread %s",
+			packet.Address.ConnID, threadID)
+		key := psm.StateKey{DID: packet.Receiver.MyDID().Did(), Nonce: threadID}
+		try.To(failAckedPSM(key, packet.Address.ConnID))
+	default:
+		glog.V(1).Infof("outcome ack (%s) from connection %s for thread %s",
+			ack.Status, packet.Address.ConnID, threadID)
+	}
+	return nil
+}
 
-		//key := psm.StateKey{
-		//	DID:   packet.Receiver.Trans().MessagePipe().In.Did(),
-		//	Nonce: im.Thread().ID,
-		//}
+// failAckedPSM fails the PSM of the failed outcome ack. Like in
+// prot.HandleProblemReport, only the connection of the PSM can fail it.
+func failAckedPSM(key psm.StateKey, connID string) (err error) {
+	defer err2.Handle(&err)
 
-		return true, nil
+	m := try.To1(psm.FindPSM(key))
+	if m == nil || m.ConnID != connID {
+		glog.Warningf("failed outcome ack from connection %s for unknown thread %s",
+			connID, key.Nonce)
+		return nil
 	}
-	return prot.ExecPSM(prot.Transition{
-		Packet:      packet,
-		SendNext:    pltype.Terminate,
-		WaitingNext: pltype.Terminate,
-		InOut:       tHandler,
-	})
+	return prot.FailPSM(key, "other end failed to process the message")
 }
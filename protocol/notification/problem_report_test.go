@@ -0,0 +1,48 @@
+package notification
+
+import (
+	"os"
+	"testing"
+
+	"github.com/findy-network/findy-agent/agent/pltype"
+	"github.com/findy-network/findy-agent/agent/psm"
+	"github.com/lainio/err2/assert"
+	"github.com/lainio/err2/try"
+)
+
+const dbPath = "notification_test.bolt"
+
+func TestMain(m *testing.M) {
+	try.To(psm.Open(dbPath))
+	code := m.Run()
+	psm.Close()
+	os.Remove(dbPath)
+	os.Exit(code)
+}
+
+func TestFailAckedPSM(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	key := psm.StateKey{DID: "agentDID", Nonce: "acked"}
+	try.To(psm.AddPSM(&psm.PSM{
+		Key:         key,
+		ConnID:      "connID",
+		StartedByUs: true,
+		States: []psm.State{
+			{PLInfo: psm.PayloadInfo{Type: pltype.CACredOffer}, Sub: psm.Sending},
+			{PLInfo: psm.PayloadInfo{Type: pltype.CACredOffer}, Sub: psm.Waiting},
+		},
+	}))
+
+	// other connections can't fail our protocol
+	assert.NoError(failAckedPSM(key, "otherConnID"))
+	assert.That(!try.To1(psm.GetPSM(key)).IsReady())
+
+	assert.NoError(failAckedPSM(psm.StateKey{DID: "agentDID", Nonce: "unknown"}, "connID"))
+
+	assert.NoError(failAckedPSM(key, "connID"))
+	m := try.To1(psm.GetPSM(key))
+	assert.That(m.IsReady())
+	assert.Equal(m.Problem(), "other end failed to process the message")
+}
@@ -0,0 +1,92 @@
+package data
+
+import (
+	"encoding/json"
+
+	"github.com/findy-network/findy-agent/agent/comm"
+	"github.com/findy-network/findy-agent/agent/didcomm"
+	"github.com/findy-network/findy-agent/agent/trust"
+	"github.com/findy-network/findy-agent/agent/vc/w3c"
+	v2 "github.com/findy-network/findy-agent/std/presentproof/v2"
+	"github.com/findy-network/findy-common-go/dto"
+	"github.com/golang/glog"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/assert"
+	"github.com/lainio/err2/try"
+)
+
+// DIFRequest returns the DIF request stored to ProofReq.
+func (rep *PresentProofRep) DIFRequest() (req *v2.DIFRequest, err error) {
+	defer err2.Handle(&err, "DIF request")
+
+	assert.Equal(rep.Format, v2.FormatDIFDefinition)
+
+	req = new(v2.DIFRequest)
+	dto.FromJSONStr(rep.ProofReq, req)
+	assert.That(req.PresentationDefinition != nil, "presentation definition missing")
+	return req, nil
+}
+
+// CreatePresentation is PROVER side helper for DIF presentation exchange. It
+// builds the verifiable presentation from the W3C credentials we hold, and
+// signs it with the key of our DID and the challenge of the request.
+func (rep *PresentProofRep) CreatePresentation(packet comm.Packet) (err error) {
+	defer err2.Handle(&err, "create DIF presentation")
+
+	req := try.To1(rep.DIFRequest())
+
+	_, ms := packet.Receiver.ManagedWallet()
+	cs := ms.Storage().CredentialStorage()
+	assert.That(cs != nil, "credential storage not available")
+	creds := try.To1(cs.W3CCredentials())
+
+	var challenge, domain string
+	if req.Options != nil {
+		challenge, domain = req.Options.Challenge, req.Options.Domain
+	}
+	vp := try.To1(w3c.CreateSubmission(req.PresentationDefinition, creds,
+		try.To1(w3c.DIDSigner(packet.Receiver.MyDID())), challenge, domain))
+	rep.Proof = string(try.To1(json.Marshal(vp)))
+	return nil
+}
+
+// VerifyPresentation is VERIFIER side helper for DIF presentation exchange.
+// It checks that the presentation in Proof satisfies the definition, verifies
+// the signatures, and stores the revealed credential subject values to
+// Attributes.
+func (rep *PresentProofRep) VerifyPresentation(packet comm.Packet) (ok bool, err error) {
+	defer err2.Handle(&err, "verify DIF presentation")
+
+	req := try.To1(rep.DIFRequest())
+
+	var challenge string
+	if req.Options != nil {
+		challenge = req.Options.Challenge
+	}
+	_, ms := packet.Receiver.ManagedWallet()
+	reg := ms.Storage().OurPackager().VDRegistry()
+	matched, err := w3c.VerifySubmission(req.PresentationDefinition,
+		[]byte(rep.Proof), reg, challenge)
+	if err != nil {
+		glog.Warningln("presentation verification failed:", err)
+		return false, nil
+	}
+
+	attrs := w3c.SubjectAttributes(matched)
+	rep.Attributes = make([]didcomm.ProofAttribute, 0, len(attrs))
+	issuers := make(map[string]bool)
+	creds := make([]trust.Credential, 0, len(matched))
+	for _, a := range attrs {
+		rep.Attributes = append(rep.Attributes, didcomm.ProofAttribute{
+			ID:    a.DescriptorID,
+			Name:  a.Name,
+			Value: a.Value,
+		})
+		if !issuers[a.Issuer] {
+			issuers[a.Issuer] = true
+			creds = append(creds, trust.Credential{Issuer: a.Issuer})
+		}
+	}
+	rep.checkTrust(packet.Receiver, creds)
+	return true, nil
+}
@@ -0,0 +1,111 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+
+	storage "github.com/findy-network/findy-agent/agent/storage/api"
+	"github.com/findy-network/findy-wrapper-go/anoncreds"
+	"github.com/golang/glog"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/try"
+)
+
+// indexCandidates drops the credentials which are deleted from the credential
+// storage. The anoncreds wallet cannot delete credentials, which is why the
+// storage keeps their deleted records. The credentials which aren't in the
+// storage at all, i.e. the ones received before it, are added to it.
+func indexCandidates(
+	cs storage.CredentialStorage,
+	cands map[string][]anoncreds.CredentialInfo,
+) (
+	_ map[string][]anoncreds.CredentialInfo,
+	err error,
+) {
+	defer err2.Handle(&err, "index candidates")
+
+	if cs == nil {
+		return cands, nil
+	}
+	stored := try.To1(cs.ListCredentials(storage.CredentialFilter{
+		Format:      storage.FormatIndy,
+		WithDeleted: true,
+	}))
+	deleted := make(map[string]bool, len(stored))
+	for _, c := range stored {
+		deleted[c.ID] = c.Deleted
+	}
+	for ref, infos := range cands {
+		usable := make([]anoncreds.CredentialInfo, 0, len(infos))
+		for _, info := range infos {
+			isDeleted, found := deleted[info.Referent]
+			if isDeleted {
+				continue
+			}
+			if !found {
+				glog.V(1).Infoln("indexing wallet credential:", info.Referent)
+				try.To(cs.SaveCredential(IndexedCredential(info)))
+				deleted[info.Referent] = false
+			}
+			usable = append(usable, info)
+		}
+		cands[ref] = usable
+	}
+	return cands, nil
+}
+
+// IndexedCredential returns the credential storage presentation of the
+// credential found from the anoncreds wallet. The wallet doesn't give the
+// credential itself, only its attributes, and the receive time is unknown.
+func IndexedCredential(info anoncreds.CredentialInfo) storage.Credential {
+	return storage.Credential{
+		ID:         info.Referent,
+		Format:     storage.FormatIndy,
+		Issuer:     storage.IndyIssuer(info.CredDefID),
+		SchemaID:   info.SchemaID,
+		CredDefID:  info.CredDefID,
+		Attributes: info.Attrs,
+	}
+}
+
+// IndexWalletCredentials adds the credentials of the anoncreds wallet, which
+// have any of the attributes, to the credential storage. The wallet can be
+// searched only by the attribute names, which is why they are needed. It
+// returns the count of the credentials added.
+func IndexWalletCredentials(
+	w2 int,
+	cs storage.CredentialStorage,
+	names []string,
+) (
+	count int,
+	err error,
+) {
+	defer err2.Handle(&err, "index wallet credentials")
+
+	if len(names) == 0 {
+		return 0, fmt.Errorf("attribute names are needed")
+	}
+	before := try.To1(cs.ListCredentials(storage.CredentialFilter{
+		Format:      storage.FormatIndy,
+		WithDeleted: true,
+	}))
+
+	proofReq := anoncreds.ProofRequest{
+		Name:                "index",
+		Version:             "0.1",
+		Nonce:               "1",
+		RequestedAttributes: make(map[string]anoncreds.AttrInfo, len(names)),
+		RequestedPredicates: map[string]anoncreds.PredicateInfo{},
+	}
+	for i, name := range names {
+		proofReq.RequestedAttributes[fmt.Sprintf("attr%d", i)] = anoncreds.AttrInfo{Name: name}
+	}
+	rep := &PresentProofRep{ProofReq: string(try.To1(json.Marshal(proofReq)))}
+	try.To1(rep.Candidates(w2, cs))
+
+	after := try.To1(cs.ListCredentials(storage.CredentialFilter{
+		Format:      storage.FormatIndy,
+		WithDeleted: true,
+	}))
+	return len(after) - len(before), nil
+}
@@ -0,0 +1,80 @@
+package data
+
+import (
+	"fmt"
+	"testing"
+
+	storage "github.com/findy-network/findy-agent/agent/storage/api"
+	"github.com/findy-network/findy-wrapper-go/anoncreds"
+	"github.com/lainio/err2/assert"
+)
+
+// credStore is the in-memory credential storage of the tests.
+type credStore map[string]storage.Credential
+
+func (s credStore) SaveCredential(cred storage.Credential) error {
+	s[cred.ID] = cred
+	return nil
+}
+
+func (s credStore) GetCredential(id string) (*storage.Credential, error) {
+	c, ok := s[id]
+	if !ok {
+		return nil, fmt.Errorf("not found: %s", id)
+	}
+	return &c, nil
+}
+
+func (s credStore) ListCredentials(filter storage.CredentialFilter) ([]storage.Credential, error) {
+	res := make([]storage.Credential, 0, len(s))
+	for _, c := range s {
+		if filter.Match(c) {
+			res = append(res, c)
+		}
+	}
+	return res, nil
+}
+
+func (s credStore) DeleteCredential(id string) error {
+	delete(s, id)
+	return nil
+}
+
+func (s credStore) W3CCredentials() ([][]byte, error) {
+	return nil, nil
+}
+
+func TestIndexCandidates(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	cs := credStore{
+		"stored":  {ID: "stored", Format: storage.FormatIndy},
+		"deleted": {ID: "deleted", Format: storage.FormatIndy, Deleted: true},
+	}
+	info := func(id string) anoncreds.CredentialInfo {
+		return anoncreds.CredentialInfo{Referent: id, CredDefID: "ISSUER:3:CL:1:T",
+			Attrs: map[string]string{"email": id + "@example.com"}}
+	}
+	cands := map[string][]anoncreds.CredentialInfo{
+		"attr1": {info("stored"), info("deleted"), info("old")},
+		"attr2": {info("old")},
+	}
+
+	cands, err := indexCandidates(cs, cands)
+	assert.NoError(err)
+	assert.SLen(cands["attr1"], 2)
+	assert.Equal(cands["attr1"][0].Referent, "stored")
+	assert.Equal(cands["attr1"][1].Referent, "old")
+	assert.SLen(cands["attr2"], 1)
+
+	old := cs["old"]
+	assert.Equal(old.Issuer, "ISSUER")
+	assert.Equal(old.Attributes["email"], "old@example.com")
+	assert.MLen(cs, 3)
+
+	cands, err = indexCandidates(nil, map[string][]anoncreds.CredentialInfo{
+		"attr1": {info("deleted")}})
+	assert.NoError(err)
+	assert.SLen(cands["attr1"], 1)
+}
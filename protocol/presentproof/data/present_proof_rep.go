@@ -1,9 +1,15 @@
 package data
 
 import (
+	"encoding/json"
+	"fmt"
+
 	"github.com/findy-network/findy-agent/agent/comm"
 	"github.com/findy-network/findy-agent/agent/didcomm"
+	"github.com/findy-network/findy-agent/agent/policy"
 	"github.com/findy-network/findy-agent/agent/psm"
+	storage "github.com/findy-network/findy-agent/agent/storage/api"
+	"github.com/findy-network/findy-agent/agent/trust"
 	"github.com/findy-network/findy-agent/agent/vc"
 	"github.com/findy-network/findy-common-go/dto"
 	"github.com/findy-network/findy-wrapper-go"
@@ -23,6 +29,70 @@ type PresentProofRep struct {
 	Values     []string // TODO: reserved for indy-WQL
 	WeProposed bool
 	Attributes []didcomm.ProofAttribute
+	Predicates []didcomm.ProofPredicate
+
+	// Format is the attachment format of the ProofReq in present proof 2.0.
+	// It's empty for the 1.0 protocol.
+	Format string
+
+	// Selection is the prover's controller's choice for the proof.
+	Selection ProofSelection
+
+	// TemplateID and TemplateVersion are the verification template the
+	// verifier requested the proof with.
+	TemplateID      string
+	TemplateVersion int
+
+	// Trust is the trust registry's check of the proof's issuers. It's nil
+	// if the verifier doesn't have the registry.
+	Trust *trust.Result
+}
+
+// ProofSelection is the prover's choice of the credentials for the referents
+// of the Indy proof request. Credentials are the wallet's credential IDs by
+// the referents, Unrevealed are the attribute referents which values aren't
+// revealed, and SelfAttested are the values of the self-attested attributes.
+// The attribute groups, i.e. the names referents, are always revealed. The
+// credential selection of the policy is used for the other referents.
+type ProofSelection struct {
+	Credentials  map[string]string
+	Unrevealed   []string
+	SelfAttested map[string]string
+}
+
+func (s ProofSelection) revealed(referent string) bool {
+	for _, r := range s.Unrevealed {
+		if r == referent {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate checks that the selection fits the proof request: the unrevealed
+// and the self-attested referents are requested attributes, the attribute
+// groups aren't unrevealed, and the self-attested attributes don't have
+// restrictions.
+func (s ProofSelection) Validate(proofReq anoncreds.ProofRequest) error {
+	for _, ref := range s.Unrevealed {
+		attr, ok := proofReq.RequestedAttributes[ref]
+		if !ok {
+			return fmt.Errorf("unrevealed %s isn't requested attribute", ref)
+		}
+		if len(attr.Names) > 0 {
+			return fmt.Errorf("attribute group %s cannot be unrevealed", ref)
+		}
+	}
+	for ref := range s.SelfAttested {
+		attr, ok := proofReq.RequestedAttributes[ref]
+		if !ok {
+			return fmt.Errorf("self-attested %s isn't requested attribute", ref)
+		}
+		if len(attr.Restrictions) > 0 {
+			return fmt.Errorf("attribute %s with restrictions cannot be self-attested", ref)
+		}
+	}
+	return nil
 }
 
 func init() {
@@ -49,7 +119,8 @@ func (rep *PresentProofRep) Type() byte {
 
 const fetchMax = 2
 
-// CreateProof is PROVER side helper.
+// CreateProof is PROVER side helper. The credentials are the ones of the
+// Selection, or they are selected by the agent's policy.
 func (rep *PresentProofRep) CreateProof(packet comm.Packet, rootDID string) (err error) {
 	defer err2.Handle(&err, "create proof")
 
@@ -62,15 +133,19 @@ func (rep *PresentProofRep) CreateProof(packet comm.Packet, rootDID string) (err
 	var proofReq anoncreds.ProofRequest
 	dto.FromJSONStr(rep.ProofReq, &proofReq)
 
-	reqCred, allCredInfos := rep.processAttributes(w2, proofReq)
+	_, ms := packet.Receiver.ManagedWallet()
+	cs := ms.Storage().CredentialStorage()
+	cands := try.To1(rep.Candidates(w2, cs))
+	reqCred, allCredInfos := try.To2(rep.processAttributes(proofReq, cands,
+		policy.Selection(packet.Receiver), cs))
 	reqCredJSON := dto.ToJSON(reqCred)
 
 	// get schemas and cred defs for all requested attributes from the ledger.
 	foundSchemas := make(map[string]struct{}, len(allCredInfos))
 	foundCredDefs := make(map[string]struct{}, len(allCredInfos))
 	for _, v := range allCredInfos {
-		foundSchemas[v.CredInfo.SchemaID] = struct{}{}
-		foundCredDefs[v.CredInfo.CredDefID] = struct{}{}
+		foundSchemas[v.SchemaID] = struct{}{}
+		foundCredDefs[v.CredDefID] = struct{}{}
 	}
 
 	schemasJSON := try.To1(schemas(rootDID, foundSchemas))
@@ -84,97 +159,150 @@ func (rep *PresentProofRep) CreateProof(packet comm.Packet, rootDID string) (err
 	return nil
 }
 
-func (rep *PresentProofRep) processAttributes(
+// Candidates returns the credentials of the wallet which match the referents
+// of the Indy proof request, i.e. the attributes and the predicates. The
+// credentials deleted from the credential storage aren't candidates.
+func (rep *PresentProofRep) Candidates(
 	w2 int,
-	proofReq anoncreds.ProofRequest,
-) (
-	anoncreds.RequestedCredentials,
-	[]anoncreds.Credentials,
-) {
+	cs storage.CredentialStorage,
+) (cands map[string][]anoncreds.CredentialInfo, err error) {
+	defer err2.Handle(&err, "proof candidates")
+
+	var proofReq anoncreds.ProofRequest
+	try.To(json.Unmarshal([]byte(rep.ProofReq), &proofReq))
+
 	// TODO: build from rep.Values, see Go findy-wrapper-go, anoncreds_test.go
 	wql := findy.NullString
 	r := <-anoncreds.ProverSearchCredentialsForProofReq(w2, rep.ProofReq, wql)
 	try.To(r.Err())
 	searchHandle := r.Handle()
+	defer func() {
+		r := <-anoncreds.ProverCloseCredentialsSearchForProofReq(searchHandle)
+		if r.Err() != nil {
+			glog.Warningln("close credential search:", r.Err())
+		}
+	}()
 
-	reqCred := anoncreds.RequestedCredentials{
-		SelfAttestedAttributes: make(map[string]string),
-		RequestedAttributes:    make(map[string]anoncreds.RequestedAttrObject),
-		RequestedPredicates:    make(map[string]anoncreds.RequestedPredObject),
+	referents := make([]string, 0,
+		len(proofReq.RequestedAttributes)+len(proofReq.RequestedPredicates))
+	for attrRef := range proofReq.RequestedAttributes {
+		referents = append(referents, attrRef)
+	}
+	for predicateRef := range proofReq.RequestedPredicates {
+		referents = append(referents, predicateRef)
 	}
 
-	allCredInfos := make([]anoncreds.Credentials, 0, fetchMax)
-
-	// gather cred infos for requested attributes.
-	for attrRef, aInfo := range proofReq.RequestedAttributes {
-		foundAlready := false
+	cands = make(map[string][]anoncreds.CredentialInfo, len(referents))
+	for _, ref := range referents {
+		infos := make([]anoncreds.CredentialInfo, 0, fetchMax)
 		for {
 			r = <-anoncreds.ProverFetchCredentialsForProofReq(searchHandle,
-				attrRef, fetchMax)
+				ref, fetchMax)
 			try.To(r.Err())
-			credentials := r.Str1()
 			credInfo := make([]anoncreds.Credentials, 0, fetchMax)
-			dto.FromJSONStr(credentials, &credInfo)
-			allCredInfos = append(allCredInfos, credInfo...)
-
-			// we add the first found cred info of the patch to requested
-			// attribute we are processing
-			if len(credInfo) > 0 {
-				foundAlready = true
-				obj := anoncreds.RequestedAttrObject{
-					CredID:    credInfo[0].CredInfo.Referent,
-					Revealed:  true,
-					Timestamp: nil,
-				}
-				reqCred.RequestedAttributes[attrRef] = obj
+			dto.FromJSONStr(r.Str1(), &credInfo)
+			for _, c := range credInfo {
+				infos = append(infos, c.CredInfo)
 			}
 
 			if len(credInfo) == fetchMax {
-				glog.V(1).Info("--- There's more cred infos for attr ---")
+				glog.V(1).Info("--- There's more cred infos for ", ref)
 				continue
 			}
 			break
 		}
-		selfAttestedNeedsToBeSet := !foundAlready && len(aInfo.Restrictions) == 0
+		cands[ref] = infos
+	}
+	return indexCandidates(cs, cands)
+}
+
+// processAttributes returns the requested credentials of the proof from the
+// candidates by the referents, and the infos of the credentials used.
+func (rep *PresentProofRep) processAttributes(
+	proofReq anoncreds.ProofRequest,
+	cands map[string][]anoncreds.CredentialInfo,
+	sel storage.CredSelection,
+	cs storage.CredentialStorage,
+) (
+	_ anoncreds.RequestedCredentials,
+	_ []anoncreds.CredentialInfo,
+	err error,
+) {
+	defer err2.Handle(&err)
+
+	try.To(rep.Selection.Validate(proofReq))
 
-		if selfAttestedNeedsToBeSet {
+	reqCred := anoncreds.RequestedCredentials{
+		SelfAttestedAttributes: make(map[string]string),
+		RequestedAttributes:    make(map[string]anoncreds.RequestedAttrObject),
+		RequestedPredicates:    make(map[string]anoncreds.RequestedPredObject),
+	}
+
+	allCredInfos := make([]anoncreds.CredentialInfo, 0, len(cands))
+
+	for attrRef, aInfo := range proofReq.RequestedAttributes {
+		if value, ok := rep.Selection.SelfAttested[attrRef]; ok {
+			reqCred.SelfAttestedAttributes[attrRef] = value
+			continue
+		}
+		info, found := try.To2(rep.selectCred(attrRef, cands[attrRef], sel, cs))
+		if found {
+			allCredInfos = append(allCredInfos, info)
+			reqCred.RequestedAttributes[attrRef] = anoncreds.RequestedAttrObject{
+				CredID:    info.Referent,
+				Revealed:  rep.Selection.revealed(attrRef),
+				Timestamp: nil,
+			}
+		} else if len(aInfo.Restrictions) == 0 {
 			glog.V(1).Info("Self attested attr:", aInfo.Name)
 			reqCred.SelfAttestedAttributes[attrRef] = "my self-attested value"
 		}
 	}
 
-	// gather cred infos for predicated attributes
 	for predicateRef := range proofReq.RequestedPredicates {
-		for {
-			r = <-anoncreds.ProverFetchCredentialsForProofReq(searchHandle,
-				predicateRef, fetchMax)
-			try.To(r.Err())
-			credentials := r.Str1()
-			credInfo := make([]anoncreds.Credentials, 0, fetchMax)
-			dto.FromJSONStr(credentials, &credInfo)
-			allCredInfos = append(allCredInfos, credInfo...)
-
-			// we add the first found cred info of the patch to requested
-			// attribute we are processing
-			if len(credInfo) > 0 {
-				obj := anoncreds.RequestedPredObject{
-					CredID:    credInfo[0].CredInfo.Referent,
-					Timestamp: nil,
-				}
-				reqCred.RequestedPredicates[predicateRef] = obj
+		info, found := try.To2(rep.selectCred(predicateRef, cands[predicateRef], sel, cs))
+		if found {
+			allCredInfos = append(allCredInfos, info)
+			reqCred.RequestedPredicates[predicateRef] = anoncreds.RequestedPredObject{
+				CredID:    info.Referent,
+				Timestamp: nil,
 			}
+		}
+	}
+	return reqCred, allCredInfos, nil
+}
 
-			if len(credInfo) == fetchMax {
-				glog.V(1).Info("--- There's more pred infos for attr ---")
-				continue
+// selectCred returns the credential for the referent: the one the controller
+// has selected, or the one the selection policy prefers.
+func (rep *PresentProofRep) selectCred(
+	ref string,
+	cands []anoncreds.CredentialInfo,
+	sel storage.CredSelection,
+	cs storage.CredentialStorage,
+) (info anoncreds.CredentialInfo, found bool, err error) {
+	if id, ok := rep.Selection.Credentials[ref]; ok {
+		for _, c := range cands {
+			if c.Referent == id {
+				return c, true, nil
 			}
-			break
 		}
+		return info, false, fmt.Errorf("credential %s doesn't match %s", id, ref)
 	}
 
-	r = <-anoncreds.ProverCloseCredentialsSearchForProofReq(searchHandle)
-	try.To(r.Err())
-	return reqCred, allCredInfos
+	creds := make([]storage.Credential, len(cands))
+	for i, c := range cands {
+		creds[i] = storage.Credential{ID: c.Referent, Issuer: storage.IndyIssuer(c.CredDefID)}
+		if cs == nil {
+			continue
+		}
+		if stored, _ := cs.GetCredential(c.Referent); stored != nil {
+			creds[i].Created = stored.Created
+		}
+	}
+	if i := sel.Select(creds); i >= 0 {
+		return cands[i], true, nil
+	}
+	return info, false, nil
 }
 
 func credDefs(DID string, credDefIDs map[string]struct{}) (cJSON string, err error) {
@@ -221,6 +349,9 @@ func (rep *PresentProofRep) VerifyProof(packet comm.Packet) (ack bool, err error
 
 	r := <-anoncreds.VerifierVerifyProof(rep.ProofReq, rep.Proof, schemasJSON, credDefsJSON, "{}", "{}")
 	try.To(r.Err())
+	if r.Yes() {
+		rep.checkTrust(packet.Receiver, indyTrustCredentials(proof.Identifiers))
+	}
 	return r.Yes(), nil
 }
 
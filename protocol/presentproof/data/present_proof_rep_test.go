@@ -0,0 +1,108 @@
+package data
+
+import (
+	"testing"
+
+	storage "github.com/findy-network/findy-agent/agent/storage/api"
+	"github.com/findy-network/findy-wrapper-go/anoncreds"
+	"github.com/lainio/err2/assert"
+)
+
+func testProofReq() anoncreds.ProofRequest {
+	employer := []anoncreds.Filter{{CredDefID: "EMPLOYER:3:CL:1:T"}}
+	return anoncreds.ProofRequest{
+		RequestedAttributes: map[string]anoncreds.AttrInfo{
+			"title":    {Name: "title", Restrictions: employer},
+			"address":  {Names: []string{"street", "city"}, Restrictions: employer},
+			"nickname": {Name: "nickname"},
+			"motto":    {Name: "motto"},
+		},
+		RequestedPredicates: map[string]anoncreds.PredicateInfo{
+			"salary": {Name: "salary", PType: ">=", PValue: 5000, Restrictions: employer},
+		},
+	}
+}
+
+func TestProofSelection_Validate(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	proofReq := testProofReq()
+	assert.NoError(ProofSelection{
+		Unrevealed:   []string{"title"},
+		SelfAttested: map[string]string{"nickname": "Al"},
+	}.Validate(proofReq))
+
+	tests := []struct {
+		name string
+		sel  ProofSelection
+	}{
+		{"unrevealed group", ProofSelection{Unrevealed: []string{"address"}}},
+		{"unrevealed predicate", ProofSelection{Unrevealed: []string{"salary"}}},
+		{"unrevealed unknown", ProofSelection{Unrevealed: []string{"unknown"}}},
+		{"restricted self-attested",
+			ProofSelection{SelfAttested: map[string]string{"title": "CEO"}}},
+		{"unknown self-attested",
+			ProofSelection{SelfAttested: map[string]string{"unknown": "value"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.PushTester(t)
+			defer assert.PopTester()
+
+			assert.Error(tt.sel.Validate(proofReq))
+		})
+	}
+}
+
+func TestPresentProofRep_processAttributes(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	info := func(id, issuer string) anoncreds.CredentialInfo {
+		return anoncreds.CredentialInfo{Referent: id, CredDefID: issuer + ":3:CL:1:T"}
+	}
+	cands := map[string][]anoncreds.CredentialInfo{
+		"title":    {info("old", "EMPLOYER"), info("new", "EMPLOYER")},
+		"address":  {info("other", "OTHER"), info("old", "EMPLOYER")},
+		"nickname": {},
+		"motto":    {},
+		"salary":   {info("old", "EMPLOYER")},
+	}
+	sel := storage.CredSelection{Issuers: []string{"EMPLOYER"}}
+	rep := &PresentProofRep{Selection: ProofSelection{
+		Credentials:  map[string]string{"title": "new"},
+		Unrevealed:   []string{"title"},
+		SelfAttested: map[string]string{"nickname": "Al"},
+	}}
+
+	reqCred, infos, err := rep.processAttributes(testProofReq(), cands, sel, nil)
+	assert.NoError(err)
+	assert.SLen(infos, 3)
+
+	assert.MLen(reqCred.RequestedAttributes, 2)
+	title := reqCred.RequestedAttributes["title"]
+	assert.Equal(title.CredID, "new") // the controller's selection
+	assert.That(!title.Revealed)
+	address := reqCred.RequestedAttributes["address"]
+	assert.Equal(address.CredID, "old") // the policy prefers the issuer
+	assert.That(address.Revealed)
+
+	assert.MLen(reqCred.SelfAttestedAttributes, 2)
+	assert.Equal(reqCred.SelfAttestedAttributes["nickname"], "Al")
+	assert.NotEmpty(reqCred.SelfAttestedAttributes["motto"])
+
+	assert.MLen(reqCred.RequestedPredicates, 1)
+	assert.Equal(reqCred.RequestedPredicates["salary"].CredID, "old")
+
+	// the selected credential must be the candidate
+	rep.Selection.Credentials["title"] = "other"
+	_, _, err = rep.processAttributes(testProofReq(), cands, sel, nil)
+	assert.Error(err)
+
+	// the attribute group is always revealed
+	rep.Selection.Credentials["title"] = "new"
+	rep.Selection.Unrevealed = []string{"address"}
+	_, _, err = rep.processAttributes(testProofReq(), cands, sel, nil)
+	assert.Error(err)
+}
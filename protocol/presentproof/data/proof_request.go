@@ -0,0 +1,191 @@
+package data
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/findy-network/findy-agent/agent/didcomm"
+	"github.com/findy-network/findy-agent/agent/utils"
+	"github.com/findy-network/findy-common-go/dto"
+	"github.com/findy-network/findy-wrapper-go/anoncreds"
+)
+
+// ProofRequest is the Indy proof request we send as a verifier. It's like
+// anoncreds.ProofRequest, but it has the full restriction set: the filters
+// are WQL queries which can have the schema version and the attribute
+// markers as well.
+type ProofRequest struct {
+	Name                string                   `json:"name"`
+	Version             string                   `json:"version"`
+	Nonce               string                   `json:"nonce"`
+	RequestedAttributes map[string]AttrInfo      `json:"requested_attributes"`
+	RequestedPredicates map[string]PredicateInfo `json:"requested_predicates"`
+}
+
+// AttrInfo is the requested attribute. It has either the name or the names.
+type AttrInfo struct {
+	Name         string                      `json:"name,omitempty"`
+	Names        []string                    `json:"names,omitempty"`
+	Restrictions []Filter                    `json:"restrictions,omitempty"`
+	NonRevoked   *anoncreds.NonRevocInterval `json:"non_revoked,omitempty"`
+}
+
+// PredicateInfo is the requested predicate.
+type PredicateInfo struct {
+	Name         string                      `json:"name"`
+	PType        string                      `json:"p_type"`
+	PValue       int                         `json:"p_value"`
+	Restrictions []Filter                    `json:"restrictions,omitempty"`
+	NonRevoked   *anoncreds.NonRevocInterval `json:"non_revoked,omitempty"`
+}
+
+// Filter is the WQL query of the restriction. The filters of the attribute
+// are alternatives.
+type Filter map[string]string
+
+// NewFilter returns the WQL query of the restriction.
+func NewFilter(r didcomm.ProofRestriction) Filter {
+	f := make(Filter)
+	set := func(key, value string) {
+		if value != "" {
+			f[key] = value
+		}
+	}
+	set("schema_id", r.SchemaID)
+	set("schema_issuer_did", r.SchemaIssuerDID)
+	set("schema_name", r.SchemaName)
+	set("schema_version", r.SchemaVersion)
+	set("issuer_did", r.IssuerDID)
+	set("cred_def_id", r.CredDefID)
+	for name, value := range r.AttrValues {
+		f["attr::"+name+"::value"] = value
+	}
+	for _, name := range r.AttrMarkers {
+		f["attr::"+name+"::marker"] = "1"
+	}
+	return f
+}
+
+// NewProofRequest builds the Indy proof request of the attributes and the
+// predicates. The referents are their IDs if given.
+func NewProofRequest(attrs []didcomm.ProofAttribute, preds []didcomm.ProofPredicate) *ProofRequest {
+	reqAttrs := make(map[string]AttrInfo, len(attrs))
+	for index, attr := range attrs {
+		id := "attr_referent_" + strconv.Itoa(index+1)
+		if attr.ID != "" {
+			id = attr.ID
+		}
+		info := AttrInfo{
+			Restrictions: filters(attr.CredDefID, attr.Restrictions),
+			NonRevoked:   nonRevoked(attr.NonRevoked),
+		}
+		if len(attr.Names) > 0 {
+			info.Names = attr.Names
+		} else {
+			info.Name = attr.Name
+		}
+		reqAttrs[id] = info
+	}
+	reqPredicates := make(map[string]PredicateInfo, len(preds))
+	for index, predicate := range preds {
+		id := "predicate_" + strconv.Itoa(index+1)
+		if predicate.ID != "" {
+			id = predicate.ID
+		}
+		reqPredicates[id] = PredicateInfo{
+			Name:         predicate.Name,
+			PType:        predicate.PType,
+			PValue:       int(predicate.PValue),
+			Restrictions: filters(predicate.CredDefID, predicate.Restrictions),
+			NonRevoked:   nonRevoked(predicate.NonRevoked),
+		}
+	}
+	return &ProofRequest{
+		Name:                "ProofReq",
+		Version:             "0.1",
+		Nonce:               utils.NewNonceStr(),
+		RequestedAttributes: reqAttrs,
+		RequestedPredicates: reqPredicates,
+	}
+}
+
+// filters returns the filters of the restrictions. The cred def ID is the
+// only restriction if there are no others.
+func filters(credDefID string, restrictions []didcomm.ProofRestriction) []Filter {
+	if len(restrictions) == 0 && credDefID != "" {
+		restrictions = []didcomm.ProofRestriction{{CredDefID: credDefID}}
+	}
+	res := make([]Filter, 0, len(restrictions))
+	for _, r := range restrictions {
+		res = append(res, NewFilter(r))
+	}
+	return res
+}
+
+func nonRevoked(i *didcomm.NonRevocInterval) *anoncreds.NonRevocInterval {
+	if i == nil {
+		return nil
+	}
+	return &anoncreds.NonRevocInterval{From: int(i.From), To: int(i.To)}
+}
+
+// indyProof is the part of the Indy proof we read the values from.
+// anoncreds.Proof doesn't have the attribute groups.
+type indyProof struct {
+	RequestedProof struct {
+		RevealedAttrs      map[string]revealedAttr `json:"revealed_attrs"`
+		RevealedAttrGroups map[string]struct {
+			SubProofIndex int                     `json:"sub_proof_index"`
+			Values        map[string]revealedAttr `json:"values"`
+		} `json:"revealed_attr_groups"`
+		SelfAttestedAttrs map[string]string `json:"self_attested_attrs"`
+		Predicates        map[string]struct {
+			SubProofIndex int `json:"sub_proof_index"`
+		} `json:"predicates"`
+	} `json:"requested_proof"`
+	Identifiers []anoncreds.IdentifiersObj `json:"identifiers"`
+}
+
+type revealedAttr struct {
+	SubProofIndex int    `json:"sub_proof_index"`
+	Raw           string `json:"raw"`
+}
+
+// StoreProofValues sets the values and the identifiers of the Indy proof to
+// the rep's attributes and predicates. The attributes of the group have the
+// IDs of the group referent and the index, see preview.StoreProofData.
+func (rep *PresentProofRep) StoreProofValues(proofData []byte) {
+	var proof indyProof
+	dto.FromJSON(proofData, &proof)
+
+	identifiers := func(index int) (schemaID, credDefID string) {
+		if index < 0 || index >= len(proof.Identifiers) {
+			return "", ""
+		}
+		return proof.Identifiers[index].SchemaID, proof.Identifiers[index].CredDefID
+	}
+	requested := proof.RequestedProof
+	for i, attr := range rep.Attributes {
+		a := &rep.Attributes[i]
+		if v, ok := requested.RevealedAttrs[attr.ID]; ok {
+			a.Value = v.Raw
+			a.SchemaID, a.CredDefID = identifiers(v.SubProofIndex)
+		} else if v, ok := requested.SelfAttestedAttrs[attr.ID]; ok {
+			a.Value = v
+			a.SelfAttested = true
+		} else if sep := strings.LastIndex(attr.ID, "_"); sep > 0 {
+			group, ok := requested.RevealedAttrGroups[attr.ID[:sep]]
+			if !ok {
+				continue
+			}
+			a.Value = group.Values[attr.Name].Raw
+			a.SchemaID, a.CredDefID = identifiers(group.SubProofIndex)
+		}
+	}
+	for i, pred := range rep.Predicates {
+		if v, ok := requested.Predicates[pred.ID]; ok {
+			p := &rep.Predicates[i]
+			p.SchemaID, p.CredDefID = identifiers(v.SubProofIndex)
+		}
+	}
+}
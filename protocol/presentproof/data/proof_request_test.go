@@ -0,0 +1,102 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/findy-network/findy-agent/agent/didcomm"
+	"github.com/findy-network/findy-common-go/dto"
+	"github.com/lainio/err2/assert"
+)
+
+func TestNewProofRequest(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	req := NewProofRequest([]didcomm.ProofAttribute{
+		{Name: "email", CredDefID: "cred-def-1"},
+		{ID: "account", Names: []string{"iban", "bic"}, Restrictions: []didcomm.ProofRestriction{
+			{IssuerDID: "bank1"},
+			{IssuerDID: "bank2", SchemaName: "account", SchemaVersion: "1.0"},
+			{IssuerDID: "bank3", AttrValues: map[string]string{"country": "FI"},
+				AttrMarkers: []string{"iban"}},
+		}, NonRevoked: &didcomm.NonRevocInterval{To: 1700000000}},
+		{ID: "nickname", Name: "nickname"},
+	}, []didcomm.ProofPredicate{
+		{Name: "age", PType: ">=", PValue: 18,
+			Restrictions: []didcomm.ProofRestriction{{SchemaID: "schema-1"}}},
+	})
+
+	email := req.RequestedAttributes["attr_referent_1"]
+	assert.Equal(email.Name, "email")
+	assert.SLen(email.Restrictions, 1)
+	assert.Equal(email.Restrictions[0]["cred_def_id"], "cred-def-1")
+	assert.That(email.NonRevoked == nil)
+
+	account := req.RequestedAttributes["account"]
+	assert.Equal(account.Name, "")
+	assert.SLen(account.Names, 2)
+	assert.SLen(account.Restrictions, 3)
+	assert.DeepEqual(account.Restrictions[1], Filter{"issuer_did": "bank2",
+		"schema_name": "account", "schema_version": "1.0"})
+	assert.DeepEqual(account.Restrictions[2], Filter{"issuer_did": "bank3",
+		"attr::country::value": "FI", "attr::iban::marker": "1"})
+	assert.Equal(account.NonRevoked.To, 1700000000)
+
+	selfAttested := req.RequestedAttributes["nickname"]
+	assert.SLen(selfAttested.Restrictions, 0)
+
+	age := req.RequestedPredicates["predicate_1"]
+	assert.Equal(age.PValue, 18)
+	assert.Equal(age.Restrictions[0]["schema_id"], "schema-1")
+
+	// the name isn't sent with the names
+	var raw struct {
+		RequestedAttributes map[string]map[string]interface{} `json:"requested_attributes"`
+	}
+	dto.FromJSONStr(dto.ToJSON(req), &raw)
+	_, hasName := raw.RequestedAttributes["account"]["name"]
+	assert.That(!hasName)
+}
+
+const testProof = `{
+  "requested_proof": {
+    "revealed_attrs": {"attr_referent_1": {"sub_proof_index": 0, "raw": "a@b.c"}},
+    "revealed_attr_groups": {"account": {"sub_proof_index": 1,
+      "values": {"iban": {"raw": "FI123"}, "bic": {"raw": "BANKFIHH"}}}},
+    "self_attested_attrs": {"nickname": "neo"},
+    "predicates": {"predicate_1": {"sub_proof_index": 1}}
+  },
+  "identifiers": [
+    {"schema_id": "schema-0", "cred_def_id": "cred-def-0"},
+    {"schema_id": "schema-1", "cred_def_id": "cred-def-1"}
+  ]
+}`
+
+func TestStoreProofValues(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	rep := &PresentProofRep{
+		Attributes: []didcomm.ProofAttribute{
+			{ID: "attr_referent_1", Name: "email"},
+			{ID: "account_0", Name: "iban"},
+			{ID: "account_1", Name: "bic"},
+			{ID: "nickname", Name: "nickname"},
+		},
+		Predicates: []didcomm.ProofPredicate{
+			{ID: "predicate_1", Name: "age", PType: ">=", PValue: 18},
+		},
+	}
+	rep.StoreProofValues([]byte(testProof))
+
+	assert.Equal(rep.Attributes[0].Value, "a@b.c")
+	assert.Equal(rep.Attributes[0].CredDefID, "cred-def-0")
+	assert.Equal(rep.Attributes[1].Value, "FI123")
+	assert.Equal(rep.Attributes[2].Value, "BANKFIHH")
+	assert.Equal(rep.Attributes[2].SchemaID, "schema-1")
+	assert.Equal(rep.Attributes[3].Value, "neo")
+	assert.That(rep.Attributes[3].SelfAttested)
+	assert.Equal(rep.Predicates[0].CredDefID, "cred-def-1")
+	assert.Equal(rep.Predicates[0].PType, ">=")
+	assert.Equal(rep.Predicates[0].PValue, int64(18))
+}
@@ -0,0 +1,32 @@
+package data
+
+import (
+	"github.com/findy-network/findy-agent/agent/comm"
+	storage "github.com/findy-network/findy-agent/agent/storage/api"
+	"github.com/findy-network/findy-agent/agent/trust"
+	"github.com/findy-network/findy-wrapper-go/anoncreds"
+)
+
+// Untrusted tells if the trust registry doesn't trust the issuers of the
+// verified proof.
+func (rep *PresentProofRep) Untrusted() bool {
+	return rep.Trust != nil && !rep.Trust.Trusted
+}
+
+// checkTrust checks the issuers of the proof against the CA's trust registry.
+// The Trust stays nil if the CA doesn't have the registry. The broken
+// governance file doesn't trust anyone.
+func (rep *PresentProofRep) checkTrust(r comm.Receiver, creds []trust.Credential) {
+	rep.Trust = trust.CheckCA(r.MyCA().MyDID().Did(), creds)
+}
+
+func indyTrustCredentials(identifiers []anoncreds.IdentifiersObj) []trust.Credential {
+	creds := make([]trust.Credential, 0, len(identifiers))
+	for _, id := range identifiers {
+		creds = append(creds, trust.Credential{
+			Issuer:   storage.IndyIssuer(id.CredDefID),
+			SchemaID: id.SchemaID,
+		})
+	}
+	return creds
+}
@@ -3,9 +3,11 @@ package preview
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/findy-network/findy-agent/agent/didcomm"
 	"github.com/findy-network/findy-agent/protocol/presentproof/data"
+	v2 "github.com/findy-network/findy-agent/std/presentproof/v2"
 	"github.com/findy-network/findy-common-go/dto"
 	"github.com/findy-network/findy-wrapper-go/anoncreds"
 )
@@ -37,4 +39,53 @@ func StoreProofData(requestData []byte, rep *data.PresentProofRep) {
 			}
 		}
 	}
+	rep.Predicates = make([]didcomm.ProofPredicate, 0, len(proofReq.RequestedPredicates))
+	for id, pred := range proofReq.RequestedPredicates {
+		rep.Predicates = append(rep.Predicates, didcomm.ProofPredicate{
+			ID:     id,
+			Name:   pred.Name,
+			PType:  pred.PType,
+			PValue: int64(pred.PValue),
+		})
+	}
+}
+
+// StoreDefinitionData stores the fields which DIF presentation definition
+// requests as attributes. The attribute name is the last element of the
+// field's first JSON path.
+func StoreDefinitionData(requestData []byte, rep *data.PresentProofRep) {
+	var req v2.DIFRequest
+	dto.FromJSON(requestData, &req)
+	rep.Attributes = make([]didcomm.ProofAttribute, 0)
+	if req.PresentationDefinition == nil {
+		return
+	}
+	for _, desc := range req.PresentationDefinition.InputDescriptors {
+		if desc.Constraints == nil {
+			continue
+		}
+		for _, field := range desc.Constraints.Fields {
+			if len(field.Path) == 0 {
+				continue
+			}
+			path := field.Path[0]
+			rep.Attributes = append(
+				rep.Attributes,
+				didcomm.ProofAttribute{
+					ID:   desc.ID,
+					Name: path[strings.LastIndex(path, ".")+1:],
+				},
+			)
+		}
+	}
+}
+
+// StoreRequestData stores the attributes of the present proof 2.0 request
+// according to its format.
+func StoreRequestData(format string, requestData []byte, rep *data.PresentProofRep) {
+	if format == v2.FormatDIFDefinition {
+		StoreDefinitionData(requestData, rep)
+	} else {
+		StoreProofData(requestData, rep)
+	}
 }
@@ -3,6 +3,7 @@ package presentproof
 
 import (
 	"encoding/gob"
+	"fmt"
 	"strconv"
 
 	"github.com/findy-network/findy-agent/agent/comm"
@@ -10,14 +11,15 @@ import (
 	"github.com/findy-network/findy-agent/agent/pltype"
 	"github.com/findy-network/findy-agent/agent/prot"
 	"github.com/findy-network/findy-agent/agent/psm"
+	"github.com/findy-network/findy-agent/agent/trust"
 	"github.com/findy-network/findy-agent/agent/utils"
 	"github.com/findy-network/findy-agent/protocol/presentproof/data"
 	"github.com/findy-network/findy-agent/protocol/presentproof/prover"
+	"github.com/findy-network/findy-agent/protocol/presentproof/template"
 	"github.com/findy-network/findy-agent/protocol/presentproof/verifier"
 	"github.com/findy-network/findy-agent/std/presentproof"
 	"github.com/findy-network/findy-common-go/dto"
 	pb "github.com/findy-network/findy-common-go/grpc/agency/v1"
-	"github.com/findy-network/findy-wrapper-go/anoncreds"
 	"github.com/golang/glog"
 	"github.com/lainio/err2"
 	"github.com/lainio/err2/assert"
@@ -29,6 +31,15 @@ type taskPresentProof struct {
 	Comment         string
 	ProofAttrs      []didcomm.ProofAttribute
 	ProofPredicates []didcomm.ProofPredicate
+
+	// Format and ProofRequest are set for present proof 2.0 tasks.
+	Format       string
+	ProofRequest string
+
+	// TemplateID and TemplateVersion are set when the proof is requested
+	// with the verification template.
+	TemplateID      string
+	TemplateVersion int
 }
 
 type continuatorFunc func(ca comm.Receiver, im didcomm.Msg)
@@ -38,11 +49,15 @@ var presentProofProcessor = comm.ProtProc{
 	Starter:     startProofProtocol,
 	Continuator: continueProtocol,
 	Handlers: map[string]comm.HandlerFunc{
-		pltype.HandlerPresentProofPropose:      verifier.HandleProposePresentation,
-		pltype.HandlerPresentProofRequest:      prover.HandleRequestPresentation,
-		pltype.HandlerPresentProofPresentation: verifier.HandlePresentation,
-		pltype.HandlerPresentProofACK:          handleProofACK,
-		pltype.HandlerPresentProofNACK:         handleProofNACK,
+		pltype.HandlerPresentProofPropose: versioned(
+			verifier.HandleProposePresentation, verifier.HandleProposePresentationV2),
+		pltype.HandlerPresentProofRequest: versioned(
+			prover.HandleRequestPresentation, prover.HandleRequestPresentationV2),
+		pltype.HandlerPresentProofPresentation: versioned(
+			verifier.HandlePresentation, verifier.HandlePresentationV2),
+		pltype.HandlerPresentProofACK:  handleProofACK,
+		pltype.HandlerPresentProofNACK: handleProofNACK,
+		pltype.HandlerProblemReport:    prot.HandleProblemReport,
 	},
 	FillStatus: fillPresentProofStatus,
 }
@@ -62,6 +77,7 @@ func createPresentProofTask(header *comm.TaskHeader, protocol *pb.Protocol) (t c
 
 	var proofAttrs []didcomm.ProofAttribute
 	var proofPredicates []didcomm.ProofPredicate
+	var format, proofRequest string
 	if protocol != nil {
 		proof := protocol.GetPresentProof()
 		assert.That(proof != nil, "present proof data missing")
@@ -70,7 +86,13 @@ func createPresentProofTask(header *comm.TaskHeader, protocol *pb.Protocol) (t c
 			"role is needed for proof protocol")
 
 		// attributes - mandatory
-		if proof.GetAttributesJSON() != "" {
+		if isJSONObject(proof.GetAttributesJSON()) {
+			// TODO: gRPC API change. A JSON object in AttributesJSON is
+			// either a presentation definition or a full proof request,
+			// and we use present proof 2.0 for it.
+			format, proofRequest = try.To2(proofRequestV2(proof.GetAttributesJSON()))
+			glog.V(3).Infoln("set proof request v2 from json, format:", format)
+		} else if proof.GetAttributesJSON() != "" {
 			dto.FromJSONStr(proof.GetAttributesJSON(), &proofAttrs)
 			glog.V(3).Infoln("set proof attrs from json:", proof.GetAttributesJSON())
 		} else {
@@ -114,47 +136,31 @@ func createPresentProofTask(header *comm.TaskHeader, protocol *pb.Protocol) (t c
 		TaskBase:        comm.TaskBase{TaskHeader: *header},
 		ProofAttrs:      proofAttrs,
 		ProofPredicates: proofPredicates,
+		Format:          format,
+		ProofRequest:    proofRequest,
 	}, nil
 }
 
-func generateProofRequest(proofTask *taskPresentProof) *anoncreds.ProofRequest {
-	reqAttrs := make(map[string]anoncreds.AttrInfo)
-	for index, attr := range proofTask.ProofAttrs {
-		restrictions := make([]anoncreds.Filter, 0)
-		if attr.CredDefID != "" {
-			restrictions = append(restrictions, anoncreds.Filter{CredDefID: attr.CredDefID})
-		}
-		id := "attr_referent_" + strconv.Itoa(index+1)
-		if attr.ID != "" {
-			id = attr.ID
-		}
-		reqAttrs[id] = anoncreds.AttrInfo{
-			Name:         attr.Name,
-			Restrictions: restrictions,
-		}
-	}
-	reqPredicates := make(map[string]anoncreds.PredicateInfo)
-	if proofTask.ProofPredicates != nil {
-		for index, predicate := range proofTask.ProofPredicates {
-			// TODO: restrictions
-			id := "predicate_" + strconv.Itoa(index+1)
-			if predicate.ID != "" {
-				id = predicate.ID
-			}
-			reqPredicates[id] = anoncreds.PredicateInfo{
-				Name:   predicate.Name,
-				PType:  predicate.PType,
-				PValue: int(predicate.PValue),
-			}
-		}
-	}
-	return &anoncreds.ProofRequest{
-		Name:                "ProofReq",
-		Version:             "0.1",
-		Nonce:               utils.NewNonceStr(),
-		RequestedAttributes: reqAttrs,
-		RequestedPredicates: reqPredicates,
+// NewTemplateRequestTask returns the task which requests the proof of the
+// verification template from the connection.
+func NewTemplateRequestTask(connID string, t *template.Template) (task comm.Task, err error) {
+	if connID == "" {
+		return nil, fmt.Errorf("connection is needed for proof request")
 	}
+	attrs, preds := t.Request()
+	return &taskPresentProof{
+		TaskBase: comm.TaskBase{TaskHeader: comm.TaskHeader{
+			TaskID:       utils.UUID(),
+			TypeID:       pltype.CAProofRequest,
+			ProtocolRole: pb.Protocol_INITIATOR,
+			ConnID:       connID,
+			Method:       utils.Settings.DIDMethod(),
+		}},
+		ProofAttrs:      attrs,
+		ProofPredicates: preds,
+		TemplateID:      t.ID,
+		TemplateVersion: t.Version,
+	}, nil
 }
 
 func startProofProtocol(ca comm.Receiver, t comm.Task) {
@@ -163,6 +169,11 @@ func startProofProtocol(ca comm.Receiver, t comm.Task) {
 	proofTask, ok := t.(*taskPresentProof)
 	assert.That(ok)
 
+	if proofTask.Format != "" {
+		startProofProtocolV2(ca, proofTask)
+		return
+	}
+
 	switch t.Type() {
 	case pltype.CAProofPropose: // ----- prover will start -----
 		try.To(prot.StartPSM(prot.Initial{
@@ -208,7 +219,8 @@ func startProofProtocol(ca comm.Receiver, t comm.Task) {
 				// we cannot share same Nonce with the proof and messages
 				// here. StartPSM() sends certain Task fields to other end
 				// as PL.Message
-				proofRequest := generateProofRequest(proofTask)
+				proofRequest := data.NewProofRequest(
+					proofTask.ProofAttrs, proofTask.ProofPredicates)
 				// get proof req from task came in
 				proofReqStr := dto.ToJSON(proofRequest)
 
@@ -222,6 +234,9 @@ func startProofProtocol(ca comm.Receiver, t comm.Task) {
 					StateKey: key,
 					// Verifier cannot provide this..
 					ProofReq: proofReqStr, //  .. but it gives this one.
+
+					TemplateID:      proofTask.TemplateID,
+					TemplateVersion: proofTask.TemplateVersion,
 				}
 				return psm.AddRep(rep)
 			},
@@ -252,6 +267,11 @@ func continueProtocol(ca comm.Receiver, im didcomm.Msg) {
 
 	proofTask := state.LastState().T
 
+	rep, _ := data.GetPresentProofRep(*key) // v1 is the default
+	if rep != nil && rep.Format != "" {
+		continuators = continuatorsV2
+	}
+
 	continuator, ok := continuators[proofTask.UserActionType()]
 	if !ok {
 		glog.Info(string(im.JSON()))
@@ -310,8 +330,15 @@ func fillPresentProofStatus(workerDID string, taskID string, ps *pb.ProtocolStat
 
 	attrs := make([]*pb.Protocol_Proof_Attribute, 0, len(proofRep.Attributes))
 
+	// only the attributes of the credentials, the predicates and the
+	// self-attested attributes are in the proof presentation, see the
+	// GetProofPresentation extension
 	for _, attr := range proofRep.Attributes {
+		if attr.SelfAttested {
+			continue
+		}
 		a := &pb.Protocol_Proof_Attribute{
+			ID:        attr.ID,
 			Name:      attr.Name,
 			CredDefID: attr.CredDefID,
 			Value:     attr.Value,
@@ -319,6 +346,23 @@ func fillPresentProofStatus(workerDID string, taskID string, ps *pb.ProtocolStat
 		attrs = append(attrs, a)
 	}
 
+	// the trust registry's check is shown with the reserved attributes, see
+	// trust.AttrIssuerTrusted
+	if proofRep.Trust != nil {
+		attrs = append(attrs, &pb.Protocol_Proof_Attribute{
+			ID:    trust.AttrIssuerTrusted,
+			Name:  trust.AttrIssuerTrusted,
+			Value: strconv.FormatBool(proofRep.Trust.Trusted),
+		})
+		for _, reason := range proofRep.Trust.Reasons {
+			attrs = append(attrs, &pb.Protocol_Proof_Attribute{
+				ID:    trust.AttrIssuerTrustReason,
+				Name:  trust.AttrIssuerTrustReason,
+				Value: reason,
+			})
+		}
+	}
+
 	status.Status = &pb.ProtocolStatus_PresentProof{
 		PresentProof: &pb.ProtocolStatus_PresentProofStatus{
 			Proof: &pb.Protocol_Proof{
@@ -0,0 +1,72 @@
+package presentproof
+
+import (
+	"os"
+	"testing"
+
+	"github.com/findy-network/findy-agent/agent/didcomm"
+	"github.com/findy-network/findy-agent/agent/psm"
+	"github.com/findy-network/findy-agent/agent/trust"
+	"github.com/findy-network/findy-agent/protocol/presentproof/data"
+	pb "github.com/findy-network/findy-common-go/grpc/agency/v1"
+	"github.com/lainio/err2/assert"
+	"github.com/lainio/err2/try"
+)
+
+const dbPath = "presentproof_test.bolt"
+
+func TestMain(m *testing.M) {
+	try.To(psm.Open(dbPath))
+	code := m.Run()
+	psm.Close()
+	os.Remove(dbPath)
+	os.Exit(code)
+}
+
+func TestFillPresentProofStatus(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	key := psm.StateKey{DID: "verifierDID", Nonce: "proof"}
+	try.To(psm.AddRep(&data.PresentProofRep{
+		StateKey: key,
+		Attributes: []didcomm.ProofAttribute{
+			{ID: "1", Name: "email", CredDefID: "credDefID", Value: "alice@example.com"},
+			{ID: "2", Name: "nickname", Value: "Al", SelfAttested: true},
+		},
+		Predicates: []didcomm.ProofPredicate{
+			{ID: "3", Name: "age", PType: ">=", PValue: 18, CredDefID: "credDefID"},
+		},
+	}))
+
+	status := fillPresentProofStatus(key.DID, key.Nonce, &pb.ProtocolStatus{})
+	attrs := status.GetPresentProof().GetProof().GetAttributes()
+	assert.SLen(attrs, 1)
+	assert.Equal(attrs[0].ID, "1")
+	assert.Equal(attrs[0].Name, "email")
+	assert.Equal(attrs[0].CredDefID, "credDefID")
+	assert.Equal(attrs[0].Value, "alice@example.com")
+}
+
+func TestFillPresentProofStatus_trust(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	key := psm.StateKey{DID: "verifierDID", Nonce: "untrustedProof"}
+	try.To(psm.AddRep(&data.PresentProofRep{
+		StateKey: key,
+		Attributes: []didcomm.ProofAttribute{
+			{ID: "1", Name: "email", CredDefID: "credDefID", Value: "alice@example.com"},
+		},
+		Trust: &trust.Result{Reasons: []string{"issuer isn't trusted"}},
+	}))
+
+	status := fillPresentProofStatus(key.DID, key.Nonce, &pb.ProtocolStatus{})
+	attrs := status.GetPresentProof().GetProof().GetAttributes()
+	assert.SLen(attrs, 3)
+	assert.Equal(attrs[1].ID, trust.AttrIssuerTrusted)
+	assert.Equal(attrs[1].Name, trust.AttrIssuerTrusted)
+	assert.Equal(attrs[1].Value, "false")
+	assert.Equal(attrs[2].ID, trust.AttrIssuerTrustReason)
+	assert.Equal(attrs[2].Value, "issuer isn't trusted")
+}
@@ -0,0 +1,127 @@
+package presentproof
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/findy-network/findy-agent/agent/aries"
+	"github.com/findy-network/findy-agent/agent/comm"
+	"github.com/findy-network/findy-agent/agent/didcomm"
+	"github.com/findy-network/findy-agent/agent/pltype"
+	"github.com/findy-network/findy-agent/agent/prot"
+	"github.com/findy-network/findy-agent/agent/psm"
+	"github.com/findy-network/findy-agent/protocol/presentproof/data"
+	"github.com/findy-network/findy-agent/protocol/presentproof/preview"
+	"github.com/findy-network/findy-agent/protocol/presentproof/prover"
+	"github.com/findy-network/findy-agent/protocol/presentproof/verifier"
+	v2 "github.com/findy-network/findy-agent/std/presentproof/v2"
+	"github.com/golang/glog"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/try"
+)
+
+const protocolVersionV2 = "2.0"
+
+var continuatorsV2 = map[string]continuatorFunc{
+	pltype.SAPresentProofAcceptPropose: verifier.ContinueProposePresentationV2,
+	pltype.SAPresentProofAcceptValues:  verifier.ContinueHandlePresentationV2,
+	pltype.CANotifyUserAction:          prover.UserActionProofPresentationV2,
+}
+
+// versioned returns handler which calls v2 for present proof 2.0 messages and
+// v1 for the others. Both of the versions use the same message names.
+func versioned(v1, v2 comm.HandlerFunc) comm.HandlerFunc {
+	return func(packet comm.Packet) error {
+		if aries.ProtocolVersionForType(packet.Payload.Type()) == protocolVersionV2 {
+			return v2(packet)
+		}
+		return v1(packet)
+	}
+}
+
+func isJSONObject(s string) bool {
+	return strings.HasPrefix(strings.TrimSpace(s), "{")
+}
+
+// proofRequestV2 builds present proof 2.0 request from the JSON object which
+// is either DIF presentation definition, DIF request including the
+// definition, or Indy proof request.
+func proofRequestV2(reqJSON string) (format, req string, err error) {
+	defer err2.Handle(&err, "proof request v2")
+
+	var fields map[string]json.RawMessage
+	try.To(json.Unmarshal([]byte(reqJSON), &fields))
+
+	var reqData []byte
+	switch {
+	case fields["presentation_definition"] != nil:
+		format = v2.FormatDIFDefinition
+		reqData = []byte(reqJSON)
+	case fields["input_descriptors"] != nil:
+		format = v2.FormatDIFDefinition
+		reqData = try.To1(json.Marshal(map[string]json.RawMessage{
+			"presentation_definition": json.RawMessage(reqJSON),
+		}))
+	case fields["requested_attributes"] != nil || fields["requested_predicates"] != nil:
+		format = v2.FormatIndyProofReq
+		reqData = []byte(reqJSON)
+	default:
+		return "", "", fmt.Errorf("unknown proof request format")
+	}
+	reqData = try.To1(verifier.RequestFromProposal(format, reqData))
+	return format, string(reqData), nil
+}
+
+func startProofProtocolV2(ca comm.Receiver, proofTask *taskPresentProof) {
+	defer err2.Catch()
+
+	switch proofTask.Type() {
+	case pltype.CAProofPropose: // ----- prover will start -----
+		try.To(prot.StartPSM(prot.Initial{
+			SendNext:    pltype.PresentProofV2Propose,
+			WaitingNext: pltype.PresentProofV2Request,
+			Ca:          ca,
+			T:           proofTask,
+			Setup: func(key psm.StateKey, msg didcomm.MessageHdr) error {
+				propose := msg.FieldObj().(*v2.Propose)
+				propose.Formats, propose.ProposalsAttach = v2.NewAttach(
+					proofTask.Format, []byte(proofTask.ProofRequest))
+				propose.Comment = proofTask.Comment
+
+				rep := &data.PresentProofRep{
+					StateKey:   key,
+					WeProposed: true,
+					Format:     proofTask.Format,
+				}
+				return psm.AddRep(rep)
+			},
+		}))
+	case pltype.CAProofRequest: // ----- verifier will start -----
+		try.To(prot.StartPSM(prot.Initial{
+			SendNext:    pltype.PresentProofV2Request,
+			WaitingNext: pltype.PresentProofV2Presentation,
+			Ca:          ca,
+			T:           proofTask,
+			Setup: func(key psm.StateKey, msg didcomm.MessageHdr) error {
+				reqData := []byte(proofTask.ProofRequest)
+
+				req := msg.FieldObj().(*v2.Request)
+				req.WillConfirm = true
+				req.Comment = proofTask.Comment
+				req.Formats, req.RequestPresentations = v2.NewAttach(
+					proofTask.Format, reqData)
+
+				rep := &data.PresentProofRep{
+					StateKey: key,
+					ProofReq: proofTask.ProofRequest,
+					Format:   proofTask.Format,
+				}
+				preview.StoreRequestData(proofTask.Format, reqData, rep)
+				return psm.AddRep(rep)
+			},
+		}))
+	default:
+		glog.Error("unsupported protocol start api type ")
+	}
+}
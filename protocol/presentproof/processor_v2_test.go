@@ -0,0 +1,65 @@
+package presentproof
+
+import (
+	"testing"
+
+	v2 "github.com/findy-network/findy-agent/std/presentproof/v2"
+	"github.com/findy-network/findy-common-go/dto"
+	"github.com/lainio/err2/assert"
+)
+
+const testDefinition = `{
+  "id": "32f54163-7166-48f1-93d8-ff217bdb0654",
+  "input_descriptors": [
+    {
+      "id": "email_input",
+      "schema": [{"uri": "https://www.w3.org/2018/credentials#VerifiableCredential"}],
+      "constraints": {
+        "fields": [{"path": ["$.credentialSubject.email"]}]
+      }
+    }
+  ]
+}`
+
+func TestProofRequestV2(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	tests := []struct {
+		name   string
+		req    string
+		format string
+	}{
+		{"definition", testDefinition, v2.FormatDIFDefinition},
+		{"dif request", `{"presentation_definition":` + testDefinition + `}`, v2.FormatDIFDefinition},
+		{"indy", `{"requested_attributes":{"attr1":{"name":"email"}}}`, v2.FormatIndyProofReq},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.PushTester(t)
+			defer assert.PopTester()
+
+			assert.That(isJSONObject(tt.req))
+			format, req, err := proofRequestV2(tt.req)
+			assert.NoError(err)
+			assert.Equal(format, tt.format)
+
+			if format == v2.FormatDIFDefinition {
+				var difReq v2.DIFRequest
+				dto.FromJSONStr(req, &difReq)
+				assert.NotEmpty(difReq.Options.Challenge)
+				assert.Equal(difReq.PresentationDefinition.ID, "32f54163-7166-48f1-93d8-ff217bdb0654")
+			} else {
+				proofReq := make(map[string]interface{})
+				dto.FromJSONStr(req, &proofReq)
+				assert.That(proofReq["nonce"] != nil)
+				assert.Equal(proofReq["name"], "ProofReq")
+			}
+		})
+	}
+
+	assert.That(!isJSONObject(`[{"name":"email"}]`))
+	_, _, err := proofRequestV2(`{"unknown":1}`)
+	assert.Error(err)
+}
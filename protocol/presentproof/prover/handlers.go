@@ -102,8 +102,24 @@ func UserActionProofPresentation(ca comm.Receiver, im didcomm.Msg) {
 	}))
 }
 
+// proofRequestData returns the indy proof request of the incoming request.
+func proofRequestData(packet comm.Packet) []byte {
+	req, ok := packet.Payload.MsgHdr().FieldObj().(*presentproof.Request)
+	if !ok || len(req.RequestPresentations) == 0 {
+		return nil
+	}
+	data, err := presentproof.ProofReqData(req)
+	if err != nil {
+		glog.Warningln("proof request data:", err)
+		return nil
+	}
+	return data
+}
+
+// checkAutoPermission returns the next states of the proof request. The
+// request is answered automatically if the auto-accept policy allows it.
 func checkAutoPermission(packet comm.Packet) (next string, wait string) {
-	if packet.Receiver.AutoPermission() {
+	if acceptByPolicy(packet, "", proofRequestData(packet)) {
 		next = pltype.PresentProofPresentation
 		wait = pltype.PresentProofACK
 	} else {
@@ -0,0 +1,165 @@
+package prover
+
+import (
+	"fmt"
+
+	"github.com/findy-network/findy-agent/agent/comm"
+	"github.com/findy-network/findy-agent/agent/didcomm"
+	"github.com/findy-network/findy-agent/agent/pltype"
+	"github.com/findy-network/findy-agent/agent/prot"
+	"github.com/findy-network/findy-agent/agent/psm"
+	"github.com/findy-network/findy-agent/protocol/presentproof/data"
+	"github.com/findy-network/findy-agent/protocol/presentproof/preview"
+	v2 "github.com/findy-network/findy-agent/std/presentproof/v2"
+	"github.com/golang/glog"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/try"
+)
+
+// HandleRequestPresentationV2 is a present proof 2.0 handler func at PROVER
+// side.
+func HandleRequestPresentationV2(packet comm.Packet) (err error) {
+	defer err2.Handle(&err)
+
+	key := psm.NewStateKey(packet.Receiver, packet.Payload.ThreadID())
+	rep, _ := data.GetPresentProofRep(key) // ignore not found error
+
+	if rep == nil {
+		rep = &data.PresentProofRep{
+			StateKey:   key,
+			WeProposed: false,
+		}
+		try.To(psm.AddRep(rep))
+	}
+
+	sendNext, waitingNext := checkAutoPermissionV2(packet)
+
+	return prot.ExecPSM(prot.Transition{
+		Packet:      packet,
+		SendNext:    sendNext,
+		WaitingNext: waitingNext,
+		SendOnNACK:  pltype.PresentProofV2ProblemReport,
+		TaskHeader:  &comm.TaskHeader{UserActionPLType: pltype.CANotifyUserAction},
+		InOut: func(_ string, im, om didcomm.MessageHdr) (ack bool, err error) {
+			defer err2.Handle(&err, "proof req v2 handler")
+
+			agent := packet.Receiver
+			repK := psm.NewStateKey(agent, im.Thread().ID)
+			rep := try.To1(data.GetPresentProofRep(repK))
+
+			req := im.FieldObj().(*v2.Request)
+			format := v2.SupportedFormat(req.Formats)
+			if format == "" {
+				glog.Warningf("no supported format in proof request: %v", req.Formats)
+				return false, nil
+			}
+			reqData := try.To1(v2.AttachData(req.Formats, req.RequestPresentations, format))
+			rep.ProofReq = string(reqData)
+			rep.Format = format
+
+			preview.StoreRequestData(format, reqData, rep)
+
+			pres, autoAccept := om.FieldObj().(*v2.Presentation)
+			if autoAccept {
+				try.To(present(rep, packet, repK.DID, pres))
+			}
+
+			// Save the proof request to the Proof Rep
+			try.To(psm.AddRep(rep))
+
+			return true, nil
+		},
+	})
+}
+
+func UserActionProofPresentationV2(ca comm.Receiver, im didcomm.Msg) {
+	defer err2.Catch()
+
+	try.To(prot.ContinuePSM(prot.Again{
+		CA:          ca,
+		InMsg:       im,
+		SendNext:    pltype.PresentProofV2Presentation,
+		WaitingNext: pltype.PresentProofV2ACK,
+		SendOnNACK:  pltype.PresentProofV2ProblemReport,
+		Transfer: func(wa comm.Receiver, im, om didcomm.MessageHdr) (ack bool, err error) {
+			defer err2.Handle(&err, "proof v2 user action handler")
+
+			// Does user allow continue?
+			iMsg := im.(didcomm.Msg)
+			if !iMsg.Ready() {
+				glog.Warning("user doesn't accept proof")
+				return false, nil
+			}
+
+			// We continue, get previous data, create the proof and send it
+			agent := wa
+			repK := psm.NewStateKey(agent, im.Thread().ID)
+			rep := try.To1(data.GetPresentProofRep(repK))
+
+			pres := om.FieldObj().(*v2.Presentation)
+			try.To(present(rep, comm.Packet{Receiver: agent}, repK.DID, pres))
+
+			// save created proof to Representative
+			try.To(psm.AddRep(rep))
+
+			return true, nil
+		},
+	}))
+}
+
+// present creates the proof or the presentation according to the request
+// format, and sets it to the outgoing presentation message.
+func present(
+	rep *data.PresentProofRep,
+	packet comm.Packet,
+	rootDID string,
+	pres *v2.Presentation,
+) (err error) {
+	defer err2.Handle(&err, "present")
+
+	switch rep.Format {
+	case v2.FormatIndyProofReq:
+		try.To(rep.CreateProof(packet, rootDID))
+		pres.Formats, pres.PresentationAttaches = v2.NewAttach(
+			v2.FormatIndyProof, []byte(rep.Proof))
+	case v2.FormatDIFDefinition:
+		try.To(rep.CreatePresentation(packet))
+		pres.Formats, pres.PresentationAttaches = v2.NewAttach(
+			v2.FormatDIFSubmission, []byte(rep.Proof))
+	default:
+		return fmt.Errorf("unsupported format: %s", rep.Format)
+	}
+	return nil
+}
+
+// proofRequestDataV2 returns the format and the data of the incoming request.
+func proofRequestDataV2(packet comm.Packet) (format string, reqData []byte) {
+	req, ok := packet.Payload.MsgHdr().FieldObj().(*v2.Request)
+	if !ok {
+		return "", nil
+	}
+	format = v2.SupportedFormat(req.Formats)
+	if format == "" {
+		return "", nil
+	}
+	reqData, err := v2.AttachData(req.Formats, req.RequestPresentations, format)
+	if err != nil {
+		glog.Warningln("proof request data:", err)
+		return "", nil
+	}
+	return format, reqData
+}
+
+// checkAutoPermissionV2 returns the next states of the proof request. The
+// request is answered automatically if the auto-accept policy allows it.
+func checkAutoPermissionV2(packet comm.Packet) (next string, wait string) {
+	format, reqData := proofRequestDataV2(packet)
+	if acceptByPolicy(packet, format, reqData) {
+		next = pltype.PresentProofV2Presentation
+		wait = pltype.PresentProofV2ACK
+	} else {
+		next = pltype.Nothing
+		wait = pltype.PresentProofV2UserAction
+	}
+	return next, wait
+}
@@ -0,0 +1,78 @@
+package prover
+
+import (
+	"encoding/json"
+
+	"github.com/findy-network/findy-agent/agent/comm"
+	"github.com/findy-network/findy-agent/agent/policy"
+	storage "github.com/findy-network/findy-agent/agent/storage/api"
+	"github.com/findy-network/findy-agent/protocol/presentproof/data"
+	"github.com/findy-network/findy-agent/protocol/presentproof/preview"
+	v2 "github.com/findy-network/findy-agent/std/presentproof/v2"
+	"github.com/findy-network/findy-wrapper-go/anoncreds"
+	"github.com/golang/glog"
+)
+
+// acceptByPolicy tells if the agent's auto-accept policy allows to answer the
+// proof request without asking the controller.
+func acceptByPolicy(packet comm.Packet, format string, requestData []byte) bool {
+	return policy.Accept(packet.Receiver, packet.Address.ConnID,
+		policyRequest(format, requestData))
+}
+
+// policyRequest returns the requested attributes and predicates and the cred
+// defs and issuers of their restrictions. The referent is restricted only if
+// all of its restrictions name the cred def or the issuer. Otherwise any
+// issuer can answer it, and the request is unrestricted. DIF requests are
+// always unrestricted.
+func policyRequest(format string, requestData []byte) storage.PolicyRequest {
+	req := storage.PolicyRequest{Protocol: storage.PolicyProof}
+	if len(requestData) == 0 {
+		return req
+	}
+	if format == v2.FormatDIFDefinition {
+		var difReq v2.DIFRequest
+		if json.Unmarshal(requestData, &difReq) == nil {
+			rep := &data.PresentProofRep{}
+			preview.StoreDefinitionData(requestData, rep)
+			for _, attr := range rep.Attributes {
+				req.Attributes = append(req.Attributes, attr.Name)
+			}
+		}
+		req.Unrestricted = true
+		return req
+	}
+
+	var proofReq anoncreds.ProofRequest
+	if err := json.Unmarshal(requestData, &proofReq); err != nil {
+		glog.Warningln("proof request:", err)
+		return req
+	}
+	addCredDefs := func(filters []anoncreds.Filter) {
+		if len(filters) == 0 {
+			req.Unrestricted = true
+		}
+		for _, f := range filters {
+			switch {
+			case f.CredDefID != "":
+				req.CredDefIDs = append(req.CredDefIDs, f.CredDefID)
+			case f.IssuerDID != "":
+				req.Issuers = append(req.Issuers, f.IssuerDID)
+			default:
+				req.Unrestricted = true
+			}
+		}
+	}
+	for _, attr := range proofReq.RequestedAttributes {
+		if attr.Name != "" {
+			req.Attributes = append(req.Attributes, attr.Name)
+		}
+		req.Attributes = append(req.Attributes, attr.Names...)
+		addCredDefs(attr.Restrictions)
+	}
+	for _, pred := range proofReq.RequestedPredicates {
+		req.Attributes = append(req.Attributes, pred.Name)
+		addCredDefs(pred.Restrictions)
+	}
+	return req
+}
@@ -0,0 +1,76 @@
+package prover
+
+import (
+	"encoding/json"
+	"testing"
+
+	storage "github.com/findy-network/findy-agent/agent/storage/api"
+	"github.com/findy-network/findy-wrapper-go/anoncreds"
+	"github.com/lainio/err2/assert"
+)
+
+func TestPolicyRequest(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	const employerCredDef = "EMPLOYERDID:3:CL:12:employee"
+	policy := storage.Policy{Rules: []storage.PolicyRule{
+		{Name: "employee proofs", Protocol: storage.PolicyProof,
+			Action: storage.PolicyAccept, CredDefIDs: []string{employerCredDef}},
+		{Name: "employer proofs", Protocol: storage.PolicyProof,
+			Action: storage.PolicyAccept, Issuers: []string{"EMPLOYERDID"}},
+	}}
+	employer := []anoncreds.Filter{{CredDefID: employerCredDef}}
+
+	tests := []struct {
+		name         string
+		attrs        map[string]anoncreds.AttrInfo
+		preds        map[string]anoncreds.PredicateInfo
+		unrestricted bool
+		rule         string
+	}{
+		{"restricted", map[string]anoncreds.AttrInfo{
+			"1": {Name: "title", Restrictions: employer},
+		}, map[string]anoncreds.PredicateInfo{
+			"2": {Name: "age", PType: ">=", PValue: 18, Restrictions: employer},
+		}, false, "employee proofs"},
+		{"issuer restricted", map[string]anoncreds.AttrInfo{
+			"1": {Name: "title", Restrictions: []anoncreds.Filter{{IssuerDID: "EMPLOYERDID"}}},
+		}, nil, false, "employer proofs"},
+		{"unrestricted attribute", map[string]anoncreds.AttrInfo{
+			"1": {Name: "title", Restrictions: employer},
+			"2": {Name: "ssn"},
+			"3": {Names: []string{"name", "address"}},
+		}, nil, true, ""},
+		{"unrestricted predicate", map[string]anoncreds.AttrInfo{
+			"1": {Name: "title", Restrictions: employer},
+		}, map[string]anoncreds.PredicateInfo{
+			"2": {Name: "salary", PType: ">=", PValue: 5000},
+		}, true, ""},
+		{"schema restriction", map[string]anoncreds.AttrInfo{
+			"1": {Name: "title", Restrictions: employer},
+			"2": {Name: "ssn", Restrictions: []anoncreds.Filter{
+				{CredDefID: employerCredDef}, {SchemaName: "employee"}}},
+		}, nil, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.PushTester(t)
+			defer assert.PopTester()
+
+			data, err := json.Marshal(anoncreds.ProofRequest{
+				RequestedAttributes: tt.attrs,
+				RequestedPredicates: tt.preds,
+			})
+			assert.NoError(err)
+
+			req := policyRequest("", data)
+			assert.Equal(req.Unrestricted, tt.unrestricted)
+			action, rule := policy.Decide(req)
+			assert.Equal(rule, tt.rule)
+			if tt.rule == "" {
+				assert.Equal(action, storage.PolicyAsk)
+			}
+		})
+	}
+}
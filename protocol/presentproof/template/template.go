@@ -0,0 +1,242 @@
+// Package template stores the verification templates of the agent. The
+// template is a named proof request which the verifier can start the present
+// proof protocol with. The templates are versioned: every save creates a new
+// version, and the old versions are kept to tell which template the proofs
+// were requested with.
+package template
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/findy-network/findy-agent/agent/comm"
+	"github.com/findy-network/findy-agent/agent/didcomm"
+	"github.com/findy-network/findy-agent/agent/psm"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/try"
+)
+
+// Template is the verification template. The attributes and the predicates
+// are the ones of the proof request. If Issuers are given, the credentials
+// must be issued by one of them. NonRevoked and the non-revocation intervals
+// of the attributes and the predicates are rejected, because the agent
+// doesn't support the revocation. This limitation of the templates waits for
+// the revocation support, see docs/backlog.md.
+type Template struct {
+	ID          string
+	Version     int
+	Description string
+	Attributes  []didcomm.ProofAttribute
+	Predicates  []didcomm.ProofPredicate
+	Issuers     []string
+	NonRevoked  bool
+	Created     int64 // Unix nano, when the version was saved
+}
+
+// Validate checks that the template has the ID and the attributes or the
+// predicates, that its restrictions don't conflict with the issuers, and that
+// it doesn't require non-revocation.
+func (t Template) Validate() error {
+	if t.ID == "" || strings.Contains(t.ID, "#") {
+		return fmt.Errorf("invalid template ID: '%s'", t.ID)
+	}
+	if len(t.Attributes)+len(t.Predicates) == 0 {
+		return fmt.Errorf("template %s doesn't have attributes or predicates", t.ID)
+	}
+	if t.NonRevoked {
+		return fmt.Errorf("template %s: revocation isn't supported", t.ID)
+	}
+	for i, attr := range t.Attributes {
+		if attr.Name == "" && len(attr.Names) == 0 {
+			return fmt.Errorf("template %s: attribute %d: name is needed", t.ID, i)
+		}
+		if attr.NonRevoked != nil {
+			return fmt.Errorf("template %s: attribute %d: revocation isn't supported", t.ID, i)
+		}
+		if err := t.validIssuers(attr.Restrictions); err != nil {
+			return fmt.Errorf("template %s: attribute %d: %w", t.ID, i, err)
+		}
+	}
+	for i, pred := range t.Predicates {
+		switch pred.PType {
+		case ">=", ">", "<=", "<":
+		default:
+			return fmt.Errorf("template %s: predicate %d: unknown type: %s", t.ID, i, pred.PType)
+		}
+		if pred.Name == "" {
+			return fmt.Errorf("template %s: predicate %d: name is needed", t.ID, i)
+		}
+		if pred.NonRevoked != nil {
+			return fmt.Errorf("template %s: predicate %d: revocation isn't supported", t.ID, i)
+		}
+		if err := t.validIssuers(pred.Restrictions); err != nil {
+			return fmt.Errorf("template %s: predicate %d: %w", t.ID, i, err)
+		}
+	}
+	return nil
+}
+
+func (t Template) validIssuers(restrictions []didcomm.ProofRestriction) error {
+	if len(t.Issuers) == 0 {
+		return nil
+	}
+	for _, r := range restrictions {
+		if r.IssuerDID != "" && !listed(r.IssuerDID, t.Issuers) {
+			return fmt.Errorf("issuer %s isn't trusted", r.IssuerDID)
+		}
+	}
+	return nil
+}
+
+// Request returns the attributes and the predicates of the proof request.
+// The trusted issuers are added to the restrictions.
+func (t Template) Request() ([]didcomm.ProofAttribute, []didcomm.ProofPredicate) {
+	attrs := make([]didcomm.ProofAttribute, 0, len(t.Attributes))
+	for _, attr := range t.Attributes {
+		attr.Restrictions = t.restrictions(attr.CredDefID, attr.Restrictions)
+		attrs = append(attrs, attr)
+	}
+	preds := make([]didcomm.ProofPredicate, 0, len(t.Predicates))
+	for _, pred := range t.Predicates {
+		pred.Restrictions = t.restrictions(pred.CredDefID, pred.Restrictions)
+		preds = append(preds, pred)
+	}
+	return attrs, preds
+}
+
+// restrictions returns the restrictions with the trusted issuers. The
+// restriction without the issuer is an alternative for each of them.
+func (t Template) restrictions(credDefID string, restrictions []didcomm.ProofRestriction) []didcomm.ProofRestriction {
+	if len(t.Issuers) == 0 {
+		return restrictions
+	}
+	if len(restrictions) == 0 {
+		restrictions = []didcomm.ProofRestriction{{CredDefID: credDefID}}
+	}
+	res := make([]didcomm.ProofRestriction, 0, len(restrictions)*len(t.Issuers))
+	for _, r := range restrictions {
+		if r.IssuerDID != "" {
+			res = append(res, r)
+			continue
+		}
+		for _, issuer := range t.Issuers {
+			r.IssuerDID = issuer
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+// locks serialise the saves and the deletes of the templates by their keys
+// that the concurrent saves get their own versions.
+var locks = struct {
+	sync.Mutex
+	m map[psm.StateKey]*sync.Mutex
+}{m: make(map[psm.StateKey]*sync.Mutex)}
+
+func lock(key psm.StateKey) (unlock func()) {
+	locks.Lock()
+	l, ok := locks.m[key]
+	if !ok {
+		l = &sync.Mutex{}
+		locks.m[key] = l
+	}
+	locks.Unlock()
+
+	l.Lock()
+	return l.Unlock
+}
+
+// Save validates the template and saves it as the new version, which is
+// returned.
+func Save(ca comm.Receiver, t Template) (version int, err error) {
+	return save(ca.WorkerEA().MyDID().Did(), t)
+}
+
+func save(agentDID string, t Template) (version int, err error) {
+	defer err2.Handle(&err, "save template")
+
+	try.To(t.Validate())
+
+	key := psm.StateKey{DID: agentDID, Nonce: t.ID}
+	defer lock(key)()
+
+	head := try.To1(getTemplateRep(key))
+	t.Version = 1
+	if head != nil {
+		t.Version = head.Version + 1
+	}
+	t.Created = time.Now().UnixNano()
+
+	try.To(psm.AddRep(&versionRep{StateKey: versionKey(key, t.Version), Template: t}))
+	try.To(psm.AddRep(&templateRep{StateKey: key, Template: t}))
+	return t.Version, nil
+}
+
+// Get returns the version of the template. The zero version is the latest,
+// which isn't found if the template is deleted. The old versions are always
+// found.
+func Get(ca comm.Receiver, id string, version int) (t *Template, err error) {
+	defer err2.Handle(&err, "get template %s", id)
+
+	key := psm.StateKey{DID: ca.WorkerEA().MyDID().Did(), Nonce: id}
+	if version > 0 {
+		rep := try.To1(getVersionRep(versionKey(key, version)))
+		if rep == nil {
+			return nil, fmt.Errorf("version %d not found", version)
+		}
+		return &rep.Template, nil
+	}
+	rep := try.To1(getTemplateRep(key))
+	if rep == nil || rep.Deleted {
+		return nil, fmt.Errorf("not found")
+	}
+	return &rep.Template, nil
+}
+
+// List returns the latest versions of the agent's templates by their IDs.
+func List(ca comm.Receiver) (templates []Template, err error) {
+	defer err2.Handle(&err, "list templates")
+
+	agentDID := ca.WorkerEA().MyDID().Did()
+	templates = make([]Template, 0)
+	for _, r := range try.To1(psm.GetAllReps(psm.BucketProofTemplate)) {
+		rep, ok := r.(*templateRep)
+		if !ok || rep.DID != agentDID || rep.Deleted {
+			continue
+		}
+		templates = append(templates, rep.Template)
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].ID < templates[j].ID
+	})
+	return templates, nil
+}
+
+// Delete deletes the template. Its versions are kept for the proofs which
+// were requested with them, and the next save continues the versions.
+func Delete(ca comm.Receiver, id string) (err error) {
+	defer err2.Handle(&err, "delete template %s", id)
+
+	key := psm.StateKey{DID: ca.WorkerEA().MyDID().Did(), Nonce: id}
+	defer lock(key)()
+
+	rep := try.To1(getTemplateRep(key))
+	if rep == nil || rep.Deleted {
+		return fmt.Errorf("not found")
+	}
+	rep.Deleted = true
+	return psm.AddRep(rep)
+}
+
+func listed(value string, list []string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
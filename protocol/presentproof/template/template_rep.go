@@ -0,0 +1,98 @@
+package template
+
+import (
+	"strconv"
+
+	"github.com/findy-network/findy-agent/agent/psm"
+	"github.com/findy-network/findy-common-go/dto"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/assert"
+	"github.com/lainio/err2/try"
+)
+
+// templateRep is the latest version of the template. The key is the worker
+// agent's DID and the template ID. The deleted template is kept to continue
+// its versions.
+type templateRep struct {
+	psm.StateKey
+	Template
+	Deleted bool
+}
+
+// versionRep is the version of the template. The nonce of the key is the
+// template ID and the version.
+type versionRep struct {
+	psm.StateKey
+	Template
+}
+
+func init() {
+	psm.Creator.Add(psm.BucketProofTemplate, newTemplateRep)
+	psm.Creator.Add(psm.BucketProofTemplateVersion, newVersionRep)
+}
+
+func newTemplateRep(d []byte) psm.Rep {
+	p := &templateRep{}
+	dto.FromGOB(d, p)
+	return p
+}
+
+func (p *templateRep) Key() psm.StateKey {
+	return p.StateKey
+}
+
+func (p *templateRep) Data() []byte {
+	return dto.ToGOB(p)
+}
+
+func (p *templateRep) Type() byte {
+	return psm.BucketProofTemplate
+}
+
+func newVersionRep(d []byte) psm.Rep {
+	p := &versionRep{}
+	dto.FromGOB(d, p)
+	return p
+}
+
+func (p *versionRep) Key() psm.StateKey {
+	return p.StateKey
+}
+
+func (p *versionRep) Data() []byte {
+	return dto.ToGOB(p)
+}
+
+func (p *versionRep) Type() byte {
+	return psm.BucketProofTemplateVersion
+}
+
+func versionKey(key psm.StateKey, version int) psm.StateKey {
+	return psm.StateKey{DID: key.DID, Nonce: key.Nonce + "#" + strconv.Itoa(version)}
+}
+
+// getTemplateRep returns nil if the template isn't found.
+func getTemplateRep(key psm.StateKey) (rep *templateRep, err error) {
+	defer err2.Handle(&err)
+
+	res := try.To1(psm.GetRep(psm.BucketProofTemplate, key))
+	if res == nil {
+		return nil, nil
+	}
+	rep, ok := res.(*templateRep)
+	assert.That(ok, "template type mismatch")
+	return rep, nil
+}
+
+// getVersionRep returns nil if the version isn't found.
+func getVersionRep(key psm.StateKey) (rep *versionRep, err error) {
+	defer err2.Handle(&err)
+
+	res := try.To1(psm.GetRep(psm.BucketProofTemplateVersion, key))
+	if res == nil {
+		return nil, nil
+	}
+	rep, ok := res.(*versionRep)
+	assert.That(ok, "template version type mismatch")
+	return rep, nil
+}
@@ -0,0 +1,166 @@
+package template
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/findy-network/findy-agent/agent/didcomm"
+	"github.com/findy-network/findy-agent/agent/psm"
+	"github.com/lainio/err2/assert"
+	"github.com/lainio/err2/try"
+)
+
+const dbPath = "template_test.bolt"
+
+func TestMain(m *testing.M) {
+	try.To(psm.Open(dbPath))
+	code := m.Run()
+	psm.Close()
+	os.Remove(dbPath)
+	os.Exit(code)
+}
+
+var employee = Template{
+	ID: "employee",
+	Attributes: []didcomm.ProofAttribute{
+		{Name: "name", CredDefID: "cred-def-1"},
+		{Names: []string{"title", "unit"}, Restrictions: []didcomm.ProofRestriction{
+			{SchemaName: "employee"},
+			{IssuerDID: "HR", SchemaName: "employee"},
+		}},
+	},
+	Predicates: []didcomm.ProofPredicate{
+		{Name: "age", PType: ">=", PValue: 18},
+	},
+	Issuers: []string{"ACME", "HR"},
+}
+
+func TestTemplate_Validate(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	assert.NoError(employee.Validate())
+	assert.Error(Template{}.Validate())
+	assert.Error(Template{ID: "a#1", Attributes: employee.Attributes}.Validate())
+	assert.Error(Template{ID: "empty"}.Validate())
+	assert.Error(Template{ID: "no name",
+		Attributes: []didcomm.ProofAttribute{{CredDefID: "cred-def-1"}}}.Validate())
+	assert.Error(Template{ID: "predicate", Predicates: []didcomm.ProofPredicate{
+		{Name: "age", PType: "=="}}}.Validate())
+	assert.Error(Template{ID: "untrusted", Issuers: []string{"ACME"},
+		Attributes: []didcomm.ProofAttribute{{Name: "name",
+			Restrictions: []didcomm.ProofRestriction{{IssuerDID: "OTHER"}}}}}.Validate())
+
+	// the revocation isn't supported
+	revocable := employee
+	revocable.NonRevoked = true
+	assert.Error(revocable.Validate())
+	assert.Error(Template{ID: "revocable attribute", Attributes: []didcomm.ProofAttribute{
+		{Name: "name", NonRevoked: &didcomm.NonRevocInterval{To: 100}}}}.Validate())
+	assert.Error(Template{ID: "revocable predicate", Predicates: []didcomm.ProofPredicate{
+		{Name: "age", PType: ">=", NonRevoked: &didcomm.NonRevocInterval{To: 100}}}}.Validate())
+}
+
+func TestTemplate_Request(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	attrs, preds := employee.Request()
+	assert.SLen(attrs, 2)
+	assert.SLen(preds, 1)
+
+	name := attrs[0]
+	assert.SLen(name.Restrictions, 2)
+	assert.DeepEqual(name.Restrictions[0],
+		didcomm.ProofRestriction{CredDefID: "cred-def-1", IssuerDID: "ACME"})
+	assert.Equal(name.Restrictions[1].IssuerDID, "HR")
+	assert.That(name.NonRevoked == nil)
+
+	// the restriction with the issuer is kept as is
+	title := attrs[1]
+	assert.SLen(title.Restrictions, 3)
+	assert.Equal(title.Restrictions[2].IssuerDID, "HR")
+
+	age := preds[0]
+	assert.SLen(age.Restrictions, 2)
+
+	// the template itself isn't changed
+	assert.SLen(employee.Attributes[0].Restrictions, 0)
+
+	attrs, _ = Template{Attributes: employee.Attributes}.Request()
+	assert.SLen(attrs[0].Restrictions, 0)
+}
+
+func TestSave_concurrent(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	const count = 10
+	var (
+		wg       sync.WaitGroup
+		lock     sync.Mutex
+		versions []int
+	)
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tmpl := employee
+			tmpl.ID = "concurrent"
+			tmpl.Description = fmt.Sprint(i)
+			version, err := save("concurrentDID", tmpl)
+			if err != nil {
+				t.Error(err)
+			}
+			lock.Lock()
+			versions = append(versions, version)
+			lock.Unlock()
+		}(i)
+	}
+	wg.Wait()
+
+	sort.Ints(versions)
+	for i, v := range versions {
+		assert.Equal(v, i+1)
+	}
+	key := psm.StateKey{DID: "concurrentDID", Nonce: "concurrent"}
+	head, err := getTemplateRep(key)
+	assert.NoError(err)
+	assert.Equal(head.Version, count)
+	for v := 1; v <= count; v++ {
+		rep, err := getVersionRep(versionKey(key, v))
+		assert.NoError(err)
+		assert.Equal(rep.Version, v)
+	}
+}
+
+func TestTemplateReps(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	key := psm.StateKey{DID: "agentDID", Nonce: employee.ID}
+	v1 := employee
+	v1.Version = 1
+	v2 := employee
+	v2.Version, v2.Description = 2, "second"
+	for _, v := range []Template{v1, v2} {
+		assert.NoError(psm.AddRep(&versionRep{StateKey: versionKey(key, v.Version), Template: v}))
+	}
+	assert.NoError(psm.AddRep(&templateRep{StateKey: key, Template: v2, Deleted: true}))
+
+	head, err := getTemplateRep(key)
+	assert.NoError(err)
+	assert.That(head.Deleted)
+	assert.Equal(head.Version, 2)
+
+	old, err := getVersionRep(versionKey(key, 1))
+	assert.NoError(err)
+	assert.DeepEqual(old.Template, v1)
+
+	missing, err := getVersionRep(versionKey(key, 3))
+	assert.NoError(err)
+	assert.That(missing == nil)
+}
@@ -9,13 +9,11 @@ import (
 	"github.com/findy-network/findy-agent/agent/pltype"
 	"github.com/findy-network/findy-agent/agent/prot"
 	"github.com/findy-network/findy-agent/agent/psm"
-	"github.com/findy-network/findy-agent/agent/utils"
 	"github.com/findy-network/findy-agent/protocol/presentproof/data"
 	"github.com/findy-network/findy-agent/protocol/presentproof/preview"
 	"github.com/findy-network/findy-agent/std/common"
 	"github.com/findy-network/findy-agent/std/presentproof"
 	"github.com/findy-network/findy-common-go/dto"
-	"github.com/findy-network/findy-wrapper-go/anoncreds"
 	"github.com/golang/glog"
 	"github.com/lainio/err2"
 	"github.com/lainio/err2/try"
@@ -23,39 +21,31 @@ import (
 
 const ackOK = "OK"
 
-func generateProofRequest(proofTask *presentproof.Propose) *anoncreds.ProofRequest {
-	reqAttrs := make(map[string]anoncreds.AttrInfo)
-	for index, attr := range proofTask.PresentationProposal.Attributes {
-		restrictions := make([]anoncreds.Filter, 0)
-		if attr.CredDefID != "" {
-			restrictions = append(restrictions, anoncreds.Filter{CredDefID: attr.CredDefID})
-		}
-		id := "attr_referent_" + strconv.Itoa(index+1)
-		reqAttrs[id] = anoncreds.AttrInfo{
-			Name:         attr.Name,
-			Restrictions: restrictions,
-		}
+// generateProofRequest returns the proof request of the proposal. The cred
+// def IDs of the preview are the restrictions.
+func generateProofRequest(proofTask *presentproof.Propose) *data.ProofRequest {
+	pp := proofTask.PresentationProposal
+	attrs := make([]didcomm.ProofAttribute, 0, len(pp.Attributes))
+	for _, attr := range pp.Attributes {
+		attrs = append(attrs, didcomm.ProofAttribute{
+			Name:      attr.Name,
+			CredDefID: attr.CredDefID,
+		})
 	}
-	reqPredicates := make(map[string]anoncreds.PredicateInfo)
-	if proofTask.PresentationProposal.Predicates != nil {
-		for index, predicate := range proofTask.PresentationProposal.Predicates {
-			// TODO: restrictions
-			id := "predicate_" + strconv.Itoa(index+1)
-			value, _ := strconv.ParseInt(predicate.Threshold, 10, 64) // TODO
-			reqPredicates[id] = anoncreds.PredicateInfo{
-				Name:   predicate.Name,
-				PType:  predicate.Predicate,
-				PValue: int(value),
-			}
+	preds := make([]didcomm.ProofPredicate, 0, len(pp.Predicates))
+	for _, predicate := range pp.Predicates {
+		value, _ := strconv.ParseInt(predicate.Threshold, 10, 64) // TODO
+		pred := didcomm.ProofPredicate{
+			Name:   predicate.Name,
+			PType:  predicate.Predicate,
+			PValue: value,
 		}
+		if predicate.CredDefID != "" {
+			pred.Restrictions = []didcomm.ProofRestriction{{CredDefID: predicate.CredDefID}}
+		}
+		preds = append(preds, pred)
 	}
-	return &anoncreds.ProofRequest{
-		Name:                "ProofReq",
-		Version:             "0.1",
-		Nonce:               utils.NewNonceStr(),
-		RequestedAttributes: reqAttrs,
-		RequestedPredicates: reqPredicates,
-	}
+	return data.NewProofRequest(attrs, preds)
 }
 
 // HandleProposePresentation is a protocol handler function at VERIFIER side.
@@ -181,15 +171,16 @@ func HandlePresentation(packet comm.Packet) (err error) {
 			}
 
 			preview.StoreProofData([]byte(rep.ProofReq), rep)
-
-			var proof anoncreds.Proof
-			dto.FromJSON(data, &proof)
-			for index, attr := range rep.Attributes {
-				rep.Attributes[index].Value = proof.RequestedProof.RevealedAttrs[attr.ID].Raw
-			}
+			rep.StoreProofValues(data)
 
 			try.To(psm.AddRep(rep))
 
+			// without the controller, the issuers must be trusted as well
+			if packet.Receiver.AutoPermission() && rep.Untrusted() {
+				glog.Warningf("untrusted issuers (nonce:%v): %v", im.Thread().ID, rep.Trust.Reasons)
+				return false, nil
+			}
+
 			// Autoaccept -> all checks done, let's send ACK
 			ackMsg, autoAccept := om.FieldObj().(*common.Ack)
 			if autoAccept {
@@ -0,0 +1,235 @@
+package verifier
+
+import (
+	"fmt"
+
+	"github.com/findy-network/findy-agent/agent/comm"
+	"github.com/findy-network/findy-agent/agent/didcomm"
+	"github.com/findy-network/findy-agent/agent/pltype"
+	"github.com/findy-network/findy-agent/agent/prot"
+	"github.com/findy-network/findy-agent/agent/psm"
+	"github.com/findy-network/findy-agent/agent/utils"
+	"github.com/findy-network/findy-agent/protocol/presentproof/data"
+	"github.com/findy-network/findy-agent/protocol/presentproof/preview"
+	"github.com/findy-network/findy-agent/std/common"
+	v2 "github.com/findy-network/findy-agent/std/presentproof/v2"
+	"github.com/findy-network/findy-common-go/dto"
+	"github.com/golang/glog"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/try"
+)
+
+// HandleProposePresentationV2 is a present proof 2.0 protocol handler
+// function at VERIFIER side.
+func HandleProposePresentationV2(packet comm.Packet) (err error) {
+	var sendNext, waitingNext string
+	if packet.Receiver.AutoPermission() {
+		sendNext = pltype.PresentProofV2Request
+		waitingNext = pltype.PresentProofV2Presentation
+	} else {
+		sendNext = pltype.Nothing
+		waitingNext = pltype.PresentProofV2UserAction
+	}
+
+	return prot.ExecPSM(prot.Transition{
+		Packet:      packet,
+		SendNext:    sendNext,
+		WaitingNext: waitingNext,
+		SendOnNACK:  pltype.PresentProofV2ProblemReport,
+		TaskHeader:  &comm.TaskHeader{UserActionPLType: pltype.SAPresentProofAcceptPropose},
+		InOut: func(_ string, im, om didcomm.MessageHdr) (ack bool, err error) {
+			defer err2.Handle(&err, "proof propose v2 handler")
+
+			agent := packet.Receiver
+			meDID := agent.MyDID().Did()
+			key := psm.StateKey{DID: meDID, Nonce: im.Thread().ID}
+
+			propose := im.FieldObj().(*v2.Propose)
+			format := v2.SupportedFormat(propose.Formats)
+			if format == "" {
+				glog.Warningf("no supported format in proposal: %v", propose.Formats)
+				return false, nil
+			}
+			proposal := try.To1(v2.AttachData(propose.Formats, propose.ProposalsAttach, format))
+			reqData := try.To1(RequestFromProposal(format, proposal))
+
+			rep := &data.PresentProofRep{
+				StateKey: key,
+				ProofReq: string(reqData),
+				Format:   format,
+			}
+			preview.StoreRequestData(format, reqData, rep)
+			try.To(psm.AddRep(rep))
+
+			req, autoAccept := om.FieldObj().(*v2.Request)
+			if autoAccept {
+				req.WillConfirm = true
+				req.Formats, req.RequestPresentations = v2.NewAttach(format, reqData)
+			}
+
+			return true, nil
+		},
+	})
+}
+
+func ContinueProposePresentationV2(ca comm.Receiver, im didcomm.Msg) {
+	defer err2.Catch()
+
+	try.To(prot.ContinuePSM(prot.Again{
+		CA:          ca,
+		InMsg:       im,
+		SendNext:    pltype.PresentProofV2Request,
+		WaitingNext: pltype.PresentProofV2Presentation,
+		SendOnNACK:  pltype.PresentProofV2ProblemReport,
+		Transfer: func(_ comm.Receiver, im, om didcomm.MessageHdr) (ack bool, err error) {
+			defer err2.Handle(&err, "proof propose v2 user action handler")
+
+			// Does user allow continue?
+			iMsg := im.(didcomm.Msg)
+			if !iMsg.Ready() {
+				glog.Warning("user doesn't accept proof propose")
+				return false, nil
+			}
+
+			repK := psm.NewStateKey(ca, im.Thread().ID)
+			rep := try.To1(data.GetPresentProofRep(repK))
+
+			req := om.FieldObj().(*v2.Request) // query interface
+			req.WillConfirm = true
+			req.Formats, req.RequestPresentations = v2.NewAttach(
+				rep.Format, []byte(rep.ProofReq))
+
+			return true, nil
+		},
+	}))
+}
+
+// HandlePresentationV2 is a present proof 2.0 protocol handler function at
+// VERIFIER side for handling proof presentation.
+func HandlePresentationV2(packet comm.Packet) (err error) {
+	var sendNext, waitingNext string
+	if packet.Receiver.AutoPermission() {
+		sendNext = pltype.PresentProofV2ACK
+		waitingNext = pltype.Terminate
+	} else {
+		sendNext = pltype.Nothing
+		waitingNext = pltype.PresentProofV2UserAction
+	}
+
+	return prot.ExecPSM(prot.Transition{
+		Packet:      packet,
+		SendNext:    sendNext,
+		WaitingNext: waitingNext,
+		SendOnNACK:  pltype.PresentProofV2ProblemReport,
+		TaskHeader:  &comm.TaskHeader{UserActionPLType: pltype.SAPresentProofAcceptValues},
+		InOut: func(_ string, im, om didcomm.MessageHdr) (ack bool, err error) {
+			defer err2.Handle(&err, "proof presentation v2 handler")
+
+			agent := packet.Receiver
+			repK := psm.NewStateKey(agent, im.Thread().ID)
+			rep := try.To1(data.GetPresentProofRep(repK))
+
+			// 1st, verify the proof by our selves
+			pres := im.FieldObj().(*v2.Presentation)
+			switch rep.Format {
+			case v2.FormatIndyProofReq:
+				proof := try.To1(v2.AttachData(pres.Formats,
+					pres.PresentationAttaches, v2.FormatIndyProof))
+				rep.Proof = string(proof)
+
+				if !try.To1(rep.VerifyProof(packet)) {
+					glog.Errorf("Cannot verify proof (nonce:%v) terminating presentation protocol", im.Thread().ID)
+					return false, nil
+				}
+				preview.StoreProofData([]byte(rep.ProofReq), rep)
+				rep.StoreProofValues(proof)
+			case v2.FormatDIFDefinition:
+				vp := try.To1(v2.AttachData(pres.Formats,
+					pres.PresentationAttaches, v2.FormatDIFSubmission))
+				rep.Proof = string(vp)
+
+				if !try.To1(rep.VerifyPresentation(packet)) {
+					glog.Errorf("Cannot verify presentation (nonce:%v) terminating presentation protocol", im.Thread().ID)
+					return false, nil
+				}
+			default:
+				return false, fmt.Errorf("unsupported format: %s", rep.Format)
+			}
+
+			try.To(psm.AddRep(rep))
+
+			// without the controller, the issuers must be trusted as well
+			if packet.Receiver.AutoPermission() && rep.Untrusted() {
+				glog.Warningf("untrusted issuers (nonce:%v): %v", im.Thread().ID, rep.Trust.Reasons)
+				return false, nil
+			}
+
+			// Autoaccept -> all checks done, let's send ACK
+			ackMsg, autoAccept := om.FieldObj().(*common.Ack)
+			if autoAccept {
+				ackMsg.Status = ackOK
+			}
+
+			return true, nil
+		},
+	})
+}
+
+func ContinueHandlePresentationV2(ca comm.Receiver, im didcomm.Msg) {
+	defer err2.Catch()
+
+	try.To(prot.ContinuePSM(prot.Again{
+		CA:          ca,
+		InMsg:       im,
+		SendNext:    pltype.PresentProofV2ACK,
+		WaitingNext: pltype.Terminate,
+		SendOnNACK:  pltype.PresentProofV2ProblemReport,
+		Transfer: func(_ comm.Receiver, im, om didcomm.MessageHdr) (ack bool, err error) {
+			defer err2.Handle(&err, "proof values v2 user action handler")
+
+			// Does user allow continue?
+			iMsg := im.(didcomm.Msg)
+			if !iMsg.Ready() {
+				glog.Warning("user doesn't accept proof values")
+				return false, nil
+			}
+
+			// All checks done, let's send ACK
+			ackMsg := om.FieldObj().(*common.Ack)
+			ackMsg.Status = ackOK
+
+			return true, nil
+		},
+	}))
+}
+
+// RequestFromProposal builds the proof request of the format from the
+// proposal. Indy proof requests get a fresh nonce, and DIF requests get a new
+// challenge.
+func RequestFromProposal(format string, proposal []byte) (req []byte, err error) {
+	defer err2.Handle(&err, "request from proposal")
+
+	switch format {
+	case v2.FormatIndyProofReq:
+		proofReq := make(map[string]interface{})
+		dto.FromJSON(proposal, &proofReq)
+		proofReq["nonce"] = utils.NewNonceStr()
+		if _, ok := proofReq["name"]; !ok {
+			proofReq["name"] = "ProofReq"
+		}
+		if _, ok := proofReq["version"]; !ok {
+			proofReq["version"] = "0.1"
+		}
+		return dto.ToJSONBytes(proofReq), nil
+	case v2.FormatDIFDefinition:
+		var difReq v2.DIFRequest
+		dto.FromJSON(proposal, &difReq)
+		if difReq.PresentationDefinition == nil {
+			return nil, fmt.Errorf("presentation definition missing")
+		}
+		try.To(difReq.PresentationDefinition.ValidateSchema())
+		difReq.Options = &v2.DIFOptions{Challenge: utils.UUID()}
+		return dto.ToJSONBytes(difReq), nil
+	}
+	return nil, fmt.Errorf("unsupported format: %s", format)
+}
@@ -0,0 +1,425 @@
+/*
+Package questionanswer implements Aries RFC 0113 question answer protocol. The
+question waits the answer of the responder's controller, which resumes the
+protocol with the selected response in the info field. The response is signed
+with the key of the connection, and the questioner verifies and stores it as a
+record of the answer. The question can have attachments, which the questioner
+signs with the key of the connection as JWS, and the responder verifies them.
+*/
+package questionanswer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/findy-network/findy-agent/agent/comm"
+	"github.com/findy-network/findy-agent/agent/didcomm"
+	"github.com/findy-network/findy-agent/agent/pltype"
+	"github.com/findy-network/findy-agent/agent/prot"
+	"github.com/findy-network/findy-agent/agent/psm"
+	"github.com/findy-network/findy-agent/agent/utils"
+	"github.com/findy-network/findy-agent/core"
+	"github.com/findy-network/findy-agent/std/decorator"
+	"github.com/findy-network/findy-agent/std/didexchange/signature"
+	"github.com/findy-network/findy-agent/std/questionanswer"
+	pb "github.com/findy-network/findy-common-go/grpc/agency/v1"
+	"github.com/golang/glog"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/assert"
+	"github.com/lainio/err2/try"
+)
+
+type taskQuestionAnswer struct {
+	comm.TaskBase
+	Question Question
+}
+
+// Question is the question and its answer. For the questioner it's the
+// record of the answer: the response is verified when it has the signature.
+type Question struct {
+	ConnectionID      string
+	QuestionText      string
+	QuestionDetail    string
+	ValidResponses    []string
+	SignatureRequired bool
+	ExpiresTime       int64 // Unix nano, 0 if the question doesn't expire
+	Nonce             string
+	SentByMe          bool
+	Timestamp         int64
+
+	Response  string
+	Signature string // base64 URL encoded
+	SigData   string // base64 URL encoded timestamp and signed data
+	Signer    string // verkey of the responder
+	Verified  bool
+	Expired   bool // the answer came after ExpiresTime, and it's rejected
+
+	// Attachments is JSON of the ~attach decorator of the question. The
+	// questioner signs the attachments with the key of the connection if
+	// SignAttachments is set, and the responder verifies the signed ones.
+	Attachments         []byte
+	SignAttachments     bool
+	AttachmentsVerified bool
+}
+
+var questionAnswerProcessor = comm.ProtProc{
+	Creator:     createQuestionAnswerTask,
+	Starter:     startQuestionAnswer,
+	Continuator: continueQuestionAnswer,
+	Handlers: map[string]comm.HandlerFunc{
+		pltype.HandlerQuestion: handleQuestion,
+		pltype.HandlerAnswer:   handleAnswer,
+	},
+	FillStatus: fillQuestionAnswerStatus,
+}
+
+func init() {
+	gob.Register(&taskQuestionAnswer{})
+	prot.AddCreator(pltype.ProtocolQuestionAnswer, questionAnswerProcessor)
+	prot.AddStarter(pltype.CAQuestionAnswer, questionAnswerProcessor)
+	prot.AddContinuator(pltype.CAContinueQuestionAnswerProtocol, questionAnswerProcessor)
+	prot.AddStatusProvider(pltype.ProtocolQuestionAnswer, questionAnswerProcessor)
+	comm.Proc.Add(pltype.ProtocolQuestionAnswer, questionAnswerProcessor)
+}
+
+// NewTask returns the task which asks the question from the connection.
+func NewTask(q Question) (t comm.Task, err error) {
+	if q.ConnectionID == "" || q.QuestionText == "" {
+		return nil, fmt.Errorf("connection and question text are needed for question")
+	}
+	if len(q.ValidResponses) == 0 {
+		return nil, fmt.Errorf("question needs valid responses")
+	}
+	if len(q.Attachments) > 0 {
+		if _, err := attachments(q.Attachments); err != nil {
+			return nil, err
+		}
+	}
+	return &taskQuestionAnswer{
+		TaskBase: comm.TaskBase{TaskHeader: comm.TaskHeader{
+			TaskID:       utils.UUID(),
+			TypeID:       pltype.CAQuestionAnswer,
+			ProtocolRole: pb.Protocol_INITIATOR,
+			ConnID:       q.ConnectionID,
+			Method:       utils.Settings.DIDMethod(),
+		}},
+		Question: q,
+	}, nil
+}
+
+// GetQuestion returns the question and the answer of the protocol.
+func GetQuestion(workerDID, protocolID string) (q *Question, err error) {
+	defer err2.Handle(&err, "get question")
+
+	rep := try.To1(getQuestionAnswerRep(psm.StateKey{DID: workerDID, Nonce: protocolID}))
+	if rep == nil {
+		return nil, fmt.Errorf("no question for protocol %s", protocolID)
+	}
+	return &rep.Question, nil
+}
+
+// IsValid tells if the response is one of the valid responses.
+func (q *Question) IsValid(response string) bool {
+	for _, r := range q.ValidResponses {
+		if r == response {
+			return true
+		}
+	}
+	return false
+}
+
+func (q *Question) expired() bool {
+	return q.ExpiresTime != 0 && time.Now().UnixNano() > q.ExpiresTime
+}
+
+// checkAnswer returns an error if the questioner doesn't accept the answer.
+func (q *Question) checkAnswer() error {
+	switch {
+	case q.Expired:
+		return fmt.Errorf("answer came after the question expired")
+	case !q.IsValid(q.Response):
+		return fmt.Errorf("invalid response: %s", q.Response)
+	case q.SignatureRequired && !q.Verified:
+		return fmt.Errorf("answer signature isn't valid")
+	}
+	return nil
+}
+
+func createQuestionAnswerTask(header *comm.TaskHeader, _ *pb.Protocol) (t comm.Task, err error) {
+	glog.V(1).Infof("Create task for QuestionAnswer with connection id %s", header.ConnID)
+
+	return &taskQuestionAnswer{
+		TaskBase: comm.TaskBase{TaskHeader: *header},
+	}, nil
+}
+
+func startQuestionAnswer(ca comm.Receiver, t comm.Task) {
+	defer err2.Catch()
+
+	qaTask, ok := t.(*taskQuestionAnswer)
+	assert.That(ok)
+
+	try.To(prot.StartPSM(prot.Initial{
+		SendNext:    pltype.QuestionAnswerQuestion,
+		WaitingNext: pltype.QuestionAnswerAnswer,
+		Ca:          ca,
+		T:           t,
+		Setup: func(key psm.StateKey, om didcomm.MessageHdr) (err error) {
+			defer err2.Handle(&err)
+
+			q := qaTask.Question
+			q.Nonce = utils.UUID()
+			q.SentByMe = true
+			q.Timestamp = time.Now().UnixNano()
+
+			msg := om.FieldObj().(*questionanswer.QuestionAnswer)
+			if len(q.Attachments) > 0 {
+				msg.Attachments = try.To1(attachments(q.Attachments))
+				if q.SignAttachments {
+					wa := ca.WorkerEA()
+					pw := try.To1(wa.FindPWByID(q.ConnectionID))
+					assert.That(pw != nil, "pairwise is nil")
+					signer := signature.Signer{DID: wa.LoadDID(pw.MyDID)}
+					for i := range msg.Attachments {
+						try.To(signer.SignAttachment(&msg.Attachments[i].Data))
+					}
+					q.Attachments = try.To1(json.Marshal(msg.Attachments))
+				}
+			}
+			try.To(psm.AddRep(&questionAnswerRep{StateKey: key, Question: q}))
+
+			msg.QuestionText = q.QuestionText
+			msg.QuestionDetail = q.QuestionDetail
+			msg.Nonce = q.Nonce
+			msg.SignatureRequired = q.SignatureRequired
+			for _, r := range q.ValidResponses {
+				msg.ValidResponses = append(msg.ValidResponses,
+					questionanswer.Response{Text: r})
+			}
+			if q.ExpiresTime != 0 {
+				msg.Timing = &decorator.Timing{ExpiresTime: time.Unix(0, q.ExpiresTime)}
+			}
+			return nil
+		},
+	}))
+}
+
+// handleQuestion stores the question and waits the answer of the controller.
+func handleQuestion(packet comm.Packet) (err error) {
+	return prot.ExecPSM(prot.Transition{
+		Packet:      packet,
+		SendNext:    pltype.Nothing,
+		WaitingNext: pltype.QuestionAnswerUserAction,
+		TaskHeader:  &comm.TaskHeader{UserActionPLType: pltype.CANotifyUserAction},
+		InOut: func(connID string, im, _ didcomm.MessageHdr) (ack bool, err error) {
+			defer err2.Handle(&err, "question handler")
+
+			msg := im.FieldObj().(*questionanswer.QuestionAnswer)
+			q := Question{
+				ConnectionID:      connID,
+				QuestionText:      msg.QuestionText,
+				QuestionDetail:    msg.QuestionDetail,
+				SignatureRequired: msg.SignatureRequired,
+				Nonce:             msg.Nonce,
+				Timestamp:         time.Now().UnixNano(),
+			}
+			for _, r := range msg.ValidResponses {
+				q.ValidResponses = append(q.ValidResponses, r.Text)
+			}
+			if msg.Timing != nil && !msg.Timing.ExpiresTime.IsZero() {
+				q.ExpiresTime = msg.Timing.ExpiresTime.UnixNano()
+			}
+			if len(msg.Attachments) > 0 {
+				q.Attachments = try.To1(json.Marshal(msg.Attachments))
+				pw := try.To1(packet.Receiver.FindPWByID(connID))
+				assert.That(pw != nil, "pairwise is nil")
+				q.AttachmentsVerified = verifyAttachments(
+					packet.Receiver.LoadTheirDID(*pw), msg.Attachments)
+			}
+			try.To(psm.AddRep(&questionAnswerRep{
+				StateKey: psm.StateKey{
+					DID:   packet.Receiver.MyDID().Did(),
+					Nonce: im.Thread().ID,
+				},
+				Question: q,
+			}))
+			return true, nil
+		},
+	})
+}
+
+// continueQuestionAnswer sends the signed answer of the controller. The
+// controller gives the response in the info field when it resumes the
+// protocol. If the response isn't valid the protocol keeps waiting for a
+// valid one.
+func continueQuestionAnswer(ca comm.Receiver, im didcomm.Msg) {
+	defer err2.Catch(err2.Err(func(err error) {
+		glog.Error("continue question answer: ", err)
+	}))
+
+	try.To(prot.ContinuePSM(prot.Again{
+		CA:          ca,
+		InMsg:       im,
+		SendNext:    pltype.QuestionAnswerAnswer,
+		WaitingNext: pltype.Terminate,
+		Transfer: func(wa comm.Receiver, im, om didcomm.MessageHdr) (ack bool, err error) {
+			defer err2.Handle(&err, "answer handler")
+
+			iMsg := im.(didcomm.Msg)
+			if !iMsg.Ready() {
+				glog.Warning("user doesn't answer the question")
+				return false, nil
+			}
+
+			key := psm.NewStateKey(wa, im.Thread().ID)
+			rep := try.To1(getQuestionAnswerRep(key))
+			assert.That(rep != nil, "question not found")
+			q := &rep.Question
+
+			response := iMsg.Info()
+			if !q.IsValid(response) {
+				return false, fmt.Errorf("invalid response: %s", response)
+			}
+			if q.expired() {
+				glog.Warning("question has expired")
+				return false, nil
+			}
+
+			pw := try.To1(wa.FindPWByID(q.ConnectionID))
+			assert.That(pw != nil, "pairwise is nil")
+			signer := signature.Signer{DID: wa.LoadDID(pw.MyDID)}
+
+			data := stamp(questionanswer.SignedData(q.QuestionText, response, q.Nonce))
+			sig := try.To1(signer.Sign(data))
+
+			q.Response = response
+			q.Signature = base64.URLEncoding.EncodeToString(sig)
+			q.SigData = base64.URLEncoding.EncodeToString(data)
+			q.Signer = signer.VerKey()
+			q.Verified = true
+			try.To(psm.AddRep(rep))
+
+			answer := om.FieldObj().(*questionanswer.QuestionAnswer)
+			answer.Response = response
+			answer.ResponseSig = &questionanswer.Signature{
+				Type:      pltype.SignatureEd25519Sha512Single,
+				Signature: q.Signature,
+				SigData:   q.SigData,
+				Signer:    q.Signer,
+			}
+			return true, nil
+		},
+	}))
+}
+
+// handleAnswer verifies the signature of the answer and stores it. The
+// protocol ends with NACK if the answer isn't valid or it came after the
+// question expired.
+func handleAnswer(packet comm.Packet) (err error) {
+	return prot.ExecPSM(prot.Transition{
+		Packet:      packet,
+		SendNext:    pltype.Terminate,
+		WaitingNext: pltype.Terminate,
+		InOut: func(connID string, im, _ didcomm.MessageHdr) (ack bool, err error) {
+			defer err2.Handle(&err, "answer handler")
+
+			key := psm.StateKey{
+				DID:   packet.Receiver.MyDID().Did(),
+				Nonce: im.Thread().ID,
+			}
+			rep := try.To1(getQuestionAnswerRep(key))
+			assert.That(rep != nil, "question not found")
+			q := &rep.Question
+
+			msg := im.FieldObj().(*questionanswer.QuestionAnswer)
+			q.Response = msg.Response
+			q.Expired = q.expired()
+			if sig := msg.ResponseSig; sig != nil {
+				q.Signature = sig.Signature
+				q.SigData = sig.SigData
+				q.Signer = sig.Signer
+
+				pw := try.To1(packet.Receiver.FindPWByID(connID))
+				assert.That(pw != nil, "pairwise is nil")
+				theirDID := packet.Receiver.LoadTheirDID(*pw)
+				q.Verified = verify(theirDID, q) == nil
+			}
+			try.To(psm.AddRep(rep))
+
+			if err := q.checkAnswer(); err != nil {
+				glog.Warning(err)
+				return false, nil
+			}
+			return true, nil
+		},
+	})
+}
+
+// stamp prefixes the data with the big endian timestamp as the signature
+// decorator needs.
+func stamp(src []byte) []byte {
+	data := make([]byte, 8+len(src))
+	binary.BigEndian.PutUint64(data, uint64(time.Now().Unix()))
+	copy(data[8:], src)
+	return data
+}
+
+// verify verifies that the answer is signed by the verkey of the connection,
+// and that the signed data is the question, the response and the nonce.
+func verify(theirDID core.DID, q *Question) (err error) {
+	defer err2.Handle(&err, "verify answer")
+
+	if q.Signer != theirDID.VerKey() {
+		glog.Warningf("answer signer %s isn't the connection key", q.Signer)
+		return fmt.Errorf("signer isn't the connection key")
+	}
+	data := try.To1(utils.DecodeB64(q.SigData))
+	if len(data) < 8 || !bytes.Equal(data[8:],
+		questionanswer.SignedData(q.QuestionText, q.Response, q.Nonce)) {
+		return fmt.Errorf("signed data doesn't match the answer")
+	}
+	sig := try.To1(utils.DecodeB64(q.Signature))
+
+	verifier := signature.Verifier{DID: theirDID}
+	try.To(verifier.Verify(data, sig))
+	return nil
+}
+
+func attachments(data []byte) (a []decorator.Attachment, err error) {
+	defer err2.Handle(&err, "attachments")
+
+	try.To(json.Unmarshal(data, &a))
+	return a, nil
+}
+
+// verifyAttachments tells if the attachments are signed by the connection.
+// All the signed attachments must be valid, and at least one of them must be
+// signed.
+func verifyAttachments(theirDID core.DID, atts []decorator.Attachment) bool {
+	verifier := signature.Verifier{DID: theirDID}
+	signed := false
+	for i := range atts {
+		if atts[i].Data.JWS == nil {
+			continue
+		}
+		if err := verifier.VerifyAttachment(&atts[i].Data); err != nil {
+			glog.Warningf("attachment %s: %v", atts[i].ID, err)
+			return false
+		}
+		signed = true
+	}
+	return signed
+}
+
+// fillQuestionAnswerStatus returns the common status only. The question and
+// the answer, with its signature and expiration state, are read with
+// GetQuestion.
+func fillQuestionAnswerStatus(_ string, _ string, ps *pb.ProtocolStatus) *pb.ProtocolStatus {
+	assert.That(ps != nil)
+	return ps
+}
@@ -0,0 +1,78 @@
+package questionanswer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lainio/err2/assert"
+)
+
+func TestNewTask(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	q := Question{
+		ConnectionID:   "conn-id",
+		QuestionText:   "Do you authorize the payment?",
+		ValidResponses: []string{"Yes", "No"},
+	}
+	task, err := NewTask(q)
+	assert.NoError(err)
+	assert.Equal(task.ConnectionID(), "conn-id")
+
+	q.Attachments = []byte(`[{"@id":"1","data":{"json":{"amount":100}}}]`)
+	_, err = NewTask(q)
+	assert.NoError(err)
+	q.Attachments = []byte("invalid")
+	_, err = NewTask(q)
+	assert.Error(err)
+	q.Attachments = nil
+
+	q.ValidResponses = nil
+	_, err = NewTask(q)
+	assert.Error(err)
+	q.QuestionText = ""
+	_, err = NewTask(q)
+	assert.Error(err)
+}
+
+func TestQuestion(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	q := Question{ValidResponses: []string{"Yes", "No"}}
+	assert.That(q.IsValid("Yes"))
+	assert.That(!q.IsValid("Maybe"))
+	assert.That(!q.expired())
+
+	q.ExpiresTime = time.Now().Add(-time.Second).UnixNano()
+	assert.That(q.expired())
+
+	q.Response = "Yes"
+	assert.NoError(q.checkAnswer())
+	q.Expired = q.expired()
+	assert.Error(q.checkAnswer())
+
+	q.Expired = false
+	q.Response = "Maybe"
+	assert.Error(q.checkAnswer())
+	q.Response = "No"
+	q.SignatureRequired = true
+	assert.Error(q.checkAnswer())
+	q.Verified = true
+	assert.NoError(q.checkAnswer())
+
+	data := stamp([]byte("data"))
+	assert.SLen(data, 12)
+	assert.Equal(string(data[8:]), "data")
+}
+
+func TestVerifyAttachments(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	atts, err := attachments([]byte(`[{"@id":"1","data":{"base64":"aGVsbG8="}}]`))
+	assert.NoError(err)
+	assert.SLen(atts, 1)
+	assert.That(!verifyAttachments(nil, atts), "unsigned attachments aren't verified")
+}
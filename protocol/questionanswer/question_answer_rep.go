@@ -0,0 +1,55 @@
+package questionanswer
+
+import (
+	"github.com/findy-network/findy-agent/agent/psm"
+	"github.com/findy-network/findy-common-go/dto"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/assert"
+	"github.com/lainio/err2/try"
+)
+
+const bucketType = psm.BucketQuestionAnswer
+
+type questionAnswerRep struct {
+	psm.StateKey
+	Question Question
+}
+
+func init() {
+	psm.Creator.Add(bucketType, NewQuestionAnswerRep)
+}
+
+func NewQuestionAnswerRep(d []byte) psm.Rep {
+	p := &questionAnswerRep{}
+	dto.FromGOB(d, p)
+	return p
+}
+
+func (p *questionAnswerRep) Key() psm.StateKey {
+	return p.StateKey
+}
+
+func (p *questionAnswerRep) Data() []byte {
+	return dto.ToGOB(p)
+}
+
+func (p *questionAnswerRep) Type() byte {
+	return bucketType
+}
+
+func getQuestionAnswerRep(key psm.StateKey) (rep *questionAnswerRep, err error) {
+	defer err2.Handle(&err)
+
+	res := try.To1(psm.GetRep(bucketType, key))
+
+	// Allow not found
+	if res == nil {
+		return nil, nil
+	}
+
+	var ok bool
+	rep, ok = res.(*questionAnswerRep)
+	assert.That(ok, "question answer type mismatch")
+
+	return rep, nil
+}
@@ -0,0 +1,303 @@
+package trustping
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/findy-network/findy-agent/agent/bus"
+	"github.com/findy-network/findy-agent/agent/comm"
+	"github.com/findy-network/findy-agent/agent/pltype"
+	"github.com/findy-network/findy-agent/agent/prot"
+	"github.com/findy-network/findy-agent/agent/psm"
+	storage "github.com/findy-network/findy-agent/agent/storage/api"
+	"github.com/findy-network/findy-agent/agent/utils"
+	pb "github.com/findy-network/findy-common-go/grpc/agency/v1"
+	"github.com/golang/glog"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/assert"
+	"github.com/lainio/err2/try"
+)
+
+// Default settings of the health monitor.
+const (
+	DefaultInterval     = 5 * time.Minute
+	DefaultTimeout      = 30 * time.Second
+	DefaultFailureLimit = 3
+	DefaultMaxPings     = 10
+)
+
+// MonitorConfig is the configuration of the connection health monitor.
+// Timeout is how long we wait the response of one ping, and the connection
+// is marked unreachable after FailureLimit successive failed pings. At most
+// MaxPings connections are pinged at the same time.
+type MonitorConfig struct {
+	Interval     time.Duration
+	Timeout      time.Duration
+	FailureLimit int
+	MaxPings     int
+}
+
+type monitor struct {
+	MonitorConfig
+	stop chan struct{}
+}
+
+// monitors are the running health monitors by the worker agent DID. The
+// configuration is saved to the agent's settings, and the monitor is started
+// again when the agent is opened after the agency restarts.
+var monitors = struct {
+	sync.Mutex
+	m map[string]*monitor
+}{m: make(map[string]*monitor)}
+
+func (c *MonitorConfig) setDefaults() {
+	if c.Interval <= 0 {
+		c.Interval = DefaultInterval
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+	if c.FailureLimit <= 0 {
+		c.FailureLimit = DefaultFailureLimit
+	}
+	if c.MaxPings <= 0 {
+		c.MaxPings = DefaultMaxPings
+	}
+}
+
+func init() {
+	comm.AddOpener(resumeMonitor)
+}
+
+// resumeMonitor starts the health monitor of the CA if it was running when
+// the agency stopped.
+func resumeMonitor(ca comm.Receiver) {
+	defer err2.Catch(err2.Err(func(err error) {
+		glog.Error("resume health monitor: ", err)
+	}))
+
+	settings := try.To1(settingStorage(ca).GetSettings())
+	if cfg := settings.HealthMonitor; cfg != nil {
+		try.To1(StartMonitor(ca, MonitorConfig(*cfg)))
+	}
+}
+
+// StartMonitor starts the health monitor of the CA. The monitor trust pings
+// all the connections of the worker agent every interval, and records the
+// results to the connections. The controllers are notified when a connection
+// becomes unreachable or reachable again. A running monitor of the CA is
+// restarted with the new configuration.
+func StartMonitor(ca comm.Receiver, cfg MonitorConfig) (_ MonitorConfig, err error) {
+	defer err2.Handle(&err, "start health monitor")
+
+	cfg.setDefaults()
+	if cfg.Timeout >= cfg.Interval {
+		return cfg, fmt.Errorf("timeout (%s) must be shorter than interval (%s)",
+			cfg.Timeout, cfg.Interval)
+	}
+	workerDID := ca.WorkerEA().MyDID().Did()
+
+	monitors.Lock()
+	defer monitors.Unlock()
+
+	try.To1(settingStorage(ca).UpdateSettings(func(s *storage.Settings) error {
+		saved := storage.HealthMonitorConfig(cfg)
+		s.HealthMonitor = &saved
+		return nil
+	}))
+	if m, ok := monitors.m[workerDID]; ok {
+		close(m.stop)
+	}
+	m := &monitor{MonitorConfig: cfg, stop: make(chan struct{})}
+	monitors.m[workerDID] = m
+	go m.run(ca)
+
+	glog.V(1).Infof("health monitor started for %s: %+v", workerDID, cfg)
+	return cfg, nil
+}
+
+// StopMonitor stops the health monitor of the CA. It returns false if the
+// monitor wasn't running.
+func StopMonitor(ca comm.Receiver) (_ bool, err error) {
+	defer err2.Handle(&err, "stop health monitor")
+
+	workerDID := ca.WorkerEA().MyDID().Did()
+
+	monitors.Lock()
+	defer monitors.Unlock()
+
+	try.To1(settingStorage(ca).UpdateSettings(func(s *storage.Settings) error {
+		s.HealthMonitor = nil
+		return nil
+	}))
+	m, ok := monitors.m[workerDID]
+	if ok {
+		close(m.stop)
+		delete(monitors.m, workerDID)
+		glog.V(1).Infoln("health monitor stopped for", workerDID)
+	}
+	return ok, nil
+}
+
+// Monitor returns the configuration of the running health monitor of the
+// worker agent.
+func Monitor(workerDID string) (cfg MonitorConfig, running bool) {
+	monitors.Lock()
+	defer monitors.Unlock()
+
+	m, ok := monitors.m[workerDID]
+	if !ok {
+		return cfg, false
+	}
+	return m.MonitorConfig, true
+}
+
+func (m *monitor) run(ca comm.Receiver) {
+	ticker := time.NewTicker(m.Interval)
+	defer ticker.Stop()
+
+	for {
+		m.pingAll(ca)
+		select {
+		case <-ticker.C:
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+// pingAll pings all the connections of the worker agent, MaxPings of them in
+// parallel, and waits until all of them are done.
+func (m *monitor) pingAll(ca comm.Receiver) {
+	defer err2.Catch(err2.Err(func(err error) {
+		glog.Error("health monitor round: ", err)
+	}))
+
+	store := connectionStorage(ca)
+	conns := try.To1(store.ListConnections())
+
+	connIDs := make([]string, 0, len(conns))
+	for _, conn := range conns {
+		if conn.TheirDID != "" { // ready
+			connIDs = append(connIDs, conn.ID)
+		}
+	}
+	forEach(connIDs, m.MaxPings, m.stop, func(connID string) {
+		m.ping(ca, store, connID)
+	})
+}
+
+// forEach calls f for the IDs in parallel, max calls at the same time, and
+// waits until all of them are done. The IDs not yet started are skipped when
+// the stop channel is closed.
+func forEach(ids []string, max int, stop <-chan struct{}, f func(id string)) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	sem := make(chan struct{}, max)
+	for _, id := range ids {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-stop:
+			return
+		}
+		wg.Add(1)
+		go func(id string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			f(id)
+		}(id)
+	}
+}
+
+func (m *monitor) ping(ca comm.Receiver, store storage.ConnectionStorage, connID string) {
+	defer err2.Catch(err2.Err(func(err error) {
+		glog.Errorf("health monitor ping %s: %s", connID, err)
+	}))
+
+	task := try.To1(NewTask(connID, true))
+	key := psm.NewStateKey(ca.WorkerEA(), task.ID())
+	statusChan := bus.WantAll.AddListener(key)
+	defer bus.WantAll.RmListener(key)
+
+	at := time.Now()
+	prot.FindAndStartTask(ca, task)
+	replied := m.waitResponse(statusChan)
+	latency := time.Since(at)
+
+	changed := false
+	conn := try.To1(store.UpdateConnection(connID,
+		func(conn *storage.Connection, found bool) error {
+			if !found { // deleted while pinging
+				return fmt.Errorf("connection not found")
+			}
+			changed = conn.Health.Update(replied, at, latency, m.FailureLimit)
+			return nil
+		}))
+
+	glog.V(3).Infof("health %s: %+v", connID, conn.Health)
+	if changed {
+		notifyHealth(key, connID, conn.Health)
+	}
+}
+
+// waitResponse waits the ping response until the timeout. It returns false
+// if the ping failed, the monitor was stopped, or the time ran out.
+func (m *monitor) waitResponse(statusChan bus.StateChan) bool {
+	timer := time.NewTimer(m.Timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case status := <-statusChan:
+			switch status {
+			case psm.ReadyACK:
+				return true
+			case psm.ReadyNACK, psm.Failure, psm.SystemReboot:
+				return false
+			}
+		case <-timer.C:
+			return false
+		case <-m.stop:
+			return false
+		}
+	}
+}
+
+// notifyHealth notifies the controllers that the connection's reachability has
+// changed. The protocol ID is the ping which caused the change.
+func notifyHealth(key psm.StateKey, connID string, h storage.ConnectionHealth) {
+	glog.V(1).Infof("connection %s unreachable: %v", connID, h.Unreachable)
+	bus.WantAllAgentActions.AgentBroadcast(bus.AgentNotify{
+		AgentKeyType:     bus.AgentKeyType{AgentDID: key.DID},
+		ID:               utils.UUID(),
+		NotificationType: pltype.CANotifyStatus,
+		ProtocolID:       key.Nonce,
+		ProtocolFamily:   pltype.ProtocolTrustPing,
+		ConnectionID:     connID,
+		Timestamp:        h.LastPing,
+		Role:             pb.Protocol_INITIATOR,
+	})
+}
+
+func settingStorage(ca comm.Receiver) storage.SettingStorage {
+	_, ms := ca.WorkerEA().ManagedWallet()
+	store := ms.Storage().SettingStorage()
+	assert.That(store != nil, "setting storage not available")
+	return store
+}
+
+func connectionStorage(ca comm.Receiver) storage.ConnectionStorage {
+	_, ms := ca.WorkerEA().ManagedWallet()
+	store := ms.Storage().ConnectionStorage()
+	assert.That(store != nil, "connection storage not available")
+	return store
+}
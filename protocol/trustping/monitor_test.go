@@ -0,0 +1,78 @@
+package trustping
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lainio/err2/assert"
+)
+
+func TestMonitorConfig_setDefaults(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	var cfg MonitorConfig
+	cfg.setDefaults()
+	assert.Equal(cfg.Interval, DefaultInterval)
+	assert.Equal(cfg.Timeout, DefaultTimeout)
+	assert.Equal(cfg.FailureLimit, DefaultFailureLimit)
+	assert.Equal(cfg.MaxPings, DefaultMaxPings)
+
+	cfg = MonitorConfig{Interval: time.Minute, Timeout: time.Second,
+		FailureLimit: 1, MaxPings: 2}
+	cfg.setDefaults()
+	assert.Equal(cfg.Interval, time.Minute)
+	assert.Equal(cfg.Timeout, time.Second)
+	assert.Equal(cfg.FailureLimit, 1)
+	assert.Equal(cfg.MaxPings, 2)
+}
+
+func TestMonitor_notRunning(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	_, running := Monitor("did:example:123")
+	assert.That(!running)
+}
+
+func TestForEach(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	ids := make([]string, 20)
+	for i := range ids {
+		ids[i] = fmt.Sprint(i)
+	}
+
+	var lk sync.Mutex
+	running, maxRunning, calls := 0, 0, 0
+	forEach(ids, 3, make(chan struct{}), func(string) {
+		lk.Lock()
+		running++
+		calls++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		lk.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		lk.Lock()
+		running--
+		lk.Unlock()
+	})
+	assert.Equal(calls, len(ids))
+	assert.That(maxRunning <= 3)
+
+	stop := make(chan struct{})
+	close(stop)
+	calls = 0
+	forEach(ids, 1, stop, func(string) {
+		lk.Lock()
+		calls++
+		lk.Unlock()
+	})
+	assert.Equal(calls, 0)
+}
@@ -2,12 +2,15 @@ package trustping
 
 import (
 	"encoding/gob"
+	"fmt"
 
 	"github.com/findy-network/findy-agent/agent/comm"
 	"github.com/findy-network/findy-agent/agent/didcomm"
 	"github.com/findy-network/findy-agent/agent/pltype"
 	"github.com/findy-network/findy-agent/agent/prot"
 	"github.com/findy-network/findy-agent/agent/psm"
+	"github.com/findy-network/findy-agent/agent/utils"
+	"github.com/findy-network/findy-agent/std/trustping"
 	pb "github.com/findy-network/findy-common-go/grpc/agency/v1"
 	"github.com/golang/glog"
 	"github.com/lainio/err2"
@@ -51,6 +54,24 @@ func createTrustPingTask(header *comm.TaskHeader, protocol *pb.Protocol) (t comm
 	}, nil
 }
 
+// NewTask creates a trust ping task for the connection. A silent task doesn't
+// notify the controllers, which is how the health monitor pings.
+func NewTask(connID string, silent bool) (t comm.Task, err error) {
+	if connID == "" {
+		return nil, fmt.Errorf("connection is needed for trust ping")
+	}
+	return &taskTrustPing{
+		TaskBase: comm.TaskBase{TaskHeader: comm.TaskHeader{
+			TaskID:       utils.UUID(),
+			TypeID:       pltype.CATrustPing,
+			ProtocolRole: pb.Protocol_INITIATOR,
+			ConnID:       connID,
+			Method:       utils.Settings.DIDMethod(),
+			Silent:       silent,
+		}},
+	}, nil
+}
+
 func startTrustPing(ca comm.Receiver, t comm.Task) {
 	defer err2.Catch()
 	try.To(prot.StartPSM(prot.Initial{
@@ -65,9 +86,15 @@ func startTrustPing(ca comm.Receiver, t comm.Task) {
 }
 
 func handleTrustPing(packet comm.Packet) (err error) {
+	sendNext := pltype.TrustPingResponse
+	ping, ok := packet.Payload.MsgHdr().FieldObj().(*trustping.Ping)
+	if ok && !ping.WantsResponse() {
+		glog.V(3).Info("ping without response request")
+		sendNext = pltype.Terminate
+	}
 	return prot.ExecPSM(prot.Transition{
 		Packet:      packet,
-		SendNext:    pltype.TrustPingResponse,
+		SendNext:    sendNext,
 		WaitingNext: pltype.Terminate,
 		InOut: func(_ string, _, om didcomm.MessageHdr) (ack bool, err error) {
 			glog.V(3).Info("-- Thread ID: ", om.Thread().ID)
@@ -88,7 +115,9 @@ func handleTrustPingResponse(packet comm.Packet) (err error) {
 	})
 }
 
-func fillTrustPingStatus(_ string, _ string, ps *pb.ProtocolStatus) *pb.ProtocolStatus {
+// fillTrustPingStatus fills the status of the ping. It's replied when the
+// PSM has ended successfully, i.e. we have got or sent the response.
+func fillTrustPingStatus(workerDID string, taskID string, ps *pb.ProtocolStatus) *pb.ProtocolStatus {
 	defer err2.Catch(err2.Err(func(err error) {
 		glog.Error("Failed to fill trust ping status: ", err)
 	}))
@@ -97,9 +126,11 @@ func fillTrustPingStatus(_ string, _ string, ps *pb.ProtocolStatus) *pb.Protocol
 
 	status := ps
 
-	// TODO
+	m := try.To1(psm.GetPSM(psm.StateKey{DID: workerDID, Nonce: taskID}))
+	replied := m.LastState().Sub == psm.ReadyACK
+
 	status.Status = &pb.ProtocolStatus_TrustPing{
-		TrustPing: &pb.ProtocolStatus_TrustPingStatus{Replied: false},
+		TrustPing: &pb.ProtocolStatus_TrustPingStatus{Replied: replied},
 	}
 
 	return status
@@ -0,0 +1,92 @@
+package actionmenu
+
+import (
+	"encoding/gob"
+
+	"github.com/findy-network/findy-agent/agent/aries"
+	"github.com/findy-network/findy-agent/agent/didcomm"
+	"github.com/findy-network/findy-agent/agent/pltype"
+	"github.com/findy-network/findy-agent/std/decorator"
+	"github.com/findy-network/findy-common-go/dto"
+)
+
+var Creator = &Factor{}
+
+type Factor struct{}
+
+func (f *Factor) NewMsg(init didcomm.MsgInit) didcomm.MessageHdr {
+	m := &ActionMenu{
+		Type:   init.Type,
+		ID:     init.AID,
+		Thread: decorator.CheckThread(init.Thread, init.AID),
+	}
+	return NewActionMenu(m)
+}
+
+func (f *Factor) NewMessage(data []byte) didcomm.MessageHdr {
+	return NewActionMenuMsg(data)
+}
+
+func init() {
+	gob.Register(&Impl{})
+	for _, t := range []string{
+		pltype.ActionMenuMenu,
+		pltype.ActionMenuMenuRequest,
+		pltype.ActionMenuPerform,
+		pltype.DIDOrgActionMenuMenu,
+		pltype.DIDOrgActionMenuMenuRequest,
+		pltype.DIDOrgActionMenuPerform,
+	} {
+		aries.Creator.Add(t, Creator)
+	}
+}
+
+func NewActionMenu(r *ActionMenu) *Impl {
+	return &Impl{ActionMenu: r}
+}
+
+func NewActionMenuMsg(data []byte) *Impl {
+	var mImpl Impl
+	dto.FromJSON(data, &mImpl)
+	mImpl.checkThread()
+	return &mImpl
+}
+
+// MARK: Helpers
+
+func (p *Impl) checkThread() {
+	p.ActionMenu.Thread = decorator.CheckThread(p.ActionMenu.Thread, p.ActionMenu.ID)
+}
+
+// MARK: Struct
+type Impl struct {
+	*ActionMenu
+}
+
+func (p *Impl) ID() string {
+	return p.ActionMenu.ID
+}
+
+func (p *Impl) Type() string {
+	return p.ActionMenu.Type
+}
+
+func (p *Impl) SetID(id string) {
+	p.ActionMenu.ID = id
+}
+
+func (p *Impl) SetType(t string) {
+	p.ActionMenu.Type = t
+}
+
+func (p *Impl) JSON() []byte {
+	return dto.ToJSONBytes(p)
+}
+
+func (p *Impl) Thread() *decorator.Thread {
+	return p.ActionMenu.Thread
+}
+
+func (p *Impl) FieldObj() interface{} {
+	return p.ActionMenu
+}
@@ -0,0 +1,91 @@
+package actionmenu
+
+import (
+	"fmt"
+
+	"github.com/findy-network/findy-agent/std/decorator"
+)
+
+// ActionMenu is the message of Aries RFC 0509 action menu protocol. The same
+// struct is used for all of the messages: menu uses the menu fields, perform
+// the Name and the Params, and menu-request has no fields.
+type ActionMenu struct {
+	Type   string            `json:"@type,omitempty"`
+	ID     string            `json:"@id,omitempty"`
+	Thread *decorator.Thread `json:"~thread,omitempty"`
+
+	Menu
+
+	Name   string            `json:"name,omitempty"`
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// Menu is the content of the menu message.
+type Menu struct {
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	ErrorMsg    string   `json:"errormsg,omitempty"`
+	Options     []Option `json:"options,omitempty"`
+}
+
+// Option is an action of the menu.
+type Option struct {
+	Name        string `json:"name"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Disabled    bool   `json:"disabled,omitempty"`
+	Form        *Form  `json:"form,omitempty"`
+}
+
+// Form is the form of the option, which values are sent as the params of the
+// perform message.
+type Form struct {
+	Title       string      `json:"title,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Params      []FormParam `json:"params,omitempty"`
+	SubmitLabel string      `json:"submit-label,omitempty"`
+}
+
+// FormParam is a field of the form.
+type FormParam struct {
+	Name        string `json:"name"`
+	Title       string `json:"title"`
+	Default     string `json:"default,omitempty"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	Type        string `json:"type,omitempty"`
+}
+
+// CheckPerform returns an error if the perform doesn't match the menu: the
+// option must be in the menu and enabled, the required params of its form
+// must be given, and other params than the form's aren't allowed.
+func (m Menu) CheckPerform(name string, params map[string]string) error {
+	for _, o := range m.Options {
+		if o.Name != name {
+			continue
+		}
+		if o.Disabled {
+			return fmt.Errorf("action menu option %s is disabled", name)
+		}
+		var formParams []FormParam
+		if o.Form != nil {
+			formParams = o.Form.Params
+		}
+		known := make(map[string]bool, len(formParams))
+		for _, p := range formParams {
+			known[p.Name] = true
+			if p.Required && params[p.Name] == "" {
+				return fmt.Errorf("action menu option %s: param %s is required",
+					name, p.Name)
+			}
+		}
+		for p := range params {
+			if !known[p] {
+				return fmt.Errorf("action menu option %s: unknown param %s",
+					name, p)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("action menu option %s not in the menu", name)
+}
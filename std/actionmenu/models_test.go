@@ -0,0 +1,91 @@
+package actionmenu
+
+import (
+	"testing"
+
+	"github.com/findy-network/findy-agent/agent/aries"
+	"github.com/lainio/err2/assert"
+)
+
+var menuJSON = `{
+    "@type": "https://didcomm.org/action-menu/1.0/menu",
+    "@id": "5678876542344",
+    "title": "Welcome to IIWBook",
+    "description": "IIWBook facilitates connections between attendees.",
+    "errormsg": "No IIWBook names were found.",
+    "options": [
+      {
+        "name": "search-introductions",
+        "title": "Search introductions",
+        "description": "Filter attendee records to make a connection",
+        "form": {
+          "title": "Search introductions",
+          "description": "Enter a participant name below to perform a search.",
+          "params": [
+            {
+              "name": "query",
+              "title": "Attendee name",
+              "default": "",
+              "description": "",
+              "required": true,
+              "type": "text"
+            }
+          ],
+          "submit-label": "Search"
+        }
+      }
+    ]
+  }`
+
+var performJSON = `{
+    "@type": "https://didcomm.org/action-menu/1.0/perform",
+    "@id": "5678876542347",
+    "~thread": {
+      "thid": "5678876542344"
+    },
+    "name": "search-introductions",
+    "params": {
+      "query": "Alice"
+    }
+  }`
+
+func TestNewActionMenu(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	ipl := aries.PayloadCreator.NewFromData([]byte(menuJSON))
+	msg, ok := ipl.MsgHdr().FieldObj().(*ActionMenu)
+	assert.That(ok)
+	assert.Equal(msg.Title, "Welcome to IIWBook")
+	assert.SLen(msg.Options, 1)
+	assert.SLen(msg.Options[0].Form.Params, 1)
+	assert.That(msg.Options[0].Form.Params[0].Required)
+
+	ipl = aries.PayloadCreator.NewFromData([]byte(performJSON))
+	assert.Equal(ipl.ThreadID(), "5678876542344")
+	msg, ok = ipl.MsgHdr().FieldObj().(*ActionMenu)
+	assert.That(ok)
+	assert.Equal(msg.Name, "search-introductions")
+	assert.Equal(msg.Params["query"], "Alice")
+}
+
+func TestCheckPerform(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	ipl := aries.PayloadCreator.NewFromData([]byte(menuJSON))
+	msg, ok := ipl.MsgHdr().FieldObj().(*ActionMenu)
+	assert.That(ok)
+	menu := msg.Menu
+
+	assert.NoError(menu.CheckPerform("search-introductions",
+		map[string]string{"query": "Alice"}))
+	assert.Error(menu.CheckPerform("search-introductions", nil))
+	assert.Error(menu.CheckPerform("search-introductions",
+		map[string]string{"query": "Alice", "other": "x"}))
+	assert.Error(menu.CheckPerform("unknown", nil))
+
+	menu.Options[0].Disabled = true
+	assert.Error(menu.CheckPerform("search-introductions",
+		map[string]string{"query": "Alice"}))
+}
@@ -1,7 +1,11 @@
 package basicmessage
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 
@@ -25,6 +29,54 @@ type Basicmessage struct {
 	Thread   *decorator.Thread `json:"~thread,omitempty"`
 	Content  string            `json:"content"`
 	SentTime AriesTime         `json:"sent_time"`
+
+	Attachments []decorator.Attachment `json:"~attach,omitempty"`
+}
+
+// Limits of the attachments. The size limit is for the decoded inline data of
+// one attachment.
+const (
+	MaxAttachments    = 10
+	MaxAttachmentSize = 5 << 20
+)
+
+// CheckAttachments checks the size limits of the attachments, and that they
+// have the content inline in base64 or as links with a hash. The hash of
+// inline data is checked if it's given.
+func CheckAttachments(atts []decorator.Attachment) (err error) {
+	defer err2.Handle(&err, "check attachments")
+
+	if len(atts) > MaxAttachments {
+		return fmt.Errorf("too many attachments: %d > %d", len(atts), MaxAttachments)
+	}
+	for _, a := range atts {
+		switch {
+		case a.Data.JSON != nil:
+			return fmt.Errorf("attachment %s: JSON data isn't supported", a.FileName)
+		case a.Data.Base64 != "":
+			data := try.To1(base64.StdEncoding.DecodeString(a.Data.Base64))
+			if len(data) > MaxAttachmentSize {
+				return fmt.Errorf("attachment %s too big: %d > %d",
+					a.FileName, len(data), MaxAttachmentSize)
+			}
+			if a.Data.Sha256 != "" && a.Data.Sha256 != Sha256(data) {
+				return fmt.Errorf("attachment %s hash mismatch", a.FileName)
+			}
+		case len(a.Data.Links) > 0:
+			if a.Data.Sha256 == "" {
+				return fmt.Errorf("attachment %s link without hash", a.FileName)
+			}
+		default:
+			return fmt.Errorf("attachment %s has no data", a.FileName)
+		}
+	}
+	return nil
+}
+
+// Sha256 returns the hex encoded hash of the attachment data.
+func Sha256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
 }
 
 func validateTimestamp(timeStr string) (t time.Time, err error) {
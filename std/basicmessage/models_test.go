@@ -1,11 +1,13 @@
 package basicmessage
 
 import (
+	"encoding/base64"
 	"testing"
 	"time"
 
 	"github.com/findy-network/findy-agent/agent/didcomm"
 	"github.com/findy-network/findy-agent/agent/pltype"
+	"github.com/findy-network/findy-agent/std/decorator"
 	"github.com/findy-network/findy-common-go/dto"
 
 	"github.com/findy-network/findy-agent/agent/aries"
@@ -89,3 +91,68 @@ func TestBasicMessage_MsgPingPong(t *testing.T) {
 	assert.Equal("hello", msg.Content)
 	//assert.Equal( offer.Thread().ID, firstMsgID)
 }
+
+func TestCheckAttachments(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	data := []byte("hello")
+	inline := decorator.Attachment{
+		FileName: "hello.txt",
+		MimeType: "text/plain",
+		Data: decorator.AttachmentData{
+			Base64: base64.StdEncoding.EncodeToString(data),
+			Sha256: Sha256(data),
+		},
+	}
+	link := decorator.Attachment{
+		FileName: "image.png",
+		Data: decorator.AttachmentData{
+			Links:  []string{"https://example.com/image.png"},
+			Sha256: Sha256(data),
+		},
+	}
+	assert.NoError(CheckAttachments([]decorator.Attachment{inline, link}))
+
+	noHash := link
+	noHash.Data.Sha256 = ""
+	assert.Error(CheckAttachments([]decorator.Attachment{noHash}))
+
+	badHash := inline
+	badHash.Data.Sha256 = Sha256([]byte("other"))
+	assert.Error(CheckAttachments([]decorator.Attachment{badHash}))
+
+	tooBig := inline
+	tooBig.Data = decorator.AttachmentData{
+		Base64: base64.StdEncoding.EncodeToString(make([]byte, MaxAttachmentSize+1)),
+	}
+	assert.Error(CheckAttachments([]decorator.Attachment{tooBig}))
+	assert.Error(CheckAttachments([]decorator.Attachment{{FileName: "empty"}}))
+	assert.Error(CheckAttachments([]decorator.Attachment{{
+		Data: decorator.AttachmentData{JSON: map[string]interface{}{"a": 1}},
+	}}))
+	assert.Error(CheckAttachments(make([]decorator.Attachment, MaxAttachments+1)))
+}
+
+func TestAttachmentJSON(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	ipl := aries.PayloadCreator.NewFromData([]byte(`{
+    "@type": "https://didcomm.org/basicmessage/1.0/message",
+    "@id": "a70a5db1-0b35-41d2-a602-e355ec4df680",
+    "content": "see attachment",
+    "sent_time": "2020-01-20 12:06:36.225671Z",
+    "~attach": [{
+      "@id": "att-1",
+      "filename": "hello.txt",
+      "mime-type": "text/plain",
+      "data": {"base64": "aGVsbG8="}
+    }]
+  }`))
+	msg, ok := ipl.MsgHdr().FieldObj().(*Basicmessage)
+	assert.That(ok)
+	assert.SLen(msg.Attachments, 1)
+	assert.Equal(msg.Attachments[0].FileName, "hello.txt")
+	assert.NoError(CheckAttachments(msg.Attachments))
+}
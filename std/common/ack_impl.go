@@ -30,10 +30,14 @@ func (f *AckFactor) NewMessage(data []byte) didcomm.MessageHdr {
 
 func init() {
 	gob.Register(&AckImpl{})
+	aries.Creator.Add(pltype.NotificationAck, AckCreator)
+	aries.Creator.Add(pltype.DIDOrgNotificationAck, AckCreator)
 	aries.Creator.Add(pltype.IssueCredentialACK, AckCreator)
 	aries.Creator.Add(pltype.PresentProofACK, AckCreator)
+	aries.Creator.Add(pltype.PresentProofV2ACK, AckCreator)
 	aries.Creator.Add(pltype.DIDOrgIssueCredentialACK, AckCreator)
 	aries.Creator.Add(pltype.DIDOrgPresentProofACK, AckCreator)
+	aries.Creator.Add(pltype.DIDOrgPresentProofV2ACK, AckCreator)
 }
 
 func NewAck(r *Ack) *AckImpl {
@@ -6,17 +6,59 @@ package common
 
 import "github.com/findy-network/findy-agent/std/decorator"
 
-// ProblemReport problem report definition
-// TODO: need to provide full ProblemReport structure https://github.com/hyperledger/aries-framework-go/issues/912
+// ProblemReport is the problem report of Aries RFC 0035. The same struct is
+// used for the problem reports of all the protocol families.
 type ProblemReport struct {
-	Type           string            `json:"@type"`
-	ID             string            `json:"@id"`
-	Description    Code              `json:"description"`
-	ExplainLongTxt string            `json:"explain-ltxt,omitempty"` // ACApy
-	Thread         *decorator.Thread `json:"~thread,omitempty"`
+	Type           string              `json:"@type"`
+	ID             string              `json:"@id"`
+	Description    Code                `json:"description"`
+	ProblemItems   []map[string]string `json:"problem_items,omitempty"`
+	WhoRetries     string              `json:"who_retries,omitempty"`
+	FixHint        *Text               `json:"fix_hint,omitempty"`
+	Impact         string              `json:"impact,omitempty"`
+	Where          string              `json:"where,omitempty"`
+	NoticedTime    string              `json:"noticed_time,omitempty"`
+	ExplainLongTxt string              `json:"explain-ltxt,omitempty"` // ACApy
+	Thread         *decorator.Thread   `json:"~thread,omitempty"`
 }
 
-// Code represents a problem report code
+// Code represents a problem report code and its English description
 type Code struct {
 	Code string `json:"code"`
+	En   string `json:"en,omitempty"`
+}
+
+// Text is a localized text, only English is supported.
+type Text struct {
+	En string `json:"en,omitempty"`
+}
+
+// Values of the impact and who_retries fields, and the problem codes we use.
+const (
+	ImpactMessage    = "message"
+	ImpactThread     = "thread"
+	ImpactConnection = "connection"
+
+	WhoRetriesMe   = "me"
+	WhoRetriesYou  = "you"
+	WhoRetriesBoth = "both"
+	WhoRetriesNone = "none"
+
+	CodeRequestNotAccepted     = "request_not_accepted"
+	CodeRequestProcessingError = "request_processing_error"
+	CodeExpired                = "expired"
+	CodeAbandoned              = "abandoned"
+)
+
+// Reason returns the human readable reason of the problem: the description,
+// the ACA-Py's explain text or the code.
+func (p *ProblemReport) Reason() string {
+	switch {
+	case p.Description.En != "":
+		return p.Description.En
+	case p.ExplainLongTxt != "":
+		return p.ExplainLongTxt
+	default:
+		return p.Description.Code
+	}
 }
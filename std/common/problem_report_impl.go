@@ -32,6 +32,14 @@ func init() {
 	gob.Register(&ProblemReportImpl{})
 	aries.Creator.Add(pltype.NotificationProblemReport, ProblemReportCreator)
 	aries.Creator.Add(pltype.DIDOrgNotificationProblemReport, ProblemReportCreator)
+	aries.Creator.Add(pltype.ReportProblemProblemReport, ProblemReportCreator)
+	aries.Creator.Add(pltype.DIDOrgReportProblemProblemReport, ProblemReportCreator)
+	aries.Creator.Add(pltype.IssueCredentialProblemReport, ProblemReportCreator)
+	aries.Creator.Add(pltype.DIDOrgIssueCredentialProblemReport, ProblemReportCreator)
+	aries.Creator.Add(pltype.PresentProofProblemReport, ProblemReportCreator)
+	aries.Creator.Add(pltype.DIDOrgPresentProofProblemReport, ProblemReportCreator)
+	aries.Creator.Add(pltype.PresentProofV2ProblemReport, ProblemReportCreator)
+	aries.Creator.Add(pltype.DIDOrgPresentProofV2ProblemReport, ProblemReportCreator)
 }
 
 func NewProblemReport(r *ProblemReport) *ProblemReportImpl {
@@ -30,3 +30,42 @@ func TestProblemReport_ReadJSON(t *testing.T) {
 	assert.That(ok)
 	assert.NotEmpty(msg.ExplainLongTxt)
 }
+
+var rfcJSON = `
+{
+  "@type": "https://didcomm.org/report-problem/1.0/problem-report",
+  "@id": "7c9de639-c51c-4d60-ab95-103fa613c805",
+  "~thread": {
+    "pthid": "1e513ad4-48c9-444e-9e7e-5b8b45c5e325"
+  },
+  "description": {
+    "en": "Unable to find a route to the specified recipient.",
+    "code": "cant-find-route"
+  },
+  "problem_items": [
+    { "recipient": "did:sov:C805sNYhMrjHiqZDTUASHg" }
+  ],
+  "who_retries": "you",
+  "impact": "message",
+  "noticed_time": "2019-05-27 18:23:06Z"
+}`
+
+func TestProblemReport_RFC0035(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	ipl := aries.PayloadCreator.NewFromData([]byte(rfcJSON))
+	msg, ok := ipl.MsgHdr().FieldObj().(*ProblemReport)
+	assert.That(ok)
+	assert.Equal(msg.Thread.PID, "1e513ad4-48c9-444e-9e7e-5b8b45c5e325")
+	assert.Equal(msg.Description.Code, "cant-find-route")
+	assert.Equal(msg.WhoRetries, WhoRetriesYou)
+	assert.Equal(msg.Impact, ImpactMessage)
+	assert.SLen(msg.ProblemItems, 1)
+	assert.Equal(msg.Reason(), "Unable to find a route to the specified recipient.")
+
+	acapy := &ProblemReport{ExplainLongTxt: "schema validation failed"}
+	assert.Equal(acapy.Reason(), "schema validation failed")
+	acapy = &ProblemReport{Description: Code{Code: "abandoned"}}
+	assert.Equal(acapy.Reason(), "abandoned")
+}
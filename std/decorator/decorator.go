@@ -21,9 +21,50 @@ type Thread struct {
 	ReceivedOrders map[string]int `json:"received_orders,omitempty"`
 }
 
-// Timing keeps expiration time
+// Timing is the timing decorator of Aries RFC 0032. The zero times are
+// omitted from the JSON.
 type Timing struct {
-	ExpiresTime time.Time `json:"expires_time,omitempty"`
+	InTime        time.Time `json:"in_time,omitempty"`
+	OutTime       time.Time `json:"out_time,omitempty"`
+	StaleTime     time.Time `json:"stale_time,omitempty"`
+	ExpiresTime   time.Time `json:"expires_time,omitempty"`
+	DelayMilli    int       `json:"delay_milli,omitempty"`
+	WaitUntilTime time.Time `json:"wait_until_time,omitempty"`
+}
+
+// PleaseAck is the please ack decorator of Aries RFC 0317. On tells when the
+// ack is wanted: on RECEIPT and/or on OUTCOME of the processing.
+type PleaseAck struct {
+	On []string `json:"on,omitempty"`
+}
+
+// Values of PleaseAck.On
+const (
+	AckOnReceipt = "RECEIPT"
+	AckOnOutcome = "OUTCOME"
+)
+
+// Wants tells if the ack is wanted on the given event. The ack is wanted on
+// receipt if On is empty.
+func (p *PleaseAck) Wants(on string) bool {
+	if p == nil {
+		return false
+	}
+	if len(p.On) == 0 {
+		return on == AckOnReceipt
+	}
+	for _, o := range p.On {
+		if o == on {
+			return true
+		}
+	}
+	return false
+}
+
+// Decorators are the generic decorators which can be in any message.
+type Decorators struct {
+	Timing    *Timing    `json:"~timing,omitempty"`
+	PleaseAck *PleaseAck `json:"~please_ack,omitempty"`
 }
 
 // Transport transport decorator
@@ -80,4 +121,8 @@ type AttachmentData struct {
 	// JSON is a directly embedded JSON data, when representing content inline instead of via links,
 	// and when the content is natively conveyable as JSON. Optional.
 	JSON interface{} `json:"json,omitempty"`
+	// JWS is a signature of the content. The payload of the JWS is detached,
+	// i.e. the signed content is the base64 data of the attachment, or the
+	// content of the links. Optional.
+	JWS *JWS `json:"jws,omitempty"`
 }
@@ -1,5 +1,14 @@
 package decorator
 
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/try"
+)
+
 func NewThread(ID, PID string) *Thread {
 	realPID := ""
 	if ID != PID {
@@ -17,3 +26,96 @@ func CheckThread(thread *Thread, ID string) *Thread {
 	}
 	return thread
 }
+
+// timeFormats are the formats we accept in the timing decorator. The RFC
+// uses ISO 8601 with a space or T as the separator.
+var timeFormats = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z0700",
+	"2006-01-02T15:04:05.999999999Z0700",
+}
+
+type jsonTiming struct {
+	InTime        string `json:"in_time,omitempty"`
+	OutTime       string `json:"out_time,omitempty"`
+	StaleTime     string `json:"stale_time,omitempty"`
+	ExpiresTime   string `json:"expires_time,omitempty"`
+	DelayMilli    int    `json:"delay_milli,omitempty"`
+	WaitUntilTime string `json:"wait_until_time,omitempty"`
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func parseTime(s string) (t time.Time, err error) {
+	if s == "" {
+		return t, nil
+	}
+	for _, f := range timeFormats {
+		if t, err = time.Parse(f, s); err == nil {
+			return t, nil
+		}
+	}
+	return t, fmt.Errorf("timing: cannot parse time %q", s)
+}
+
+func (t Timing) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonTiming{
+		InTime:        formatTime(t.InTime),
+		OutTime:       formatTime(t.OutTime),
+		StaleTime:     formatTime(t.StaleTime),
+		ExpiresTime:   formatTime(t.ExpiresTime),
+		DelayMilli:    t.DelayMilli,
+		WaitUntilTime: formatTime(t.WaitUntilTime),
+	})
+}
+
+func (t *Timing) UnmarshalJSON(data []byte) (err error) {
+	defer err2.Handle(&err)
+
+	var jt jsonTiming
+	try.To(json.Unmarshal(data, &jt))
+	*t = Timing{
+		InTime:        try.To1(parseTime(jt.InTime)),
+		OutTime:       try.To1(parseTime(jt.OutTime)),
+		StaleTime:     try.To1(parseTime(jt.StaleTime)),
+		ExpiresTime:   try.To1(parseTime(jt.ExpiresTime)),
+		DelayMilli:    jt.DelayMilli,
+		WaitUntilTime: try.To1(parseTime(jt.WaitUntilTime)),
+	}
+	return nil
+}
+
+// Expired tells if the expiration time of the message has passed.
+func (t *Timing) Expired(now time.Time) bool {
+	return t != nil && !t.ExpiresTime.IsZero() && now.After(t.ExpiresTime)
+}
+
+// ParseDecorators returns the generic decorators of the JSON message.
+func ParseDecorators(data []byte) (d Decorators, err error) {
+	err = json.Unmarshal(data, &d)
+	return d, err
+}
+
+// AddDecorators adds the decorators to the JSON message. The decorators
+// already in the message are kept as they are.
+func AddDecorators(data []byte, d Decorators) (_ []byte, err error) {
+	defer err2.Handle(&err, "add decorators")
+
+	var msg map[string]json.RawMessage
+	try.To(json.Unmarshal(data, &msg))
+
+	var decorators map[string]json.RawMessage
+	try.To(json.Unmarshal(try.To1(json.Marshal(d)), &decorators))
+	for name, value := range decorators {
+		if _, ok := msg[name]; !ok {
+			msg[name] = value
+		}
+	}
+	return json.Marshal(msg)
+}
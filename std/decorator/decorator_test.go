@@ -1,8 +1,10 @@
 package decorator
 
 import (
+	"encoding/json"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestNewThread(t *testing.T) {
@@ -60,3 +62,89 @@ func TestCheckThread(t *testing.T) {
 		})
 	}
 }
+
+func TestTiming_JSON(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want time.Time
+	}{
+		{"RFC3339", `{"expires_time":"2018-12-15T05:29:23Z"}`,
+			time.Date(2018, 12, 15, 5, 29, 23, 0, time.UTC)},
+		{"space", `{"expires_time":"2018-12-15 05:29:23Z"}`,
+			time.Date(2018, 12, 15, 5, 29, 23, 0, time.UTC)},
+		{"offset", `{"expires_time":"2018-12-15 05:29:23+0000"}`,
+			time.Date(2018, 12, 15, 5, 29, 23, 0, time.UTC)},
+		{"missing", `{"delay_milli":10}`, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var timing Timing
+			if err := json.Unmarshal([]byte(tt.json), &timing); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if !timing.ExpiresTime.Equal(tt.want) {
+				t.Errorf("ExpiresTime = %v, want %v", timing.ExpiresTime, tt.want)
+			}
+		})
+	}
+
+	var timing Timing
+	if err := json.Unmarshal([]byte(`{"expires_time":"tomorrow"}`), &timing); err == nil {
+		t.Error("Unmarshal() should fail")
+	}
+	data, _ := json.Marshal(Timing{OutTime: time.Date(2018, 12, 15, 5, 29, 23, 0, time.UTC)})
+	if string(data) != `{"out_time":"2018-12-15T05:29:23Z"}` {
+		t.Errorf("Marshal() = %s", data)
+	}
+}
+
+func TestTiming_Expired(t *testing.T) {
+	now := time.Now()
+	var none *Timing
+	if none.Expired(now) || (&Timing{}).Expired(now) {
+		t.Error("no expiration time should not expire")
+	}
+	if !(&Timing{ExpiresTime: now.Add(-time.Second)}).Expired(now) {
+		t.Error("should be expired")
+	}
+	if (&Timing{ExpiresTime: now.Add(time.Second)}).Expired(now) {
+		t.Error("should not be expired")
+	}
+}
+
+func TestAddDecorators(t *testing.T) {
+	msg := `{"@id":"1","~please_ack":{"on":["OUTCOME"]}}`
+	data, err := AddDecorators([]byte(msg), Decorators{
+		Timing:    &Timing{OutTime: time.Date(2018, 12, 15, 5, 29, 23, 0, time.UTC)},
+		PleaseAck: &PleaseAck{On: []string{AckOnReceipt}},
+	})
+	if err != nil {
+		t.Fatalf("AddDecorators() error = %v", err)
+	}
+	d, err := ParseDecorators(data)
+	if err != nil {
+		t.Fatalf("ParseDecorators() error = %v", err)
+	}
+	if d.Timing == nil || d.Timing.OutTime.IsZero() {
+		t.Errorf("timing missing: %s", data)
+	}
+	if d.PleaseAck == nil || !reflect.DeepEqual(d.PleaseAck.On, []string{AckOnOutcome}) {
+		t.Errorf("please ack should be kept: %s", data)
+	}
+}
+
+func TestPleaseAck_Wants(t *testing.T) {
+	var none *PleaseAck
+	if none.Wants(AckOnReceipt) || none.Wants(AckOnOutcome) {
+		t.Error("no ack should be wanted without the decorator")
+	}
+	empty := &PleaseAck{}
+	if !empty.Wants(AckOnReceipt) || empty.Wants(AckOnOutcome) {
+		t.Error("receipt ack is the default")
+	}
+	outcome := &PleaseAck{On: []string{AckOnOutcome}}
+	if outcome.Wants(AckOnReceipt) || !outcome.Wants(AckOnOutcome) {
+		t.Error("only outcome ack should be wanted")
+	}
+}
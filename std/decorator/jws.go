@@ -0,0 +1,283 @@
+package decorator
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/aries-framework-go/pkg/vdr/fingerprint"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/assert"
+	"github.com/lainio/err2/try"
+)
+
+// AlgEdDSA is the only JWS algorithm we support for the attachments.
+const AlgEdDSA = "EdDSA"
+
+// JWS is a JSON web signature of the attachment data as specified in Aries
+// RFC 0017. The payload is always detached, i.e. the signed content is the
+// data of the attachment. A single signature uses the flattened serialization
+// (RFC 7515), and multiple signatures the general one.
+type JWS struct {
+	JWSSignature
+	Signatures []JWSSignature `json:"signatures,omitempty"`
+}
+
+// JWSSignature is one signature of the JWS. The key ID is in the unprotected
+// header. The protected header is base64 URL encoded JSON.
+type JWSSignature struct {
+	Header    *JWSHeader `json:"header,omitempty"`
+	Protected string     `json:"protected,omitempty"`
+	Signature string     `json:"signature,omitempty"`
+}
+
+// JWSHeader is the unprotected header of the signature.
+type JWSHeader struct {
+	KID string `json:"kid,omitempty"`
+}
+
+type jwsProtected struct {
+	Alg string  `json:"alg"`
+	KID string  `json:"kid,omitempty"`
+	JWK *jwsJWK `json:"jwk,omitempty"`
+}
+
+type jwsJWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	KID string `json:"kid,omitempty"`
+}
+
+// Signer signs the data with its Ed25519 key, e.g. with the key of the DID.
+type Signer interface {
+	Sign(data []byte) ([]byte, error)
+}
+
+// KeyResolver returns the Ed25519 verification key of the key ID, which is a
+// DID URL like did:key or a key of the DID document.
+type KeyResolver func(kid string) (ed25519.PublicKey, error)
+
+// DIDKeyResolver resolves did:key key IDs offline.
+func DIDKeyResolver(kid string) (_ ed25519.PublicKey, err error) {
+	defer err2.Handle(&err, "resolve did:key %s", kid)
+
+	didKey := strings.Split(kid, "#")[0]
+	return ed25519.PublicKey(try.To1(fingerprint.PubKeyFromDIDKey(didKey))), nil
+}
+
+// SignDetached signs the payload with the signer and returns the JWS with the
+// detached payload. The kid is the DID URL of the signer's public key. The
+// public key is also included to the protected header as JWK for the other
+// implementations.
+func SignDetached(payload []byte, kid string, pubKey ed25519.PublicKey, s Signer) (_ *JWS, err error) {
+	return signB64(base64.RawURLEncoding.EncodeToString(payload), kid, pubKey, s)
+}
+
+// signB64 signs the base64 URL encoded payload as it is. The payload of the
+// inline data is signed as received, not as re-encoded.
+func signB64(b64Payload string, kid string, pubKey ed25519.PublicKey, s Signer) (_ *JWS, err error) {
+	defer err2.Handle(&err, "sign JWS")
+
+	assert.NotEmpty(kid, "key ID is needed for signing")
+	assert.That(s != nil, "signer is needed")
+
+	protected := try.To1(json.Marshal(jwsProtected{
+		Alg: AlgEdDSA,
+		KID: kid,
+		JWK: &jwsJWK{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(pubKey),
+			KID: kid,
+		},
+	}))
+	b64Protected := base64.RawURLEncoding.EncodeToString(protected)
+	sig := try.To1(s.Sign(signingInput(b64Protected, b64Payload)))
+
+	return &JWS{JWSSignature: JWSSignature{
+		Header:    &JWSHeader{KID: kid},
+		Protected: b64Protected,
+		Signature: base64.RawURLEncoding.EncodeToString(sig),
+	}}, nil
+}
+
+// AddSignature adds the signature to the JWS. The JWS is converted to the
+// general serialization when it has more than one signature.
+func (j *JWS) AddSignature(other *JWS) {
+	sigs := append(j.signatures(), other.signatures()...)
+	if len(sigs) == 1 {
+		*j = JWS{JWSSignature: sigs[0]}
+		return
+	}
+	*j = JWS{Signatures: sigs}
+}
+
+// Verify verifies all the signatures of the JWS against the detached payload.
+// The keys are resolved by their key IDs. It returns the key IDs of the
+// signers.
+func (j *JWS) Verify(payload []byte, resolve KeyResolver) (kids []string, err error) {
+	return j.verifyB64(base64.RawURLEncoding.EncodeToString(payload), resolve)
+}
+
+func (j *JWS) verifyB64(b64Payload string, resolve KeyResolver) (kids []string, err error) {
+	defer err2.Handle(&err, "verify JWS")
+
+	sigs := j.signatures()
+	if len(sigs) == 0 {
+		return nil, fmt.Errorf("no signatures")
+	}
+	kids = make([]string, 0, len(sigs))
+	for _, sig := range sigs {
+		kids = append(kids, try.To1(sig.verify(b64Payload, resolve)))
+	}
+	return kids, nil
+}
+
+func (j *JWS) signatures() []JWSSignature {
+	sigs := make([]JWSSignature, 0, len(j.Signatures)+1)
+	if j.Signature != "" {
+		sigs = append(sigs, j.JWSSignature)
+	}
+	return append(sigs, j.Signatures...)
+}
+
+func (s JWSSignature) verify(b64Payload string, resolve KeyResolver) (kid string, err error) {
+	var protected jwsProtected
+	try.To(json.Unmarshal(try.To1(base64.RawURLEncoding.DecodeString(s.Protected)), &protected))
+	if protected.Alg != AlgEdDSA {
+		return "", fmt.Errorf("unsupported algorithm: %s", protected.Alg)
+	}
+
+	kid = protected.KID
+	if kid == "" && protected.JWK != nil {
+		kid = protected.JWK.KID
+	}
+	if kid == "" && s.Header != nil {
+		kid = s.Header.KID
+	}
+	if kid == "" {
+		return "", fmt.Errorf("signature doesn't have key ID")
+	}
+
+	pubKey := try.To1(resolve(kid))
+	if len(pubKey) != ed25519.PublicKeySize {
+		return "", fmt.Errorf("invalid key of %s", kid)
+	}
+	sig := try.To1(base64.RawURLEncoding.DecodeString(s.Signature))
+	if !ed25519.Verify(pubKey, signingInput(s.Protected, b64Payload), sig) {
+		return "", fmt.Errorf("signature of %s isn't valid", kid)
+	}
+	return kid, nil
+}
+
+func signingInput(b64Protected, b64Payload string) []byte {
+	return []byte(b64Protected + "." + b64Payload)
+}
+
+// Bytes returns the content of the inline attachment data, i.e. the decoded
+// base64 data or the JSON data.
+func (d *AttachmentData) Bytes() (_ []byte, err error) {
+	defer err2.Handle(&err, "attachment data")
+
+	switch {
+	case d.Base64 != "":
+		return try.To1(base64.StdEncoding.DecodeString(d.Base64)), nil
+	case d.JSON != nil:
+		return try.To1(json.Marshal(d.JSON)), nil
+	}
+	return nil, fmt.Errorf("no inline data")
+}
+
+// Sign signs the inline data of the attachment. The JWS payload is the base64
+// data as it is in the attachment, see Aries RFC 0017. JSON data is converted
+// to base64 data before signing, because the JSON can't be signed as such: the
+// receiver wouldn't get the same bytes. If the data is already signed, the
+// signature is added to the existing ones.
+func (d *AttachmentData) Sign(kid string, pubKey ed25519.PublicKey, s Signer) (err error) {
+	defer err2.Handle(&err, "sign attachment")
+
+	if d.Base64 == "" && d.JSON != nil {
+		assert.That(d.JWS == nil, "signed JSON data")
+		d.Base64 = base64.StdEncoding.EncodeToString(try.To1(json.Marshal(d.JSON)))
+		d.JSON = nil
+	}
+	d.addJWS(try.To1(signB64(try.To1(d.b64Payload()), kid, pubKey, s)))
+	return nil
+}
+
+// SignLinked signs the content of the links of the attachment. The content is
+// fetched elsewhere, and it's signed as the detached payload. The SHA-256 of
+// the content is set to the attachment if it's missing, to make the links
+// tamper-evident.
+func (d *AttachmentData) SignLinked(content []byte, kid string, pubKey ed25519.PublicKey, s Signer) (err error) {
+	defer err2.Handle(&err, "sign linked attachment")
+
+	if len(d.Links) == 0 {
+		return fmt.Errorf("attachment doesn't have links")
+	}
+	if d.Sha256 == "" {
+		d.Sha256 = sha256Hex(content)
+	}
+	try.To(d.checkSha256(content))
+	d.addJWS(try.To1(SignDetached(content, kid, pubKey, s)))
+	return nil
+}
+
+func (d *AttachmentData) addJWS(jws *JWS) {
+	if d.JWS == nil {
+		d.JWS = jws
+		return
+	}
+	d.JWS.AddSignature(jws)
+}
+
+// Verify verifies the signatures of the inline base64 data and returns the key
+// IDs of the signers.
+func (d *AttachmentData) Verify(resolve KeyResolver) (_ []string, err error) {
+	defer err2.Handle(&err, "verify attachment")
+
+	if d.JWS == nil {
+		return nil, fmt.Errorf("attachment isn't signed")
+	}
+	return d.JWS.verifyB64(try.To1(d.b64Payload()), resolve)
+}
+
+// VerifyDetached verifies the signatures of the attachment against the
+// content fetched elsewhere, e.g. from the links of the attachment. The
+// SHA-256 of the attachment is checked as well when it's given.
+func (d *AttachmentData) VerifyDetached(content []byte, resolve KeyResolver) (_ []string, err error) {
+	defer err2.Handle(&err, "verify attachment")
+
+	if d.JWS == nil {
+		return nil, fmt.Errorf("attachment isn't signed")
+	}
+	try.To(d.checkSha256(content))
+	return d.JWS.Verify(content, resolve)
+}
+
+// b64Payload returns the base64 data in the URL encoding without padding,
+// which is the payload of the JWS.
+func (d *AttachmentData) b64Payload() (string, error) {
+	if d.Base64 == "" {
+		return "", fmt.Errorf("JWS needs base64 data")
+	}
+	b64 := strings.TrimRight(d.Base64, "=")
+	return strings.NewReplacer("+", "-", "/", "_").Replace(b64), nil
+}
+
+func (d *AttachmentData) checkSha256(content []byte) error {
+	if d.Sha256 != "" && !strings.EqualFold(d.Sha256, sha256Hex(content)) {
+		return fmt.Errorf("SHA-256 of the content doesn't match")
+	}
+	return nil
+}
+
+func sha256Hex(content []byte) string {
+	h := sha256.Sum256(content)
+	return hex.EncodeToString(h[:])
+}
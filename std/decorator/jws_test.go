@@ -0,0 +1,169 @@
+package decorator
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/vdr/fingerprint"
+)
+
+type testSigner ed25519.PrivateKey
+
+func (s testSigner) Sign(data []byte) ([]byte, error) {
+	return ed25519.Sign(ed25519.PrivateKey(s), data), nil
+}
+
+func newTestKey(t *testing.T) (kid string, pub ed25519.PublicKey, s testSigner) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, kid = fingerprint.CreateDIDKey(pub)
+	return kid, pub, testSigner(priv)
+}
+
+func TestAttachmentData_SignVerify(t *testing.T) {
+	kid, pub, s := newTestKey(t)
+	kid2, pub2, s2 := newTestKey(t)
+
+	tests := []struct {
+		name string
+		data AttachmentData
+	}{
+		{"base64", AttachmentData{Base64: base64.StdEncoding.EncodeToString([]byte("hello"))}},
+		{"json", AttachmentData{JSON: map[string]interface{}{"hello": "world"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := tt.data
+			if err := d.Sign(kid, pub, s); err != nil {
+				t.Fatalf("Sign() error = %v", err)
+			}
+			if d.JSON != nil || d.Base64 == "" {
+				t.Errorf("signed data should be base64: %+v", d)
+			}
+
+			// thru the wire
+			b, err := json.Marshal(d)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got AttachmentData
+			if err := json.Unmarshal(b, &got); err != nil {
+				t.Fatal(err)
+			}
+			kids, err := got.Verify(DIDKeyResolver)
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if !reflect.DeepEqual(kids, []string{kid}) {
+				t.Errorf("Verify() = %v, want %v", kids, []string{kid})
+			}
+
+			if err := got.Sign(kid2, pub2, s2); err != nil {
+				t.Fatalf("Sign() error = %v", err)
+			}
+			if got.JWS.Signature != "" || len(got.JWS.Signatures) != 2 {
+				t.Errorf("should be general serialization: %+v", got.JWS)
+			}
+			kids, err = got.Verify(DIDKeyResolver)
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if !reflect.DeepEqual(kids, []string{kid, kid2}) {
+				t.Errorf("Verify() = %v, want %v", kids, []string{kid, kid2})
+			}
+		})
+	}
+}
+
+func TestAttachmentData_VerifyFails(t *testing.T) {
+	kid, pub, s := newTestKey(t)
+	_, otherPub, _ := newTestKey(t)
+
+	d := AttachmentData{Base64: base64.StdEncoding.EncodeToString([]byte("hello"))}
+	if _, err := d.Verify(DIDKeyResolver); err == nil {
+		t.Error("unsigned data should not verify")
+	}
+	if err := d.Sign(kid, pub, s); err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	wrongKey := func(string) (ed25519.PublicKey, error) { return otherPub, nil }
+	if _, err := d.Verify(wrongKey); err == nil {
+		t.Error("should not verify with other key")
+	}
+
+	d.Base64 = base64.StdEncoding.EncodeToString([]byte("hellO"))
+	if _, err := d.Verify(DIDKeyResolver); err == nil {
+		t.Error("modified data should not verify")
+	}
+	if _, err := d.VerifyDetached([]byte("hello"), DIDKeyResolver); err != nil {
+		t.Errorf("VerifyDetached() error = %v", err)
+	}
+}
+
+func TestAttachmentData_VerifyAsReceived(t *testing.T) {
+	kid, pub, s := newTestKey(t)
+
+	// padded standard encoding which has the characters of the URL encoding
+	content := []byte{0xfb, 0xff, 0xbf, 0x01}
+	d := AttachmentData{Base64: base64.StdEncoding.EncodeToString(content)}
+	if err := d.Sign(kid, pub, s); err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if _, err := d.Verify(DIDKeyResolver); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	// the payload is the base64 data as received, RFC 0017
+	sig, err := base64.RawURLEncoding.DecodeString(d.JWS.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := d.JWS.Protected + "." + base64.RawURLEncoding.EncodeToString(content)
+	if !ed25519.Verify(pub, []byte(input), sig) {
+		t.Error("signing input should be the base64 URL data")
+	}
+
+	d = AttachmentData{JSON: map[string]interface{}{"hello": "world"}}
+	d.JWS = &JWS{JWSSignature: JWSSignature{Signature: "sig"}}
+	if _, err := d.Verify(DIDKeyResolver); err == nil {
+		t.Error("JSON data should not verify")
+	}
+}
+
+func TestAttachmentData_SignLinked(t *testing.T) {
+	kid, pub, s := newTestKey(t)
+	content := []byte("linked content")
+
+	d := AttachmentData{}
+	if err := d.SignLinked(content, kid, pub, s); err == nil {
+		t.Error("data without links should not be signed")
+	}
+
+	d = AttachmentData{Links: []string{"https://example.com/content"}}
+	if err := d.SignLinked(content, kid, pub, s); err != nil {
+		t.Fatalf("SignLinked() error = %v", err)
+	}
+	if d.Sha256 == "" {
+		t.Error("SHA-256 should be set")
+	}
+	kids, err := d.VerifyDetached(content, DIDKeyResolver)
+	if err != nil {
+		t.Fatalf("VerifyDetached() error = %v", err)
+	}
+	if !reflect.DeepEqual(kids, []string{kid}) {
+		t.Errorf("VerifyDetached() = %v, want %v", kids, []string{kid})
+	}
+	if _, err := d.VerifyDetached([]byte("other content"), DIDKeyResolver); err == nil {
+		t.Error("other content should not verify")
+	}
+	if _, err := d.Verify(DIDKeyResolver); err == nil {
+		t.Error("linked data doesn't have inline data to verify")
+	}
+}
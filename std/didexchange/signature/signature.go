@@ -1,8 +1,15 @@
 package signature
 
 import (
+	"bytes"
+	"crypto/ed25519"
+	"fmt"
+
+	"github.com/findy-network/findy-agent/agent/vdr"
 	"github.com/findy-network/findy-agent/core"
+	"github.com/findy-network/findy-agent/std/decorator"
 	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/pkg/vdr/fingerprint"
 	"github.com/lainio/err2"
 	"github.com/lainio/err2/try"
 	"github.com/mr-tron/base58"
@@ -45,3 +52,69 @@ func (v *Verifier) Verify(data, signature []byte) (err error) {
 func (v *Verifier) VerifyWithKey(key string, data, signature []byte) (err error) {
 	return v.verify(key, data, signature)
 }
+
+// SignAttachment signs the inline data of the attachment with the key of the
+// DID. The key ID of the signature is the did:key of the verkey.
+func (s *Signer) SignAttachment(d *decorator.AttachmentData) (err error) {
+	defer err2.Handle(&err, "sign attachment")
+
+	pubKey, kid := s.attachmentKey()
+	return d.Sign(kid, pubKey, s)
+}
+
+// SignLinkedAttachment signs the content of the links of the attachment with
+// the key of the DID. The content is the detached payload of the signature.
+func (s *Signer) SignLinkedAttachment(d *decorator.AttachmentData, content []byte) (err error) {
+	defer err2.Handle(&err, "sign linked attachment")
+
+	pubKey, kid := s.attachmentKey()
+	return d.SignLinked(content, kid, pubKey, s)
+}
+
+func (s *Signer) attachmentKey() (ed25519.PublicKey, string) {
+	pubKey := ed25519.PublicKey(try.To1(base58.Decode(s.VerKey())))
+	_, kid := fingerprint.CreateDIDKey(pubKey)
+	return pubKey, kid
+}
+
+// VerifyAttachment verifies the signatures of the attachment. The keys are
+// resolved thru the VDR of our packager, and one of the signers must be the
+// verifier's DID.
+func (v *Verifier) VerifyAttachment(d *decorator.AttachmentData) (err error) {
+	defer err2.Handle(&err, "verify attachment")
+
+	return v.verifyAttachment(func(resolve decorator.KeyResolver) ([]string, error) {
+		return d.Verify(resolve)
+	})
+}
+
+// VerifyLinkedAttachment verifies the signatures of the attachment against the
+// content of its links. One of the signers must be the verifier's DID.
+func (v *Verifier) VerifyLinkedAttachment(d *decorator.AttachmentData, content []byte) (err error) {
+	defer err2.Handle(&err, "verify linked attachment")
+
+	return v.verifyAttachment(func(resolve decorator.KeyResolver) ([]string, error) {
+		return d.VerifyDetached(content, resolve)
+	})
+}
+
+func (v *Verifier) verifyAttachment(
+	verify func(resolve decorator.KeyResolver) ([]string, error),
+) (err error) {
+	defer err2.Handle(&err)
+
+	verKey := try.To1(base58.Decode(v.VerKey()))
+	signedByDID := false
+	resolve := vdr.KeyResolver(v.Packager().VDRegistry())
+	try.To1(verify(func(kid string) (ed25519.PublicKey, error) {
+		key, err := resolve(kid)
+		if err == nil && bytes.Equal(key, verKey) {
+			signedByDID = true
+		}
+		return key, err
+	}))
+	if !signedByDID {
+		return fmt.Errorf("attachment isn't signed by %s", v.Did())
+	}
+	return nil
+}
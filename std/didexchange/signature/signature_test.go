@@ -1,6 +1,7 @@
 package signature_test
 
 import (
+	"encoding/base64"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -11,6 +12,7 @@ import (
 	"github.com/findy-network/findy-agent/agent/utils"
 	"github.com/findy-network/findy-agent/core"
 	"github.com/findy-network/findy-agent/method"
+	"github.com/findy-network/findy-agent/std/decorator"
 	"github.com/findy-network/findy-agent/std/didexchange/signature"
 	"github.com/lainio/err2"
 	"github.com/lainio/err2/assert"
@@ -97,6 +99,15 @@ func TestSignVerifyWithSeparatedWallets(t *testing.T) {
 
 			verifier := signature.Verifier{DID: didOut2}
 			assert.NoError(verifier.Verify(message, signatureData))
+
+			data := decorator.AttachmentData{
+				Base64: base64.StdEncoding.EncodeToString(message)}
+			assert.NoError(signer.SignAttachment(&data))
+			assert.NoError(verifier.VerifyAttachment(&data))
+
+			linked := decorator.AttachmentData{Links: []string{"https://example.com"}}
+			assert.NoError(signer.SignLinkedAttachment(&linked, message))
+			assert.NoError(verifier.VerifyLinkedAttachment(&linked, message))
 		})
 	}
 }
@@ -0,0 +1,95 @@
+package discoverfeatures
+
+import (
+	"encoding/gob"
+
+	"github.com/findy-network/findy-agent/agent/aries"
+	"github.com/findy-network/findy-agent/agent/didcomm"
+	"github.com/findy-network/findy-agent/agent/pltype"
+	"github.com/findy-network/findy-agent/std/decorator"
+	"github.com/findy-network/findy-common-go/dto"
+)
+
+var Creator = &Factor{}
+
+type Factor struct{}
+
+func (f *Factor) NewMsg(init didcomm.MsgInit) didcomm.MessageHdr {
+	m := &Features{
+		Type:    init.Type,
+		ID:      init.AID,
+		Comment: init.Info,
+		Thread:  decorator.CheckThread(init.Thread, init.AID),
+	}
+	return NewFeatures(m)
+}
+
+func (f *Factor) NewMessage(data []byte) didcomm.MessageHdr {
+	return NewFeaturesMsg(data)
+}
+
+func init() {
+	gob.Register(&Impl{})
+	for _, t := range []string{
+		pltype.DiscoverFeaturesQuery,
+		pltype.DiscoverFeaturesDisclose,
+		pltype.DiscoverFeaturesV2Queries,
+		pltype.DiscoverFeaturesV2Disclosures,
+		pltype.DIDOrgDiscoverFeaturesQuery,
+		pltype.DIDOrgDiscoverFeaturesDisclose,
+		pltype.DIDOrgDiscoverFeaturesV2Queries,
+		pltype.DIDOrgDiscoverFeaturesV2Disclosures,
+	} {
+		aries.Creator.Add(t, Creator)
+	}
+}
+
+func NewFeatures(r *Features) *Impl {
+	return &Impl{Features: r}
+}
+
+func NewFeaturesMsg(data []byte) *Impl {
+	var mImpl Impl
+	dto.FromJSON(data, &mImpl)
+	mImpl.checkThread()
+	return &mImpl
+}
+
+// MARK: Helpers
+
+func (p *Impl) checkThread() {
+	p.Features.Thread = decorator.CheckThread(p.Features.Thread, p.Features.ID)
+}
+
+// MARK: Struct
+type Impl struct {
+	*Features
+}
+
+func (p *Impl) ID() string {
+	return p.Features.ID
+}
+
+func (p *Impl) Type() string {
+	return p.Features.Type
+}
+
+func (p *Impl) SetID(id string) {
+	p.Features.ID = id
+}
+
+func (p *Impl) SetType(t string) {
+	p.Features.Type = t
+}
+
+func (p *Impl) JSON() []byte {
+	return dto.ToJSONBytes(p)
+}
+
+func (p *Impl) Thread() *decorator.Thread {
+	return p.Features.Thread
+}
+
+func (p *Impl) FieldObj() interface{} {
+	return p.Features
+}
@@ -0,0 +1,54 @@
+package discoverfeatures
+
+import (
+	"strings"
+
+	"github.com/findy-network/findy-agent/std/decorator"
+)
+
+// FeatureTypeProtocol is the only feature type of the v2 we disclose.
+const FeatureTypeProtocol = "protocol"
+
+// Features is the message of Aries RFC 0031 v1 and RFC 0557 v2. The same
+// struct is used for all of the messages: v1 query uses the Query, v1 disclose
+// the Protocols, v2 queries the Queries, and v2 disclosures the Disclosures.
+type Features struct {
+	Type   string            `json:"@type,omitempty"`
+	ID     string            `json:"@id,omitempty"`
+	Thread *decorator.Thread `json:"~thread,omitempty"`
+
+	Query     string     `json:"query,omitempty"`
+	Comment   string     `json:"comment,omitempty"`
+	Protocols []Protocol `json:"protocols,omitempty"`
+
+	Queries     []Query      `json:"queries,omitempty"`
+	Disclosures []Disclosure `json:"disclosures,omitempty"`
+}
+
+// Protocol is the protocol disclosed in v1.
+type Protocol struct {
+	PID   string   `json:"pid"`
+	Roles []string `json:"roles,omitempty"`
+}
+
+// Query is the query of v2.
+type Query struct {
+	FeatureType string `json:"feature-type"`
+	Match       string `json:"match"`
+}
+
+// Disclosure is the feature disclosed in v2.
+type Disclosure struct {
+	FeatureType string   `json:"feature-type"`
+	ID          string   `json:"id"`
+	Roles       []string `json:"roles,omitempty"`
+}
+
+// Match tells if the feature ID matches the query pattern. The only wildcard
+// is * at the end of the pattern.
+func Match(pattern, id string) bool {
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(id, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == id
+}
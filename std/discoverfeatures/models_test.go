@@ -0,0 +1,52 @@
+package discoverfeatures
+
+import (
+	"testing"
+
+	"github.com/findy-network/findy-agent/agent/aries"
+	"github.com/lainio/err2/assert"
+)
+
+var queryJSON = `{
+    "@type": "https://didcomm.org/discover-features/1.0/query",
+    "@id": "yWd8wfYzhmuXX3hmLNaV5bVbAjbWaU",
+    "query": "https://didcomm.org/tictactoe/1.*",
+    "comment": "I'm wondering if we can play a game..."
+  }`
+
+var queriesJSON = `{
+    "@type": "https://didcomm.org/discover-features/2.0/queries",
+    "@id": "yWd8wfYzhmuXX3hmLNaV5bVbAjbWaU",
+    "queries": [
+        { "feature-type": "protocol", "match": "https://didcomm.org/tictactoe/1.*" },
+        { "feature-type": "goal-code", "match": "org.didcomm.*" }
+    ]
+  }`
+
+func TestNewFeatures(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	ipl := aries.PayloadCreator.NewFromData([]byte(queryJSON))
+	assert.Equal(ipl.ID(), ipl.ThreadID())
+	msg, ok := ipl.MsgHdr().FieldObj().(*Features)
+	assert.That(ok)
+	assert.Equal(msg.Query, "https://didcomm.org/tictactoe/1.*")
+
+	ipl = aries.PayloadCreator.NewFromData([]byte(queriesJSON))
+	msg, ok = ipl.MsgHdr().FieldObj().(*Features)
+	assert.That(ok)
+	assert.SLen(msg.Queries, 2)
+	assert.Equal(msg.Queries[0].FeatureType, FeatureTypeProtocol)
+}
+
+func TestMatch(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	assert.That(Match("https://didcomm.org/tictactoe/1.*", "https://didcomm.org/tictactoe/1.0"))
+	assert.That(Match("*", "https://didcomm.org/tictactoe/1.0"))
+	assert.That(Match("https://didcomm.org/tictactoe/1.0", "https://didcomm.org/tictactoe/1.0"))
+	assert.That(!Match("https://didcomm.org/tictactoe/1.0", "https://didcomm.org/tictactoe/1.1"))
+	assert.That(!Match("https://didcomm.org/tictactoe/2.*", "https://didcomm.org/tictactoe/1.0"))
+}
@@ -49,7 +49,8 @@ type Propose struct {
 
 // Offer is a message sent by the Issuer to the potential Holder,
 // describing the credential they intend to offer and possibly the price they expect to be paid.
-// TODO: Need to add ~payment_request and ~timing.expires_time decorators [Issue #1297]
+// The generic decorators like ~timing are processed in prot.ExecPSM.
+// TODO: Need to add ~payment_request decorator [Issue #1297]
 type Offer struct {
 	ID   string `json:"@id,omitempty"`
 	Type string `json:"@type,omitempty"`
@@ -86,8 +87,8 @@ type Request struct {
 }
 
 // Issue contains as attached payload the credentials being issued and is
-// sent in response to a valid Request Credential message.
-// TODO: Need to add ~please-ack decorator [Issue #1299]
+// sent in response to a valid Request Credential message. The ~please_ack
+// decorator is processed in prot.ExecPSM.
 type Issue struct {
 	ID   string `json:"@id,omitempty"`
 	Type string `json:"@type,omitempty"`
@@ -0,0 +1,80 @@
+package v2
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/findy-network/findy-agent/agent/utils"
+	"github.com/findy-network/findy-agent/std/decorator"
+	"github.com/lainio/err2"
+	"github.com/lainio/err2/try"
+)
+
+var supportedFormats = map[string]bool{
+	FormatIndyProofReq:  true,
+	FormatIndyProof:     true,
+	FormatDIFDefinition: true,
+	FormatDIFSubmission: true,
+}
+
+// NewAttach builds the formats and attachments fields for the data. Indy
+// formats are sent base64 encoded and DIF formats are embedded as JSON.
+func NewAttach(format string, data []byte) ([]Format, []decorator.Attachment) {
+	id := utils.UUID()
+	ad := decorator.AttachmentData{}
+	if isJSONFormat(format) {
+		ad.JSON = json.RawMessage(data)
+	} else {
+		ad.Base64 = base64.StdEncoding.EncodeToString(data)
+	}
+	return []Format{{AttachID: id, Format: format}},
+		[]decorator.Attachment{{
+			ID:       id,
+			MimeType: "application/json",
+			Data:     ad,
+		}}
+}
+
+// SupportedFormat returns the first format we support. If there is none, it
+// returns an empty string.
+func SupportedFormat(formats []Format) string {
+	for _, f := range formats {
+		if supportedFormats[f.Format] {
+			return f.Format
+		}
+	}
+	return ""
+}
+
+// AttachData returns the data of the attachment with the format.
+func AttachData(
+	formats []Format,
+	attaches []decorator.Attachment,
+	format string,
+) (
+	data []byte,
+	err error,
+) {
+	defer err2.Handle(&err, "attachment data for %s", format)
+
+	for _, f := range formats {
+		if f.Format != format {
+			continue
+		}
+		for _, a := range attaches {
+			if a.ID != f.AttachID {
+				continue
+			}
+			if a.Data.JSON != nil {
+				return try.To1(json.Marshal(a.Data.JSON)), nil
+			}
+			return base64.StdEncoding.DecodeString(a.Data.Base64)
+		}
+	}
+	return nil, fmt.Errorf("no attachment")
+}
+
+func isJSONFormat(format string) bool {
+	return format == FormatDIFDefinition || format == FormatDIFSubmission
+}
@@ -0,0 +1,79 @@
+// Package v2 includes the message types of the Aries present proof 2.0
+// protocol (RFC 0454). The protocol carries the actual proof requests and
+// proofs as attachments whose formats are told in the formats field.
+package v2
+
+import (
+	"github.com/findy-network/findy-agent/std/decorator"
+	"github.com/hyperledger/aries-framework-go/component/models/presexch"
+)
+
+// Attachment formats we support.
+//
+//	https://github.com/hyperledger/aries-rfcs/tree/main/features/0454-present-proof-v2#presentation-formats
+const (
+	FormatIndyProofReq  = "hlindy/proof-req@v2.0"
+	FormatIndyProof     = "hlindy/proof@v2.0"
+	FormatDIFDefinition = "dif/presentation-exchange/definitions@v1.0"
+	FormatDIFSubmission = "dif/presentation-exchange/submission@v1.0"
+)
+
+// MARK: Format
+
+// Format binds the attachment ID to the attachment format.
+type Format struct {
+	AttachID string `json:"attach_id"`
+	Format   string `json:"format"`
+}
+
+// MARK: Propose
+
+type Propose struct {
+	Type            string                 `json:"@type,omitempty"`
+	ID              string                 `json:"@id,omitempty"`
+	GoalCode        string                 `json:"goal_code,omitempty"`
+	Comment         string                 `json:"comment,omitempty"`
+	Formats         []Format               `json:"formats,omitempty"`
+	ProposalsAttach []decorator.Attachment `json:"proposals~attach,omitempty"`
+	Thread          *decorator.Thread      `json:"~thread,omitempty"`
+}
+
+// MARK: Request
+
+type Request struct {
+	Type                 string                 `json:"@type,omitempty"`
+	ID                   string                 `json:"@id,omitempty"`
+	GoalCode             string                 `json:"goal_code,omitempty"`
+	Comment              string                 `json:"comment,omitempty"`
+	WillConfirm          bool                   `json:"will_confirm,omitempty"`
+	PresentMultiple      bool                   `json:"present_multiple,omitempty"`
+	Formats              []Format               `json:"formats,omitempty"`
+	RequestPresentations []decorator.Attachment `json:"request_presentations~attach,omitempty"`
+	Thread               *decorator.Thread      `json:"~thread,omitempty"`
+}
+
+// MARK: Presentation
+
+type Presentation struct {
+	Type                 string                 `json:"@type,omitempty"`
+	ID                   string                 `json:"@id,omitempty"`
+	GoalCode             string                 `json:"goal_code,omitempty"`
+	Comment              string                 `json:"comment,omitempty"`
+	Formats              []Format               `json:"formats,omitempty"`
+	PresentationAttaches []decorator.Attachment `json:"presentations~attach,omitempty"`
+	Thread               *decorator.Thread      `json:"~thread,omitempty"`
+}
+
+// MARK: DIF
+
+// DIFRequest is the attachment data of the FormatDIFDefinition.
+type DIFRequest struct {
+	Options                *DIFOptions                      `json:"options,omitempty"`
+	PresentationDefinition *presexch.PresentationDefinition `json:"presentation_definition"`
+}
+
+// DIFOptions are the proof options for the DIF presentation.
+type DIFOptions struct {
+	Challenge string `json:"challenge,omitempty"`
+	Domain    string `json:"domain,omitempty"`
+}
@@ -0,0 +1,149 @@
+package v2
+
+import (
+	"testing"
+
+	"github.com/findy-network/findy-agent/agent/aries"
+	"github.com/findy-network/findy-agent/agent/didcomm"
+	"github.com/findy-network/findy-agent/agent/pltype"
+	"github.com/findy-network/findy-agent/std/decorator"
+	"github.com/findy-network/findy-common-go/dto"
+	"github.com/lainio/err2/assert"
+)
+
+var difRequest = `{
+  "@type": "https://didcomm.org/present-proof/2.0/request-presentation",
+  "@id": "0ac534c8-98ed-4fe3-8a41-3600775e1e92",
+  "will_confirm": true,
+  "formats": [
+    {
+      "attach_id": "5d1a2f7e-9d4b-4c1f-b3b3-4d2a3d1bb0e5",
+      "format": "dif/presentation-exchange/definitions@v1.0"
+    }
+  ],
+  "request_presentations~attach": [
+    {
+      "@id": "5d1a2f7e-9d4b-4c1f-b3b3-4d2a3d1bb0e5",
+      "mime-type": "application/json",
+      "data": {
+        "json": {
+          "options": {
+            "challenge": "23516943-1d79-4ebd-8981-623f036365ef",
+            "domain": "4jt78h47fh47"
+          },
+          "presentation_definition": {
+            "id": "32f54163-7166-48f1-93d8-ff217bdb0654",
+            "input_descriptors": [
+              {
+                "id": "citizenship_input_1",
+                "name": "EU Driver's License",
+                "schema": [{"uri": "https://www.w3.org/2018/credentials#VerifiableCredential"}],
+                "constraints": {
+                  "fields": [{"path": ["$.credentialSubject.givenName"]}]
+                }
+              }
+            ]
+          }
+        }
+      }
+    }
+  ]
+}`
+
+func TestRequest_DIF(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	ipl := aries.PayloadCreator.NewFromData([]byte(difRequest))
+	assert.Equal(ipl.Type(), pltype.DIDOrgPresentProofV2Request)
+
+	req := ipl.MsgHdr().FieldObj().(*Request)
+	assert.That(req.WillConfirm)
+	assert.Equal(SupportedFormat(req.Formats), FormatDIFDefinition)
+
+	data, err := AttachData(req.Formats, req.RequestPresentations, FormatDIFDefinition)
+	assert.NoError(err)
+
+	var difReq DIFRequest
+	dto.FromJSON(data, &difReq)
+	assert.Equal(difReq.Options.Challenge, "23516943-1d79-4ebd-8981-623f036365ef")
+	assert.Equal(difReq.PresentationDefinition.ID, "32f54163-7166-48f1-93d8-ff217bdb0654")
+	assert.SLen(difReq.PresentationDefinition.InputDescriptors, 1)
+
+	_, err = AttachData(req.Formats, req.RequestPresentations, FormatIndyProofReq)
+	assert.Error(err)
+}
+
+func TestNewAttach(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	tests := []struct {
+		name   string
+		format string
+		data   string
+	}{
+		{"indy proof req", FormatIndyProofReq, `{"name":"ProofReq","version":"0.1"}`},
+		{"indy proof", FormatIndyProof, `{"proof":{}}`},
+		{"dif definition", FormatDIFDefinition, `{"presentation_definition":{"id":"1"}}`},
+		{"dif submission", FormatDIFSubmission, `{"type":["VerifiablePresentation"]}`},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.PushTester(t)
+			defer assert.PopTester()
+
+			formats, attaches := NewAttach(tt.format, []byte(tt.data))
+			msg := NewPresentation(&Presentation{
+				Type:                 pltype.PresentProofV2Presentation,
+				ID:                   "TEST_ID",
+				Formats:              formats,
+				PresentationAttaches: attaches,
+				Thread:               decorator.NewThread("TEST_ID", ""),
+			})
+
+			// go through the wire format
+			m2 := PresentationCreator.NewMessage(msg.JSON())
+			pres := m2.FieldObj().(*Presentation)
+
+			assert.Equal(SupportedFormat(pres.Formats), tt.format)
+			data, err := AttachData(pres.Formats, pres.PresentationAttaches, tt.format)
+			assert.NoError(err)
+			assert.Equal(string(data), tt.data)
+		})
+	}
+}
+
+func TestPropose_New(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	msg := aries.MsgCreator.Create(didcomm.MsgInit{
+		Type:   pltype.PresentProofV2Propose,
+		Thread: decorator.NewThread("TEST_ID", ""),
+	})
+	propose, ok := msg.FieldObj().(*Propose)
+	assert.That(ok)
+	propose.Formats, propose.ProposalsAttach = NewAttach(FormatIndyProofReq, []byte("{}"))
+
+	opl := aries.PayloadCreator.NewMsg("TEST_ID", pltype.PresentProofV2Propose, msg)
+	ipl := aries.PayloadCreator.NewFromData(opl.JSON())
+	assert.Equal(ipl.Type(), pltype.PresentProofV2Propose)
+	assert.Equal(aries.ProtocolVersionForType(ipl.Type()), "2.0")
+
+	p2 := ipl.MsgHdr().FieldObj().(*Propose)
+	assert.Equal(SupportedFormat(p2.Formats), FormatIndyProofReq)
+}
+
+func TestSupportedFormat(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	assert.Equal(SupportedFormat(nil), "")
+	assert.Equal(SupportedFormat([]Format{{AttachID: "1", Format: "anoncreds/proof-request@v1.0"}}), "")
+	assert.Equal(SupportedFormat([]Format{
+		{AttachID: "1", Format: "anoncreds/proof-request@v1.0"},
+		{AttachID: "2", Format: FormatIndyProofReq},
+	}), FormatIndyProofReq)
+}
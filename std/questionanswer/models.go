@@ -0,0 +1,58 @@
+package questionanswer
+
+import (
+	"github.com/findy-network/findy-agent/std/decorator"
+)
+
+// QuestionAnswer is the message of Aries RFC 0113 question answer protocol.
+// The same struct is used for the question and the answer.
+type QuestionAnswer struct {
+	Type   string            `json:"@type,omitempty"`
+	ID     string            `json:"@id,omitempty"`
+	Thread *decorator.Thread `json:"~thread,omitempty"`
+	Timing *decorator.Timing `json:"~timing,omitempty"`
+
+	QuestionText      string     `json:"question_text,omitempty"`
+	QuestionDetail    string     `json:"question_detail,omitempty"`
+	Nonce             string     `json:"nonce,omitempty"`
+	SignatureRequired bool       `json:"signature_required,omitempty"`
+	ValidResponses    []Response `json:"valid_responses,omitempty"`
+
+	Response    string     `json:"response,omitempty"`
+	ResponseSig *Signature `json:"response~sig,omitempty"`
+
+	// Attachments of the question, e.g. the signed request the question is
+	// about.
+	Attachments []decorator.Attachment `json:"~attach,omitempty"`
+}
+
+// Response is one of the valid responses of the question.
+type Response struct {
+	Text string `json:"text"`
+}
+
+// Signature is the signature decorator of the response. The signed data is
+// the 8 byte big endian timestamp followed by SignedData.
+type Signature struct {
+	Type      string `json:"@type,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	SigData   string `json:"sig_data,omitempty"`
+	Signer    string `json:"signer,omitempty"`
+}
+
+// SignedData returns the data the responder signs: the question text, the
+// response and the nonce of the question.
+func SignedData(questionText, response, nonce string) []byte {
+	return []byte(questionText + response + nonce)
+}
+
+// IsValid tells if the response is one of the valid responses of the
+// question.
+func (q *QuestionAnswer) IsValid(response string) bool {
+	for _, r := range q.ValidResponses {
+		if r.Text == response {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package questionanswer
+
+import (
+	"testing"
+
+	"github.com/findy-network/findy-agent/agent/aries"
+	"github.com/lainio/err2/assert"
+)
+
+var questionJSON = `{
+    "@type": "https://didcomm.org/questionanswer/1.0/question",
+    "@id": "518be002-de8e-456e-b3d5-8fe472477a86",
+    "question_text": "Alice, are you on the phone with Bob from Faber Bank right now?",
+    "question_detail": "This is optional fine-print giving context to the question and its various answers.",
+    "nonce": "<valid_nonce>",
+    "signature_required": true,
+    "valid_responses": [
+      {"text": "Yes, it's me"},
+      {"text": "No, that's not me!"}
+    ],
+    "~timing": {
+      "expires_time": "2018-12-13T17:29:06Z"
+    }
+  }`
+
+var answerJSON = `{
+    "@type": "https://didcomm.org/questionanswer/1.0/answer",
+    "@id": "8ae5e3bc-9ea1-45df-bea6-c5d6c7d1ab08",
+    "~thread": { "thid": "518be002-de8e-456e-b3d5-8fe472477a86" },
+    "response": "Yes, it's me",
+    "response~sig": {
+      "@type": "https://didcomm.org/signature/1.0/ed25519Sha512_single",
+      "signature": "c2lnbmF0dXJl",
+      "sig_data": "ZGF0YQ==",
+      "signer": "signer-verkey"
+    }
+  }`
+
+func TestNewQuestionAnswer(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	ipl := aries.PayloadCreator.NewFromData([]byte(questionJSON))
+	msg, ok := ipl.MsgHdr().FieldObj().(*QuestionAnswer)
+	assert.That(ok)
+	assert.Equal(msg.Nonce, "<valid_nonce>")
+	assert.That(msg.SignatureRequired)
+	assert.SLen(msg.ValidResponses, 2)
+	assert.That(msg.IsValid("Yes, it's me"))
+	assert.That(!msg.IsValid("Maybe"))
+
+	ipl = aries.PayloadCreator.NewFromData([]byte(answerJSON))
+	assert.Equal(ipl.ThreadID(), "518be002-de8e-456e-b3d5-8fe472477a86")
+	msg, ok = ipl.MsgHdr().FieldObj().(*QuestionAnswer)
+	assert.That(ok)
+	assert.Equal(msg.Response, "Yes, it's me")
+	assert.That(msg.ResponseSig != nil)
+	assert.Equal(msg.ResponseSig.Signer, "signer-verkey")
+}
@@ -0,0 +1,90 @@
+package questionanswer
+
+import (
+	"encoding/gob"
+
+	"github.com/findy-network/findy-agent/agent/aries"
+	"github.com/findy-network/findy-agent/agent/didcomm"
+	"github.com/findy-network/findy-agent/agent/pltype"
+	"github.com/findy-network/findy-agent/std/decorator"
+	"github.com/findy-network/findy-common-go/dto"
+)
+
+var Creator = &Factor{}
+
+type Factor struct{}
+
+func (f *Factor) NewMsg(init didcomm.MsgInit) didcomm.MessageHdr {
+	m := &QuestionAnswer{
+		Type:   init.Type,
+		ID:     init.AID,
+		Thread: decorator.CheckThread(init.Thread, init.AID),
+	}
+	return NewQuestionAnswer(m)
+}
+
+func (f *Factor) NewMessage(data []byte) didcomm.MessageHdr {
+	return NewQuestionAnswerMsg(data)
+}
+
+func init() {
+	gob.Register(&Impl{})
+	for _, t := range []string{
+		pltype.QuestionAnswerQuestion,
+		pltype.QuestionAnswerAnswer,
+		pltype.DIDOrgQuestionAnswerQuestion,
+		pltype.DIDOrgQuestionAnswerAnswer,
+	} {
+		aries.Creator.Add(t, Creator)
+	}
+}
+
+func NewQuestionAnswer(r *QuestionAnswer) *Impl {
+	return &Impl{QuestionAnswer: r}
+}
+
+func NewQuestionAnswerMsg(data []byte) *Impl {
+	var mImpl Impl
+	dto.FromJSON(data, &mImpl)
+	mImpl.checkThread()
+	return &mImpl
+}
+
+// MARK: Helpers
+
+func (p *Impl) checkThread() {
+	p.QuestionAnswer.Thread = decorator.CheckThread(p.QuestionAnswer.Thread, p.QuestionAnswer.ID)
+}
+
+// MARK: Struct
+type Impl struct {
+	*QuestionAnswer
+}
+
+func (p *Impl) ID() string {
+	return p.QuestionAnswer.ID
+}
+
+func (p *Impl) Type() string {
+	return p.QuestionAnswer.Type
+}
+
+func (p *Impl) SetID(id string) {
+	p.QuestionAnswer.ID = id
+}
+
+func (p *Impl) SetType(t string) {
+	p.QuestionAnswer.Type = t
+}
+
+func (p *Impl) JSON() []byte {
+	return dto.ToJSONBytes(p)
+}
+
+func (p *Impl) Thread() *decorator.Thread {
+	return p.QuestionAnswer.Thread
+}
+
+func (p *Impl) FieldObj() interface{} {
+	return p.QuestionAnswer
+}
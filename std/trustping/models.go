@@ -0,0 +1,23 @@
+package trustping
+
+import (
+	"github.com/findy-network/findy-agent/std/decorator"
+)
+
+// Ping is the message of Aries RFC 0048 trust ping protocol. The same struct
+// is used for the ping and the ping response.
+type Ping struct {
+	Type    string            `json:"@type,omitempty"`
+	ID      string            `json:"@id,omitempty"`
+	Thread  *decorator.Thread `json:"~thread,omitempty"`
+	Timing  *decorator.Timing `json:"~timing,omitempty"`
+	Comment string            `json:"comment,omitempty"`
+
+	// ResponseRequested is true by default, i.e. when it's missing.
+	ResponseRequested *bool `json:"response_requested,omitempty"`
+}
+
+// WantsResponse tells if the sender of the ping wants a response to it.
+func (p *Ping) WantsResponse() bool {
+	return p.ResponseRequested == nil || *p.ResponseRequested
+}
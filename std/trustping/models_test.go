@@ -0,0 +1,44 @@
+package trustping
+
+import (
+	"testing"
+
+	"github.com/findy-network/findy-agent/agent/aries"
+	"github.com/lainio/err2/assert"
+)
+
+var pingJSON = `{
+    "@type": "https://didcomm.org/trust_ping/1.0/ping",
+    "@id": "518be002-de8e-456e-b3d5-8fe472477a86",
+    "~timing": {
+      "out_time": "2018-12-15T04:29:23Z",
+      "expires_time": "2018-12-15T05:29:23Z",
+      "delay_milli": 0
+    },
+    "comment": "Hi. Are you listening?",
+    "response_requested": false
+  }`
+
+var responseJSON = `{
+    "@type": "https://didcomm.org/trust_ping/1.0/ping_response",
+    "@id": "e002518b-456e-b3d5-de8e-7a86fe472847",
+    "~thread": { "thid": "518be002-de8e-456e-b3d5-8fe472477a86" },
+    "comment": "Hi yourself. I'm here."
+  }`
+
+func TestNewPing(t *testing.T) {
+	assert.PushTester(t)
+	defer assert.PopTester()
+
+	ipl := aries.PayloadCreator.NewFromData([]byte(pingJSON))
+	msg, ok := ipl.MsgHdr().FieldObj().(*Ping)
+	assert.That(ok)
+	assert.Equal(msg.Comment, "Hi. Are you listening?")
+	assert.That(!msg.WantsResponse())
+
+	ipl = aries.PayloadCreator.NewFromData([]byte(responseJSON))
+	assert.Equal(ipl.ThreadID(), "518be002-de8e-456e-b3d5-8fe472477a86")
+	msg, ok = ipl.MsgHdr().FieldObj().(*Ping)
+	assert.That(ok)
+	assert.That(msg.WantsResponse())
+}
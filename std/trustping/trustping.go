@@ -0,0 +1,91 @@
+package trustping
+
+import (
+	"encoding/gob"
+
+	"github.com/findy-network/findy-agent/agent/aries"
+	"github.com/findy-network/findy-agent/agent/didcomm"
+	"github.com/findy-network/findy-agent/agent/pltype"
+	"github.com/findy-network/findy-agent/std/decorator"
+	"github.com/findy-network/findy-common-go/dto"
+)
+
+var Creator = &Factor{}
+
+type Factor struct{}
+
+func (f *Factor) NewMsg(init didcomm.MsgInit) didcomm.MessageHdr {
+	m := &Ping{
+		Type:    init.Type,
+		ID:      init.AID,
+		Comment: init.Info,
+		Thread:  decorator.CheckThread(init.Thread, init.AID),
+	}
+	return NewPing(m)
+}
+
+func (f *Factor) NewMessage(data []byte) didcomm.MessageHdr {
+	return NewPingMsg(data)
+}
+
+func init() {
+	gob.Register(&Impl{})
+	for _, t := range []string{
+		pltype.TrustPingPing,
+		pltype.TrustPingResponse,
+		pltype.DIDOrgTrustPingPing,
+		pltype.DIDOrgTrustPingResponse,
+	} {
+		aries.Creator.Add(t, Creator)
+	}
+}
+
+func NewPing(r *Ping) *Impl {
+	return &Impl{Ping: r}
+}
+
+func NewPingMsg(data []byte) *Impl {
+	var mImpl Impl
+	dto.FromJSON(data, &mImpl)
+	mImpl.checkThread()
+	return &mImpl
+}
+
+// MARK: Helpers
+
+func (p *Impl) checkThread() {
+	p.Ping.Thread = decorator.CheckThread(p.Ping.Thread, p.Ping.ID)
+}
+
+// MARK: Struct
+type Impl struct {
+	*Ping
+}
+
+func (p *Impl) ID() string {
+	return p.Ping.ID
+}
+
+func (p *Impl) Type() string {
+	return p.Ping.Type
+}
+
+func (p *Impl) SetID(id string) {
+	p.Ping.ID = id
+}
+
+func (p *Impl) SetType(t string) {
+	p.Ping.Type = t
+}
+
+func (p *Impl) JSON() []byte {
+	return dto.ToJSONBytes(p)
+}
+
+func (p *Impl) Thread() *decorator.Thread {
+	return p.Ping.Thread
+}
+
+func (p *Impl) FieldObj() interface{} {
+	return p.Ping
+}